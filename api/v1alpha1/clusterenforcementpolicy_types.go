@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ClusterEnforcementPolicySpec defines the desired state of ClusterEnforcementPolicy
+type ClusterEnforcementPolicySpec struct {
+	// Rules map the severity of an intent and the namespace it is bound in to the
+	// action to enforce it with. Rules are evaluated in order and the first
+	// matching rule decides the effective action.
+	Rules []EnforcementRule `json:"rules"`
+}
+
+// EnforcementRule overrides the action of the intents it matches.
+type EnforcementRule struct {
+	// Severities of the intents the rule applies to, e.g. Critical. Matching is
+	// case-insensitive. Applies to intents of any severity when empty.
+	Severities []string `json:"severities,omitempty"`
+
+	// Namespaces the rule applies to. Applies to any namespace when both
+	// Namespaces and NamespaceSelector are empty. Rules restricted to namespaces
+	// never apply to cluster-wide policies.
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// NamespaceSelector selects the namespaces the rule applies to by their
+	// labels.
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// Action is the effective action of the matching intents.
+	//+kubebuilder:validation:Enum=Audit;Block;Warn;Allow;Mutate
+	Action string `json:"action"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster,shortName="cep"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// ClusterEnforcementPolicy is the Schema for the clusterenforcementpolicies API.
+// It lets cluster administrators decide the effective action of intents based
+// on their severity and on the namespace they are bound in. Policies are
+// evaluated in name order.
+type ClusterEnforcementPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ClusterEnforcementPolicySpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ClusterEnforcementPolicyList contains a list of ClusterEnforcementPolicy
+type ClusterEnforcementPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterEnforcementPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterEnforcementPolicy{}, &ClusterEnforcementPolicyList{})
+}
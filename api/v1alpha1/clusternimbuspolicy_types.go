@@ -13,14 +13,32 @@ type ClusterNimbusPolicySpec struct {
 	NsSelector       NamespaceSelector `json:"nsSelector,omitempty"`
 	WorkloadSelector LabelSelector     `json:"workloadSelector,omitempty"`
 	NimbusRules      []NimbusRules     `json:"rules"`
+	// ExcludedNamespaces lists, per intent ID, the selected namespaces where
+	// a conflicting NimbusPolicy takes precedence, so that the cluster-wide
+	// policies don't enforce the intent there.
+	ExcludedNamespaces map[string][]string `json:"excludedNamespaces,omitempty"`
 }
 
 // ClusterNimbusPolicyStatus defines the observed state of ClusterNimbusPolicy
 type ClusterNimbusPolicyStatus struct {
-	Status                  string      `json:"status"`
-	LastUpdated             metav1.Time `json:"lastUpdated,omitempty"`
-	NumberOfAdapterPolicies int32       `json:"numberOfAdapterPolicies"`
-	Policies                []string    `json:"adapterPolicies,omitempty"`
+	Status                  string           `json:"status"`
+	LastUpdated             metav1.Time      `json:"lastUpdated,omitempty"`
+	NumberOfAdapterPolicies int32            `json:"numberOfAdapterPolicies"`
+	Policies                []string         `json:"adapterPolicies,omitempty"`
+	ActionDecisions         []ActionDecision `json:"actionDecisions,omitempty"`
+	// UnsupportedActions are reported by the adapters for the rules they
+	// skipped because their engine can't enforce the effective action.
+	UnsupportedActions []UnsupportedAction `json:"unsupportedActions,omitempty"`
+	// UnsupportedSelectors are the security engines whose adapters skipped the
+	// policy because they can't select the workloads of its workload selector,
+	// e.g. by expressions.
+	UnsupportedSelectors []string `json:"unsupportedSelectors,omitempty"`
+	// PolicyReports sum up, per rule, the results the security engines
+	// reported for the resources checked against the adapter policies.
+	PolicyReports []PolicyReportSummary `json:"policyReports,omitempty"`
+	// Conflicts are the intents that conflicting NimbusPolicies take
+	// precedence over in some namespaces, listed in ExcludedNamespaces.
+	Conflicts []PolicyConflict `json:"conflicts,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -19,21 +19,37 @@ type ClusterMatchWorkloads struct {
 }
 
 // ClusterSecurityIntentBindingSpec defines the desired state of ClusterSecurityIntentBinding
+// +kubebuilder:validation:XValidation:rule="has(self.intents) || has(self.intentSelector)",message="at least one of intents or intentSelector must be set"
 type ClusterSecurityIntentBindingSpec struct {
-	Intents  []MatchIntent         `json:"intents"`
-	Selector ClusterMatchWorkloads `json:"selector,omitempty"`
-	CEL      []string              `json:"cel,omitempty"`
+	Intents        []MatchIntent         `json:"intents,omitempty"`
+	IntentSelector *IntentSelector       `json:"intentSelector,omitempty"`
+	Selector       ClusterMatchWorkloads `json:"selector,omitempty"`
+	CEL            []string              `json:"cel,omitempty"`
+	// Rollout enforces the bound Block intents progressively, auditing them
+	// until no violation was reported for a quiet period.
+	Rollout *RolloutStrategy `json:"rollout,omitempty"`
 }
 
 // ClusterSecurityIntentBindingStatus defines the observed state of ClusterSecurityIntentBinding
 type ClusterSecurityIntentBindingStatus struct {
-	Status                 string      `json:"status"`
-	LastUpdated            metav1.Time `json:"lastUpdated,omitempty"`
-	NumberOfBoundIntents   int32       `json:"numberOfBoundIntents"`
-	BoundIntents           []string    `json:"boundIntents,omitempty"`
-	ClusterNimbusPolicy    string      `json:"clusterNimbusPolicy"`
-	NumberOfNimbusPolicies int32       `json:"numberOfNimbusPolicies"`
-	NimbusPolicyNamespaces []string    `json:"nimbusPolicyNamespaces,omitempty"`
+	Status                 string             `json:"status"`
+	LastUpdated            metav1.Time        `json:"lastUpdated,omitempty"`
+	NumberOfBoundIntents   int32              `json:"numberOfBoundIntents"`
+	BoundIntents           []string           `json:"boundIntents,omitempty"`
+	ClusterNimbusPolicy    string             `json:"clusterNimbusPolicy"`
+	NumberOfNimbusPolicies int32              `json:"numberOfNimbusPolicies"`
+	NimbusPolicyNamespaces []string           `json:"nimbusPolicyNamespaces,omitempty"`
+	IntentStatuses         []IntentStatus     `json:"intentStatuses,omitempty"`
+	EnforcementPhase       EnforcementPhase   `json:"enforcementPhase,omitempty"`
+	UnresolvedIntents      []UnresolvedIntent `json:"unresolvedIntents,omitempty"`
+	Conflicts              []PolicyConflict   `json:"conflicts,omitempty"`
+	// Violations are reported by the adapters that relay the alerts of their
+	// security engine.
+	Violations []IntentViolation `json:"violations,omitempty"`
+	// PolicyReports are reported by the adapters that aggregate the policy
+	// reports of their security engine.
+	PolicyReports []PolicyReportSummary `json:"policyReports,omitempty"`
+	Rollout       *RolloutStatus        `json:"rollout,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -44,6 +60,8 @@ type ClusterSecurityIntentBindingStatus struct {
 //+kubebuilder:printcolumn:name="Intents",type="integer",JSONPath=".status.numberOfBoundIntents"
 //+kubebuilder:printcolumn:name="NimbusPolicies",type="integer",JSONPath=".status.numberOfNimbusPolicies"
 //+kubebuilder:printcolumn:name="ClusterNimbusPolicy",type="string",JSONPath=".status.clusterNimbusPolicy"
+//+kubebuilder:printcolumn:name="Enforcement",type="string",JSONPath=".status.enforcementPhase"
+//+kubebuilder:printcolumn:name="Rollout",type="string",JSONPath=".status.rollout.phase",priority=1
 //+k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 // ClusterSecurityIntentBinding is the Schema for the clustersecurityintentbindings API
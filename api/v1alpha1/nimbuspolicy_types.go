@@ -27,16 +27,133 @@ type NimbusRules struct {
 }
 
 type Rule struct {
+	//+kubebuilder:validation:Enum=Audit;Block;Warn;Allow;Mutate
 	RuleAction string              `json:"action"`
 	Params     map[string][]string `json:"params,omitempty"`
 }
 
+// ActionDecision records how the effective action of a rule was decided.
+type ActionDecision struct {
+	ID       string `json:"id"`
+	Severity string `json:"severity,omitempty"`
+	// RequestedAction is the action set in the SecurityIntent.
+	RequestedAction string `json:"requestedAction"`
+	// EffectiveAction is the action the rule is enforced with.
+	EffectiveAction string `json:"effectiveAction"`
+	// DecidedBy is the ClusterEnforcementPolicy rule that decided the effective
+	// action, as "<name>/rules/<index>", or "rollout" when the rollout of the
+	// binding audits its Block intents. Empty when no rule matched and the
+	// requested action is used.
+	DecidedBy string `json:"decidedBy,omitempty"`
+	// RejectedAction is the action set by a matching ClusterEnforcementPolicy
+	// rule that none of the engines supporting the intent can enforce it
+	// with, so that the rule was ignored. Empty when no rule was rejected.
+	RejectedAction string `json:"rejectedAction,omitempty"`
+	// RejectedBy is the rule that set the RejectedAction, as
+	// "<name>/rules/<index>".
+	RejectedBy string `json:"rejectedBy,omitempty"`
+	// RejectionReason explains why the RejectedAction was rejected.
+	RejectionReason string `json:"rejectionReason,omitempty"`
+}
+
+// UnsupportedAction describes a rule whose intent a security engine supports
+// but can't enforce with the effective action, so that the engine skipped it.
+type UnsupportedAction struct {
+	ID     string `json:"id"`
+	Engine string `json:"engine"`
+	// Action is the effective action of the rule.
+	Action string `json:"action"`
+}
+
+// Reasons of the PolicyConflict resolutions.
+const (
+	// ConflictReasonNamespacedBinding is used when the NimbusPolicy of a
+	// SecurityIntentBinding wins over one generated for a
+	// ClusterSecurityIntentBinding.
+	ConflictReasonNamespacedBinding = "NamespacedBindingWins"
+	// ConflictReasonStricterAction is used when the NimbusPolicy with the
+	// stricter action wins.
+	ConflictReasonStricterAction = "StricterActionWins"
+)
+
+// PolicyConflict describes an intent that another NimbusPolicy in the same
+// namespace also binds, or binds an incompatible intent of, with an
+// overlapping selector but a different action.
+type PolicyConflict struct {
+	// ID of the conflicting intent.
+	ID string `json:"id"`
+	// ConflictingID is the intent of the other NimbusPolicy when it differs
+	// from ID, that is when they are incompatible since the engines enforce
+	// them with the same policies.
+	ConflictingID string `json:"conflictingId,omitempty"`
+	// Namespace of the conflicting NimbusPolicies. Only set in the status of
+	// ClusterSecurityIntentBindings and ClusterNimbusPolicies.
+	Namespace string `json:"namespace,omitempty"`
+	// NimbusPolicy is the name of the other NimbusPolicy.
+	NimbusPolicy string `json:"nimbusPolicy"`
+	// Binding owning the other NimbusPolicy, as "<kind>/<name>".
+	Binding string `json:"binding,omitempty"`
+	// Action is the effective action decided for the intent in this policy.
+	Action string `json:"action"`
+	// ConflictingAction is the effective action decided for the intent in the
+	// other NimbusPolicy.
+	ConflictingAction string `json:"conflictingAction"`
+	// ResolvedAction is the action both NimbusPolicies enforce the intent with.
+	ResolvedAction string `json:"resolvedAction"`
+	// Reason explains why ResolvedAction won.
+	Reason string `json:"reason"`
+}
+
+// States of the virtual-patch feed.
+const (
+	// VirtualPatchFeedLoaded is used when the feed was loaded, verified and
+	// validated.
+	VirtualPatchFeedLoaded = "Loaded"
+	// VirtualPatchFeedFailed is used when the feed couldn't be loaded, verified
+	// or validated.
+	VirtualPatchFeedFailed = "Failed"
+)
+
+// VirtualPatchFeedStatus describes the feed of virtual patches the policies of
+// the virtualPatch intent are built from.
+type VirtualPatchFeedStatus struct {
+	// Source of the feed, e.g. "configmap://nimbus/nimbus-virtual-patch-feed".
+	Source string `json:"source"`
+	// State is either Loaded or Failed.
+	State string `json:"state"`
+	// Message explains why the feed failed to load.
+	Message string `json:"message,omitempty"`
+	// SchemaVersion of the loaded feed.
+	SchemaVersion string `json:"schemaVersion,omitempty"`
+	// Digest of the loaded feed, as "sha256:<hex>".
+	Digest string `json:"digest,omitempty"`
+	// Verification of the loaded feed: Signature, Checksum or None.
+	Verification string `json:"verification,omitempty"`
+	// LastLoaded is when the feed was last loaded, or failed to load.
+	LastLoaded metav1.Time `json:"lastLoaded,omitempty"`
+}
+
 // NimbusPolicyStatus defines the observed state of NimbusPolicy
 type NimbusPolicyStatus struct {
-	Status                  string      `json:"status"`
-	LastUpdated             metav1.Time `json:"lastUpdated,omitempty"`
-	NumberOfAdapterPolicies int32       `json:"numberOfAdapterPolicies"`
-	Policies                []string    `json:"adapterPolicies,omitempty"`
+	Status                  string           `json:"status"`
+	LastUpdated             metav1.Time      `json:"lastUpdated,omitempty"`
+	NumberOfAdapterPolicies int32            `json:"numberOfAdapterPolicies"`
+	Policies                []string         `json:"adapterPolicies,omitempty"`
+	ActionDecisions         []ActionDecision `json:"actionDecisions,omitempty"`
+	// UnsupportedActions are reported by the adapters for the rules they
+	// skipped because their engine can't enforce the effective action.
+	UnsupportedActions []UnsupportedAction `json:"unsupportedActions,omitempty"`
+	// UnsupportedSelectors are the security engines whose adapters skipped the
+	// policy because they can't select the workloads of its workload selector,
+	// e.g. by expressions.
+	UnsupportedSelectors []string         `json:"unsupportedSelectors,omitempty"`
+	Conflicts            []PolicyConflict `json:"conflicts,omitempty"`
+	// VirtualPatchFeed is the feed the virtualPatch intent was last enforced
+	// with. Only set when the policy has the virtualPatch intent.
+	VirtualPatchFeed *VirtualPatchFeedStatus `json:"virtualPatchFeed,omitempty"`
+	// PolicyReports sum up, per rule, the results the security engines
+	// reported for the resources checked against the adapter policies.
+	PolicyReports []PolicyReportSummary `json:"policyReports,omitempty"`
 }
 
 //+kubebuilder:object:root=true
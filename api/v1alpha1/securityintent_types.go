@@ -12,6 +12,20 @@ type SecurityIntentSpec struct {
 	Intent Intent `json:"intent"` // Define the details of the security policy.
 }
 
+// Actions are the normalized vocabulary that intents are enforced with.
+const (
+	// ActionAudit logs the violations without preventing them.
+	ActionAudit = "Audit"
+	// ActionBlock prevents the violations.
+	ActionBlock = "Block"
+	// ActionWarn lets the violations happen while warning the user causing them.
+	ActionWarn = "Warn"
+	// ActionAllow explicitly allows the described behavior.
+	ActionAllow = "Allow"
+	// ActionMutate mutates the workloads to comply with the intent.
+	ActionMutate = "Mutate"
+)
+
 // Intent defines the security policy details
 type Intent struct {
 	// ID is predefined in adapter ID pool.
@@ -22,7 +36,10 @@ type Intent struct {
 	// Description is human-readable explanation of the intent's purpose.
 	Description string `json:"description,omitempty"`
 
-	// Action defines how the security policy will be enforced.
+	// Action defines how the security policy will be enforced. Every adapter
+	// maps it to the actions of its security engine, and reports the intents
+	// whose action it doesn't support.
+	//+kubebuilder:validation:Enum=Audit;Block;Warn;Allow;Mutate
 	Action string `json:"action"`
 
 	// Severity defines the potential impact of a security violation related to the intent.
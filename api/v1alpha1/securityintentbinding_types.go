@@ -8,10 +8,57 @@ import (
 )
 
 // SecurityIntentBindingSpec defines the desired state of SecurityIntentBinding
+// +kubebuilder:validation:XValidation:rule="has(self.intents) || has(self.intentSelector)",message="at least one of intents or intentSelector must be set"
 type SecurityIntentBindingSpec struct {
-	Intents  []MatchIntent  `json:"intents"`
-	Selector MatchWorkloads `json:"selector"`
-	CEL      []string       `json:"cel,omitempty"`
+	Intents        []MatchIntent   `json:"intents,omitempty"`
+	IntentSelector *IntentSelector `json:"intentSelector,omitempty"`
+	Selector       MatchWorkloads  `json:"selector"`
+	CEL            []string        `json:"cel,omitempty"`
+	// Rollout enforces the bound Block intents progressively, auditing them
+	// until no violation was reported for a quiet period.
+	Rollout *RolloutStrategy `json:"rollout,omitempty"`
+}
+
+// RolloutStrategy describes how the Block intents of a binding are rolled out.
+// They are enforced with Audit first, and promoted to Block once the engines
+// didn't report any violation of them for the quiet period. The rollout holds
+// if violations are reported, until the spec of the binding changes.
+type RolloutStrategy struct {
+	// QuietPeriod is how long the Block intents are audited without violations
+	// before being promoted to Block.
+	// +kubebuilder:default="24h"
+	QuietPeriod metav1.Duration `json:"quietPeriod,omitempty"`
+}
+
+// RolloutPhase is the phase of the rollout of the Block intents of a binding.
+// +kubebuilder:validation:Enum=Auditing;Holding;Promoted
+type RolloutPhase string
+
+const (
+	// RolloutPhaseAuditing means that the Block intents are audited until the
+	// quiet period elapses.
+	RolloutPhaseAuditing RolloutPhase = "Auditing"
+	// RolloutPhaseHolding means that violations were reported during the audit,
+	// so the Block intents are kept audited.
+	RolloutPhaseHolding RolloutPhase = "Holding"
+	// RolloutPhasePromoted means that the Block intents are enforced with Block.
+	RolloutPhasePromoted RolloutPhase = "Promoted"
+)
+
+// RolloutStatus is the observed state of the rollout of a binding.
+type RolloutStatus struct {
+	Phase RolloutPhase `json:"phase"`
+	// ObservedGeneration is the generation of the binding the rollout started
+	// for. Changing the spec of the binding restarts the rollout.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// AuditStartTime is when the Block intents started being audited.
+	AuditStartTime metav1.Time `json:"auditStartTime,omitempty"`
+	// PromotionTime is when the Block intents were promoted to Block.
+	PromotionTime *metav1.Time `json:"promotionTime,omitempty"`
+	// ViolatedIntents are the IDs of the intents whose violations hold the
+	// rollout.
+	ViolatedIntents []string `json:"violatedIntents,omitempty"`
+	Message         string   `json:"message,omitempty"`
 }
 
 // MatchIntent struct defines the request for a specific SecurityIntent
@@ -19,22 +66,155 @@ type MatchIntent struct {
 	Name string `json:"name"`
 }
 
+// IntentSelector selects SecurityIntents by their tags and labels. A
+// SecurityIntent is selected when it has all the given tags and labels.
+// +kubebuilder:validation:XValidation:rule="has(self.matchTags) || has(self.matchLabels)",message="at least one of matchTags or matchLabels must be set"
+type IntentSelector struct {
+	MatchTags   []string          `json:"matchTags,omitempty"`
+	MatchLabels map[string]string `json:"matchLabels,omitempty"`
+}
+
 // Selector defines the selection criteria for resources
 type MatchWorkloads struct {
 	WorkloadSelector LabelSelector `json:"workloadSelector,omitempty"`
 }
 
 type LabelSelector struct {
-	MatchLabels map[string]string `json:"matchLabels,omitempty"`
+	MatchLabels      map[string]string                 `json:"matchLabels,omitempty"`
+	MatchExpressions []metav1.LabelSelectorRequirement `json:"matchExpressions,omitempty"`
+}
+
+// EnforcementPhase describes how much of a binding, or of a single bound
+// intent, is enforced by the security engines.
+// +kubebuilder:validation:Enum=Enforced;Partial;NotEnforced
+type EnforcementPhase string
+
+const (
+	// EnforcementPhaseEnforced means that every intent is enforced by at least
+	// one engine policy.
+	EnforcementPhaseEnforced EnforcementPhase = "Enforced"
+	// EnforcementPhasePartial means that only some of the intents, or only some
+	// of the namespaces of a cluster binding, are enforced.
+	EnforcementPhasePartial EnforcementPhase = "Partial"
+	// EnforcementPhaseNotEnforced means that no engine policy enforces the
+	// intent(s) yet.
+	EnforcementPhaseNotEnforced EnforcementPhase = "NotEnforced"
+)
+
+// IntentStatus is the enforcement status of a single bound SecurityIntent as
+// reported by the adapters through the NimbusPolicy status.
+type IntentStatus struct {
+	// Name of the bound SecurityIntent.
+	Name string `json:"name"`
+	// ID of the bound SecurityIntent.
+	ID string `json:"id"`
+	// Engines that enforce the intent, e.g. kubearmor, netpol, kyverno or k8tls.
+	Engines []string `json:"engines,omitempty"`
+	// Policies are the engine policies that enforce the intent.
+	Policies []string         `json:"policies,omitempty"`
+	Phase    EnforcementPhase `json:"phase"`
+}
+
+// UnresolvedIntent is a SecurityIntent referenced by a binding that couldn't be
+// resolved.
+type UnresolvedIntent struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// MaxViolationSamples is the number of alerts kept as samples of an
+// IntentViolation.
+const MaxViolationSamples = 5
+
+// IntentViolation counts the alerts the security engines raised for the
+// policies that enforce a bound intent.
+type IntentViolation struct {
+	// ID of the violated intent.
+	ID string `json:"id"`
+	// Engine that raised the alerts, e.g. kubearmor.
+	Engine string `json:"engine"`
+	// Count is the number of alerts raised so far.
+	Count    int64       `json:"count"`
+	LastSeen metav1.Time `json:"lastSeen,omitempty"`
+	// Samples are the last alerts, the most recent first.
+	Samples []ViolationSample `json:"samples,omitempty"`
+}
+
+// ViolationSample describes an alert raised for a violated intent.
+type ViolationSample struct {
+	Time metav1.Time `json:"time"`
+	// Policy is the engine policy that raised the alert, as "<kind>/<name>".
+	Policy    string `json:"policy"`
+	Namespace string `json:"namespace,omitempty"`
+	Pod       string `json:"pod,omitempty"`
+	Container string `json:"container,omitempty"`
+	// Operation is the kind of the violating operation, e.g. Process, File,
+	// Network or Capabilities.
+	Operation string `json:"operation,omitempty"`
+	// Resource is the resource the operation was performed on, e.g. the path
+	// of the executed binary.
+	Resource string `json:"resource,omitempty"`
+	// Source is the process that performed the operation.
+	Source string `json:"source,omitempty"`
+	// Action is the action of the engine, e.g. Block or Audit.
+	Action string `json:"action,omitempty"`
+}
+
+// MaxReportedResources is the number of resources kept in a
+// PolicyReportSummary.
+const MaxReportedResources = 10
+
+// PolicyReportSummary sums up the results the security engine reported for
+// the resources checked against the policies that enforce an intent, e.g. in
+// the Kyverno PolicyReports.
+type PolicyReportSummary struct {
+	// ID of the intent.
+	ID string `json:"id"`
+	// Engine that reported the results, e.g. kyverno.
+	Engine string `json:"engine"`
+	Pass   int32  `json:"pass"`
+	Fail   int32  `json:"fail"`
+	Warn   int32  `json:"warn"`
+	Error  int32  `json:"error,omitempty"`
+	Skip   int32  `json:"skip,omitempty"`
+	// FailingResources are the resources that failed the policies, or that
+	// the policies warned about, the failed ones first.
+	FailingResources []ReportedResource `json:"failingResources,omitempty"`
+}
+
+// ReportedResource is a resource that didn't pass a policy enforcing an
+// intent.
+type ReportedResource struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	// Policy is the engine policy the resource was checked against, as
+	// "<kind>/<name>".
+	Policy string `json:"policy"`
+	Rule   string `json:"rule,omitempty"`
+	// Result of the check, either fail, warn or error.
+	Result  string `json:"result"`
+	Message string `json:"message,omitempty"`
 }
 
 // SecurityIntentBindingStatus defines the observed state of SecurityIntentBinding
 type SecurityIntentBindingStatus struct {
-	Status               string      `json:"status"`
-	LastUpdated          metav1.Time `json:"lastUpdated,omitempty"`
-	NumberOfBoundIntents int32       `json:"numberOfBoundIntents"`
-	BoundIntents         []string    `json:"boundIntents,omitempty"`
-	NimbusPolicy         string      `json:"nimbusPolicy"`
+	Status               string             `json:"status"`
+	LastUpdated          metav1.Time        `json:"lastUpdated,omitempty"`
+	NumberOfBoundIntents int32              `json:"numberOfBoundIntents"`
+	BoundIntents         []string           `json:"boundIntents,omitempty"`
+	NimbusPolicy         string             `json:"nimbusPolicy"`
+	IntentStatuses       []IntentStatus     `json:"intentStatuses,omitempty"`
+	EnforcementPhase     EnforcementPhase   `json:"enforcementPhase,omitempty"`
+	UnresolvedIntents    []UnresolvedIntent `json:"unresolvedIntents,omitempty"`
+	Conflicts            []PolicyConflict   `json:"conflicts,omitempty"`
+	// Violations are reported by the adapters that relay the alerts of their
+	// security engine.
+	Violations []IntentViolation `json:"violations,omitempty"`
+	// PolicyReports are reported by the adapters that aggregate the policy
+	// reports of their security engine.
+	PolicyReports []PolicyReportSummary `json:"policyReports,omitempty"`
+	Rollout       *RolloutStatus        `json:"rollout,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -44,6 +224,8 @@ type SecurityIntentBindingStatus struct {
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 // +kubebuilder:printcolumn:name="Intents",type="integer",JSONPath=".status.numberOfBoundIntents"
 // +kubebuilder:printcolumn:name="NimbusPolicy",type="string",JSONPath=".status.nimbusPolicy"
+// +kubebuilder:printcolumn:name="Enforcement",type="string",JSONPath=".status.enforcementPhase"
+// +kubebuilder:printcolumn:name="Rollout",type="string",JSONPath=".status.rollout.phase",priority=1
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 // SecurityIntentBinding is the Schema for the securityintentbindings API
@@ -8,9 +8,105 @@
 package v1alpha1
 
 import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActionDecision) DeepCopyInto(out *ActionDecision) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActionDecision.
+func (in *ActionDecision) DeepCopy() *ActionDecision {
+	if in == nil {
+		return nil
+	}
+	out := new(ActionDecision)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterEnforcementPolicy) DeepCopyInto(out *ClusterEnforcementPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterEnforcementPolicy.
+func (in *ClusterEnforcementPolicy) DeepCopy() *ClusterEnforcementPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterEnforcementPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterEnforcementPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterEnforcementPolicyList) DeepCopyInto(out *ClusterEnforcementPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterEnforcementPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterEnforcementPolicyList.
+func (in *ClusterEnforcementPolicyList) DeepCopy() *ClusterEnforcementPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterEnforcementPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterEnforcementPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterEnforcementPolicySpec) DeepCopyInto(out *ClusterEnforcementPolicySpec) {
+	*out = *in
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]EnforcementRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterEnforcementPolicySpec.
+func (in *ClusterEnforcementPolicySpec) DeepCopy() *ClusterEnforcementPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterEnforcementPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterMatchWorkloads) DeepCopyInto(out *ClusterMatchWorkloads) {
 	*out = *in
@@ -101,6 +197,22 @@ func (in *ClusterNimbusPolicySpec) DeepCopyInto(out *ClusterNimbusPolicySpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.ExcludedNamespaces != nil {
+		in, out := &in.ExcludedNamespaces, &out.ExcludedNamespaces
+		*out = make(map[string][]string, len(*in))
+		for key, val := range *in {
+			var outVal []string
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				inVal := (*in)[key]
+				in, out := &inVal, &outVal
+				*out = make([]string, len(*in))
+				copy(*out, *in)
+			}
+			(*out)[key] = outVal
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterNimbusPolicySpec.
@@ -122,6 +234,33 @@ func (in *ClusterNimbusPolicyStatus) DeepCopyInto(out *ClusterNimbusPolicyStatus
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.ActionDecisions != nil {
+		in, out := &in.ActionDecisions, &out.ActionDecisions
+		*out = make([]ActionDecision, len(*in))
+		copy(*out, *in)
+	}
+	if in.UnsupportedActions != nil {
+		in, out := &in.UnsupportedActions, &out.UnsupportedActions
+		*out = make([]UnsupportedAction, len(*in))
+		copy(*out, *in)
+	}
+	if in.UnsupportedSelectors != nil {
+		in, out := &in.UnsupportedSelectors, &out.UnsupportedSelectors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PolicyReports != nil {
+		in, out := &in.PolicyReports, &out.PolicyReports
+		*out = make([]PolicyReportSummary, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Conflicts != nil {
+		in, out := &in.Conflicts, &out.Conflicts
+		*out = make([]PolicyConflict, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterNimbusPolicyStatus.
@@ -201,12 +340,22 @@ func (in *ClusterSecurityIntentBindingSpec) DeepCopyInto(out *ClusterSecurityInt
 		*out = make([]MatchIntent, len(*in))
 		copy(*out, *in)
 	}
+	if in.IntentSelector != nil {
+		in, out := &in.IntentSelector, &out.IntentSelector
+		*out = new(IntentSelector)
+		(*in).DeepCopyInto(*out)
+	}
 	in.Selector.DeepCopyInto(&out.Selector)
 	if in.CEL != nil {
 		in, out := &in.CEL, &out.CEL
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Rollout != nil {
+		in, out := &in.Rollout, &out.Rollout
+		*out = new(RolloutStrategy)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterSecurityIntentBindingSpec.
@@ -233,6 +382,42 @@ func (in *ClusterSecurityIntentBindingStatus) DeepCopyInto(out *ClusterSecurityI
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.IntentStatuses != nil {
+		in, out := &in.IntentStatuses, &out.IntentStatuses
+		*out = make([]IntentStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.UnresolvedIntents != nil {
+		in, out := &in.UnresolvedIntents, &out.UnresolvedIntents
+		*out = make([]UnresolvedIntent, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conflicts != nil {
+		in, out := &in.Conflicts, &out.Conflicts
+		*out = make([]PolicyConflict, len(*in))
+		copy(*out, *in)
+	}
+	if in.Violations != nil {
+		in, out := &in.Violations, &out.Violations
+		*out = make([]IntentViolation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PolicyReports != nil {
+		in, out := &in.PolicyReports, &out.PolicyReports
+		*out = make([]PolicyReportSummary, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Rollout != nil {
+		in, out := &in.Rollout, &out.Rollout
+		*out = new(RolloutStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterSecurityIntentBindingStatus.
@@ -245,6 +430,36 @@ func (in *ClusterSecurityIntentBindingStatus) DeepCopy() *ClusterSecurityIntentB
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EnforcementRule) DeepCopyInto(out *EnforcementRule) {
+	*out = *in
+	if in.Severities != nil {
+		in, out := &in.Severities, &out.Severities
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EnforcementRule.
+func (in *EnforcementRule) DeepCopy() *EnforcementRule {
+	if in == nil {
+		return nil
+	}
+	out := new(EnforcementRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Intent) DeepCopyInto(out *Intent) {
 	*out = *in
@@ -281,6 +496,81 @@ func (in *Intent) DeepCopy() *Intent {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IntentSelector) DeepCopyInto(out *IntentSelector) {
+	*out = *in
+	if in.MatchTags != nil {
+		in, out := &in.MatchTags, &out.MatchTags
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MatchLabels != nil {
+		in, out := &in.MatchLabels, &out.MatchLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IntentSelector.
+func (in *IntentSelector) DeepCopy() *IntentSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(IntentSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IntentStatus) DeepCopyInto(out *IntentStatus) {
+	*out = *in
+	if in.Engines != nil {
+		in, out := &in.Engines, &out.Engines
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Policies != nil {
+		in, out := &in.Policies, &out.Policies
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IntentStatus.
+func (in *IntentStatus) DeepCopy() *IntentStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(IntentStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IntentViolation) DeepCopyInto(out *IntentViolation) {
+	*out = *in
+	in.LastSeen.DeepCopyInto(&out.LastSeen)
+	if in.Samples != nil {
+		in, out := &in.Samples, &out.Samples
+		*out = make([]ViolationSample, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IntentViolation.
+func (in *IntentViolation) DeepCopy() *IntentViolation {
+	if in == nil {
+		return nil
+	}
+	out := new(IntentViolation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LabelSelector) DeepCopyInto(out *LabelSelector) {
 	*out = *in
@@ -291,6 +581,13 @@ func (in *LabelSelector) DeepCopyInto(out *LabelSelector) {
 			(*out)[key] = val
 		}
 	}
+	if in.MatchExpressions != nil {
+		in, out := &in.MatchExpressions, &out.MatchExpressions
+		*out = make([]v1.LabelSelectorRequirement, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LabelSelector.
@@ -450,6 +747,38 @@ func (in *NimbusPolicyStatus) DeepCopyInto(out *NimbusPolicyStatus) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.ActionDecisions != nil {
+		in, out := &in.ActionDecisions, &out.ActionDecisions
+		*out = make([]ActionDecision, len(*in))
+		copy(*out, *in)
+	}
+	if in.UnsupportedActions != nil {
+		in, out := &in.UnsupportedActions, &out.UnsupportedActions
+		*out = make([]UnsupportedAction, len(*in))
+		copy(*out, *in)
+	}
+	if in.UnsupportedSelectors != nil {
+		in, out := &in.UnsupportedSelectors, &out.UnsupportedSelectors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conflicts != nil {
+		in, out := &in.Conflicts, &out.Conflicts
+		*out = make([]PolicyConflict, len(*in))
+		copy(*out, *in)
+	}
+	if in.VirtualPatchFeed != nil {
+		in, out := &in.VirtualPatchFeed, &out.VirtualPatchFeed
+		*out = new(VirtualPatchFeedStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PolicyReports != nil {
+		in, out := &in.PolicyReports, &out.PolicyReports
+		*out = make([]PolicyReportSummary, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NimbusPolicyStatus.
@@ -478,6 +807,97 @@ func (in *NimbusRules) DeepCopy() *NimbusRules {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolicyConflict) DeepCopyInto(out *PolicyConflict) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicyConflict.
+func (in *PolicyConflict) DeepCopy() *PolicyConflict {
+	if in == nil {
+		return nil
+	}
+	out := new(PolicyConflict)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolicyReportSummary) DeepCopyInto(out *PolicyReportSummary) {
+	*out = *in
+	if in.FailingResources != nil {
+		in, out := &in.FailingResources, &out.FailingResources
+		*out = make([]ReportedResource, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicyReportSummary.
+func (in *PolicyReportSummary) DeepCopy() *PolicyReportSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(PolicyReportSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReportedResource) DeepCopyInto(out *ReportedResource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReportedResource.
+func (in *ReportedResource) DeepCopy() *ReportedResource {
+	if in == nil {
+		return nil
+	}
+	out := new(ReportedResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutStatus) DeepCopyInto(out *RolloutStatus) {
+	*out = *in
+	in.AuditStartTime.DeepCopyInto(&out.AuditStartTime)
+	if in.PromotionTime != nil {
+		in, out := &in.PromotionTime, &out.PromotionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.ViolatedIntents != nil {
+		in, out := &in.ViolatedIntents, &out.ViolatedIntents
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutStatus.
+func (in *RolloutStatus) DeepCopy() *RolloutStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutStrategy) DeepCopyInto(out *RolloutStrategy) {
+	*out = *in
+	out.QuietPeriod = in.QuietPeriod
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutStrategy.
+func (in *RolloutStrategy) DeepCopy() *RolloutStrategy {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutStrategy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Rule) DeepCopyInto(out *Rule) {
 	*out = *in
@@ -603,12 +1023,22 @@ func (in *SecurityIntentBindingSpec) DeepCopyInto(out *SecurityIntentBindingSpec
 		*out = make([]MatchIntent, len(*in))
 		copy(*out, *in)
 	}
+	if in.IntentSelector != nil {
+		in, out := &in.IntentSelector, &out.IntentSelector
+		*out = new(IntentSelector)
+		(*in).DeepCopyInto(*out)
+	}
 	in.Selector.DeepCopyInto(&out.Selector)
 	if in.CEL != nil {
 		in, out := &in.CEL, &out.CEL
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Rollout != nil {
+		in, out := &in.Rollout, &out.Rollout
+		*out = new(RolloutStrategy)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecurityIntentBindingSpec.
@@ -630,6 +1060,42 @@ func (in *SecurityIntentBindingStatus) DeepCopyInto(out *SecurityIntentBindingSt
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.IntentStatuses != nil {
+		in, out := &in.IntentStatuses, &out.IntentStatuses
+		*out = make([]IntentStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.UnresolvedIntents != nil {
+		in, out := &in.UnresolvedIntents, &out.UnresolvedIntents
+		*out = make([]UnresolvedIntent, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conflicts != nil {
+		in, out := &in.Conflicts, &out.Conflicts
+		*out = make([]PolicyConflict, len(*in))
+		copy(*out, *in)
+	}
+	if in.Violations != nil {
+		in, out := &in.Violations, &out.Violations
+		*out = make([]IntentViolation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PolicyReports != nil {
+		in, out := &in.PolicyReports, &out.PolicyReports
+		*out = make([]PolicyReportSummary, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Rollout != nil {
+		in, out := &in.Rollout, &out.Rollout
+		*out = new(RolloutStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecurityIntentBindingStatus.
@@ -704,3 +1170,65 @@ func (in *SecurityIntentStatus) DeepCopy() *SecurityIntentStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UnresolvedIntent) DeepCopyInto(out *UnresolvedIntent) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UnresolvedIntent.
+func (in *UnresolvedIntent) DeepCopy() *UnresolvedIntent {
+	if in == nil {
+		return nil
+	}
+	out := new(UnresolvedIntent)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ViolationSample) DeepCopyInto(out *ViolationSample) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ViolationSample.
+func (in *ViolationSample) DeepCopy() *ViolationSample {
+	if in == nil {
+		return nil
+	}
+	out := new(ViolationSample)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UnsupportedAction) DeepCopyInto(out *UnsupportedAction) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UnsupportedAction.
+func (in *UnsupportedAction) DeepCopy() *UnsupportedAction {
+	if in == nil {
+		return nil
+	}
+	out := new(UnsupportedAction)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VirtualPatchFeedStatus) DeepCopyInto(out *VirtualPatchFeedStatus) {
+	*out = *in
+	in.LastLoaded.DeepCopyInto(&out.LastLoaded)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualPatchFeedStatus.
+func (in *VirtualPatchFeedStatus) DeepCopy() *VirtualPatchFeedStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VirtualPatchFeedStatus)
+	in.DeepCopyInto(out)
+	return out
+}
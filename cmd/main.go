@@ -4,6 +4,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"github.com/5GSEC/nimbus/pkg/util"
 	k8sruntime "k8s.io/apimachinery/pkg/runtime"
@@ -14,10 +15,12 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/config"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 
 	v1 "github.com/5GSEC/nimbus/api/v1alpha1"
 	"github.com/5GSEC/nimbus/internal/controller"
+	"github.com/5GSEC/nimbus/pkg/tracing"
 	// Importing third-party Kubernetes resource types
 	//+kubebuilder:scaffold:imports
 )
@@ -81,30 +84,36 @@ func main() {
 	}
 
 	if err = (&controller.SecurityIntentReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("securityintent-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "Unable to create controller", "controller", "SecurityIntent")
 		os.Exit(1)
 	}
 
 	if err = (&controller.SecurityIntentBindingReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("securityintentbinding-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "Unable to create controller", "controller", "SecurityIntentBinding")
 		os.Exit(1)
 	}
 
 	if err = (&controller.ClusterSecurityIntentBindingReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("clustersecurityintentbinding-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ClusterSecurityIntentBinding")
 		os.Exit(1)
 	}
 	//+kubebuilder:scaffold:builder
 
+	// Reporting the state of the Nimbus resources along with the controller metrics.
+	metrics.Registry.MustRegister(controller.NewStateCollector(mgr.GetCache()))
+
 	// Adding health and readiness checks.
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "Unable to set up health check")
@@ -115,10 +124,23 @@ func main() {
 		os.Exit(1)
 	}
 
+	ctx := ctrl.SetupSignalHandler()
+
+	// Exporting the traces of the reconciliations, if an OTLP endpoint is set.
+	shutdownTracing, err := tracing.Setup(ctx, "nimbus-operator")
+	if err != nil {
+		setupLog.Error(err, "Unable to set up tracing")
+		os.Exit(1)
+	}
+
 	// Starting the controller manager.
 	setupLog.Info("Starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	if err := mgr.Start(ctx); err != nil {
 		setupLog.Error(err, "Problem running manager")
+		_ = shutdownTracing(context.Background())
 		os.Exit(1)
 	}
+	if err := shutdownTracing(context.Background()); err != nil {
+		setupLog.Error(err, "Unable to flush traces")
+	}
 }
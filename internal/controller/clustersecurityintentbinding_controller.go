@@ -9,11 +9,15 @@ import (
 	"slices"
 
 	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
@@ -26,13 +30,16 @@ import (
 
 	v1alpha1 "github.com/5GSEC/nimbus/api/v1alpha1"
 	processorerrors "github.com/5GSEC/nimbus/pkg/processor/errors"
+	"github.com/5GSEC/nimbus/pkg/processor/intentbinder"
 	"github.com/5GSEC/nimbus/pkg/processor/policybuilder"
+	"github.com/5GSEC/nimbus/pkg/tracing"
 )
 
 // ClusterSecurityIntentBindingReconciler reconciles a ClusterSecurityIntentBinding object
 type ClusterSecurityIntentBindingReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=intent.security.nimbus.com,resources=clustersecurityintentbindings,verbs=get;list;watch;create;update;patch;delete
@@ -40,14 +47,29 @@ type ClusterSecurityIntentBindingReconciler struct {
 //+kubebuilder:rbac:groups=intent.security.nimbus.com,resources=clusternimbuspolicies,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=intent.security.nimbus.com,resources=clusternimbuspolicies/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups=intent.security.nimbus.com,resources=clusterenforcementpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=wgpolicyk8s.io,resources=policyreports;clusterpolicyreports,verbs=get;list
+//+kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=list
+//+kubebuilder:rbac:groups=security.kubearmor.com,resources=kubearmorpolicies;kubearmorclusterpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=policy.networking.k8s.io,resources=adminnetworkpolicies;baselineadminnetworkpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=cilium.io,resources=ciliumnetworkpolicies;ciliumclusterwidenetworkpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=projectcalico.org,resources=networkpolicies;globalnetworkpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=kyverno.io,resources=policies;clusterpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
-func (r *ClusterSecurityIntentBindingReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *ClusterSecurityIntentBindingReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, err error) {
+	ctx, span := tracing.Start(ctx, "ClusterSecurityIntentBinding.Reconcile", trace.WithAttributes(
+		attribute.String("ClusterSecurityIntentBinding.Name", req.Name),
+	))
+	defer func() { tracing.End(span, err) }()
 	logger := log.FromContext(ctx)
 
 	csib := &v1alpha1.ClusterSecurityIntentBinding{}
-	err := r.Get(ctx, req.NamespacedName, csib)
+	err = r.Get(ctx, req.NamespacedName, csib)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
 			logger.Info("ClusterSecurityIntentBinding not found. Ignoring since object must be deleted")
@@ -80,6 +102,14 @@ func (r *ClusterSecurityIntentBindingReconciler) Reconcile(ctx context.Context,
 		return requeueWithError(err)
 	}
 
+	// Advance the rollout before building the Nimbus policies, so that a
+	// promotion enforces the Block intents right away.
+	recheckAfter, err := r.updateRollout(ctx, req)
+	if err != nil {
+		logger.Error(err, "failed to update the rollout", "ClusterSecurityIntentBinding.Name", req.Name)
+		return requeueWithError(err)
+	}
+
 	if err = r.createOrUpdateCwnp(ctx, logger, req); err != nil {
 		return requeueWithError(err)
 	}
@@ -97,6 +127,9 @@ func (r *ClusterSecurityIntentBindingReconciler) Reconcile(ctx context.Context,
 		return requeueWithError(err)
 	}
 
+	if recheckAfter > 0 {
+		return requeueAfter(recheckAfter)
+	}
 	return doNotRequeue()
 }
 
@@ -117,6 +150,12 @@ func (r *ClusterSecurityIntentBindingReconciler) SetupWithManager(mgr ctrl.Manag
 		Watches(&v1alpha1.SecurityIntent{},
 			handler.EnqueueRequestsFromMapFunc(r.findCsibsForSi),
 		).
+		Watches(&v1alpha1.ClusterEnforcementPolicy{},
+			handler.EnqueueRequestsFromMapFunc(r.findCsibsForCep),
+		).
+		Watches(&v1alpha1.NimbusPolicy{},
+			handler.EnqueueRequestsFromMapFunc(r.findCsibsForNp),
+		).
 		Watches(&corev1.Namespace{},
 			handler.EnqueueRequestsFromMapFunc(r.findCsibsForNamespace),
 			builder.WithPredicates(predicate.Funcs{
@@ -142,7 +181,19 @@ func (r *ClusterSecurityIntentBindingReconciler) createFn(createEvent event.Crea
 func (r *ClusterSecurityIntentBindingReconciler) updateFn(updateEvent event.UpdateEvent) bool {
 	// TODO: Handle update event for ClusterNimbusPolicy update so that reconciler don't process it
 	// twice.
-	return updateEvent.ObjectOld.GetGeneration() != updateEvent.ObjectNew.GetGeneration()
+	if updateEvent.ObjectOld.GetGeneration() != updateEvent.ObjectNew.GetGeneration() {
+		return true
+	}
+	if intentLabelsChanged(updateEvent) {
+		return true
+	}
+	if actionDecisionsChanged(updateEvent) {
+		return true
+	}
+	// Adapters only update the ClusterNimbusPolicy and NimbusPolicy status, so
+	// watch for changes to the policies they report in order to roll them up
+	// into the binding status.
+	return adapterPoliciesChanged(updateEvent)
 }
 
 func (r *ClusterSecurityIntentBindingReconciler) deleteFn(deleteEvent event.DeleteEvent) bool {
@@ -153,10 +204,13 @@ func (r *ClusterSecurityIntentBindingReconciler) deleteFn(deleteEvent event.Dele
 	if _, ok := obj.(*v1alpha1.SecurityIntent); ok {
 		return true
 	}
+	if _, ok := obj.(*v1alpha1.ClusterEnforcementPolicy); ok {
+		return true
+	}
 	if _, ok := obj.(*corev1.Namespace); ok {
 		return true
 	}
-	return ownerExists(r.Client, obj)
+	return hasConflicts(obj) || ownerExists(r.Client, obj)
 }
 
 func (r *ClusterSecurityIntentBindingReconciler) createOrUpdateCwnp(ctx context.Context, logger logr.Logger, req ctrl.Request) error {
@@ -186,25 +240,34 @@ func (r *ClusterSecurityIntentBindingReconciler) createCwnp(ctx context.Context,
 		if errors.Is(err, processorerrors.ErrSecurityIntentsNotFound) {
 			// Since the SecurityIntent(s) referenced in ClusterSecurityIntentBinding spec do not
 			// exist, so delete ClusterNimbusPolicy if it exists.
-			if err := r.deleteCwnp(ctx, csib.GetName()); err != nil {
+			if err := r.deleteCwnp(ctx, csib); err != nil {
 				return err
 			}
 			return nil
 		}
 		logger.Error(err, "failed to build ClusterNimbusPolicy")
+		recordPolicyFailure(r.Recorder, &csib, "ClusterNimbusPolicy", "build", err)
 		return err
 	}
 
+	// Keep the action decisions and conflicts since creating the
+	// ClusterNimbusPolicy resets its status.
+	actionDecisions, conflicts := clusterNp.Status.ActionDecisions, clusterNp.Status.Conflicts
+	tracing.Inject(ctx, clusterNp)
 	if err := r.Create(ctx, clusterNp); err != nil {
 		logger.Error(err, "failed to create ClusterNimbusPolicy", "ClusterNimbusPolicy.Name", clusterNp.Name)
+		recordPolicyFailure(r.Recorder, &csib, "ClusterNimbusPolicy", "create", err)
 		return err
 	}
 	logger.Info("ClusterNimbusPolicy created", "ClusterNimbusPolicy.Name", clusterNp.Name)
+	recordPolicyEvent(r.Recorder, &csib, "ClusterNimbusPolicy", clusterNp, ReasonPolicyCreated, "created")
 
 	return r.updateCwnpStatus(ctx, logger, ctrl.Request{
 		NamespacedName: types.NamespacedName{
 			Name: csib.Name,
 		}},
+		actionDecisions,
+		conflicts,
 	)
 }
 
@@ -220,26 +283,43 @@ func (r *ClusterSecurityIntentBindingReconciler) updateCwnp(ctx context.Context,
 		if errors.Is(err, processorerrors.ErrSecurityIntentsNotFound) {
 			// Since the SecurityIntent(s) referenced in ClusterSecurityIntentBinding spec do not
 			// exist, so delete ClusterNimbusPolicy if it exists.
-			if err := r.deleteCwnp(ctx, csib.GetName()); err != nil {
+			if err := r.deleteCwnp(ctx, csib); err != nil {
 				return err
 			}
 			return nil
 		}
 		logger.Error(err, "failed to build ClusterNimbusPolicy")
+		recordPolicyFailure(r.Recorder, &csib, "ClusterNimbusPolicy", "build", err)
 		return err
 	}
 
 	clusterNp.ObjectMeta.ResourceVersion = existingCwnp.ObjectMeta.ResourceVersion
+	actionDecisions, conflicts := clusterNp.Status.ActionDecisions, clusterNp.Status.Conflicts
+	// Only trace the reconciliations that change the ClusterNimbusPolicy down
+	// to the adapters.
+	if equality.Semantic.DeepEqual(existingCwnp.Spec, clusterNp.Spec) {
+		tracing.Preserve(&existingCwnp, clusterNp)
+	} else {
+		tracing.Inject(ctx, clusterNp)
+	}
 	if err := r.Update(ctx, clusterNp); err != nil {
 		logger.Error(err, "failed to configure ClusterNimbusPolicy", "ClusterNimbusPolicy.Name", clusterNp.Name)
+		recordPolicyFailure(r.Recorder, &csib, "ClusterNimbusPolicy", "configure", err)
 		return err
 	}
 	logger.Info("ClusterNimbusPolicy configured", "ClusterNimbusPolicy.Name", clusterNp.Name)
+	// The binding is reconciled for every change to the policies of the
+	// adapters, so only report actual changes to the ClusterNimbusPolicy.
+	if clusterNp.GetGeneration() != existingCwnp.GetGeneration() {
+		recordPolicyEvent(r.Recorder, &csib, "ClusterNimbusPolicy", clusterNp, ReasonPolicyConfigured, "configured")
+	}
 
 	return r.updateCwnpStatus(ctx, logger, ctrl.Request{
 		NamespacedName: types.NamespacedName{
 			Name: csib.Name,
 		}},
+		actionDecisions,
+		conflicts,
 	)
 }
 
@@ -252,25 +332,46 @@ func (r *ClusterSecurityIntentBindingReconciler) findCsibsForSi(ctx context.Cont
 		return []reconcile.Request{}
 	}
 
-	requests := make([]reconcile.Request, len(csibs.Items))
+	var requests []reconcile.Request
 
-	for idx, csib := range csibs.Items {
-		for _, intent := range csib.Spec.Intents {
-			if intent.Name == si.GetName() {
-				requests[idx] = ctrl.Request{
-					NamespacedName: types.NamespacedName{
-						Namespace: csib.GetNamespace(),
-						Name:      csib.GetName(),
-					},
-				}
-				break
-			}
+	for _, csib := range csibs.Items {
+		if bindsIntent(csib.Spec.Intents, csib.Spec.IntentSelector, csib.Status.BoundIntents, si) {
+			requests = append(requests, ctrl.Request{
+				NamespacedName: types.NamespacedName{
+					Namespace: csib.GetNamespace(),
+					Name:      csib.GetName(),
+				},
+			})
 		}
 	}
 
 	return requests
 }
 
+// findCsibsForCep enqueues all the ClusterSecurityIntentBindings, since a change to a
+// ClusterEnforcementPolicy may change the effective action of any bound intent.
+func (r *ClusterSecurityIntentBindingReconciler) findCsibsForCep(ctx context.Context, _ client.Object) []reconcile.Request {
+	logger := log.FromContext(ctx)
+
+	csibs := &v1alpha1.ClusterSecurityIntentBindingList{}
+	if err := r.List(ctx, csibs); err != nil {
+		logger.Error(err, "failed to list ClusterSecurityIntentBindings")
+		return []reconcile.Request{}
+	}
+
+	var requests []reconcile.Request
+	for _, csib := range csibs.Items {
+		requests = append(requests, ctrl.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: csib.GetNamespace(),
+				Name:      csib.GetName(),
+			},
+		})
+	}
+
+	return requests
+}
+
 type npTrackingObj struct {
 	create bool
 	update bool
@@ -282,6 +383,23 @@ var nsBlackList = []string{"kube-system"}
 
 const wildcard = "*"
 
+// findCsibsForNp enqueues the ClusterSecurityIntentBindings of the
+// NimbusPolicies that share intents with the given NimbusPolicy, since a change
+// to it may create or resolve conflicts with them.
+func (r *ClusterSecurityIntentBindingReconciler) findCsibsForNp(ctx context.Context, np client.Object) []reconcile.Request {
+	var requests []reconcile.Request
+	for _, other := range nimbusPoliciesSharingIntents(ctx, r.Client, np) {
+		if name, ok := ownedBy(other, "ClusterSecurityIntentBinding"); ok {
+			requests = append(requests, ctrl.Request{
+				NamespacedName: types.NamespacedName{
+					Name: name,
+				},
+			})
+		}
+	}
+	return requests
+}
+
 func (r *ClusterSecurityIntentBindingReconciler) isValidCsib(ctx context.Context, logger logr.Logger, req ctrl.Request) bool {
 
 	// get the csib
@@ -297,11 +415,13 @@ func (r *ClusterSecurityIntentBindingReconciler) isValidCsib(ctx context.Context
 	if matchLen > 0 && excludeLen > 0 {
 		err := errors.New("invalid clustersecurityintentbinding")
 		logger.Error(err, "Both MatchNames and ExcludeNames should not be set", "ClusterSecurityIntentBinding.Name", req.Name)
+		recordValidationFailure(r.Recorder, &csib, "Both MatchNames and ExcludeNames should not be set")
 		return false
 	}
 	if matchLen == 0 && excludeLen == 0 {
 		err := errors.New("invalid clustersecurityintentbinding")
 		logger.Error(err, "Atleast one of MatchNames or ExcludeNames should be set", "ClusterSecurityIntentBinding.Name", req.Name)
+		recordValidationFailure(r.Recorder, &csib, "Atleast one of MatchNames or ExcludeNames should be set")
 		return false
 	}
 	// In MatchNames, if a  "*" is present, it should be the only entry
@@ -309,6 +429,7 @@ func (r *ClusterSecurityIntentBindingReconciler) isValidCsib(ctx context.Context
 		if ns == wildcard && i > 0 {
 			err := errors.New("invalid clustersecurityintentbinding")
 			logger.Error(err, "If * is present, it should be only entry", "ClusterSecurityIntentBinding.Name", req.Name)
+			recordValidationFailure(r.Recorder, &csib, "If * is present, it should be only entry")
 			return false
 		}
 	}
@@ -417,8 +538,13 @@ func (r *ClusterSecurityIntentBindingReconciler) createOrUpdateNp(ctx context.Co
 	// run through the tracking list, and create/update/delete the nimbus policies
 	for _, nobj := range npFilteredTrackingList {
 		if nobj.create {
+			// Keep the action decisions and conflicts since creating the
+			// NimbusPolicy resets its status.
+			actionDecisions, conflicts := nobj.np.Status.ActionDecisions, nobj.np.Status.Conflicts
+			tracing.Inject(ctx, nobj.np)
 			if err := r.Create(ctx, nobj.np); err != nil {
 				logger.Error(err, "failed to create NimbusPolicy", "NimbusPolicy.Name", nobj.np.Name)
+				recordPolicyFailure(r.Recorder, &csib, "NimbusPolicy", "create", err)
 				return err
 			}
 			npReq := ctrl.Request{
@@ -426,10 +552,11 @@ func (r *ClusterSecurityIntentBindingReconciler) createOrUpdateNp(ctx context.Co
 					Namespace: nobj.np.GetNamespace(),
 					Name:      nobj.np.GetName(),
 				}}
-			if err = r.updateNpStatus(ctx, logger, npReq, StatusCreated); err != nil {
+			if err = r.updateNpStatus(ctx, logger, npReq, StatusCreated, actionDecisions, conflicts); err != nil {
 				return err
 			}
 			logger.Info("NimbusPolicy created", "NimbusPolicy.Name", nobj.np.Name)
+			recordPolicyEvent(r.Recorder, &csib, "NimbusPolicy", nobj.np, ReasonPolicyCreated, "created")
 
 		} else if nobj.update {
 			// update intents, parameters. Build a new Nimbus Policy
@@ -439,12 +566,13 @@ func (r *ClusterSecurityIntentBindingReconciler) createOrUpdateNp(ctx context.Co
 				if errors.Is(err, processorerrors.ErrSecurityIntentsNotFound) {
 					// Since the SecurityIntent(s) referenced in ClusterSecurityIntentBinding spec do not
 					// exist, so delete ClusterNimbusPolicy if it exists.
-					if err := r.deleteCwnp(ctx, csib.GetName()); err != nil {
+					if err := r.deleteCwnp(ctx, csib); err != nil {
 						return err
 					}
 					return nil
 				}
 				logger.Error(err, "failed to build ClusterNimbusPolicy")
+				recordPolicyFailure(r.Recorder, &csib, "NimbusPolicy", "build", err)
 				return err
 			}
 
@@ -452,14 +580,29 @@ func (r *ClusterSecurityIntentBindingReconciler) createOrUpdateNp(ctx context.Co
 			// Another option is to check which CSIB was used to generate this nimbus policy
 			if reason, equal := nobj.np.Equal(*newNimbusPolicy); equal {
 				logger.Info("NimbusPolicy not updated as objects are same", "NimbusPolicy.name", nobj.np.Name, "Namespace", nobj.np.Namespace)
+				// Conflicts with other NimbusPolicies may appear or go away without
+				// changing the spec, when this one takes precedence.
+				if !equality.Semantic.DeepEqual(nobj.np.Status.Conflicts, newNimbusPolicy.Status.Conflicts) {
+					npReq := ctrl.Request{
+						NamespacedName: types.NamespacedName{
+							Namespace: nobj.np.GetNamespace(),
+							Name:      nobj.np.GetName(),
+						}}
+					if err = r.updateNpStatus(ctx, logger, npReq, StatusCreated, newNimbusPolicy.Status.ActionDecisions, newNimbusPolicy.Status.Conflicts); err != nil {
+						return err
+					}
+				}
 				continue
 			} else {
 				logger.Info("NimbusPolicy updated as objects are not same", "NimbusPolicy.name", nobj.np.Name, "Namespace", nobj.np.Namespace, "Reason", reason)
 			}
 
 			newNimbusPolicy.ObjectMeta.ResourceVersion = nobj.np.ObjectMeta.ResourceVersion
+			actionDecisions, conflicts := newNimbusPolicy.Status.ActionDecisions, newNimbusPolicy.Status.Conflicts
+			tracing.Inject(ctx, newNimbusPolicy)
 			if err := r.Update(ctx, newNimbusPolicy); err != nil {
 				logger.Error(err, "failed to update NimbusPolicy", "NimbusPolicy.Name", newNimbusPolicy.Name)
+				recordPolicyFailure(r.Recorder, &csib, "NimbusPolicy", "configure", err)
 				return err
 			}
 			npReq := ctrl.Request{
@@ -467,10 +610,11 @@ func (r *ClusterSecurityIntentBindingReconciler) createOrUpdateNp(ctx context.Co
 					Namespace: newNimbusPolicy.GetNamespace(),
 					Name:      newNimbusPolicy.GetName(),
 				}}
-			if err = r.updateNpStatus(ctx, logger, npReq, StatusCreated); err != nil {
+			if err = r.updateNpStatus(ctx, logger, npReq, StatusCreated, actionDecisions, conflicts); err != nil {
 				return err
 			}
 			logger.Info("NimbusPolicy updated", "NimbusPolicy.Name", newNimbusPolicy.Name)
+			recordPolicyEvent(r.Recorder, &csib, "NimbusPolicy", newNimbusPolicy, ReasonPolicyConfigured, "configured")
 
 			return nil
 
@@ -479,16 +623,18 @@ func (r *ClusterSecurityIntentBindingReconciler) createOrUpdateNp(ctx context.Co
 			logger.Info("Deleting NimbusPolicy since no namespaces found", "NimbusPolicyName", nobj.np.Name)
 			if err = r.Delete(ctx, nobj.np); err != nil {
 				logger.Error(err, "failed to delete NimbusPolicy", "NimbusPolicyName", nobj.np.Name)
+				recordPolicyFailure(r.Recorder, &csib, "NimbusPolicy", "delete", err)
 				return err
 			}
 			logger.Info("NimbusPolicy deleted", "NimbusPolicyName", nobj.np.Name)
+			recordPolicyEvent(r.Recorder, &csib, "NimbusPolicy", nobj.np, ReasonPolicyDeleted, "deleted since no namespaces found")
 		}
 	}
 
 	return nil
 }
 
-func (r *ClusterSecurityIntentBindingReconciler) updateNpStatus(ctx context.Context, logger logr.Logger, req ctrl.Request, status string) error {
+func (r *ClusterSecurityIntentBindingReconciler) updateNpStatus(ctx context.Context, logger logr.Logger, req ctrl.Request, status string, actionDecisions []v1alpha1.ActionDecision, conflicts []v1alpha1.PolicyConflict) error {
 	np := &v1alpha1.NimbusPolicy{}
 
 	// Get the np object. This might take multiple retries since object might have been just created
@@ -509,6 +655,8 @@ func (r *ClusterSecurityIntentBindingReconciler) updateNpStatus(ctx context.Cont
 
 		np.Status.Status = status
 		np.Status.LastUpdated = metav1.Now()
+		np.Status.ActionDecisions = actionDecisions
+		np.Status.Conflicts = conflicts
 		if err := r.Status().Update(ctx, np); err != nil {
 			return err
 		}
@@ -607,7 +755,7 @@ func (r *ClusterSecurityIntentBindingReconciler) updateCsibStatus(ctx context.Co
 	return nil
 }
 
-func (r *ClusterSecurityIntentBindingReconciler) updateCwnpStatus(ctx context.Context, logger logr.Logger, req ctrl.Request) error {
+func (r *ClusterSecurityIntentBindingReconciler) updateCwnpStatus(ctx context.Context, logger logr.Logger, req ctrl.Request, actionDecisions []v1alpha1.ActionDecision, conflicts []v1alpha1.PolicyConflict) error {
 	cwnp := &v1alpha1.ClusterNimbusPolicy{}
 
 	// To handle potential latency or outdated cache issues with the Kubernetes API
@@ -637,6 +785,8 @@ func (r *ClusterSecurityIntentBindingReconciler) updateCwnpStatus(ctx context.Co
 		}
 		cwnp.Status.Status = StatusCreated
 		cwnp.Status.LastUpdated = metav1.Now()
+		cwnp.Status.ActionDecisions = actionDecisions
+		cwnp.Status.Conflicts = conflicts
 		if err := r.Status().Update(ctx, cwnp); err != nil {
 			return err
 		}
@@ -649,8 +799,9 @@ func (r *ClusterSecurityIntentBindingReconciler) updateCwnpStatus(ctx context.Co
 	return nil
 }
 
-func (r *ClusterSecurityIntentBindingReconciler) deleteCwnp(ctx context.Context, name string) error {
+func (r *ClusterSecurityIntentBindingReconciler) deleteCwnp(ctx context.Context, csib v1alpha1.ClusterSecurityIntentBinding) error {
 	logger := log.FromContext(ctx)
+	name := csib.GetName()
 
 	var cwnp v1alpha1.ClusterNimbusPolicy
 	err := r.Get(ctx, types.NamespacedName{Name: name}, &cwnp)
@@ -665,8 +816,10 @@ func (r *ClusterSecurityIntentBindingReconciler) deleteCwnp(ctx context.Context,
 	logger.Info("ClusterNimbusPolicy deleted", "clusterNimbusPolicyName", name)
 	if err = r.Delete(context.Background(), &cwnp); err != nil {
 		logger.Error(err, "failed to delete ClusterNimbusPolicy", "clusterNimbusPolicyName", name)
+		recordPolicyFailure(r.Recorder, &csib, "ClusterNimbusPolicy", "delete", err)
 		return err
 	}
+	recordPolicyEvent(r.Recorder, &csib, "ClusterNimbusPolicy", &cwnp, ReasonPolicyDeleted, "deleted since no SecurityIntents found")
 
 	return nil
 }
@@ -678,6 +831,14 @@ func (r *ClusterSecurityIntentBindingReconciler) updateCSibStatusWithBoundSisAnd
 		return err
 	}
 
+	_, unresolvedIntents, err := intentbinder.ExtractIntents(ctx, r.Client, latestCsib)
+	if err != nil {
+		logger.Error(err, "failed to fetch SecurityIntents", "ClusterSecurityIntentBinding.Name", req.Name)
+		return err
+	}
+	recordUnresolvedIntents(r.Recorder, latestCsib, latestCsib.Status.UnresolvedIntents, unresolvedIntents)
+	latestCsib.Status.UnresolvedIntents = unresolvedIntents
+
 	latestCwnp := &v1alpha1.ClusterNimbusPolicy{}
 	if retryErr := retry.OnError(retry.DefaultRetry, apierrors.IsNotFound, func() error {
 		if err := r.Get(ctx, req.NamespacedName, latestCwnp); err != nil {
@@ -696,6 +857,9 @@ func (r *ClusterSecurityIntentBindingReconciler) updateCSibStatusWithBoundSisAnd
 		latestCsib.Status.ClusterNimbusPolicy = ""
 		latestCsib.Status.NumberOfNimbusPolicies = 0
 		latestCsib.Status.NimbusPolicyNamespaces = nil
+		latestCsib.Status.IntentStatuses = nil
+		latestCsib.Status.EnforcementPhase = v1alpha1.EnforcementPhaseNotEnforced
+		latestCsib.Status.Conflicts = nil
 		if err := r.Status().Update(ctx, latestCsib); err != nil {
 			logger.Error(err, "failed to update ClusterSecurityIntentBinding status", "ClusterSecurityIntentBinding.Name", latestCsib.Name)
 			return err
@@ -704,8 +868,13 @@ func (r *ClusterSecurityIntentBindingReconciler) updateCSibStatusWithBoundSisAnd
 	}
 
 	// Update ClusterSecurityIntentBinding status with bound SecurityIntent(s) and NimbusPolicy.
+	boundIntents, err := extractBoundIntentsNameFromCSib(ctx, r.Client, req.Name)
+	if err != nil {
+		logger.Error(err, "failed to fetch bound SecurityIntents", "ClusterSecurityIntentBinding.Name", req.Name)
+		return err
+	}
 	latestCsib.Status.NumberOfBoundIntents = int32(len(latestCwnp.Spec.NimbusRules))
-	latestCsib.Status.BoundIntents = extractBoundIntentsNameFromCSib(ctx, r.Client, req.Name)
+	latestCsib.Status.BoundIntents = boundIntents
 	latestCsib.Status.ClusterNimbusPolicy = req.Name
 
 	if err := r.Status().Update(ctx, latestCsib); err != nil {
@@ -741,6 +910,9 @@ func (r *ClusterSecurityIntentBindingReconciler) updateCsibStatusWithNpNamespace
 		latestCsib.Status.ClusterNimbusPolicy = ""
 		latestCsib.Status.NumberOfNimbusPolicies = 0
 		latestCsib.Status.NimbusPolicyNamespaces = nil
+		latestCsib.Status.IntentStatuses = nil
+		latestCsib.Status.EnforcementPhase = v1alpha1.EnforcementPhaseNotEnforced
+		latestCsib.Status.Conflicts = nil
 		if err := r.Status().Update(ctx, latestCsib); err != nil {
 			logger.Error(err, "failed to update ClusterSecurityIntentBinding status", "ClusterSecurityIntentBinding.Name", latestCsib.Name)
 			return err
@@ -750,9 +922,26 @@ func (r *ClusterSecurityIntentBindingReconciler) updateCsibStatusWithNpNamespace
 
 	// Update necessary fields of ClusterSecurityIntentBinding status.
 	// The other fields will remain the same
+	cwnpPolicies, err := fromClusterNimbusPolicy(ctx, r.Client, *latestCwnp)
+	if err != nil {
+		logger.Error(err, "failed to fetch adapter policies", "ClusterNimbusPolicy.Name", req.Name)
+		return err
+	}
+	npPolicies, err := extractNPAdapterPoliciesFromCsib(ctx, r.Client, req.Name)
+	if err != nil {
+		logger.Error(err, "failed to fetch adapter policies of NimbusPolicies", "ClusterSecurityIntentBinding.Name", req.Name)
+		return err
+	}
 	npNamespaces := extractNPNamespacesFromCsib(ctx, r.Client, req.Name)
 	latestCsib.Status.NumberOfNimbusPolicies = int32(len(npNamespaces))
 	latestCsib.Status.NimbusPolicyNamespaces = npNamespaces
+	latestCsib.Status.IntentStatuses, latestCsib.Status.EnforcementPhase = rollUpIntentStatuses(
+		fetchSecurityIntents(ctx, r.Client, latestCsib.Status.BoundIntents),
+		&cwnpPolicies,
+		npPolicies,
+	)
+	latestCsib.Status.Conflicts = extractNPConflictsFromCsib(ctx, r.Client, req.Name, *latestCwnp)
+	recordConflicts(r.Recorder, latestCsib, latestCsib.Status.Conflicts)
 
 	if err := r.Status().Update(ctx, latestCsib); err != nil {
 		logger.Error(err, "failed to update ClusterSecurityIntentBinding status", "ClusterSecurityIntentBinding.Name", latestCsib.Name)
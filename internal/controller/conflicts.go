@@ -0,0 +1,115 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package controller
+
+import (
+	"context"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	v1alpha1 "github.com/5GSEC/nimbus/api/v1alpha1"
+	"github.com/5GSEC/nimbus/pkg/adapter/idpool"
+)
+
+// nimbusPoliciesSharingIntents returns the other NimbusPolicies in the
+// namespace of the given NimbusPolicy that bind at least one of its intents or
+// of the incompatible ones, and so may conflict with it.
+func nimbusPoliciesSharingIntents(ctx context.Context, c client.Client, npObj client.Object) []v1alpha1.NimbusPolicy {
+	logger := log.FromContext(ctx)
+
+	np, ok := npObj.(*v1alpha1.NimbusPolicy)
+	if !ok {
+		return nil
+	}
+
+	nps := &v1alpha1.NimbusPolicyList{}
+	if err := c.List(ctx, nps, client.InNamespace(np.Namespace)); err != nil {
+		logger.Error(err, "failed to list NimbusPolicies", "NimbusPolicy.Namespace", np.Namespace)
+		return nil
+	}
+
+	var ids []string
+	for _, id := range ruleIDs(np.Spec.NimbusRules) {
+		ids = append(ids, id)
+		ids = append(ids, idpool.IncompatibleIDs(id)...)
+	}
+	var sharing []v1alpha1.NimbusPolicy
+	for _, other := range nps.Items {
+		if other.Name == np.Name {
+			continue
+		}
+		if slices.ContainsFunc(ruleIDs(other.Spec.NimbusRules), func(id string) bool {
+			return slices.Contains(ids, id)
+		}) {
+			sharing = append(sharing, other)
+		}
+	}
+	return sharing
+}
+
+// ownedBy reports whether the NimbusPolicy is controlled by a binding of the
+// given kind, and returns the name of that binding.
+func ownedBy(np v1alpha1.NimbusPolicy, kind string) (string, bool) {
+	owner := metav1.GetControllerOf(&np)
+	if owner == nil || owner.Kind != kind {
+		return "", false
+	}
+	return owner.Name, true
+}
+
+// actionDecisionsChanged reports whether the action decisions recorded in the
+// status of a NimbusPolicy changed. NimbusPolicies are compared on these
+// decisions to detect conflicts, and they may change without the spec
+// changing when a conflicting rule was aligned with another NimbusPolicy.
+func actionDecisionsChanged(updateEvent event.UpdateEvent) bool {
+	oldObj, ok := updateEvent.ObjectOld.(*v1alpha1.NimbusPolicy)
+	if !ok {
+		return false
+	}
+	newObj, ok := updateEvent.ObjectNew.(*v1alpha1.NimbusPolicy)
+	return ok && !equality.Semantic.DeepEqual(oldObj.Status.ActionDecisions, newObj.Status.ActionDecisions)
+}
+
+// hasConflicts reports whether the object is a NimbusPolicy in conflict with
+// other ones, which need to be reconciled again once it's deleted.
+func hasConflicts(obj client.Object) bool {
+	np, ok := obj.(*v1alpha1.NimbusPolicy)
+	return ok && len(np.Status.Conflicts) > 0
+}
+
+// extractNPConflictsFromCsib returns the conflicts of the NimbusPolicies
+// generated for the given ClusterSecurityIntentBinding, qualified with their
+// namespace, along with the ones of its ClusterNimbusPolicy not reported by
+// these NimbusPolicies already.
+func extractNPConflictsFromCsib(ctx context.Context, c client.Client, name string, cwnp v1alpha1.ClusterNimbusPolicy) []v1alpha1.PolicyConflict {
+	logger := log.FromContext(ctx)
+
+	nps := &v1alpha1.NimbusPolicyList{}
+	if err := c.List(ctx, nps); err != nil {
+		logger.Error(err, "failed to list Nimbus Policies")
+		return nil
+	}
+
+	var conflicts []v1alpha1.PolicyConflict
+	for _, np := range nps.Items {
+		if np.Name != "nimbus-ctlr-gen-"+name {
+			continue
+		}
+		for _, conflict := range np.Status.Conflicts {
+			conflict.Namespace = np.Namespace
+			conflicts = append(conflicts, conflict)
+		}
+	}
+	for _, conflict := range cwnp.Status.Conflicts {
+		if !slices.Contains(conflicts, conflict) {
+			conflicts = append(conflicts, conflict)
+		}
+	}
+	return conflicts
+}
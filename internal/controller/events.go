@@ -0,0 +1,129 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package controller
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1alpha1 "github.com/5GSEC/nimbus/api/v1alpha1"
+)
+
+// Reasons of the Events emitted by the controllers.
+const (
+	ReasonUnresolvedIntents  = "UnresolvedIntents"
+	ReasonConflictingIntents = "ConflictingIntents"
+	ReasonValidationFailed   = "ValidationFailed"
+	ReasonUnsupportedIntent  = "UnsupportedIntent"
+	ReasonPolicyCreated      = "PolicyCreated"
+	ReasonPolicyConfigured   = "PolicyConfigured"
+	ReasonPolicyDeleted      = "PolicyDeleted"
+	ReasonPolicyFailed       = "PolicyFailed"
+	ReasonRolloutStarted     = "RolloutStarted"
+	ReasonRolloutHeld        = "RolloutHeld"
+	ReasonRolloutPromoted    = "RolloutPromoted"
+)
+
+// recordUnresolvedIntents emits a warning Event on the binding listing the
+// SecurityIntents it references that couldn't be resolved, when they differ
+// from the previously reported ones, so that requeues don't repeat it.
+func recordUnresolvedIntents(recorder record.EventRecorder, binding runtime.Object, previous, unresolvedIntents []v1alpha1.UnresolvedIntent) {
+	if recorder == nil || len(unresolvedIntents) == 0 || equality.Semantic.DeepEqual(previous, unresolvedIntents) {
+		return
+	}
+
+	var details []string
+	for _, intent := range unresolvedIntents {
+		details = append(details, fmt.Sprintf("%s (%s)", intent.Name, intent.Reason))
+	}
+	recorder.Eventf(binding, corev1.EventTypeWarning, ReasonUnresolvedIntents,
+		"Failed to resolve SecurityIntent(s): %s", strings.Join(details, ", "))
+}
+
+// recordConflicts emits a warning Event on the binding listing the intents it
+// binds that conflict with other bindings, and how they were resolved.
+func recordConflicts(recorder record.EventRecorder, binding runtime.Object, conflicts []v1alpha1.PolicyConflict) {
+	if recorder == nil || len(conflicts) == 0 {
+		return
+	}
+
+	var details []string
+	for _, conflict := range conflicts {
+		with := conflict.NimbusPolicy
+		if conflict.Binding != "" {
+			with = conflict.Binding
+		}
+		if conflict.Namespace != "" {
+			with = conflict.Namespace + "/" + with
+		}
+		if conflict.ConflictingID != "" {
+			with = conflict.ConflictingID + " of " + with
+		}
+		details = append(details, fmt.Sprintf("%s with %s (%s and %s resolved to %s, %s)", conflict.ID, with,
+			conflict.Action, conflict.ConflictingAction, conflict.ResolvedAction, conflict.Reason))
+	}
+	recorder.Eventf(binding, corev1.EventTypeWarning, ReasonConflictingIntents,
+		"Resolved conflicting intent(s): %s", strings.Join(details, ", "))
+}
+
+// recordValidationFailure emits a warning Event on an object that failed
+// validation.
+func recordValidationFailure(recorder record.EventRecorder, obj runtime.Object, message string) {
+	if recorder == nil {
+		return
+	}
+	recorder.Event(obj, corev1.EventTypeWarning, ReasonValidationFailed, message)
+}
+
+// recordPolicyEvent emits a normal Event on the binding for the NimbusPolicy
+// or ClusterNimbusPolicy of the given kind generated for it, and on that
+// policy unless it was deleted.
+func recordPolicyEvent(recorder record.EventRecorder, binding runtime.Object, kind string, policy client.Object, reason, verb string) {
+	if recorder == nil {
+		return
+	}
+	recorder.Eventf(binding, corev1.EventTypeNormal, reason, "%s %s %s", kind, client.ObjectKeyFromObject(policy), verb)
+	if reason != ReasonPolicyDeleted {
+		recorder.Eventf(policy, corev1.EventTypeNormal, reason, "%s %s", kind, verb)
+	}
+}
+
+// recordPolicyFailure emits a warning Event on the binding for the
+// NimbusPolicy or ClusterNimbusPolicy of the given kind that couldn't be
+// built, created, updated or deleted, and counts the failure.
+func recordPolicyFailure(recorder record.EventRecorder, binding runtime.Object, kind, verb string, err error) {
+	policyFailures.WithLabelValues(kind, verb).Inc()
+	if recorder == nil {
+		return
+	}
+	recorder.Eventf(binding, corev1.EventTypeWarning, ReasonPolicyFailed, "Failed to %s %s: %v", verb, kind, err)
+}
+
+// recordRollout emits an Event on the binding when its rollout starts, holds
+// or gets promoted.
+func recordRollout(recorder record.EventRecorder, binding runtime.Object, previous, current *v1alpha1.RolloutStatus) {
+	if recorder == nil || current == nil {
+		return
+	}
+	if previous != nil && previous.Phase == current.Phase && previous.ObservedGeneration == current.ObservedGeneration &&
+		slices.Equal(previous.ViolatedIntents, current.ViolatedIntents) {
+		return
+	}
+
+	switch current.Phase {
+	case v1alpha1.RolloutPhaseAuditing:
+		recorder.Event(binding, corev1.EventTypeNormal, ReasonRolloutStarted, current.Message)
+	case v1alpha1.RolloutPhaseHolding:
+		recorder.Event(binding, corev1.EventTypeWarning, ReasonRolloutHeld, current.Message)
+	case v1alpha1.RolloutPhasePromoted:
+		recorder.Event(binding, corev1.EventTypeNormal, ReasonRolloutPromoted, current.Message)
+	}
+}
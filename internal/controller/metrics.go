@@ -0,0 +1,118 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	v1alpha1 "github.com/5GSEC/nimbus/api/v1alpha1"
+	"github.com/5GSEC/nimbus/pkg/adapter/idpool"
+)
+
+var (
+	policyFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nimbus_policy_failures_total",
+			Help: "Total number of NimbusPolicies and ClusterNimbusPolicies that couldn't be built, i.e. translated from the bound intents, created, configured or deleted.",
+		},
+		[]string{"kind", "operation"},
+	)
+
+	boundIntentsDesc = prometheus.NewDesc(
+		"nimbus_binding_bound_intents",
+		"Number of SecurityIntents bound by a SecurityIntentBinding or ClusterSecurityIntentBinding.",
+		[]string{"kind", "namespace", "name"}, nil,
+	)
+	nimbusPoliciesDesc = prometheus.NewDesc(
+		"nimbus_nimbuspolicies",
+		"Number of NimbusPolicies per namespace.",
+		[]string{"namespace"}, nil,
+	)
+	unsupportedIntentsDesc = prometheus.NewDesc(
+		"nimbus_unsupported_securityintents",
+		"Number of SecurityIntents whose intent ID isn't supported by any adapter.",
+		[]string{"intent"}, nil,
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(policyFailures)
+}
+
+// stateCollector reports the state of the Nimbus resources, as read from the
+// given reader when the metrics are scraped.
+type stateCollector struct {
+	reader client.Reader
+}
+
+// NewStateCollector returns a Prometheus collector reporting the intents bound
+// by every binding, the NimbusPolicies per namespace and the unsupported
+// SecurityIntents. The reader is meant to be the manager's cache.
+func NewStateCollector(reader client.Reader) prometheus.Collector {
+	return &stateCollector{reader: reader}
+}
+
+func (c *stateCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- boundIntentsDesc
+	ch <- nimbusPoliciesDesc
+	ch <- unsupportedIntentsDesc
+}
+
+func (c *stateCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var sibs v1alpha1.SecurityIntentBindingList
+	if err := c.reader.List(ctx, &sibs); err != nil {
+		ch <- prometheus.NewInvalidMetric(boundIntentsDesc, err)
+	} else {
+		for _, sib := range sibs.Items {
+			ch <- prometheus.MustNewConstMetric(boundIntentsDesc, prometheus.GaugeValue,
+				float64(sib.Status.NumberOfBoundIntents), "SecurityIntentBinding", sib.Namespace, sib.Name)
+		}
+	}
+
+	var csibs v1alpha1.ClusterSecurityIntentBindingList
+	if err := c.reader.List(ctx, &csibs); err != nil {
+		ch <- prometheus.NewInvalidMetric(boundIntentsDesc, err)
+	} else {
+		for _, csib := range csibs.Items {
+			ch <- prometheus.MustNewConstMetric(boundIntentsDesc, prometheus.GaugeValue,
+				float64(csib.Status.NumberOfBoundIntents), "ClusterSecurityIntentBinding", "", csib.Name)
+		}
+	}
+
+	var nps v1alpha1.NimbusPolicyList
+	if err := c.reader.List(ctx, &nps); err != nil {
+		ch <- prometheus.NewInvalidMetric(nimbusPoliciesDesc, err)
+	} else {
+		perNamespace := make(map[string]int)
+		for _, np := range nps.Items {
+			perNamespace[np.Namespace]++
+		}
+		for namespace, count := range perNamespace {
+			ch <- prometheus.MustNewConstMetric(nimbusPoliciesDesc, prometheus.GaugeValue, float64(count), namespace)
+		}
+	}
+
+	var sis v1alpha1.SecurityIntentList
+	if err := c.reader.List(ctx, &sis); err != nil {
+		ch <- prometheus.NewInvalidMetric(unsupportedIntentsDesc, err)
+	} else {
+		perId := make(map[string]int)
+		for _, si := range sis.Items {
+			if !idpool.IsIdSupported(si.Spec.Intent.ID) {
+				perId[si.Spec.Intent.ID]++
+			}
+		}
+		for id, count := range perId {
+			ch <- prometheus.MustNewConstMetric(unsupportedIntentsDesc, prometheus.GaugeValue, float64(count), id)
+		}
+	}
+}
@@ -0,0 +1,345 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1alpha1 "github.com/5GSEC/nimbus/api/v1alpha1"
+	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
+	"github.com/5GSEC/nimbus/pkg/processor/policybuilder"
+)
+
+// rolloutCheckInterval is how often the violations of the intents are checked
+// while they are audited.
+const rolloutCheckInterval = time.Minute
+
+// The lists of the PolicyReports and ClusterPolicyReports in which Kyverno
+// reports the results of its policies.
+var (
+	policyReportListGVK        = schema.GroupVersionKind{Group: "wgpolicyk8s.io", Version: "v1alpha2", Kind: "PolicyReportList"}
+	clusterPolicyReportListGVK = schema.GroupVersionKind{Group: "wgpolicyk8s.io", Version: "v1alpha2", Kind: "ClusterPolicyReportList"}
+)
+
+// nextRollout returns the next state of the rollout of a binding with the
+// given strategy, current state and generation, along with how long to wait
+// before checking it again, zero when there's nothing left to check. The
+// rollout starts auditing the Block intents whenever the generation of the
+// binding changes, and holds as soon as audit reports violations. It also
+// holds instead of promoting the intents when audit reports some of them
+// can't be audited, until they can and have been for the quiet period.
+func nextRollout(strategy *v1alpha1.RolloutStrategy, current *v1alpha1.RolloutStatus, generation int64, now time.Time,
+	audit func(since, now time.Time) (intentAudit, error)) (*v1alpha1.RolloutStatus, time.Duration, error) {
+	if strategy == nil {
+		return nil, 0, nil
+	}
+
+	rollout := current.DeepCopy()
+	if rollout == nil || rollout.ObservedGeneration != generation {
+		rollout = &v1alpha1.RolloutStatus{
+			Phase:              v1alpha1.RolloutPhaseAuditing,
+			ObservedGeneration: generation,
+			AuditStartTime:     metav1.NewTime(now).Rfc3339Copy(),
+		}
+	}
+	// A rollout held for lack of audit results resumes once they're
+	// available, unlike one held by violations.
+	unavailable := rollout.Phase == v1alpha1.RolloutPhaseHolding && len(rollout.ViolatedIntents) == 0
+	if rollout.Phase != v1alpha1.RolloutPhaseAuditing && !unavailable {
+		return rollout, 0, nil
+	}
+
+	result, err := audit(rollout.AuditStartTime.Time, now)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(result.violated) > 0 {
+		rollout.Phase = v1alpha1.RolloutPhaseHolding
+		rollout.ViolatedIntents = result.violated
+		rollout.Message = fmt.Sprintf("Holding since intent(s) %s were violated while audited", strings.Join(result.violated, ", "))
+		return rollout, 0, nil
+	}
+
+	quietPeriod := strategy.QuietPeriod.Duration
+	if now.Sub(rollout.AuditStartTime.Time) >= quietPeriod && len(result.unaudited) > 0 {
+		rollout.Phase = v1alpha1.RolloutPhaseHolding
+		rollout.Message = fmt.Sprintf("Holding since the audit results of intent(s) %s are unavailable: none of their engines reports violations",
+			strings.Join(result.unaudited, ", "))
+		return rollout, rolloutCheckInterval, nil
+	}
+
+	// The quiet period only counts since the intents can be audited.
+	auditedSince := rollout.AuditStartTime.Time
+	if result.auditedSince.After(auditedSince) {
+		auditedSince = result.auditedSince
+	}
+	elapsed := now.Sub(auditedSince)
+	if elapsed >= quietPeriod {
+		promotionTime := metav1.NewTime(now).Rfc3339Copy()
+		rollout.Phase = v1alpha1.RolloutPhasePromoted
+		rollout.PromotionTime = &promotionTime
+		rollout.Message = fmt.Sprintf("Promoted to Block after %s without violations", quietPeriod)
+		return rollout, 0, nil
+	}
+
+	rollout.Phase = v1alpha1.RolloutPhaseAuditing
+	rollout.Message = fmt.Sprintf("Auditing the Block intents until %s",
+		auditedSince.Add(quietPeriod).UTC().Format(time.RFC3339))
+	return rollout, min(quietPeriod-elapsed, rolloutCheckInterval), nil
+}
+
+// rolledOutIntents returns the IDs of the Block intents audited by the rollout
+// according to the given action decisions.
+func rolledOutIntents(decisions []v1alpha1.ActionDecision) []string {
+	var ids []string
+	for _, decision := range decisions {
+		if decision.DecidedBy == policybuilder.RolloutDecision && !slices.Contains(ids, decision.ID) {
+			ids = append(ids, decision.ID)
+		}
+	}
+	return ids
+}
+
+// intentAudit is the outcome of auditing the rolled out intents of a binding.
+type intentAudit struct {
+	// violated are the intents violated while audited.
+	violated []string
+	// unaudited are the intents none of whose engines reports violations.
+	unaudited []string
+	// auditedSince is the latest time the engines of the other intents
+	// started reporting violations.
+	auditedSince time.Time
+}
+
+// auditIntents audits the given rolled out intents since the given time. They
+// are violated according to the violations that adapters reported in the
+// binding status or to the failed results of the Kyverno policies enforcing
+// them. They are audited as long as one of the engines enforcing them has a
+// violation source: an adapter relaying its violations, or the PolicyReports
+// for Kyverno.
+func auditIntents(ctx context.Context, c client.Client, ids []string, violations []v1alpha1.IntentViolation,
+	policies []adapterPolicies, namespace string, since, now time.Time) (intentAudit, error) {
+	var result intentAudit
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	failed, reported, err := failedKyvernoPolicies(ctx, c, namespace, since)
+	if err != nil {
+		return result, err
+	}
+	sources, err := adapterutil.ActiveViolationSources(ctx, c, now)
+	if err != nil {
+		return result, fmt.Errorf("failed to list the violation sources: %w", err)
+	}
+	if reported {
+		sources["kyverno"] = time.Time{}
+	}
+
+	for _, id := range ids {
+		if slices.ContainsFunc(violations, func(violation v1alpha1.IntentViolation) bool {
+			return violation.ID == id && !violation.LastSeen.Time.Before(since)
+		}) {
+			result.violated = append(result.violated, id)
+			continue
+		}
+
+		var enforcing []string
+		for _, p := range policies {
+			enforcing = append(enforcing, p.policiesForIntent(id)...)
+		}
+		if slices.ContainsFunc(enforcing, func(policy string) bool {
+			return failed[policy]
+		}) {
+			result.violated = append(result.violated, id)
+			continue
+		}
+
+		audited, auditedSince := false, time.Time{}
+		for _, policy := range enforcing {
+			sourceSince, ok := sources[engineOf(policy)]
+			if ok && (!audited || sourceSince.Before(auditedSince)) {
+				audited, auditedSince = true, sourceSince
+			}
+		}
+		if !audited {
+			result.unaudited = append(result.unaudited, id)
+			continue
+		}
+		if auditedSince.After(result.auditedSince) {
+			result.auditedSince = auditedSince
+		}
+	}
+	return result, nil
+}
+
+// failedKyvernoPolicies returns the Kyverno policies with failed results since
+// the given time in the PolicyReports of the given namespace, or of all the
+// namespaces along with the ClusterPolicyReports when it's empty. The policies
+// are named the way adapters report them in the NimbusPolicy status, also
+// qualified with their namespace. There aren't any when the PolicyReport CRDs
+// aren't installed, which is reported by reported being false.
+func failedKyvernoPolicies(ctx context.Context, c client.Client, namespace string, since time.Time) (failed map[string]bool, reported bool, err error) {
+	failed = make(map[string]bool)
+
+	gvks := []schema.GroupVersionKind{policyReportListGVK}
+	if namespace == "" {
+		gvks = append(gvks, clusterPolicyReportListGVK)
+	}
+	for _, gvk := range gvks {
+		reports := &unstructured.UnstructuredList{}
+		reports.SetGroupVersionKind(gvk)
+		if err := c.List(ctx, reports, client.InNamespace(namespace)); err != nil {
+			if meta.IsNoMatchError(err) {
+				continue
+			}
+			return nil, false, fmt.Errorf("failed to list %s: %w", strings.TrimSuffix(gvk.Kind, "List"), err)
+		}
+		reported = true
+
+		for _, report := range reports.Items {
+			results, _, _ := unstructured.NestedSlice(report.Object, "results")
+			for _, r := range results {
+				result, ok := r.(map[string]any)
+				if !ok || result["result"] != "fail" {
+					continue
+				}
+				seconds, _, _ := unstructured.NestedInt64(result, "timestamp", "seconds")
+				if time.Unix(seconds, 0).Before(since) {
+					continue
+				}
+
+				policy, _ := result["policy"].(string)
+				if policy == "" {
+					continue
+				}
+				kind, ns, name := adapterutil.ReportedKyvernoPolicy(policy)
+				failed[kind+"/"+name] = true
+				if ns != "" {
+					failed[ns+"/"+kind+"/"+name] = true
+				}
+			}
+		}
+	}
+
+	return failed, reported, nil
+}
+
+// updateRollout advances the rollout of the SecurityIntentBinding, and returns
+// how long to wait before checking it again.
+func (r *SecurityIntentBindingReconciler) updateRollout(ctx context.Context, req ctrl.Request) (time.Duration, error) {
+	var recheckAfter time.Duration
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		sib := &v1alpha1.SecurityIntentBinding{}
+		if err := r.Get(ctx, req.NamespacedName, sib); err != nil {
+			return err
+		}
+
+		rollout, after, err := nextRollout(sib.Spec.Rollout, sib.Status.Rollout, sib.Generation, time.Now(), func(since, now time.Time) (intentAudit, error) {
+			var np v1alpha1.NimbusPolicy
+			if err := r.Get(ctx, req.NamespacedName, &np); err != nil {
+				return intentAudit{}, client.IgnoreNotFound(err)
+			}
+			policies, err := fromNimbusPolicy(ctx, r.Client, np, false)
+			if err != nil {
+				return intentAudit{}, err
+			}
+			return auditIntents(ctx, r.Client, rolledOutIntents(np.Status.ActionDecisions), sib.Status.Violations,
+				[]adapterPolicies{policies}, np.Namespace, since, now)
+		})
+		if err != nil {
+			return err
+		}
+		recheckAfter = after
+
+		if equality.Semantic.DeepEqual(rollout, sib.Status.Rollout) {
+			return nil
+		}
+		previous := sib.Status.Rollout
+		sib.Status.Rollout = rollout
+		if err := r.Status().Update(ctx, sib); err != nil {
+			return err
+		}
+		recordRollout(r.Recorder, sib, previous, rollout)
+		return nil
+	})
+	return recheckAfter, err
+}
+
+// updateRollout advances the rollout of the ClusterSecurityIntentBinding, and
+// returns how long to wait before checking it again.
+func (r *ClusterSecurityIntentBindingReconciler) updateRollout(ctx context.Context, req ctrl.Request) (time.Duration, error) {
+	var recheckAfter time.Duration
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		csib := &v1alpha1.ClusterSecurityIntentBinding{}
+		if err := r.Get(ctx, req.NamespacedName, csib); err != nil {
+			return err
+		}
+
+		rollout, after, err := nextRollout(csib.Spec.Rollout, csib.Status.Rollout, csib.Generation, time.Now(), func(since, now time.Time) (intentAudit, error) {
+			var ids []string
+			var policies []adapterPolicies
+
+			var cwnp v1alpha1.ClusterNimbusPolicy
+			if err := r.Get(ctx, types.NamespacedName{Name: csib.Name}, &cwnp); err == nil {
+				cwnpPolicies, err := fromClusterNimbusPolicy(ctx, r.Client, cwnp)
+				if err != nil {
+					return intentAudit{}, err
+				}
+				ids = append(ids, rolledOutIntents(cwnp.Status.ActionDecisions)...)
+				policies = append(policies, cwnpPolicies)
+			} else if client.IgnoreNotFound(err) != nil {
+				return intentAudit{}, err
+			}
+
+			var nps v1alpha1.NimbusPolicyList
+			if err := r.List(ctx, &nps); err != nil {
+				return intentAudit{}, err
+			}
+			for _, np := range nps.Items {
+				if np.Name == "nimbus-ctlr-gen-"+csib.Name {
+					npPolicies, err := fromNimbusPolicy(ctx, r.Client, np, true)
+					if err != nil {
+						return intentAudit{}, err
+					}
+					ids = append(ids, rolledOutIntents(np.Status.ActionDecisions)...)
+					policies = append(policies, npPolicies)
+				}
+			}
+
+			slices.Sort(ids)
+			return auditIntents(ctx, r.Client, slices.Compact(ids), csib.Status.Violations, policies, "", since, now)
+		})
+		if err != nil {
+			return err
+		}
+		recheckAfter = after
+
+		if equality.Semantic.DeepEqual(rollout, csib.Status.Rollout) {
+			return nil
+		}
+		previous := csib.Status.Rollout
+		csib.Status.Rollout = rollout
+		if err := r.Status().Update(ctx, csib); err != nil {
+			return err
+		}
+		recordRollout(r.Recorder, csib, previous, rollout)
+		return nil
+	})
+	return recheckAfter, err
+}
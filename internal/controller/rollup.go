@@ -0,0 +1,312 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	v1alpha1 "github.com/5GSEC/nimbus/api/v1alpha1"
+	"github.com/5GSEC/nimbus/pkg/adapter/idpool"
+	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
+)
+
+// engineByPolicyKind maps the kind that adapters use when reporting their
+// policies in NimbusPolicy status to the engine that enforces them.
+var engineByPolicyKind = map[string]string{
+	"KubeArmorPolicy":                "kubearmor",
+	"KubeArmorClusterPolicy":         "kubearmor",
+	"NetworkPolicy":                  "netpol",
+	"AdminNetworkPolicy":             "netpol",
+	"BaselineAdminNetworkPolicy":     "netpol",
+	"CiliumNetworkPolicy":            "cilium",
+	"CiliumClusterwideNetworkPolicy": "cilium",
+	"CalicoNetworkPolicy":            "calico",
+	"GlobalNetworkPolicy":            "calico",
+	"KyvernoPolicy":                  "kyverno",
+	"KyvernoClusterPolicy":           "kyverno",
+	"CronJob":                        "k8tls",
+}
+
+// policyGVKs maps the kind that adapters use when reporting their policies in
+// NimbusPolicy status to the kind of the policies, to look up the intents they
+// enforce.
+var policyGVKs = map[string]schema.GroupVersionKind{
+	"KubeArmorPolicy":                {Group: "security.kubearmor.com", Version: "v1", Kind: "KubeArmorPolicy"},
+	"KubeArmorClusterPolicy":         {Group: "security.kubearmor.com", Version: "v1", Kind: "KubeArmorClusterPolicy"},
+	"NetworkPolicy":                  {Group: "networking.k8s.io", Version: "v1", Kind: "NetworkPolicy"},
+	"AdminNetworkPolicy":             {Group: "policy.networking.k8s.io", Version: "v1alpha1", Kind: "AdminNetworkPolicy"},
+	"BaselineAdminNetworkPolicy":     {Group: "policy.networking.k8s.io", Version: "v1alpha1", Kind: "BaselineAdminNetworkPolicy"},
+	"CiliumNetworkPolicy":            {Group: "cilium.io", Version: "v2", Kind: "CiliumNetworkPolicy"},
+	"CiliumClusterwideNetworkPolicy": {Group: "cilium.io", Version: "v2", Kind: "CiliumClusterwideNetworkPolicy"},
+	"CalicoNetworkPolicy":            {Group: "projectcalico.org", Version: "v3", Kind: "NetworkPolicy"},
+	"GlobalNetworkPolicy":            {Group: "projectcalico.org", Version: "v3", Kind: "GlobalNetworkPolicy"},
+	"KyvernoPolicy":                  {Group: "kyverno.io", Version: "v1", Kind: "Policy"},
+	"KyvernoClusterPolicy":           {Group: "kyverno.io", Version: "v1", Kind: "ClusterPolicy"},
+	"CronJob":                        {Group: "batch", Version: "v1", Kind: "CronJob"},
+}
+
+// adapterPolicies holds the policies that adapters reported for a single
+// NimbusPolicy or ClusterNimbusPolicy.
+type adapterPolicies struct {
+	ruleIDs []string
+	// actions maps the rule IDs to their action.
+	actions  map[string]string
+	policies []string
+	// intents maps the policies to the IDs of the intents they enforce, read
+	// from their intents annotation. Policies that couldn't be looked up
+	// aren't mapped.
+	intents map[string][]string
+	// namespace, when set, qualifies the reported policy names. It is used for
+	// the NimbusPolicies fanned out by a ClusterSecurityIntentBinding, which
+	// share the same name across namespaces.
+	namespace string
+}
+
+func fromNimbusPolicy(ctx context.Context, c client.Reader, np v1alpha1.NimbusPolicy, qualify bool) (adapterPolicies, error) {
+	intents, err := policyIntents(ctx, c, np.Status.Policies, np.Namespace)
+	if err != nil {
+		return adapterPolicies{}, err
+	}
+	policies := adapterPolicies{
+		ruleIDs:  ruleIDs(np.Spec.NimbusRules),
+		actions:  ruleActions(np.Spec.NimbusRules),
+		policies: np.Status.Policies,
+		intents:  intents,
+	}
+	if qualify {
+		policies.namespace = np.Namespace
+	}
+	return policies, nil
+}
+
+func fromClusterNimbusPolicy(ctx context.Context, c client.Reader, cwnp v1alpha1.ClusterNimbusPolicy) (adapterPolicies, error) {
+	intents, err := policyIntents(ctx, c, cwnp.Status.Policies, "")
+	if err != nil {
+		return adapterPolicies{}, err
+	}
+	return adapterPolicies{
+		ruleIDs:  ruleIDs(cwnp.Spec.NimbusRules),
+		actions:  ruleActions(cwnp.Spec.NimbusRules),
+		policies: cwnp.Status.Policies,
+		intents:  intents,
+	}, nil
+}
+
+// policyIntents looks up the intents annotation of the given policies, named
+// "[<namespace>/]<kind>/<name>", the namespaced ones without namespace being
+// in the given namespace. Only the metadata of the policies is read, from the
+// cache of the client. The policies that don't exist anymore, or whose kind
+// isn't served, aren't mapped.
+func policyIntents(ctx context.Context, c client.Reader, policies []string, namespace string) (map[string][]string, error) {
+	intents := make(map[string][]string)
+	for _, policy := range policies {
+		parts := strings.Split(policy, "/")
+		if len(parts) < 2 {
+			continue
+		}
+		gvk, ok := policyGVKs[parts[len(parts)-2]]
+		if !ok {
+			continue
+		}
+		key := types.NamespacedName{Name: parts[len(parts)-1], Namespace: namespace}
+		if len(parts) > 2 {
+			key.Namespace = parts[len(parts)-3]
+		}
+
+		obj := &metav1.PartialObjectMetadata{}
+		obj.SetGroupVersionKind(gvk)
+		if err := c.Get(ctx, key, obj); err != nil {
+			if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to get %s: %w", policy, err)
+		}
+		if annotation := obj.GetAnnotations()[adapterutil.IntentsAnnotation]; annotation != "" {
+			intents[policy] = strings.Split(annotation, ",")
+		}
+	}
+	return intents, nil
+}
+
+func ruleIDs(rules []v1alpha1.NimbusRules) []string {
+	var ids []string
+	for _, rule := range rules {
+		ids = append(ids, rule.ID)
+	}
+	return ids
+}
+
+func ruleActions(rules []v1alpha1.NimbusRules) map[string]string {
+	actions := make(map[string]string)
+	for _, rule := range rules {
+		actions[rule.ID] = rule.Rule.RuleAction
+	}
+	return actions
+}
+
+// policiesForIntent returns the policies that enforce the given intent ID,
+// according to the intents annotation adapters set on their policies.
+// Policies whose annotation couldn't be read are attributed to the only rule
+// of the owner, if there is just one. Consolidated ones, merging the rules of
+// all the intents their engine enforces with their action, are attributed to
+// each of these intents, and so is the BaselineAdminNetworkPolicy, a singleton
+// named "default" that merges the rules of the baseline tier intents.
+func (a adapterPolicies) policiesForIntent(id string) []string {
+	var policies []string
+	for _, policy := range a.policies {
+		if ids, ok := a.intents[policy]; ok {
+			if slices.Contains(ids, id) {
+				policies = append(policies, a.qualified(policy))
+			}
+			continue
+		}
+
+		parts := strings.Split(policy, "/")
+		name := parts[len(parts)-1]
+		if strings.HasPrefix(policy, "BaselineAdminNetworkPolicy/") {
+			if idpool.IsIdSupportedBy(id, engineOf(policy)) {
+				policies = append(policies, a.qualified(policy))
+			}
+			continue
+		}
+		if action, ok := adapterutil.ConsolidatedPolicyAction(name); ok {
+			if strings.EqualFold(a.actions[id], action) && idpool.IsIdSupportedBy(id, engineOf(policy)) {
+				policies = append(policies, a.qualified(policy))
+			}
+			continue
+		}
+		if len(a.ruleIDs) == 1 && a.ruleIDs[0] == id {
+			policies = append(policies, a.qualified(policy))
+		}
+	}
+	return policies
+}
+
+func (a adapterPolicies) qualified(policy string) string {
+	if a.namespace == "" || len(strings.Split(policy, "/")) > 2 {
+		return policy
+	}
+	return a.namespace + "/" + policy
+}
+
+func engineOf(policy string) string {
+	parts := strings.Split(policy, "/")
+	if len(parts) < 2 {
+		return ""
+	}
+	kind := parts[len(parts)-2]
+	if engine, ok := engineByPolicyKind[kind]; ok {
+		return engine
+	}
+	return strings.ToLower(kind)
+}
+
+// rollUpIntentStatuses builds the per-intent enforcement status of a binding
+// from the adapter policies of its cluster-wide policy and its namespaced
+// policies. An intent enforced cluster-wide is Enforced. Otherwise, it is
+// Enforced when every namespaced policy has at least one adapter policy for
+// it, Partial when only some have, and NotEnforced when none have.
+func rollUpIntentStatuses(intents []v1alpha1.SecurityIntent, clusterPolicies *adapterPolicies, nsPolicies []adapterPolicies) ([]v1alpha1.IntentStatus, v1alpha1.EnforcementPhase) {
+	var statuses []v1alpha1.IntentStatus
+	var enforced int
+
+	for _, si := range intents {
+		status := v1alpha1.IntentStatus{
+			Name:  si.Name,
+			ID:    si.Spec.Intent.ID,
+			Phase: v1alpha1.EnforcementPhaseNotEnforced,
+		}
+
+		var clusterWide bool
+		if clusterPolicies != nil {
+			if policies := clusterPolicies.policiesForIntent(si.Spec.Intent.ID); len(policies) > 0 {
+				status.Policies = append(status.Policies, policies...)
+				clusterWide = true
+			}
+		}
+
+		var enforcedNps int
+		for _, np := range nsPolicies {
+			policies := np.policiesForIntent(si.Spec.Intent.ID)
+			if len(policies) > 0 {
+				status.Policies = append(status.Policies, policies...)
+				enforcedNps++
+			}
+		}
+
+		switch {
+		case clusterWide || (enforcedNps > 0 && enforcedNps == len(nsPolicies)):
+			status.Phase = v1alpha1.EnforcementPhaseEnforced
+			enforced++
+		case enforcedNps > 0:
+			status.Phase = v1alpha1.EnforcementPhasePartial
+		}
+
+		for _, policy := range status.Policies {
+			if engine := engineOf(policy); engine != "" && !slices.Contains(status.Engines, engine) {
+				status.Engines = append(status.Engines, engine)
+			}
+		}
+		slices.Sort(status.Engines)
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses, bindingPhase(statuses, enforced)
+}
+
+func bindingPhase(statuses []v1alpha1.IntentStatus, enforced int) v1alpha1.EnforcementPhase {
+	if len(statuses) > 0 && enforced == len(statuses) {
+		return v1alpha1.EnforcementPhaseEnforced
+	}
+	for _, status := range statuses {
+		if status.Phase != v1alpha1.EnforcementPhaseNotEnforced {
+			return v1alpha1.EnforcementPhasePartial
+		}
+	}
+	return v1alpha1.EnforcementPhaseNotEnforced
+}
+
+// fetchSecurityIntents returns the SecurityIntents with the given names that
+// exist in the cluster.
+func fetchSecurityIntents(ctx context.Context, c client.Client, names []string) []v1alpha1.SecurityIntent {
+	logger := log.FromContext(ctx)
+
+	var intents []v1alpha1.SecurityIntent
+	for _, name := range names {
+		var si v1alpha1.SecurityIntent
+		if err := c.Get(ctx, types.NamespacedName{Name: name}, &si); err != nil {
+			logger.Error(err, "failed to fetch SecurityIntent", "SecurityIntent.Name", name)
+			continue
+		}
+		intents = append(intents, si)
+	}
+	return intents
+}
+
+// adapterPoliciesChanged reports whether the adapters changed the set of
+// policies reported in the status of a NimbusPolicy or ClusterNimbusPolicy.
+func adapterPoliciesChanged(updateEvent event.UpdateEvent) bool {
+	switch oldObj := updateEvent.ObjectOld.(type) {
+	case *v1alpha1.NimbusPolicy:
+		newObj, ok := updateEvent.ObjectNew.(*v1alpha1.NimbusPolicy)
+		return ok && !slices.Equal(oldObj.Status.Policies, newObj.Status.Policies)
+	case *v1alpha1.ClusterNimbusPolicy:
+		newObj, ok := updateEvent.ObjectNew.(*v1alpha1.ClusterNimbusPolicy)
+		return ok && !slices.Equal(oldObj.Status.Policies, newObj.Status.Policies)
+	}
+	return false
+}
@@ -6,24 +6,29 @@ package controller
 import (
 	"context"
 
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	v1alpha1 "github.com/5GSEC/nimbus/api/v1alpha1"
+	"github.com/5GSEC/nimbus/pkg/adapter/idpool"
 )
 
 type SecurityIntentReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=intent.security.nimbus.com,resources=securityintents,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=intent.security.nimbus.com,resources=securityintents/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -47,6 +52,14 @@ func (r *SecurityIntentReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		logger.Info("SecurityIntent configured", "SecurityIntent.Name", si.Name)
 	}
 
+	if !idpool.IsIdSupported(si.Spec.Intent.ID) {
+		logger.Info("No adapter supports this ID", "ID", si.Spec.Intent.ID, "SecurityIntent.Name", si.Name)
+		if r.Recorder != nil {
+			r.Recorder.Eventf(si, corev1.EventTypeWarning, ReasonUnsupportedIntent,
+				"Intent ID %s is not supported by any adapter, so it won't be enforced", si.Spec.Intent.ID)
+		}
+	}
+
 	if err = r.updateStatus(ctx, req.Name); err != nil {
 		logger.Error(err, "failed to update SecurityIntent status", "SecurityIntent.Name", req.Name)
 		return requeueWithError(err)
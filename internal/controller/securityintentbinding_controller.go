@@ -9,10 +9,14 @@ import (
 	"strings"
 
 	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -24,27 +28,46 @@ import (
 
 	v1alpha1 "github.com/5GSEC/nimbus/api/v1alpha1"
 	processorerrors "github.com/5GSEC/nimbus/pkg/processor/errors"
+	"github.com/5GSEC/nimbus/pkg/processor/intentbinder"
 	"github.com/5GSEC/nimbus/pkg/processor/policybuilder"
+	"github.com/5GSEC/nimbus/pkg/tracing"
 )
 
 // SecurityIntentBindingReconciler reconciles a SecurityIntentBinding object
 type SecurityIntentBindingReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=intent.security.nimbus.com,resources=securityintentbindings,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=intent.security.nimbus.com,resources=securityintentbindings/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=intent.security.nimbus.com,resources=nimbuspolicies,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=intent.security.nimbus.com,resources=nimbuspolicies/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=intent.security.nimbus.com,resources=clusterenforcementpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=wgpolicyk8s.io,resources=policyreports;clusterpolicyreports,verbs=get;list
+//+kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=list
+//+kubebuilder:rbac:groups=security.kubearmor.com,resources=kubearmorpolicies;kubearmorclusterpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=policy.networking.k8s.io,resources=adminnetworkpolicies;baselineadminnetworkpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=cilium.io,resources=ciliumnetworkpolicies;ciliumclusterwidenetworkpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=projectcalico.org,resources=networkpolicies;globalnetworkpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=kyverno.io,resources=policies;clusterpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
-func (r *SecurityIntentBindingReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *SecurityIntentBindingReconciler) Reconcile(ctx context.Context, req ctrl.Request) (_ ctrl.Result, err error) {
+	ctx, span := tracing.Start(ctx, "SecurityIntentBinding.Reconcile", trace.WithAttributes(
+		attribute.String("SecurityIntentBinding.Name", req.Name),
+		attribute.String("SecurityIntentBinding.Namespace", req.Namespace),
+	))
+	defer func() { tracing.End(span, err) }()
 	logger := log.FromContext(ctx)
 
 	sib := &v1alpha1.SecurityIntentBinding{}
-	err := r.Get(ctx, req.NamespacedName, sib)
+	err = r.Get(ctx, req.NamespacedName, sib)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
 			logger.Info("SecurityIntentBinding not found. Ignoring since object must be deleted")
@@ -64,6 +87,14 @@ func (r *SecurityIntentBindingReconciler) Reconcile(ctx context.Context, req ctr
 		return requeueWithError(err)
 	}
 
+	// Advance the rollout before building the NimbusPolicy, so that a
+	// promotion enforces the Block intents right away.
+	recheckAfter, err := r.updateRollout(ctx, req)
+	if err != nil {
+		logger.Error(err, "failed to update the rollout", "SecurityIntentBinding.Name", req.Name, "SecurityIntentBinding.Namespace", req.Namespace)
+		return requeueWithError(err)
+	}
+
 	if err = r.createOrUpdateNp(ctx, logger, req); err != nil {
 		return requeueWithError(err)
 	}
@@ -72,6 +103,9 @@ func (r *SecurityIntentBindingReconciler) Reconcile(ctx context.Context, req ctr
 		return requeueWithError(err)
 	}
 
+	if recheckAfter > 0 {
+		return requeueAfter(recheckAfter)
+	}
 	return doNotRequeue()
 }
 
@@ -90,6 +124,12 @@ func (r *SecurityIntentBindingReconciler) SetupWithManager(mgr ctrl.Manager) err
 		Watches(&v1alpha1.SecurityIntent{},
 			handler.EnqueueRequestsFromMapFunc(r.findSibsForSi),
 		).
+		Watches(&v1alpha1.ClusterEnforcementPolicy{},
+			handler.EnqueueRequestsFromMapFunc(r.findSibsForCep),
+		).
+		Watches(&v1alpha1.NimbusPolicy{},
+			handler.EnqueueRequestsFromMapFunc(r.findSibsForNp),
+		).
 		Complete(r)
 }
 
@@ -103,7 +143,18 @@ func (r *SecurityIntentBindingReconciler) createFn(createEvent event.CreateEvent
 func (r *SecurityIntentBindingReconciler) updateFn(updateEvent event.UpdateEvent) bool {
 	// TODO: Handle update event for NimbusPolicy update so that reconciler don't process it
 	// twice.
-	return updateEvent.ObjectOld.GetGeneration() != updateEvent.ObjectNew.GetGeneration()
+	if updateEvent.ObjectOld.GetGeneration() != updateEvent.ObjectNew.GetGeneration() {
+		return true
+	}
+	if intentLabelsChanged(updateEvent) {
+		return true
+	}
+	if actionDecisionsChanged(updateEvent) {
+		return true
+	}
+	// Adapters only update the NimbusPolicy status, so watch for changes to the
+	// policies they report in order to roll them up into the binding status.
+	return adapterPoliciesChanged(updateEvent)
 }
 
 func (r *SecurityIntentBindingReconciler) deleteFn(deleteEvent event.DeleteEvent) bool {
@@ -114,7 +165,10 @@ func (r *SecurityIntentBindingReconciler) deleteFn(deleteEvent event.DeleteEvent
 	if _, ok := obj.(*v1alpha1.SecurityIntent); ok {
 		return true
 	}
-	return ownerExists(r.Client, obj)
+	if _, ok := obj.(*v1alpha1.ClusterEnforcementPolicy); ok {
+		return true
+	}
+	return hasConflicts(obj) || ownerExists(r.Client, obj)
 }
 
 func (r *SecurityIntentBindingReconciler) createOrUpdateNp(ctx context.Context, logger logr.Logger, req ctrl.Request) error {
@@ -146,17 +200,19 @@ func (r *SecurityIntentBindingReconciler) createNp(ctx context.Context, logger l
 		// Error is caused due to CEL, so don't retry to build NimbusPolicy.
 		if strings.Contains(err.Error(), "error processing CEL") {
 			logger.Error(err, "failed to build NimbusPolicy")
+			recordPolicyFailure(r.Recorder, &sib, "NimbusPolicy", "build", err)
 			return nil
 		}
 		if errors.Is(err, processorerrors.ErrSecurityIntentsNotFound) {
 			// Since the SecurityIntent(s) referenced in SecurityIntentBinding spec do not
 			// exist, so delete NimbusPolicy if it exists.
-			if err := r.deleteNp(ctx, sib.GetName(), sib.GetNamespace()); err != nil {
+			if err := r.deleteNp(ctx, sib); err != nil {
 				return err
 			}
 			return nil
 		}
 		logger.Error(err, "failed to build NimbusPolicy")
+		recordPolicyFailure(r.Recorder, &sib, "NimbusPolicy", "build", err)
 		return err
 	}
 	if nimbusPolicy == nil {
@@ -164,17 +220,25 @@ func (r *SecurityIntentBindingReconciler) createNp(ctx context.Context, logger l
 		return nil
 	}
 
+	// Keep the action decisions and conflicts since creating the NimbusPolicy
+	// resets its status.
+	actionDecisions, conflicts := nimbusPolicy.Status.ActionDecisions, nimbusPolicy.Status.Conflicts
+	tracing.Inject(ctx, nimbusPolicy)
 	if err := r.Create(ctx, nimbusPolicy); err != nil {
 		logger.Error(err, "failed to create NimbusPolicy", "NimbusPolicy.Name", nimbusPolicy.Name, "NimbusPolicy.Namespace", nimbusPolicy.Namespace)
+		recordPolicyFailure(r.Recorder, &sib, "NimbusPolicy", "create", err)
 		return err
 	}
 	logger.Info("NimbusPolicy created", "NimbusPolicy.Name", nimbusPolicy.Name, "NimbusPolicy.Namespace", nimbusPolicy.Namespace)
+	recordPolicyEvent(r.Recorder, &sib, "NimbusPolicy", nimbusPolicy, ReasonPolicyCreated, "created")
 
 	return r.updateNpStatus(ctx, logger, ctrl.Request{
 		NamespacedName: types.NamespacedName{
 			Namespace: sib.Namespace,
 			Name:      sib.Name,
 		}},
+		actionDecisions,
+		conflicts,
 	)
 }
 
@@ -191,17 +255,19 @@ func (r *SecurityIntentBindingReconciler) updateNp(ctx context.Context, logger l
 		// Error is caused due to CEL, so don't retry to build NimbusPolicy.
 		if strings.Contains(err.Error(), "error processing CEL") {
 			logger.Error(err, "failed to build NimbusPolicy")
+			recordPolicyFailure(r.Recorder, &sib, "NimbusPolicy", "build", err)
 			return nil
 		}
 		if errors.Is(err, processorerrors.ErrSecurityIntentsNotFound) {
 			// Since the SecurityIntent(s) referenced in SecurityIntentBinding spec do not
 			// exist, so delete NimbusPolicy if it exists.
-			if err := r.deleteNp(ctx, sib.GetName(), sib.GetNamespace()); err != nil {
+			if err := r.deleteNp(ctx, sib); err != nil {
 				return err
 			}
 			return nil
 		}
 		logger.Error(err, "failed to build NimbusPolicy")
+		recordPolicyFailure(r.Recorder, &sib, "NimbusPolicy", "build", err)
 		return err
 	}
 	if nimbusPolicy == nil {
@@ -210,17 +276,33 @@ func (r *SecurityIntentBindingReconciler) updateNp(ctx context.Context, logger l
 	}
 
 	nimbusPolicy.ObjectMeta.ResourceVersion = existingNp.ObjectMeta.ResourceVersion
+	actionDecisions, conflicts := nimbusPolicy.Status.ActionDecisions, nimbusPolicy.Status.Conflicts
+	// Only trace the reconciliations that change the NimbusPolicy down to the
+	// adapters.
+	if equality.Semantic.DeepEqual(existingNp.Spec, nimbusPolicy.Spec) {
+		tracing.Preserve(&existingNp, nimbusPolicy)
+	} else {
+		tracing.Inject(ctx, nimbusPolicy)
+	}
 	if err := r.Update(ctx, nimbusPolicy); err != nil {
 		logger.Error(err, "failed to configure NimbusPolicy", "NimbusPolicy.Name", nimbusPolicy.Name, "NimbusPolicy.Namespace", nimbusPolicy.Namespace)
+		recordPolicyFailure(r.Recorder, &sib, "NimbusPolicy", "configure", err)
 		return err
 	}
 	logger.Info("NimbusPolicy configured", "NimbusPolicy.Name", nimbusPolicy.Name, "NimbusPolicy.Namespace", nimbusPolicy.Namespace)
+	// The binding is reconciled for every change to the policies of the
+	// adapters, so only report actual changes to the NimbusPolicy.
+	if nimbusPolicy.GetGeneration() != existingNp.GetGeneration() {
+		recordPolicyEvent(r.Recorder, &sib, "NimbusPolicy", nimbusPolicy, ReasonPolicyConfigured, "configured")
+	}
 
 	return r.updateNpStatus(ctx, logger, ctrl.Request{
 		NamespacedName: types.NamespacedName{
 			Namespace: sib.Namespace,
 			Name:      sib.Name,
 		}},
+		actionDecisions,
+		conflicts,
 	)
 }
 
@@ -233,28 +315,68 @@ func (r *SecurityIntentBindingReconciler) findSibsForSi(ctx context.Context, si
 		return []reconcile.Request{}
 	}
 
-	requests := make([]reconcile.Request, len(sibs.Items))
+	var requests []reconcile.Request
 
-	for idx, sib := range sibs.Items {
-		for _, intent := range sib.Spec.Intents {
-			if intent.Name == si.GetName() {
-				requests[idx] = ctrl.Request{
-					NamespacedName: types.NamespacedName{
-						Namespace: sib.GetNamespace(),
-						Name:      sib.GetName(),
-					},
-				}
-				break
-			}
+	for _, sib := range sibs.Items {
+		if bindsIntent(sib.Spec.Intents, sib.Spec.IntentSelector, sib.Status.BoundIntents, si) {
+			requests = append(requests, ctrl.Request{
+				NamespacedName: types.NamespacedName{
+					Namespace: sib.GetNamespace(),
+					Name:      sib.GetName(),
+				},
+			})
 		}
 	}
 
 	return requests
 }
 
-func (r *SecurityIntentBindingReconciler) deleteNp(ctx context.Context, name, namespace string) error {
+// findSibsForCep enqueues all the SecurityIntentBindings, since a change to a
+// ClusterEnforcementPolicy may change the effective action of any bound intent.
+func (r *SecurityIntentBindingReconciler) findSibsForCep(ctx context.Context, _ client.Object) []reconcile.Request {
 	logger := log.FromContext(ctx)
 
+	sibs := &v1alpha1.SecurityIntentBindingList{}
+	if err := r.List(ctx, sibs); err != nil {
+		logger.Error(err, "failed to list SecurityIntentBindings")
+		return []reconcile.Request{}
+	}
+
+	var requests []reconcile.Request
+	for _, sib := range sibs.Items {
+		requests = append(requests, ctrl.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: sib.GetNamespace(),
+				Name:      sib.GetName(),
+			},
+		})
+	}
+
+	return requests
+}
+
+// findSibsForNp enqueues the SecurityIntentBindings of the NimbusPolicies that
+// share intents with the given NimbusPolicy, since a change to it may create or
+// resolve conflicts with them.
+func (r *SecurityIntentBindingReconciler) findSibsForNp(ctx context.Context, np client.Object) []reconcile.Request {
+	var requests []reconcile.Request
+	for _, other := range nimbusPoliciesSharingIntents(ctx, r.Client, np) {
+		if name, ok := ownedBy(other, "SecurityIntentBinding"); ok {
+			requests = append(requests, ctrl.Request{
+				NamespacedName: types.NamespacedName{
+					Namespace: other.GetNamespace(),
+					Name:      name,
+				},
+			})
+		}
+	}
+	return requests
+}
+
+func (r *SecurityIntentBindingReconciler) deleteNp(ctx context.Context, sib v1alpha1.SecurityIntentBinding) error {
+	logger := log.FromContext(ctx)
+	name, namespace := sib.GetName(), sib.GetNamespace()
+
 	var np v1alpha1.NimbusPolicy
 	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, &np)
 	if err != nil {
@@ -268,13 +390,15 @@ func (r *SecurityIntentBindingReconciler) deleteNp(ctx context.Context, name, na
 	logger.Info("NimbusPolicy deleted", "nimbusPolicyName", name, "nimbusPolicyNamespace", namespace)
 	if err = r.Delete(context.Background(), &np); err != nil {
 		logger.Error(err, "failed to delete NimbusPolicy", "nimbusPolicyName", name, "nimbusPolicyNamespace", namespace)
+		recordPolicyFailure(r.Recorder, &sib, "NimbusPolicy", "delete", err)
 		return err
 	}
+	recordPolicyEvent(r.Recorder, &sib, "NimbusPolicy", &np, ReasonPolicyDeleted, "deleted since no SecurityIntents found")
 
 	return nil
 }
 
-func (r *SecurityIntentBindingReconciler) updateNpStatus(ctx context.Context, logger logr.Logger, req ctrl.Request) error {
+func (r *SecurityIntentBindingReconciler) updateNpStatus(ctx context.Context, logger logr.Logger, req ctrl.Request, actionDecisions []v1alpha1.ActionDecision, conflicts []v1alpha1.PolicyConflict) error {
 	np := &v1alpha1.NimbusPolicy{}
 
 	// To handle potential latency or outdated cache issues with the Kubernetes API
@@ -305,6 +429,8 @@ func (r *SecurityIntentBindingReconciler) updateNpStatus(ctx context.Context, lo
 
 		np.Status.Status = StatusCreated
 		np.Status.LastUpdated = metav1.Now()
+		np.Status.ActionDecisions = actionDecisions
+		np.Status.Conflicts = conflicts
 		if err := r.Status().Update(ctx, np); err != nil {
 			return err
 		}
@@ -346,6 +472,14 @@ func (r *SecurityIntentBindingReconciler) updateSibStatusWithBoundSisAndNpInfo(c
 		return err
 	}
 
+	_, unresolvedIntents, err := intentbinder.ExtractIntents(ctx, r.Client, latestSib)
+	if err != nil {
+		logger.Error(err, "failed to fetch SecurityIntents", "SecurityIntentBinding.Name", req.Name, "SecurityIntentBinding.Namespace", req.Namespace)
+		return err
+	}
+	recordUnresolvedIntents(r.Recorder, latestSib, latestSib.Status.UnresolvedIntents, unresolvedIntents)
+	latestSib.Status.UnresolvedIntents = unresolvedIntents
+
 	latestNp := &v1alpha1.NimbusPolicy{}
 	if retryErr := retry.OnError(retry.DefaultRetry, apierrors.IsNotFound, func() error {
 		if err := r.Get(ctx, req.NamespacedName, latestNp); err != nil {
@@ -362,6 +496,9 @@ func (r *SecurityIntentBindingReconciler) updateSibStatusWithBoundSisAndNpInfo(c
 		latestSib.Status.NumberOfBoundIntents = 0
 		latestSib.Status.BoundIntents = nil
 		latestSib.Status.NimbusPolicy = ""
+		latestSib.Status.IntentStatuses = nil
+		latestSib.Status.EnforcementPhase = v1alpha1.EnforcementPhaseNotEnforced
+		latestSib.Status.Conflicts = nil
 		if err := r.Status().Update(ctx, latestSib); err != nil {
 			logger.Error(err, "failed to update SecurityIntentBinding status", "SecurityIntentBinding.Name", req.Name, "SecurityIntentBinding.Namespace", req.Namespace)
 			return err
@@ -371,9 +508,26 @@ func (r *SecurityIntentBindingReconciler) updateSibStatusWithBoundSisAndNpInfo(c
 	}
 
 	// Update SecurityIntentBinding status with bound SecurityIntent(s) and NimbusPolicy.
+	boundIntents, err := extractBoundIntentsNameFromSib(ctx, r.Client, req.Name, req.Namespace)
+	if err != nil {
+		logger.Error(err, "failed to fetch bound SecurityIntents", "SecurityIntentBinding.Name", req.Name, "SecurityIntentBinding.Namespace", req.Namespace)
+		return err
+	}
+	npPolicies, err := fromNimbusPolicy(ctx, r.Client, *latestNp, false)
+	if err != nil {
+		logger.Error(err, "failed to fetch adapter policies", "NimbusPolicy.Name", req.Name, "NimbusPolicy.Namespace", req.Namespace)
+		return err
+	}
 	latestSib.Status.NumberOfBoundIntents = int32(len(latestNp.Spec.NimbusRules))
-	latestSib.Status.BoundIntents = extractBoundIntentsNameFromSib(ctx, r.Client, req.Name, req.Namespace)
+	latestSib.Status.BoundIntents = boundIntents
 	latestSib.Status.NimbusPolicy = req.Name
+	latestSib.Status.IntentStatuses, latestSib.Status.EnforcementPhase = rollUpIntentStatuses(
+		fetchSecurityIntents(ctx, r.Client, latestSib.Status.BoundIntents),
+		nil,
+		[]adapterPolicies{npPolicies},
+	)
+	latestSib.Status.Conflicts = latestNp.Status.Conflicts
+	recordConflicts(r.Recorder, latestSib, latestSib.Status.Conflicts)
 
 	if err := r.Status().Update(ctx, latestSib); err != nil {
 		logger.Error(err, "failed to update SecurityIntentBinding status", "SecurityIntentBinding.Name", req.Name, "SecurityIntentBinding.Namespace", req.Namespace)
@@ -5,14 +5,20 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	v1alpha1 "github.com/5GSEC/nimbus/api/v1alpha1"
+	"github.com/5GSEC/nimbus/pkg/processor/intentbinder"
 )
 
 // TODO: Add constants for recommend labels and update objects accordingly.
@@ -31,45 +37,43 @@ func requeueWithError(err error) (ctrl.Result, error) {
 	return ctrl.Result{}, err
 }
 
-func extractBoundIntentsNameFromSib(ctx context.Context, c client.Client, name, namespace string) []string {
-	logger := log.FromContext(ctx)
-
-	var boundIntentsName []string
+func requeueAfter(after time.Duration) (ctrl.Result, error) {
+	return ctrl.Result{RequeueAfter: after}, nil
+}
 
+// extractBoundIntentsNameFromSib returns the names of the SecurityIntents bound
+// by the given SecurityIntentBinding, none when it doesn't exist anymore.
+func extractBoundIntentsNameFromSib(ctx context.Context, c client.Client, name, namespace string) ([]string, error) {
 	var sib v1alpha1.SecurityIntentBinding
 	if err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, &sib); err != nil {
-		logger.Error(err, "failed to fetch SecurityIntentBinding", "securityIntentBindingName", name, "securityIntentBindingNamespace", namespace)
-		return boundIntentsName
+		return nil, client.IgnoreNotFound(err)
 	}
-
-	for _, intent := range sib.Spec.Intents {
-		var si v1alpha1.SecurityIntent
-		if err := c.Get(ctx, types.NamespacedName{Name: intent.Name}, &si); err == nil {
-			boundIntentsName = append(boundIntentsName, intent.Name)
-		}
-	}
-
-	return boundIntentsName
+	return boundIntentsName(ctx, c, &sib)
 }
-func extractBoundIntentsNameFromCSib(ctx context.Context, c client.Client, name string) []string {
-	logger := log.FromContext(ctx)
-
-	var boundIntentsName []string
 
+// extractBoundIntentsNameFromCSib returns the names of the SecurityIntents
+// bound by the given ClusterSecurityIntentBinding, none when it doesn't exist
+// anymore.
+func extractBoundIntentsNameFromCSib(ctx context.Context, c client.Client, name string) ([]string, error) {
 	var csib v1alpha1.ClusterSecurityIntentBinding
 	if err := c.Get(ctx, types.NamespacedName{Name: name}, &csib); err != nil {
-		logger.Error(err, "failed to fetch ClusterSecurityIntentBinding", "ClusterSecurityIntentBinding", name)
-		return boundIntentsName
+		return nil, client.IgnoreNotFound(err)
 	}
+	return boundIntentsName(ctx, c, &csib)
+}
 
-	for _, intent := range csib.Spec.Intents {
-		var si v1alpha1.SecurityIntent
-		if err := c.Get(ctx, types.NamespacedName{Name: intent.Name}, &si); err == nil {
-			boundIntentsName = append(boundIntentsName, intent.Name)
-		}
+// boundIntentsName returns the names of the SecurityIntents bound by the given
+// binding.
+func boundIntentsName(ctx context.Context, c client.Client, binding client.Object) ([]string, error) {
+	intents, _, err := intentbinder.ExtractIntents(ctx, c, binding)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch SecurityIntents bound by %s: %w", binding.GetName(), err)
 	}
-
-	return boundIntentsName
+	var boundIntentsName []string
+	for _, si := range intents {
+		boundIntentsName = append(boundIntentsName, si.Name)
+	}
+	return boundIntentsName, nil
 }
 
 func extractNPNamespacesFromCsib(ctx context.Context, c client.Client, name string) []string {
@@ -92,6 +96,60 @@ func extractNPNamespacesFromCsib(ctx context.Context, c client.Client, name stri
 	return npNs
 }
 
+func extractNPAdapterPoliciesFromCsib(ctx context.Context, c client.Client, name string) ([]adapterPolicies, error) {
+	var policies []adapterPolicies
+
+	nps := &v1alpha1.NimbusPolicyList{}
+	if err := c.List(ctx, nps); err != nil {
+		return nil, err
+	}
+
+	for _, np := range nps.Items {
+		if np.Name == "nimbus-ctlr-gen-"+name {
+			npPolicies, err := fromNimbusPolicy(ctx, c, np, true)
+			if err != nil {
+				return nil, err
+			}
+			policies = append(policies, npPolicies)
+		}
+	}
+
+	return policies, nil
+}
+
+// bindsIntent reports whether a binding with the given intents, intent selector
+// and bound intents is affected by a change to the given SecurityIntent. It is
+// affected when it references the SecurityIntent by name, when its intent
+// selector matches it or when the SecurityIntent was bound to it, since it may
+// no longer match after being re-tagged or re-labeled.
+func bindsIntent(intents []v1alpha1.MatchIntent, intentSelector *v1alpha1.IntentSelector, boundIntents []string, siObj client.Object) bool {
+	for _, intent := range intents {
+		if intent.Name == siObj.GetName() {
+			return true
+		}
+	}
+
+	if slices.Contains(boundIntents, siObj.GetName()) {
+		return true
+	}
+
+	si, ok := siObj.(*v1alpha1.SecurityIntent)
+	if !ok {
+		return false
+	}
+	return intentbinder.MatchesSelector(intentSelector, *si)
+}
+
+// intentLabelsChanged reports whether the labels of a SecurityIntent changed.
+// Unlike its tags, the labels of a SecurityIntent don't change its generation,
+// though they are used to select it.
+func intentLabelsChanged(updateEvent event.UpdateEvent) bool {
+	if _, ok := updateEvent.ObjectNew.(*v1alpha1.SecurityIntent); !ok {
+		return false
+	}
+	return !maps.Equal(updateEvent.ObjectOld.GetLabels(), updateEvent.ObjectNew.GetLabels())
+}
+
 func ownerExists(c client.Client, controllee client.Object) bool {
 	// Don't even try to look if it has no ControllerRef.
 	controller := metav1.GetControllerOf(controllee)
@@ -0,0 +1,261 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+// Package clustercidrs discovers the networks of the cluster, which the
+// network adapters consider internal to it.
+package clustercidrs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// Params of the network intents overriding the networks discovered in the
+// cluster.
+const (
+	// ParamPodCIDRs replaces the discovered pod CIDRs.
+	ParamPodCIDRs = "podCIDRs"
+	// ParamServiceCIDRs replaces the discovered Service CIDRs.
+	ParamServiceCIDRs = "serviceCIDRs"
+	// ParamExtraCIDRs lists more networks considered internal to the cluster by
+	// denyExternalNetworkAccess, e.g. the ones of a VPC.
+	ParamExtraCIDRs = "extraCIDRs"
+)
+
+// fallbackNetworks are the networks considered internal to the cluster when
+// none of its pod CIDRs could be discovered nor were given in the params.
+var fallbackNetworks = []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7"}
+
+// The kubeadm ConfigMap holding the ClusterConfiguration of the cluster.
+var kubeadmConfig = types.NamespacedName{Namespace: "kube-system", Name: "kubeadm-config"}
+
+// The lists of the IP pools of the CNIs managing the pod IPs themselves, and
+// of the ServiceCIDRs of the clusters supporting multiple Service CIDRs.
+var (
+	calicoIPPoolListGVK    = schema.GroupVersionKind{Group: "crd.projectcalico.org", Version: "v1", Kind: "IPPoolList"}
+	ciliumPodIPPoolListGVK = schema.GroupVersionKind{Group: "cilium.io", Version: "v2alpha1", Kind: "CiliumPodIPPoolList"}
+	serviceCIDRListGVKs    = []schema.GroupVersionKind{
+		{Group: "networking.k8s.io", Version: "v1", Kind: "ServiceCIDRList"},
+		{Group: "networking.k8s.io", Version: "v1beta1", Kind: "ServiceCIDRList"},
+	}
+)
+
+// CIDRs are the networks of the cluster, each one either IPv4 or IPv6 so that
+// dual-stack clusters have both.
+type CIDRs struct {
+	// Pods are the CIDRs the pod IPs are allocated from.
+	Pods []string
+	// Services are the CIDRs the ClusterIPs are allocated from.
+	Services []string
+	// Nodes are the single-address CIDRs of the node IPs, as host-network pods
+	// and the API server use them.
+	Nodes []string
+	// Extra are the networks given by the extraCIDRs param.
+	Extra []string
+}
+
+// Internal returns all the networks internal to the cluster, falling back to
+// the private networks in place of the pod CIDRs when none are known. It's
+// never empty, so that the policies don't end up with empty IP blocks.
+func (c CIDRs) Internal() []string {
+	pods := c.Pods
+	if len(pods) == 0 {
+		pods = fallbackNetworks
+	}
+	return uniqueCIDRs(slices.Concat(pods, c.Services, c.Nodes, c.Extra))
+}
+
+// WithParams returns the CIDRs overridden by the params of an intent.
+func (c CIDRs) WithParams(params map[string][]string) (CIDRs, error) {
+	var errs []error
+	if len(params[ParamPodCIDRs]) > 0 {
+		c.Pods = parseCIDRs(params[ParamPodCIDRs], &errs)
+	}
+	if len(params[ParamServiceCIDRs]) > 0 {
+		c.Services = parseCIDRs(params[ParamServiceCIDRs], &errs)
+	}
+	c.Extra = parseCIDRs(params[ParamExtraCIDRs], &errs)
+	return c, errors.Join(errs...)
+}
+
+// Discover discovers the networks of the cluster from:
+//
+//   - the pod CIDRs and the internal IPs of the nodes,
+//   - the networking section of the kubeadm ClusterConfiguration,
+//   - the Calico IPPools and the Cilium CiliumPodIPPools, whose CNIs manage
+//     the pod IPs without setting the pod CIDRs of the nodes,
+//   - the ServiceCIDRs.
+//
+// Sources that aren't available in the cluster are skipped. The CIDRs
+// discovered are returned along with the errors of the other sources.
+func Discover(ctx context.Context, k8sClient client.Client) (CIDRs, error) {
+	var cidrs CIDRs
+	var errs []error
+
+	nodes := &corev1.NodeList{}
+	if err := k8sClient.List(ctx, nodes); err != nil {
+		errs = append(errs, fmt.Errorf("failed to list nodes: %w", err))
+	}
+	for _, node := range nodes.Items {
+		podCIDRs := node.Spec.PodCIDRs
+		if len(podCIDRs) == 0 && node.Spec.PodCIDR != "" {
+			podCIDRs = []string{node.Spec.PodCIDR}
+		}
+		cidrs.Pods = append(cidrs.Pods, parseCIDRs(podCIDRs, &errs)...)
+
+		for _, address := range node.Status.Addresses {
+			if address.Type != corev1.NodeInternalIP {
+				continue
+			}
+			ip, err := netip.ParseAddr(address.Address)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("invalid address of node %s: %w", node.Name, err))
+				continue
+			}
+			cidrs.Nodes = append(cidrs.Nodes, netip.PrefixFrom(ip, ip.BitLen()).String())
+		}
+	}
+
+	podSubnets, serviceSubnets, err := kubeadmSubnets(ctx, k8sClient)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	cidrs.Pods = append(cidrs.Pods, parseCIDRs(podSubnets, &errs)...)
+	cidrs.Services = append(cidrs.Services, parseCIDRs(serviceSubnets, &errs)...)
+
+	calicoCIDRs, err := listCIDRs(ctx, k8sClient, calicoIPPoolListGVK, func(pool map[string]any) []string {
+		if disabled, _, _ := unstructured.NestedBool(pool, "spec", "disabled"); disabled {
+			return nil
+		}
+		cidr, _, _ := unstructured.NestedString(pool, "spec", "cidr")
+		return []string{cidr}
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	cidrs.Pods = append(cidrs.Pods, parseCIDRs(calicoCIDRs, &errs)...)
+
+	ciliumCIDRs, err := listCIDRs(ctx, k8sClient, ciliumPodIPPoolListGVK, func(pool map[string]any) []string {
+		ipv4, _, _ := unstructured.NestedStringSlice(pool, "spec", "ipv4", "cidrs")
+		ipv6, _, _ := unstructured.NestedStringSlice(pool, "spec", "ipv6", "cidrs")
+		return append(ipv4, ipv6...)
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	cidrs.Pods = append(cidrs.Pods, parseCIDRs(ciliumCIDRs, &errs)...)
+
+	// ServiceCIDRs went GA in networking.k8s.io/v1 and were beta before, so use
+	// the first version served.
+	for _, gvk := range serviceCIDRListGVKs {
+		serviceCIDRs, err := listCIDRs(ctx, k8sClient, gvk, func(serviceCIDR map[string]any) []string {
+			cidrs, _, _ := unstructured.NestedStringSlice(serviceCIDR, "spec", "cidrs")
+			return cidrs
+		})
+		if err != nil {
+			errs = append(errs, err)
+			break
+		}
+		if serviceCIDRs != nil {
+			cidrs.Services = append(cidrs.Services, parseCIDRs(serviceCIDRs, &errs)...)
+			break
+		}
+	}
+
+	cidrs.Pods = uniqueCIDRs(cidrs.Pods)
+	cidrs.Services = uniqueCIDRs(cidrs.Services)
+	cidrs.Nodes = uniqueCIDRs(cidrs.Nodes)
+	return cidrs, errors.Join(errs...)
+}
+
+// kubeadmSubnets returns the pod and Service subnets of the kubeadm
+// ClusterConfiguration, which are comma-separated in dual-stack clusters.
+// There are none in clusters not set up with kubeadm.
+func kubeadmSubnets(ctx context.Context, k8sClient client.Client) ([]string, []string, error) {
+	var configMap corev1.ConfigMap
+	if err := k8sClient.Get(ctx, kubeadmConfig, &configMap); err != nil {
+		if apierrors.IsNotFound(err) || apierrors.IsForbidden(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, fmt.Errorf("failed to get kubeadm ClusterConfiguration: %w", err)
+	}
+
+	var clusterConfiguration struct {
+		Networking struct {
+			PodSubnet     string `json:"podSubnet"`
+			ServiceSubnet string `json:"serviceSubnet"`
+		} `json:"networking"`
+	}
+	if err := yaml.Unmarshal([]byte(configMap.Data["ClusterConfiguration"]), &clusterConfiguration); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse kubeadm ClusterConfiguration: %w", err)
+	}
+	return splitSubnets(clusterConfiguration.Networking.PodSubnet), splitSubnets(clusterConfiguration.Networking.ServiceSubnet), nil
+}
+
+// listCIDRs lists the objects of the given list kind and returns the CIDRs
+// of each one, or nil when the kind isn't served by the cluster.
+func listCIDRs(ctx context.Context, k8sClient client.Client, gvk schema.GroupVersionKind, cidrsOf func(map[string]any) []string) ([]string, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk)
+	if err := k8sClient.List(ctx, list); err != nil {
+		if meta.IsNoMatchError(err) || apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list %s: %w", strings.TrimSuffix(gvk.Kind, "List"), err)
+	}
+
+	cidrs := []string{}
+	for _, item := range list.Items {
+		cidrs = append(cidrs, cidrsOf(item.Object)...)
+	}
+	return cidrs, nil
+}
+
+// parseCIDRs returns the given CIDRs in their canonical form, skipping the
+// empty ones and appending an error for each invalid one.
+func parseCIDRs(values []string, errs *[]error) []string {
+	var cidrs []string
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			*errs = append(*errs, fmt.Errorf("invalid CIDR %q: %w", value, err))
+			continue
+		}
+		cidrs = append(cidrs, prefix.Masked().String())
+	}
+	return cidrs
+}
+
+// splitSubnets splits the comma-separated subnets of the kubeadm
+// ClusterConfiguration.
+func splitSubnets(subnets string) []string {
+	if subnets == "" {
+		return nil
+	}
+	return strings.Split(subnets, ",")
+}
+
+// uniqueCIDRs sorts the given CIDRs and drops the duplicates, so that the
+// policies built from them don't change with the order they're discovered in.
+func uniqueCIDRs(cidrs []string) []string {
+	cidrs = slices.Clone(cidrs)
+	slices.Sort(cidrs)
+	return slices.Compact(cidrs)
+}
@@ -0,0 +1,351 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package clustercidrs
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+// kinds are the kinds of the IP pools and the ServiceCIDRs served by a fake
+// cluster.
+type kinds []schema.GroupVersionKind
+
+var (
+	calicoIPPool     = schema.GroupVersionKind{Group: "crd.projectcalico.org", Version: "v1", Kind: "IPPool"}
+	ciliumPodIPPool  = schema.GroupVersionKind{Group: "cilium.io", Version: "v2alpha1", Kind: "CiliumPodIPPool"}
+	serviceCIDRv1    = schema.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "ServiceCIDR"}
+	serviceCIDRBeta1 = schema.GroupVersionKind{Group: "networking.k8s.io", Version: "v1beta1", Kind: "ServiceCIDR"}
+)
+
+// newClient returns a fake client of a cluster serving the given kinds on top
+// of the built-in ones and holding the given objects. Listing the kinds it
+// doesn't serve fails with a no match error, as with a real cluster.
+func newClient(t *testing.T, served kinds, funcs interceptor.Funcs, objs ...client.Object) client.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	list := funcs.List
+	funcs.List = func(ctx context.Context, c client.WithWatch, obj client.ObjectList, opts ...client.ListOption) error {
+		if unstructuredList, ok := obj.(*unstructured.UnstructuredList); ok {
+			gvk := unstructuredList.GroupVersionKind()
+			gvk.Kind = strings.TrimSuffix(gvk.Kind, "List")
+			if !slices.Contains(served, gvk) {
+				return &meta.NoKindMatchError{GroupKind: gvk.GroupKind(), SearchedVersions: []string{gvk.Version}}
+			}
+		}
+		if list != nil {
+			return list(ctx, c, obj, opts...)
+		}
+		return c.List(ctx, obj, opts...)
+	}
+	return fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objs...).
+		WithInterceptorFuncs(funcs).
+		Build()
+}
+
+// object returns an unstructured object of the given kind and spec.
+func object(gvk schema.GroupVersionKind, name string, spec map[string]any) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]any{"spec": spec}}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetName(name)
+	return obj
+}
+
+// node returns a node with the given pod CIDRs and internal IP.
+func node(name string, podCIDR string, podCIDRs []string, internalIP string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       corev1.NodeSpec{PodCIDR: podCIDR, PodCIDRs: podCIDRs},
+		Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{
+			{Type: corev1.NodeHostName, Address: name},
+			{Type: corev1.NodeInternalIP, Address: internalIP},
+		}},
+	}
+}
+
+// kubeadmConfigMap returns the kubeadm ConfigMap with the given networking
+// section of the ClusterConfiguration.
+func kubeadmConfigMap(networking string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: kubeadmConfig.Namespace, Name: kubeadmConfig.Name},
+		Data: map[string]string{
+			"ClusterConfiguration": "apiVersion: kubeadm.k8s.io/v1beta3\nkind: ClusterConfiguration\nnetworking:\n" + networking,
+		},
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	tests := []struct {
+		name    string
+		served  kinds
+		funcs   interceptor.Funcs
+		objs    []client.Object
+		want    CIDRs
+		wantErr bool
+	}{
+		{
+			name: "no sources",
+		},
+		{
+			name: "node pod CIDRs take precedence over the pod CIDR",
+			objs: []client.Object{
+				node("dual-stack", "10.244.0.0/24", []string{"10.244.0.0/24", "fd00:10:244::/64"}, "192.168.1.10"),
+				node("single-stack", "10.244.1.7/24", nil, "192.168.1.11"),
+				node("duplicate", "", []string{"10.244.0.0/24"}, "192.168.1.10"),
+			},
+			want: CIDRs{
+				Pods:  []string{"10.244.0.0/24", "10.244.1.0/24", "fd00:10:244::/64"},
+				Nodes: []string{"192.168.1.10/32", "192.168.1.11/32"},
+			},
+		},
+		{
+			name: "kubeadm dual-stack subnets",
+			objs: []client.Object{
+				kubeadmConfigMap("  podSubnet: 10.244.0.0/16, fd00:10:244::/56\n  serviceSubnet: 10.96.0.0/12,fd00:10:96::/112\n"),
+			},
+			want: CIDRs{
+				Pods:     []string{"10.244.0.0/16", "fd00:10:244::/56"},
+				Services: []string{"10.96.0.0/12", "fd00:10:96::/112"},
+			},
+		},
+		{
+			name:   "Calico and Cilium IP pools",
+			served: kinds{calicoIPPool, ciliumPodIPPool},
+			objs: []client.Object{
+				object(calicoIPPool, "default-ipv4-ippool", map[string]any{"cidr": "192.168.0.0/16"}),
+				object(calicoIPPool, "disabled", map[string]any{"cidr": "10.10.0.0/16", "disabled": true}),
+				object(ciliumPodIPPool, "default", map[string]any{
+					"ipv4": map[string]any{"cidrs": []any{"10.20.0.0/16"}},
+					"ipv6": map[string]any{"cidrs": []any{"fd00:20::/104"}},
+				}),
+			},
+			want: CIDRs{Pods: []string{"10.20.0.0/16", "192.168.0.0/16", "fd00:20::/104"}},
+		},
+		{
+			name:   "ServiceCIDRs falling back to v1beta1",
+			served: kinds{serviceCIDRBeta1},
+			objs: []client.Object{
+				object(serviceCIDRBeta1, "kubernetes", map[string]any{"cidrs": []any{"10.96.0.0/12", "fd00:10:96::/112"}}),
+			},
+			want: CIDRs{Services: []string{"10.96.0.0/12", "fd00:10:96::/112"}},
+		},
+		{
+			name:   "ServiceCIDRs v1 preferred",
+			served: kinds{serviceCIDRv1, serviceCIDRBeta1},
+			objs: []client.Object{
+				object(serviceCIDRv1, "kubernetes", map[string]any{"cidrs": []any{"10.96.0.0/12"}}),
+				object(serviceCIDRBeta1, "stale", map[string]any{"cidrs": []any{"10.100.0.0/16"}}),
+			},
+			want: CIDRs{Services: []string{"10.96.0.0/12"}},
+		},
+		{
+			name: "kubeadm ConfigMap forbidden",
+			funcs: interceptor.Funcs{Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				return apierrors.NewForbidden(schema.GroupResource{Resource: "configmaps"}, key.Name, errors.New("forbidden"))
+			}},
+			objs: []client.Object{node("node", "10.244.0.0/24", nil, "192.168.1.10")},
+			want: CIDRs{Pods: []string{"10.244.0.0/24"}, Nodes: []string{"192.168.1.10/32"}},
+		},
+		{
+			name: "invalid sources",
+			objs: []client.Object{
+				node("invalid", "10.244.0.0/33", nil, "192.168.1.300"),
+				node("valid", "10.244.1.0/24", nil, "192.168.1.11"),
+				kubeadmConfigMap("  podSubnet: [\n"),
+			},
+			want:    CIDRs{Pods: []string{"10.244.1.0/24"}, Nodes: []string{"192.168.1.11/32"}},
+			wantErr: true,
+		},
+		{
+			name: "failing nodes list",
+			funcs: interceptor.Funcs{List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+				if _, ok := list.(*corev1.NodeList); ok {
+					return errors.New("unavailable")
+				}
+				return c.List(ctx, list, opts...)
+			}},
+			objs: []client.Object{
+				kubeadmConfigMap("  podSubnet: 10.244.0.0/16\n"),
+			},
+			want:    CIDRs{Pods: []string{"10.244.0.0/16"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k8sClient := newClient(t, tt.served, tt.funcs, tt.objs...)
+			got, err := Discover(context.Background(), k8sClient)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Discover() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Discover() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithParams(t *testing.T) {
+	discovered := CIDRs{
+		Pods:     []string{"10.244.0.0/16"},
+		Services: []string{"10.96.0.0/12"},
+		Nodes:    []string{"192.168.1.10/32"},
+	}
+	tests := []struct {
+		name    string
+		params  map[string][]string
+		want    CIDRs
+		wantErr bool
+	}{
+		{
+			name: "no params",
+			want: discovered,
+		},
+		{
+			name: "overrides",
+			params: map[string][]string{
+				ParamPodCIDRs:     {"10.1.2.3/16", "fd00:1::/48"},
+				ParamServiceCIDRs: {" 10.2.0.0/16 "},
+				ParamExtraCIDRs:   {"172.31.0.0/16", ""},
+			},
+			want: CIDRs{
+				Pods:     []string{"10.1.0.0/16", "fd00:1::/48"},
+				Services: []string{"10.2.0.0/16"},
+				Nodes:    discovered.Nodes,
+				Extra:    []string{"172.31.0.0/16"},
+			},
+		},
+		{
+			name:   "empty params keep the discovered CIDRs",
+			params: map[string][]string{ParamPodCIDRs: {}, ParamServiceCIDRs: nil},
+			want:   discovered,
+		},
+		{
+			name: "invalid params",
+			params: map[string][]string{
+				ParamPodCIDRs:   {"10.1.0.0/16", "not-a-cidr"},
+				ParamExtraCIDRs: {"172.31.0.0"},
+			},
+			want: CIDRs{
+				Pods:     []string{"10.1.0.0/16"},
+				Services: discovered.Services,
+				Nodes:    discovered.Nodes,
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := discovered.WithParams(tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WithParams() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WithParams() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCIDRs(t *testing.T) {
+	var errs []error
+	got := parseCIDRs([]string{"10.244.1.7/16", " fd00:10:244::1/56 ", "", "  ", "10.0.0.0/33", "10.0.0.1"}, &errs)
+	if want := []string{"10.244.0.0/16", "fd00:10:244::/56"}; !slices.Equal(got, want) {
+		t.Errorf("parseCIDRs() = %v, want %v", got, want)
+	}
+	if len(errs) != 2 {
+		t.Errorf("parseCIDRs() errors = %v, want 2", errs)
+	}
+}
+
+func TestSplitSubnets(t *testing.T) {
+	tests := []struct {
+		subnets string
+		want    []string
+	}{
+		{"", nil},
+		{"10.244.0.0/16", []string{"10.244.0.0/16"}},
+		{"10.244.0.0/16,fd00:10:244::/56", []string{"10.244.0.0/16", "fd00:10:244::/56"}},
+	}
+
+	for _, tt := range tests {
+		if got := splitSubnets(tt.subnets); !slices.Equal(got, tt.want) {
+			t.Errorf("splitSubnets(%q) = %v, want %v", tt.subnets, got, tt.want)
+		}
+	}
+}
+
+func TestInternal(t *testing.T) {
+	tests := []struct {
+		name  string
+		cidrs CIDRs
+		want  []string
+	}{
+		{
+			name: "nothing discovered",
+			want: []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7"},
+		},
+		{
+			name: "no pod CIDRs",
+			cidrs: CIDRs{
+				Services: []string{"100.64.0.0/16"},
+				Nodes:    []string{"203.0.113.10/32"},
+				Extra:    []string{"198.51.100.0/24", "10.0.0.0/8"},
+			},
+			want: []string{"10.0.0.0/8", "100.64.0.0/16", "172.16.0.0/12", "192.168.0.0/16", "198.51.100.0/24", "203.0.113.10/32", "fc00::/7"},
+		},
+		{
+			name: "pod CIDRs",
+			cidrs: CIDRs{
+				Pods:     []string{"10.244.0.0/16", "fd00:10:244::/56"},
+				Services: []string{"10.96.0.0/12"},
+				Nodes:    []string{"192.168.1.10/32", "192.168.1.11/32"},
+				Extra:    []string{"10.244.0.0/16"},
+			},
+			want: []string{"10.244.0.0/16", "10.96.0.0/12", "192.168.1.10/32", "192.168.1.11/32", "fd00:10:244::/56"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.cidrs.Internal()
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Internal() = %v, want %v", got, tt.want)
+			}
+			// The policies build an IP block for each of them.
+			if len(got) == 0 || slices.Contains(got, "") {
+				t.Errorf("Internal() = %v, want no empty network", got)
+			}
+		})
+	}
+
+	// Internal doesn't modify the fallback networks.
+	CIDRs{Extra: []string{"1.2.3.0/24"}}.Internal()
+	if want := []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7"}; !slices.Equal(fallbackNetworks, want) {
+		t.Errorf("fallbackNetworks = %v, want %v", fallbackNetworks, want)
+	}
+}
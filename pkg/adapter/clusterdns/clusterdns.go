@@ -0,0 +1,249 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+// Package clusterdns discovers the DNS servers of the cluster, which the
+// network adapters allow the workloads to query.
+package clusterdns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/netip"
+	"slices"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Params of the network intents overriding the cluster DNS discovered.
+const (
+	// ParamNamespace replaces the namespace of the DNS pods.
+	ParamNamespace = "dnsNamespace"
+	// ParamSelector replaces the labels of the DNS pods, given as "key=value".
+	ParamSelector = "dnsSelector"
+	// ParamIPs replaces the IPs or CIDRs of the DNS servers not selected as
+	// pods, e.g. the ClusterIP of the DNS Service or a NodeLocal DNSCache.
+	ParamIPs = "dnsIPs"
+)
+
+// Port is the port the DNS clients send their queries to.
+const Port int32 = 53
+
+// dnsServices are the DNS Services of the common distributions, looked up in
+// order before the Services labeled as kube-dns in any namespace.
+var dnsServices = []types.NamespacedName{
+	{Namespace: "kube-system", Name: "kube-dns"},
+	{Namespace: "openshift-dns", Name: "dns-default"},
+	{Namespace: "kube-system", Name: "coredns"},
+}
+
+// dnsServiceLabels are the labels of the DNS Services of the other clusters.
+var dnsServiceLabels = client.MatchingLabels{"k8s-app": "kube-dns"}
+
+// The NodeLocal DNSCache DaemonSet, whose pods answer the queries sent to its
+// -localip addresses on every node.
+var nodeLocalDNS = types.NamespacedName{Namespace: "kube-system", Name: "node-local-dns"}
+
+// defaultDNS is the cluster DNS assumed when no DNS Service is found.
+var defaultDNS = DNS{
+	Namespace: "kube-system",
+	Selector:  map[string]string{"k8s-app": "kube-dns"},
+	Ports:     []int32{Port},
+}
+
+// DNS are the DNS servers of the cluster.
+type DNS struct {
+	// Namespace and Selector select the DNS pods, if any.
+	Namespace string
+	Selector  map[string]string
+	// IPs are the CIDRs of the DNS servers not selected as pods.
+	IPs []string
+	// Ports are the ports the DNS servers listen on, over both UDP and TCP.
+	Ports []int32
+}
+
+// WithParams returns the cluster DNS overridden by the params of an intent.
+func (d DNS) WithParams(params map[string][]string) (DNS, error) {
+	var errs []error
+	if len(params[ParamNamespace]) > 0 {
+		d.Namespace = params[ParamNamespace][0]
+	}
+	if len(params[ParamSelector]) > 0 {
+		d.Selector = make(map[string]string)
+		for _, label := range params[ParamSelector] {
+			key, value, ok := strings.Cut(label, "=")
+			if !ok || key == "" {
+				errs = append(errs, fmt.Errorf("invalid DNS selector label %q, expected key=value", label))
+				continue
+			}
+			d.Selector[key] = value
+		}
+	}
+	if len(params[ParamIPs]) > 0 {
+		d.IPs = parseIPsOrCIDRs(params[ParamIPs], &errs)
+	}
+	return d, errors.Join(errs...)
+}
+
+// Discover discovers the DNS servers of the cluster from its DNS Service:
+//
+//   - its selector selects the DNS pods, in its namespace,
+//   - its ClusterIPs are answered by NodeLocal DNSCache when it intercepts
+//     them, and its endpoints are the DNS servers when it has no selector,
+//   - the ports of its EndpointSlices are the ones the DNS pods listen on,
+//     e.g. 5353 on OpenShift.
+//
+// The -localip addresses of NodeLocal DNSCache are added when it's deployed.
+// The kube-dns pods of kube-system are assumed when there's no DNS Service.
+func Discover(ctx context.Context, k8sClient client.Client) (DNS, error) {
+	var errs []error
+
+	service, err := dnsService(ctx, k8sClient)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	dns := DNS{
+		Namespace: defaultDNS.Namespace,
+		Selector:  maps.Clone(defaultDNS.Selector),
+		Ports:     slices.Clone(defaultDNS.Ports),
+	}
+	if service != nil {
+		dns.Namespace = service.Namespace
+		dns.Selector = service.Spec.Selector
+		dns.IPs = parseIPsOrCIDRs(service.Spec.ClusterIPs, &errs)
+
+		addresses, ports, err := endpointsOf(ctx, k8sClient, service)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if len(service.Spec.Selector) == 0 {
+			dns.IPs = append(dns.IPs, parseIPsOrCIDRs(addresses, &errs)...)
+		}
+		dns.Ports = append(dns.Ports, ports...)
+	}
+
+	localIPs, err := nodeLocalDNSIPs(ctx, k8sClient)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	dns.IPs = append(dns.IPs, parseIPsOrCIDRs(localIPs, &errs)...)
+	slices.Sort(dns.IPs)
+	dns.IPs = slices.Compact(dns.IPs)
+
+	slices.Sort(dns.Ports)
+	dns.Ports = slices.Compact(dns.Ports)
+	return dns, errors.Join(errs...)
+}
+
+// dnsService returns the DNS Service of the cluster, nil when there's none.
+func dnsService(ctx context.Context, k8sClient client.Client) (*corev1.Service, error) {
+	for _, name := range dnsServices {
+		var service corev1.Service
+		err := k8sClient.Get(ctx, name, &service)
+		if err == nil {
+			return &service, nil
+		}
+		if !apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to get DNS Service %s: %w", name, err)
+		}
+	}
+
+	var services corev1.ServiceList
+	if err := k8sClient.List(ctx, &services, dnsServiceLabels); err != nil {
+		return nil, fmt.Errorf("failed to list DNS Services: %w", err)
+	}
+	if len(services.Items) == 0 {
+		return nil, nil
+	}
+	slices.SortFunc(services.Items, func(a, b corev1.Service) int {
+		return strings.Compare(a.Namespace+"/"+a.Name, b.Namespace+"/"+b.Name)
+	})
+	return &services.Items[0], nil
+}
+
+// endpointsOf returns the addresses and the UDP and TCP ports of the
+// EndpointSlices of the given Service.
+func endpointsOf(ctx context.Context, k8sClient client.Client, service *corev1.Service) ([]string, []int32, error) {
+	var endpointSlices discoveryv1.EndpointSliceList
+	if err := k8sClient.List(ctx, &endpointSlices, client.InNamespace(service.Namespace),
+		client.MatchingLabels{discoveryv1.LabelServiceName: service.Name}); err != nil {
+		return nil, nil, fmt.Errorf("failed to list EndpointSlices of DNS Service %s/%s: %w", service.Namespace, service.Name, err)
+	}
+
+	var addresses []string
+	var ports []int32
+	for _, slice := range endpointSlices.Items {
+		for _, endpoint := range slice.Endpoints {
+			addresses = append(addresses, endpoint.Addresses...)
+		}
+		for _, port := range slice.Ports {
+			if port.Port != nil && (port.Protocol == nil || *port.Protocol != corev1.ProtocolSCTP) {
+				ports = append(ports, *port.Port)
+			}
+		}
+	}
+	return addresses, ports, nil
+}
+
+// nodeLocalDNSIPs returns the -localip addresses of NodeLocal DNSCache, none
+// when it isn't deployed.
+func nodeLocalDNSIPs(ctx context.Context, k8sClient client.Client) ([]string, error) {
+	var daemonSet appsv1.DaemonSet
+	if err := k8sClient.Get(ctx, nodeLocalDNS, &daemonSet); err != nil {
+		if apierrors.IsNotFound(err) || apierrors.IsForbidden(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get NodeLocal DNSCache: %w", err)
+	}
+
+	var ips []string
+	for _, container := range daemonSet.Spec.Template.Spec.Containers {
+		args := slices.Concat(container.Command, container.Args)
+		for i, arg := range args {
+			value, ok := strings.CutPrefix(arg, "-localip=")
+			if !ok && arg == "-localip" && i+1 < len(args) {
+				value, ok = args[i+1], true
+			}
+			if ok {
+				ips = append(ips, strings.Split(value, ",")...)
+			}
+		}
+	}
+	return ips, nil
+}
+
+// parseIPsOrCIDRs returns the given IPs as single-address CIDRs and the given
+// CIDRs in their canonical form, skipping the empty ones and "None" of the
+// headless Services, and appending an error for each invalid one.
+func parseIPsOrCIDRs(values []string, errs *[]error) []string {
+	var cidrs []string
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" || value == corev1.ClusterIPNone {
+			continue
+		}
+		if strings.Contains(value, "/") {
+			prefix, err := netip.ParsePrefix(value)
+			if err != nil {
+				*errs = append(*errs, fmt.Errorf("invalid CIDR %q: %w", value, err))
+				continue
+			}
+			cidrs = append(cidrs, prefix.Masked().String())
+			continue
+		}
+		ip, err := netip.ParseAddr(value)
+		if err != nil {
+			*errs = append(*errs, fmt.Errorf("invalid IP %q: %w", value, err))
+			continue
+		}
+		cidrs = append(cidrs, netip.PrefixFrom(ip, ip.BitLen()).String())
+	}
+	return cidrs
+}
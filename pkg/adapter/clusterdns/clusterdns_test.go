@@ -0,0 +1,389 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package clusterdns
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+// newClient returns a fake client holding the given objects.
+func newClient(t *testing.T, funcs interceptor.Funcs, objs ...client.Object) client.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objs...).
+		WithInterceptorFuncs(funcs).
+		Build()
+}
+
+// service returns a DNS Service with the given selector and ClusterIPs.
+func service(namespace, name string, labels, selector map[string]string, clusterIPs ...string) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: labels},
+		Spec:       corev1.ServiceSpec{Selector: selector, ClusterIPs: clusterIPs},
+	}
+}
+
+// endpointSlice returns an EndpointSlice of the given Service with the given
+// addresses and ports.
+func endpointSlice(namespace, serviceName string, addresses []string, ports ...discoveryv1.EndpointPort) *discoveryv1.EndpointSlice {
+	slice := &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      serviceName + "-abcde",
+			Labels:    map[string]string{discoveryv1.LabelServiceName: serviceName},
+		},
+		AddressType: discoveryv1.AddressTypeIPv4,
+		Ports:       ports,
+	}
+	for _, address := range addresses {
+		slice.Endpoints = append(slice.Endpoints, discoveryv1.Endpoint{Addresses: []string{address}})
+	}
+	return slice
+}
+
+// port returns an EndpointSlice port.
+func port(number int32, protocol corev1.Protocol) discoveryv1.EndpointPort {
+	return discoveryv1.EndpointPort{Port: ptr.To(number), Protocol: ptr.To(protocol)}
+}
+
+// nodeLocalDNSDaemonSet returns the NodeLocal DNSCache DaemonSet running the
+// given command and args.
+func nodeLocalDNSDaemonSet(command, args []string) *appsv1.DaemonSet {
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: nodeLocalDNS.Namespace, Name: nodeLocalDNS.Name},
+		Spec: appsv1.DaemonSetSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "node-cache", Command: command, Args: args}},
+		}}},
+	}
+}
+
+var kubeDNSLabels = map[string]string{"k8s-app": "kube-dns"}
+
+func TestDiscover(t *testing.T) {
+	tests := []struct {
+		name    string
+		funcs   interceptor.Funcs
+		objs    []client.Object
+		want    DNS
+		wantErr bool
+	}{
+		{
+			name: "no DNS Service",
+			want: defaultDNS,
+		},
+		{
+			name: "kube-dns dual-stack",
+			objs: []client.Object{
+				service("kube-system", "kube-dns", kubeDNSLabels, kubeDNSLabels, "10.96.0.10", "fd00:10:96::a"),
+				endpointSlice("kube-system", "kube-dns", []string{"10.244.0.5", "10.244.1.7"},
+					port(53, corev1.ProtocolUDP), port(53, corev1.ProtocolTCP), port(9153, corev1.ProtocolSCTP)),
+			},
+			want: DNS{
+				Namespace: "kube-system",
+				Selector:  kubeDNSLabels,
+				IPs:       []string{"10.96.0.10/32", "fd00:10:96::a/128"},
+				Ports:     []int32{53},
+			},
+		},
+		{
+			name: "OpenShift",
+			objs: []client.Object{
+				service("openshift-dns", "dns-default", nil, map[string]string{"dns.operator.openshift.io/daemonset-dns": "default"}, "172.30.0.10"),
+				endpointSlice("openshift-dns", "dns-default", []string{"10.128.0.4"},
+					port(5353, corev1.ProtocolUDP), port(5353, corev1.ProtocolTCP)),
+			},
+			want: DNS{
+				Namespace: "openshift-dns",
+				Selector:  map[string]string{"dns.operator.openshift.io/daemonset-dns": "default"},
+				IPs:       []string{"172.30.0.10/32"},
+				Ports:     []int32{53, 5353},
+			},
+		},
+		{
+			name: "custom CoreDNS without selector",
+			objs: []client.Object{
+				service("dns", "coredns-external", kubeDNSLabels, nil, "10.96.0.53"),
+				endpointSlice("dns", "coredns-external", []string{"192.168.10.53", "192.168.10.54"},
+					discoveryv1.EndpointPort{Port: ptr.To[int32](1053)}),
+				service("other", "coredns", kubeDNSLabels, kubeDNSLabels, "10.96.0.54"),
+			},
+			want: DNS{
+				Namespace: "dns",
+				IPs:       []string{"10.96.0.53/32", "192.168.10.53/32", "192.168.10.54/32"},
+				Ports:     []int32{53, 1053},
+			},
+		},
+		{
+			name: "NodeLocal DNSCache",
+			objs: []client.Object{
+				service("kube-system", "kube-dns", kubeDNSLabels, kubeDNSLabels, "10.96.0.10"),
+				nodeLocalDNSDaemonSet(nil, []string{"-localip", "169.254.20.10,10.96.0.10", "-conf", "/etc/Corefile"}),
+			},
+			want: DNS{
+				Namespace: "kube-system",
+				Selector:  kubeDNSLabels,
+				IPs:       []string{"10.96.0.10/32", "169.254.20.10/32"},
+				Ports:     []int32{53},
+			},
+		},
+		{
+			name: "failing DNS Service get",
+			funcs: interceptor.Funcs{Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				if _, ok := obj.(*corev1.Service); ok {
+					return errors.New("unavailable")
+				}
+				return c.Get(ctx, key, obj, opts...)
+			}},
+			objs: []client.Object{
+				service("kube-system", "kube-dns", kubeDNSLabels, kubeDNSLabels, "10.96.0.10"),
+				nodeLocalDNSDaemonSet([]string{"/node-cache", "-localip=169.254.20.10"}, nil),
+			},
+			want: DNS{
+				Namespace: defaultDNS.Namespace,
+				Selector:  defaultDNS.Selector,
+				IPs:       []string{"169.254.20.10/32"},
+				Ports:     defaultDNS.Ports,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid ClusterIP",
+			objs: []client.Object{
+				service("kube-system", "kube-dns", kubeDNSLabels, kubeDNSLabels, "10.96.0.300", "10.96.0.10"),
+			},
+			want: DNS{
+				Namespace: "kube-system",
+				Selector:  kubeDNSLabels,
+				IPs:       []string{"10.96.0.10/32"},
+				Ports:     []int32{53},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k8sClient := newClient(t, tt.funcs, tt.objs...)
+			got, err := Discover(context.Background(), k8sClient)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Discover() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Discover() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// The default DNS isn't modified by the discoveries.
+	if want := (DNS{Namespace: "kube-system", Selector: kubeDNSLabels, Ports: []int32{Port}}); !reflect.DeepEqual(defaultDNS, want) {
+		t.Errorf("defaultDNS = %+v, want %+v", defaultDNS, want)
+	}
+}
+
+func TestDNSService(t *testing.T) {
+	tests := []struct {
+		name string
+		objs []client.Object
+		// want is the namespace/name of the DNS Service, empty if none.
+		want string
+	}{
+		{
+			name: "none",
+			objs: []client.Object{service("default", "kubernetes", nil, nil, "10.96.0.1")},
+		},
+		{
+			name: "well-known names in order",
+			objs: []client.Object{
+				service("kube-system", "coredns", nil, kubeDNSLabels),
+				service("openshift-dns", "dns-default", nil, nil),
+				service("dns", "custom", kubeDNSLabels, kubeDNSLabels),
+			},
+			want: "openshift-dns/dns-default",
+		},
+		{
+			name: "kube-dns first",
+			objs: []client.Object{
+				service("kube-system", "coredns", nil, kubeDNSLabels),
+				service("kube-system", "kube-dns", nil, kubeDNSLabels),
+			},
+			want: "kube-system/kube-dns",
+		},
+		{
+			name: "labeled Services sorted",
+			objs: []client.Object{
+				service("dns", "z-coredns", kubeDNSLabels, kubeDNSLabels),
+				service("dns", "a-coredns", kubeDNSLabels, kubeDNSLabels),
+				service("apps", "resolver", map[string]string{"k8s-app": "resolver"}, nil),
+			},
+			want: "dns/a-coredns",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := dnsService(context.Background(), newClient(t, interceptor.Funcs{}, tt.objs...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var name string
+			if got != nil {
+				name = got.Namespace + "/" + got.Name
+			}
+			if name != tt.want {
+				t.Errorf("dnsService() = %q, want %q", name, tt.want)
+			}
+		})
+	}
+}
+
+func TestNodeLocalDNSIPs(t *testing.T) {
+	forbidden := interceptor.Funcs{Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+		return apierrors.NewForbidden(schema.GroupResource{Group: "apps", Resource: "daemonsets"}, key.Name, errors.New("forbidden"))
+	}}
+	tests := []struct {
+		name    string
+		funcs   interceptor.Funcs
+		objs    []client.Object
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "not deployed",
+		},
+		{
+			name:  "forbidden",
+			funcs: forbidden,
+			objs:  []client.Object{nodeLocalDNSDaemonSet(nil, []string{"-localip=169.254.20.10"})},
+		},
+		{
+			name: "separate value",
+			objs: []client.Object{nodeLocalDNSDaemonSet(nil, []string{"-localip", "169.254.20.10,10.96.0.10", "-upstreamsvc", "kube-dns-upstream"})},
+			want: []string{"169.254.20.10", "10.96.0.10"},
+		},
+		{
+			name: "command and args",
+			objs: []client.Object{nodeLocalDNSDaemonSet([]string{"/node-cache", "-localip=169.254.20.10"}, []string{"-localip=fd00::10"})},
+			want: []string{"169.254.20.10", "fd00::10"},
+		},
+		{
+			name: "missing value",
+			objs: []client.Object{nodeLocalDNSDaemonSet(nil, []string{"-conf", "/etc/Corefile", "-localip"})},
+		},
+		{
+			name: "failing get",
+			funcs: interceptor.Funcs{Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				return errors.New("unavailable")
+			}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := nodeLocalDNSIPs(context.Background(), newClient(t, tt.funcs, tt.objs...))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("nodeLocalDNSIPs() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("nodeLocalDNSIPs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithParams(t *testing.T) {
+	discovered := DNS{
+		Namespace: "kube-system",
+		Selector:  kubeDNSLabels,
+		IPs:       []string{"10.96.0.10/32"},
+		Ports:     []int32{53},
+	}
+	tests := []struct {
+		name    string
+		params  map[string][]string
+		want    DNS
+		wantErr bool
+	}{
+		{
+			name: "no params",
+			want: discovered,
+		},
+		{
+			name: "overrides",
+			params: map[string][]string{
+				ParamNamespace: {"dns"},
+				ParamSelector:  {"app=coredns", "tier=dns"},
+				ParamIPs:       {"169.254.20.10", " 10.53.0.0/16 ", "fd00::10"},
+			},
+			want: DNS{
+				Namespace: "dns",
+				Selector:  map[string]string{"app": "coredns", "tier": "dns"},
+				IPs:       []string{"169.254.20.10/32", "10.53.0.0/16", "fd00::10/128"},
+				Ports:     discovered.Ports,
+			},
+		},
+		{
+			name:   "empty label value",
+			params: map[string][]string{ParamSelector: {"dns="}},
+			want: DNS{
+				Namespace: discovered.Namespace,
+				Selector:  map[string]string{"dns": ""},
+				IPs:       discovered.IPs,
+				Ports:     discovered.Ports,
+			},
+		},
+		{
+			name: "invalid params",
+			params: map[string][]string{
+				ParamSelector: {"app", "=coredns", "k8s-app=coredns"},
+				ParamIPs:      {"169.254.20.300", "10.53.0.0/33", "169.254.20.10"},
+			},
+			want: DNS{
+				Namespace: discovered.Namespace,
+				Selector:  map[string]string{"k8s-app": "coredns"},
+				IPs:       []string{"169.254.20.10/32"},
+				Ports:     discovered.Ports,
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := discovered.WithParams(tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WithParams() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WithParams() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if !reflect.DeepEqual(discovered.Selector, kubeDNSLabels) {
+		t.Errorf("WithParams() modified the discovered selector: %v", discovered.Selector)
+	}
+}
@@ -14,3 +14,7 @@ const (
 	K8sClientKey     ContextKey = "k8sClient"
 	NamespaceNameKey ContextKey = "K8tlsNamespace"
 )
+
+// IntentsAnnotation lists, comma separated, the IDs of the intents that an
+// adapter policy enforces.
+const IntentsAnnotation = "intent.security.nimbus.com/intents"
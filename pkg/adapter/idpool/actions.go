@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package idpool
+
+import (
+	"slices"
+	"strings"
+
+	v1alpha1 "github.com/5GSEC/nimbus/api/v1alpha1"
+)
+
+// engineActions lists the actions each security engine can enforce the IDs it
+// supports with. The adapters map them to the actions of their policies.
+var engineActions = map[string][]string{
+	"kubearmor": {v1alpha1.ActionAudit, v1alpha1.ActionBlock},
+	"netpol":    {v1alpha1.ActionBlock},
+	"cilium":    {v1alpha1.ActionBlock},
+	"calico":    {v1alpha1.ActionAudit, v1alpha1.ActionBlock},
+	"k8tls":     {v1alpha1.ActionAudit},
+}
+
+// kyvernoActions lists the actions Kyverno can enforce each ID with, which
+// depend on the kind of rules of its policies.
+var kyvernoActions = map[string][]string{
+	EscapeToHost: {v1alpha1.ActionAudit, v1alpha1.ActionBlock},
+	CocoWorkload: {v1alpha1.ActionMutate},
+	VirtualPatch: {v1alpha1.ActionBlock},
+}
+
+// engines are the security engines, in the order they are reported in.
+var engines = []string{"kubearmor", "netpol", "cilium", "calico", "kyverno", "k8tls"}
+
+// IsActionSupportedBy determines whether a security engine can enforce a given
+// ID with a given action.
+func IsActionSupportedBy(id, action, securityEngine string) bool {
+	securityEngine = strings.ToLower(securityEngine)
+	if !IsIdSupportedBy(id, securityEngine) {
+		return false
+	}
+	if securityEngine == "kyverno" {
+		return slices.Contains(kyvernoActions[id], action)
+	}
+	return slices.Contains(engineActions[securityEngine], action)
+}
+
+// EnginesSupporting returns the security engines that support a given ID.
+func EnginesSupporting(id string) []string {
+	var supporting []string
+	for _, engine := range engines {
+		if IsIdSupportedBy(id, engine) {
+			supporting = append(supporting, engine)
+		}
+	}
+	return supporting
+}
+
+// IsActionSupported determines whether any security engine supporting a given
+// ID can enforce it with a given action.
+func IsActionSupported(id, action string) bool {
+	return slices.ContainsFunc(EnginesSupporting(id), func(engine string) bool {
+		return IsActionSupportedBy(id, action, engine)
+	})
+}
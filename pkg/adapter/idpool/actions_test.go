@@ -0,0 +1,81 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package idpool
+
+import (
+	"slices"
+	"testing"
+
+	v1alpha1 "github.com/5GSEC/nimbus/api/v1alpha1"
+)
+
+// TestIsActionSupportedBy checks the actions each security engine enforces
+// each ID with. The managers of the adapters report the unsupported actions
+// from the idpool, so the tables must agree with the action mappings of the
+// adapters.
+func TestIsActionSupportedBy(t *testing.T) {
+	ids := []string{
+		SwDeploymentTools, UnAuthorizedSaTokenAccess, DNSManipulation, EscapeToHost, ExploitPFA,
+		CocoWorkload, AssessTLS, DenyENAccess, VirtualPatch, EgressAllowList,
+	}
+	actions := []string{v1alpha1.ActionAudit, v1alpha1.ActionBlock, v1alpha1.ActionWarn, v1alpha1.ActionAllow, v1alpha1.ActionMutate}
+	auditAndBlock := []string{v1alpha1.ActionAudit, v1alpha1.ActionBlock}
+	block := []string{v1alpha1.ActionBlock}
+	tests := []struct {
+		engine string
+		// supported are the IDs the engine supports and the actions it
+		// enforces them with.
+		supported map[string][]string
+	}{
+		{"kubearmor", map[string][]string{
+			SwDeploymentTools:         auditAndBlock,
+			UnAuthorizedSaTokenAccess: auditAndBlock,
+			DNSManipulation:           auditAndBlock,
+			EscapeToHost:              auditAndBlock,
+			ExploitPFA:                auditAndBlock,
+		}},
+		{"netpol", map[string][]string{
+			DNSManipulation: block,
+			DenyENAccess:    block,
+		}},
+		{"cilium", map[string][]string{
+			DNSManipulation: block,
+			DenyENAccess:    block,
+			EgressAllowList: block,
+		}},
+		{"calico", map[string][]string{
+			DNSManipulation: auditAndBlock,
+			DenyENAccess:    auditAndBlock,
+		}},
+		{"kyverno", map[string][]string{
+			EscapeToHost: auditAndBlock,
+			CocoWorkload: {v1alpha1.ActionMutate},
+			VirtualPatch: block,
+		}},
+		{"k8tls", map[string][]string{
+			AssessTLS: {v1alpha1.ActionAudit},
+		}},
+	}
+
+	var tested []string
+	for _, tt := range tests {
+		tested = append(tested, tt.engine)
+		t.Run(tt.engine, func(t *testing.T) {
+			for _, id := range ids {
+				want, ok := tt.supported[id]
+				if got := IsIdSupportedBy(id, tt.engine); got != ok {
+					t.Errorf("IsIdSupportedBy(%q, %q) = %v, want %v", id, tt.engine, got, ok)
+				}
+				for _, action := range actions {
+					if got := IsActionSupportedBy(id, action, tt.engine); got != slices.Contains(want, action) {
+						t.Errorf("IsActionSupportedBy(%q, %q, %q) = %v, want %v", id, action, tt.engine, got, !got)
+					}
+				}
+			}
+		})
+	}
+	if !slices.Equal(tested, engines) {
+		t.Errorf("tested engines %v, want %v", tested, engines)
+	}
+}
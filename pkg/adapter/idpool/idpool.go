@@ -5,6 +5,7 @@
 package idpool
 
 import (
+	"slices"
 	"strings"
 )
 
@@ -20,6 +21,7 @@ const (
 	AssessTLS                 = "assessTLS"
 	DenyENAccess              = "denyExternalNetworkAccess"
 	VirtualPatch              = "virtualPatch"
+	EgressAllowList           = "egressAllowList"
 )
 
 // KaIds are IDs supported by KubeArmor.
@@ -42,6 +44,19 @@ var NetPolIDs = []string{
 	DenyENAccess,
 }
 
+// CiliumIDs are IDs supported by Cilium.
+var CiliumIDs = []string{
+	DNSManipulation,
+	DenyENAccess,
+	EgressAllowList,
+}
+
+// CalicoIDs are IDs supported by Calico.
+var CalicoIDs = []string{
+	DNSManipulation,
+	DenyENAccess,
+}
+
 // KyvIds are IDs supported by Kyverno.
 var KyvIds = []string{
 	EscapeToHost,
@@ -61,6 +76,10 @@ func IsIdSupportedBy(id, securityEngine string) bool {
 		return in(id, KaIds)
 	case "netpol":
 		return in(id, NetPolIDs)
+	case "cilium":
+		return in(id, CiliumIDs)
+	case "calico":
+		return in(id, CalicoIDs)
 	case "kyverno":
 		return in(id, KyvIds)
 	case "k8tls":
@@ -70,6 +89,35 @@ func IsIdSupportedBy(id, securityEngine string) bool {
 	}
 }
 
+// IsIdSupported determines whether a given ID is supported by any security
+// engine.
+func IsIdSupported(id string) bool {
+	return in(id, KaIds) || in(id, NetPolIDs) || in(id, CiliumIDs) || in(id, CalicoIDs) || in(id, KyvIds) || in(id, k8tlsIds)
+}
+
+// IncompatibleIDs returns the IDs enforced with some of the same engine
+// policies as the given ID, e.g. KubeArmor enforces escapeToHost with the
+// policy of swDeploymentTools among others. Binding incompatible IDs with
+// different actions to the same workloads enforces that policy twice.
+func IncompatibleIDs(id string) []string {
+	var ids []string
+	for satisfied, policies := range KaIDPolicies {
+		switch {
+		case satisfied == id:
+			for _, policy := range policies {
+				if policy != id && in(policy, KaIds) && !in(policy, ids) {
+					ids = append(ids, policy)
+				}
+			}
+		case in(id, policies) && !in(satisfied, ids):
+			ids = append(ids, satisfied)
+		}
+	}
+	// Keep the order stable, since it's iterated to report the conflicts.
+	slices.Sort(ids)
+	return ids
+}
+
 func in(id string, securityEngineIds []string) bool {
 	for _, currId := range securityEngineIds {
 		if currId == id {
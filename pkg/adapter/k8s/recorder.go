@@ -0,0 +1,22 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package k8s
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// NewEventRecorderOrDie returns an EventRecorder that emits Events on behalf of
+// the given adapter, and panics if there is an error in the config. The scheme
+// must know the types of the objects the Events are emitted on.
+func NewEventRecorderOrDie(scheme *runtime.Scheme, adapterName string) record.EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{
+		Interface: NewOrDieStaticClient().CoreV1().Events(""),
+	})
+	return broadcaster.NewRecorder(scheme, corev1.EventSource{Component: adapterName})
+}
@@ -0,0 +1,226 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+// Package metrics defines the Prometheus metrics exposed by the adapters and
+// serves them.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/5GSEC/nimbus/api/v1alpha1"
+	"github.com/5GSEC/nimbus/pkg/adapter/common"
+	"github.com/5GSEC/nimbus/pkg/adapter/idpool"
+)
+
+const (
+	// BindAddressEnv is the environment variable holding the address the
+	// metrics endpoint of an adapter binds to. Set it to "0" to disable the
+	// metrics endpoint.
+	BindAddressEnv     = "METRICS_BIND_ADDRESS"
+	defaultBindAddress = ":8080"
+
+	// Outcomes of the k8tls scans.
+	ScanSucceeded = "succeeded"
+	ScanFailed    = "failed"
+)
+
+var (
+	// Registry is the registry of the adapter metrics.
+	Registry = prometheus.NewRegistry()
+
+	policiesGenerated = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nimbus_adapter_policies_generated_total",
+			Help: "Total number of security engine policies created or configured, per intent ID.",
+		},
+		[]string{"adapter", "kind", "intent"},
+	)
+	policyFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nimbus_adapter_policy_failures_total",
+			Help: "Total number of security engine policies that couldn't be created or configured.",
+		},
+		[]string{"adapter", "kind"},
+	)
+	unsupportedIntents = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nimbus_adapter_unsupported_intents_total",
+			Help: "Total number of intents skipped because the security engine doesn't support their ID, once per policy generation.",
+		},
+		[]string{"adapter", "intent"},
+	)
+	translationErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nimbus_adapter_translation_errors_total",
+			Help: "Total number of errors translating intents into security engine policies, e.g. invalid params.",
+		},
+		[]string{"adapter", "intent"},
+	)
+	reconcileDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "nimbus_adapter_reconcile_duration_seconds",
+			Help:    "Time taken to reconcile the security engine policies of a NimbusPolicy or ClusterNimbusPolicy.",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"adapter"},
+	)
+	intentViolations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nimbus_adapter_intent_violations_total",
+			Help: "Total number of alerts raised by the security engine for the policies enforcing an intent.",
+		},
+		[]string{"adapter", "intent", "action"},
+	)
+	k8tlsScans = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nimbus_k8tls_scans_total",
+			Help: "Total number of finished k8tls scan Jobs, per outcome.",
+		},
+		[]string{"cronjob", "outcome"},
+	)
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		policiesGenerated,
+		policyFailures,
+		unsupportedIntents,
+		translationErrors,
+		reconcileDuration,
+		intentViolations,
+		k8tlsScans,
+	)
+}
+
+// PolicyGenerated counts a security engine policy of the given kind that was
+// created or configured, once for every intent it enforces.
+func PolicyGenerated(adapter, kind string, policy metav1.Object) {
+	intents := policy.GetAnnotations()[common.IntentsAnnotation]
+	if intents == "" {
+		policiesGenerated.WithLabelValues(adapter, kind, "").Inc()
+		return
+	}
+	for _, id := range strings.Split(intents, ",") {
+		policiesGenerated.WithLabelValues(adapter, kind, id).Inc()
+	}
+}
+
+// PolicyFailed counts a security engine policy of the given kind that couldn't
+// be created or configured.
+func PolicyFailed(adapter, kind string) {
+	policyFailures.WithLabelValues(adapter, kind).Inc()
+}
+
+// UnsupportedIntents counts the rules of the given NimbusPolicy or
+// ClusterNimbusPolicy whose intent ID the given security engine doesn't
+// support. The rules of a policy are counted once per generation, however many
+// times it's reconciled.
+func UnsupportedIntents(adapter string, policy metav1.Object, rules []v1alpha1.NimbusRules, securityEngine string) {
+	if !countedGenerations.update(policy) {
+		return
+	}
+	for _, rule := range rules {
+		if !idpool.IsIdSupportedBy(rule.ID, securityEngine) {
+			unsupportedIntents.WithLabelValues(adapter, rule.ID).Inc()
+		}
+	}
+}
+
+// TranslationFailed counts an intent that couldn't be translated into the
+// policies of the security engine, e.g. because of invalid params.
+func TranslationFailed(adapter, intent string) {
+	translationErrors.WithLabelValues(adapter, intent).Inc()
+}
+
+// generations tracks the last generation of the policies whose unsupported
+// intents were counted.
+type generations struct {
+	mu   sync.Mutex
+	byID map[types.UID]int64
+}
+
+var countedGenerations = &generations{byID: make(map[types.UID]int64)}
+
+// update records the generation of the given policy and reports whether it
+// differs from the one previously recorded.
+func (g *generations) update(policy metav1.Object) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if generation, ok := g.byID[policy.GetUID()]; ok && generation == policy.GetGeneration() {
+		return false
+	}
+	g.byID[policy.GetUID()] = policy.GetGeneration()
+	return true
+}
+
+// ObserveReconcile records the time elapsed since the given start of a
+// reconciliation. It's meant to be deferred.
+func ObserveReconcile(adapter string, start time.Time) {
+	reconcileDuration.WithLabelValues(adapter).Observe(time.Since(start).Seconds())
+}
+
+// IntentViolated counts an alert the security engine raised with the given
+// action for a policy enforcing the given intent.
+func IntentViolated(adapter, intent, action string) {
+	intentViolations.WithLabelValues(adapter, intent, action).Inc()
+}
+
+// ScanFinished counts a k8tls scan Job of the given CronJob that finished with
+// the given outcome.
+func ScanFinished(cronJobName, outcome string) {
+	k8tlsScans.WithLabelValues(cronJobName, outcome).Inc()
+}
+
+// Serve serves the adapter metrics on the address set in the
+// METRICS_BIND_ADDRESS environment variable, ":8080" by default, until the
+// context is done.
+func Serve(ctx context.Context) {
+	logger := log.FromContext(ctx)
+
+	addr := os.Getenv(BindAddressEnv)
+	if addr == "" {
+		addr = defaultBindAddress
+	}
+	if addr == "0" {
+		logger.Info("Metrics server disabled")
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(Registry, promhttp.HandlerOpts{}))
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.Error(err, "failed to shut down metrics server")
+		}
+	}()
+
+	logger.Info("Serving metrics", "Address", addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error(err, "failed to serve metrics")
+	}
+}
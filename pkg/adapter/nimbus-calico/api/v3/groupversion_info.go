@@ -0,0 +1,26 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+// Package v3 contains the subset of the NetworkPolicy, GlobalNetworkPolicy and
+// Tier APIs of the projectcalico.org/v3 group, served by the Calico API
+// server, that the adapter generates, so that it doesn't depend on the whole
+// Calico module.
+// +kubebuilder:object:generate=true
+// +groupName=projectcalico.org
+package v3
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+)
+
+var (
+	// GroupVersion is group version used to register these objects
+	GroupVersion = schema.GroupVersion{Group: "projectcalico.org", Version: "v3"}
+
+	// SchemeBuilder is used to add go types to the GroupVersionKind scheme
+	SchemeBuilder = &scheme.Builder{GroupVersion: GroupVersion}
+
+	// AddToScheme adds the types in this group-version to the given scheme.
+	AddToScheme = SchemeBuilder.AddToScheme
+)
@@ -0,0 +1,187 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package v3
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// Action is the action of a rule matching the traffic.
+// +kubebuilder:validation:Enum=Allow;Deny;Log;Pass
+type Action string
+
+const (
+	// ActionDeny drops the traffic.
+	ActionDeny Action = "Deny"
+	// ActionLog logs the traffic and goes on with the next rule.
+	ActionLog Action = "Log"
+	// ActionPass skips the remaining policies of the tier, and goes on with
+	// the first policy of the next tier selecting the endpoint.
+	ActionPass Action = "Pass"
+)
+
+// PolicyType is a direction of the traffic a policy applies to.
+type PolicyType string
+
+const (
+	PolicyTypeIngress PolicyType = "Ingress"
+	PolicyTypeEgress  PolicyType = "Egress"
+)
+
+// EntityRule selects the source or destination of the traffic. All the given
+// fields must match.
+type EntityRule struct {
+	// Nets matches the IPs in any of the given CIDRs.
+	// +optional
+	Nets []string `json:"nets,omitempty"`
+	// NotNets matches the IPs in none of the given CIDRs.
+	// +optional
+	NotNets []string `json:"notNets,omitempty"`
+	// Selector matches the endpoints by their labels, e.g. "app == 'nginx'".
+	// +optional
+	Selector string `json:"selector,omitempty"`
+	// NamespaceSelector matches the endpoints by the labels of their
+	// namespace.
+	// +optional
+	NamespaceSelector string `json:"namespaceSelector,omitempty"`
+	// Ports matches the given port numbers, ranges as "min:max", or names.
+	// +optional
+	Ports []intstr.IntOrString `json:"ports,omitempty"`
+}
+
+// Rule matches traffic and sets the action taken on it.
+type Rule struct {
+	Action Action `json:"action"`
+	// IPVersion restricts the rule to IPv4 (4) or IPv6 (6) traffic.
+	// +optional
+	IPVersion *int `json:"ipVersion,omitempty"`
+	// Protocol matches the L4 protocol, e.g. "TCP" or "UDP". It's required by
+	// the rules matching ports.
+	// +optional
+	Protocol string `json:"protocol,omitempty"`
+	// +optional
+	Source EntityRule `json:"source,omitempty"`
+	// +optional
+	Destination EntityRule `json:"destination,omitempty"`
+}
+
+// NetworkPolicySpec defines the rules of a policy. The rules of the policies
+// selecting an endpoint are evaluated in the order of their tier, then of the
+// policies in the tier, until one matches the traffic with an Allow, Deny or
+// Pass action. The traffic no rule of a tier matches is denied.
+type NetworkPolicySpec struct {
+	// Tier is the tier of the policy, "default" when empty. The name of the
+	// policies of the other tiers must be prefixed with "<tier>.".
+	// +optional
+	Tier string `json:"tier,omitempty"`
+	// Order is the order of the policy in its tier, lower first.
+	// +optional
+	Order *float64 `json:"order,omitempty"`
+	// Selector selects the endpoints the policy applies to, "all()" for all of
+	// them.
+	// +optional
+	Selector string `json:"selector,omitempty"`
+	// +optional
+	Ingress []Rule `json:"ingress,omitempty"`
+	// +optional
+	Egress []Rule `json:"egress,omitempty"`
+	// Types are the directions of the traffic the policy applies to.
+	// +optional
+	Types []PolicyType `json:"types,omitempty"`
+}
+
+// GlobalNetworkPolicySpec defines the rules of a policy applying to the
+// endpoints of all the namespaces, and to the host endpoints.
+type GlobalNetworkPolicySpec struct {
+	// +optional
+	Tier string `json:"tier,omitempty"`
+	// +optional
+	Order *float64 `json:"order,omitempty"`
+	// +optional
+	Selector string `json:"selector,omitempty"`
+	// NamespaceSelector restricts the endpoints the policy applies to to the
+	// ones of the namespaces it selects.
+	// +optional
+	NamespaceSelector string `json:"namespaceSelector,omitempty"`
+	// +optional
+	Ingress []Rule `json:"ingress,omitempty"`
+	// +optional
+	Egress []Rule `json:"egress,omitempty"`
+	// +optional
+	Types []PolicyType `json:"types,omitempty"`
+}
+
+// TierSpec defines the order of a tier of policies.
+type TierSpec struct {
+	// Order is the order of the tier, lower first. The default tier comes
+	// last.
+	// +optional
+	Order *float64 `json:"order,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// NetworkPolicy is the Schema for the Calico networkpolicies API
+type NetworkPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec NetworkPolicySpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// NetworkPolicyList contains a list of NetworkPolicy
+type NetworkPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NetworkPolicy `json:"items"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+
+// GlobalNetworkPolicy is the Schema for the globalnetworkpolicies API
+type GlobalNetworkPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec GlobalNetworkPolicySpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GlobalNetworkPolicyList contains a list of GlobalNetworkPolicy
+type GlobalNetworkPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GlobalNetworkPolicy `json:"items"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+
+// Tier is the Schema for the tiers API
+type Tier struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec TierSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// TierList contains a list of Tier
+type TierList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Tier `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NetworkPolicy{}, &NetworkPolicyList{},
+		&GlobalNetworkPolicy{}, &GlobalNetworkPolicyList{},
+		&Tier{}, &TierList{})
+}
@@ -0,0 +1,69 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package manager
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/5GSEC/nimbus/api/v1alpha1"
+	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
+
+	calicov3 "github.com/5GSEC/nimbus/pkg/adapter/nimbus-calico/api/v3"
+	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-calico/processor"
+)
+
+func reconcileGlobalNetpols(ctx context.Context, cwnpName string, deleted bool) {
+	logger := log.FromContext(ctx)
+	if cwnpName == "" {
+		return
+	}
+	if deleted {
+		logger.V(2).Info("Reconciling deleted GlobalNetworkPolicy", "ClusterNimbusPolicy.Name", cwnpName)
+	} else {
+		logger.V(2).Info("Reconciling modified GlobalNetworkPolicy", "ClusterNimbusPolicy.Name", cwnpName)
+	}
+	createOrUpdateGlobalNetpols(ctx, cwnpName)
+}
+
+func createOrUpdateGlobalNetpols(ctx context.Context, cwnpName string) {
+	adapterutil.ReconcileClusterPolicies(ctx, k8sClient, scheme, recorder, adapterName, "calico", cwnpName, adapterutil.ClusterPolicies[calicov3.GlobalNetworkPolicy]{
+		Kind: "GlobalNetworkPolicy",
+		Build: func(ctx context.Context, cwnp *v1alpha1.ClusterNimbusPolicy) []calicov3.GlobalNetworkPolicy {
+			return processor.BuildGlobalNetPolsFrom(log.FromContext(ctx), *cwnp, k8sClient)
+		},
+		List: func(ctx context.Context) ([]calicov3.GlobalNetworkPolicy, error) {
+			var gnps calicov3.GlobalNetworkPolicyList
+			err := k8sClient.List(ctx, &gnps)
+			return gnps.Items, err
+		},
+	})
+}
+
+func logGlobalNetpolsToDelete(ctx context.Context, deletedCwnp *unstructured.Unstructured) {
+	logger := log.FromContext(ctx)
+	var gnps calicov3.GlobalNetworkPolicyList
+
+	if err := k8sClient.List(ctx, &gnps); err != nil {
+		logger.Error(err, "failed to list GlobalNetworkPolicies")
+		return
+	}
+
+	// Kubernetes GC automatically deletes the child when the parent/owner is
+	// deleted. So, we don't need to delete the policy because ClusterNimbusPolicy
+	// is the owner and when it gets deleted all the corresponding policies will be
+	// automatically deleted.
+	for _, gnp := range gnps.Items {
+		for _, ownerRef := range gnp.OwnerReferences {
+			if ownerRef.Name == deletedCwnp.GetName() && ownerRef.UID == deletedCwnp.GetUID() {
+				logger.Info("GlobalNetworkPolicy already deleted due to ClusterNimbusPolicy deletion",
+					"GlobalNetworkPolicy.Name", gnp.Name, "ClusterNimbusPolicy.Name", deletedCwnp.GetName(),
+				)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,322 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package manager
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/5GSEC/nimbus/api/v1alpha1"
+	"github.com/5GSEC/nimbus/pkg/adapter/common"
+	"github.com/5GSEC/nimbus/pkg/adapter/idpool"
+	"github.com/5GSEC/nimbus/pkg/adapter/k8s"
+	"github.com/5GSEC/nimbus/pkg/adapter/metrics"
+	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
+	globalwatcher "github.com/5GSEC/nimbus/pkg/adapter/watcher"
+	"github.com/5GSEC/nimbus/pkg/tracing"
+
+	calicov3 "github.com/5GSEC/nimbus/pkg/adapter/nimbus-calico/api/v3"
+	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-calico/processor"
+	calicowatcher "github.com/5GSEC/nimbus/pkg/adapter/nimbus-calico/watcher"
+)
+
+const adapterName = "nimbus-calico"
+
+// netpolKind is the kind of the Calico NetworkPolicies in the status of the
+// NimbusPolicies, set apart from the Kubernetes NetworkPolicies.
+const netpolKind = "CalicoNetworkPolicy"
+
+var (
+	scheme    = runtime.NewScheme()
+	k8sClient client.Client
+	recorder  record.EventRecorder
+)
+
+func init() {
+	utilruntime.Must(v1alpha1.AddToScheme(scheme))
+	utilruntime.Must(calicov3.AddToScheme(scheme))
+	utilruntime.Must(corev1.AddToScheme(scheme))
+	utilruntime.Must(appsv1.AddToScheme(scheme))
+	utilruntime.Must(discoveryv1.AddToScheme(scheme))
+	k8sClient = k8s.NewOrDie(scheme)
+	recorder = k8s.NewEventRecorderOrDie(scheme, adapterName)
+}
+
+func Run(ctx context.Context) {
+	ensureTier(ctx)
+
+	npCh := make(chan common.Request)
+	deletedNpCh := make(chan *unstructured.Unstructured)
+	go globalwatcher.WatchNimbusPolicies(ctx, npCh, deletedNpCh, "SecurityIntentBinding", "ClusterSecurityIntentBinding")
+
+	clusterNpCh := make(chan string)
+	deletedClusterNpCh := make(chan *unstructured.Unstructured)
+	go globalwatcher.WatchClusterNimbusPolicies(ctx, clusterNpCh, deletedClusterNpCh)
+
+	updatedNetpolCh := make(chan common.Request)
+	deletedNetpolCh := make(chan common.Request)
+	go calicowatcher.WatchNetpols(ctx, updatedNetpolCh, deletedNetpolCh)
+
+	updatedGnpCh := make(chan string)
+	deletedGnpCh := make(chan string)
+	go calicowatcher.WatchGlobalNetpols(ctx, updatedGnpCh, deletedGnpCh)
+
+	// Without the pass policy, the policies of the intents would deny all the
+	// traffic they don't pass themselves.
+	passPolicyChangedCh := make(chan struct{}, 1)
+	go calicowatcher.WatchPassPolicy(ctx, processor.PassPolicyName, passPolicyChangedCh)
+
+	// The policies allow the networks of the cluster, which change as nodes
+	// join or leave it.
+	nodesChangedCh := make(chan struct{}, 1)
+	go globalwatcher.WatchNodes(ctx, nodesChangedCh)
+
+	for {
+		select {
+		case <-ctx.Done():
+			close(npCh)
+			close(deletedNpCh)
+			close(clusterNpCh)
+			close(deletedClusterNpCh)
+			close(updatedNetpolCh)
+			close(deletedNetpolCh)
+			close(updatedGnpCh)
+			close(deletedGnpCh)
+			close(passPolicyChangedCh)
+			close(nodesChangedCh)
+			return
+		case createdNp := <-npCh:
+			createOrUpdateNetpols(ctx, createdNp.Name, createdNp.Namespace)
+		case createdCwnp := <-clusterNpCh:
+			createOrUpdateGlobalNetpols(ctx, createdCwnp)
+		case deletedNp := <-deletedNpCh:
+			logNetpolsToDelete(ctx, deletedNp)
+		case deletedCwnp := <-deletedClusterNpCh:
+			logGlobalNetpolsToDelete(ctx, deletedCwnp)
+		case updatedNetpol := <-updatedNetpolCh:
+			reconcileNetpols(ctx, updatedNetpol.Name, updatedNetpol.Namespace, false)
+		case deletedNetpol := <-deletedNetpolCh:
+			reconcileNetpols(ctx, deletedNetpol.Name, deletedNetpol.Namespace, true)
+		case updatedGnp := <-updatedGnpCh:
+			reconcileGlobalNetpols(ctx, updatedGnp, false)
+		case deletedGnp := <-deletedGnpCh:
+			reconcileGlobalNetpols(ctx, deletedGnp, true)
+		case <-passPolicyChangedCh:
+			ensureTier(ctx)
+		case <-nodesChangedCh:
+			reconcileAllPolicies(ctx)
+		}
+	}
+}
+
+func reconcileNetpols(ctx context.Context, npName, namespace string, deleted bool) {
+	logger := log.FromContext(ctx)
+	if npName == "" {
+		return
+	}
+	if deleted {
+		logger.V(2).Info("Reconciling deleted Calico NetworkPolicy", "NimbusPolicy.Name", npName, "NimbusPolicy.Namespace", namespace)
+	} else {
+		logger.V(2).Info("Reconciling modified Calico NetworkPolicy", "NimbusPolicy.Name", npName, "NimbusPolicy.Namespace", namespace)
+	}
+	createOrUpdateNetpols(ctx, npName, namespace)
+}
+
+// reconcileAllPolicies rebuilds the policies of all the NimbusPolicies and
+// ClusterNimbusPolicies with intents supported by this adapter, so that they
+// allow the current networks of the cluster.
+func reconcileAllPolicies(ctx context.Context) {
+	logger := log.FromContext(ctx)
+	logger.Info("Cluster nodes changed, reconciling policies")
+
+	var nps v1alpha1.NimbusPolicyList
+	if err := k8sClient.List(ctx, &nps); err != nil {
+		logger.Error(err, "failed to list NimbusPolicies")
+	}
+	for _, np := range nps.Items {
+		if hasCalicoIntents(np.Spec.NimbusRules) {
+			createOrUpdateNetpols(ctx, np.Name, np.Namespace)
+		}
+	}
+
+	var cwnps v1alpha1.ClusterNimbusPolicyList
+	if err := k8sClient.List(ctx, &cwnps); err != nil {
+		logger.Error(err, "failed to list ClusterNimbusPolicies")
+		return
+	}
+	for _, cwnp := range cwnps.Items {
+		if hasCalicoIntents(cwnp.Spec.NimbusRules) {
+			createOrUpdateGlobalNetpols(ctx, cwnp.Name)
+		}
+	}
+}
+
+func hasCalicoIntents(rules []v1alpha1.NimbusRules) bool {
+	return slices.ContainsFunc(rules, func(rule v1alpha1.NimbusRules) bool {
+		return idpool.IsIdSupportedBy(rule.ID, "calico")
+	})
+}
+
+func createOrUpdateNetpols(ctx context.Context, npName, npNamespace string) {
+	defer metrics.ObserveReconcile(adapterName, time.Now())
+	logger := log.FromContext(ctx)
+	var np v1alpha1.NimbusPolicy
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: npName, Namespace: npNamespace}, &np); err != nil {
+		if !errors.IsNotFound(err) {
+			logger.Error(err, "failed to get NimbusPolicy", "NimbusPolicy.Name", npName, "NimbusPolicy.Namespace", npNamespace)
+		}
+		return
+	}
+
+	if adapterutil.IsOrphan(np.GetOwnerReferences(), "SecurityIntentBinding", "ClusterSecurityIntentBinding") {
+		logger.V(4).Info("Ignoring orphan NimbusPolicy", "NimbusPolicy.Name", npName, "NimbusPolicy.Namespace", npNamespace)
+		return
+	}
+
+	// The ClusterSecurityIntentBindings are enforced by GlobalNetworkPolicies
+	// built from their ClusterNimbusPolicy, so only drop the NetworkPolicies
+	// built from the NimbusPolicies generated for them.
+	if adapterutil.IsOrphan(np.GetOwnerReferences(), "SecurityIntentBinding") {
+		logger.V(4).Info("Ignoring NimbusPolicy of a ClusterSecurityIntentBinding", "NimbusPolicy.Name", npName, "NimbusPolicy.Namespace", npNamespace)
+		deleteDanglingNetpols(ctx, np, nil, logger)
+		return
+	}
+
+	// Continue the trace of the reconciliation that changed the NimbusPolicy.
+	ctx, span := tracing.Start(tracing.Extract(ctx, &np), adapterName+".Reconcile", tracing.WithObject("NimbusPolicy", &np))
+	defer span.End()
+
+	adapterutil.RecordUnsupportedIntents(recorder, &np, np.Spec.NimbusRules, "calico")
+	adapterutil.RecordUnsupportedActions(recorder, &np, np.Spec.NimbusRules, "calico")
+	if err := adapterutil.UpdateUnsupportedActions(ctx, k8sClient, "NimbusPolicy", np.Name, np.Namespace, "calico", np.Spec.NimbusRules); err != nil {
+		logger.Error(err, "failed to update unsupported actions status in NimbusPolicy", "NimbusPolicy.Name", np.Name, "NimbusPolicy.Namespace", np.Namespace)
+	}
+	metrics.UnsupportedIntents(adapterName, &np, np.Spec.NimbusRules, "calico")
+	_, translateSpan := tracing.Start(ctx, "CalicoNetworkPolicy.Translate")
+	netpols := processor.BuildNetPolsFrom(logger, np, k8sClient)
+	translateSpan.End()
+	deleteDanglingNetpols(ctx, np, netpols, logger)
+
+	// Iterate using a separate index variable to avoid aliasing
+	for idx := range netpols {
+		netpol := netpols[idx]
+
+		// Set NimbusPolicy as the owner of the network policy
+		if err := ctrl.SetControllerReference(&np, &netpol, scheme); err != nil {
+			logger.Error(err, "failed to set OwnerReference on Calico NetworkPolicy", "NetworkPolicy.Name", netpol.Name, "NetworkPolicy.Namespace", netpol.Namespace)
+			return
+		}
+
+		var existingNetpol calicov3.NetworkPolicy
+		err := k8sClient.Get(ctx, types.NamespacedName{Name: netpol.Name, Namespace: netpol.Namespace}, &existingNetpol)
+		if err != nil && !errors.IsNotFound(err) {
+			logger.Error(err, "failed to get existing Calico NetworkPolicy", "NetworkPolicy.Name", netpol.Name, "NetworkPolicy.Namespace", netpol.Namespace)
+			return
+		}
+		if err != nil {
+			if err = tracing.Trace(ctx, "CalicoNetworkPolicy.Create", func(ctx context.Context) error {
+				return k8sClient.Create(ctx, &netpol)
+			}, tracing.WithObject("NetworkPolicy", &netpol)); err != nil {
+				logger.Error(err, "failed to create Calico NetworkPolicy", "NetworkPolicy.Name", netpol.Name, "NetworkPolicy.Namespace", netpol.Namespace)
+				adapterutil.RecordPolicyFailed(recorder, &np, netpolKind+"/"+netpol.Name, err)
+				metrics.PolicyFailed(adapterName, netpolKind)
+				return
+			}
+			logger.Info("Calico NetworkPolicy created", "NetworkPolicy.Name", netpol.Name, "NetworkPolicy.Namespace", netpol.Namespace)
+			adapterutil.RecordPolicyCreated(recorder, &np, netpolKind+"/"+netpol.Name)
+			metrics.PolicyGenerated(adapterName, netpolKind, &netpol)
+		} else {
+			netpol.ObjectMeta.ResourceVersion = existingNetpol.ObjectMeta.ResourceVersion
+			if err = tracing.Trace(ctx, "CalicoNetworkPolicy.Update", func(ctx context.Context) error {
+				return k8sClient.Update(ctx, &netpol)
+			}, tracing.WithObject("NetworkPolicy", &netpol)); err != nil {
+				logger.Error(err, "failed to configure existing Calico NetworkPolicy", "NetworkPolicy.Name", netpol.Name, "NetworkPolicy.Namespace", netpol.Namespace)
+				adapterutil.RecordPolicyFailed(recorder, &np, netpolKind+"/"+netpol.Name, err)
+				metrics.PolicyFailed(adapterName, netpolKind)
+				return
+			}
+			logger.Info("Calico NetworkPolicy configured", "NetworkPolicy.Name", netpol.Name, "NetworkPolicy.Namespace", netpol.Namespace)
+			if netpol.ResourceVersion != existingNetpol.ResourceVersion {
+				adapterutil.RecordPolicyConfigured(recorder, &np, netpolKind+"/"+netpol.Name)
+				metrics.PolicyGenerated(adapterName, netpolKind, &netpol)
+			}
+		}
+
+		if err = adapterutil.UpdateNpStatus(ctx, k8sClient, netpolKind+"/"+netpol.Name, np.Name, np.Namespace, false); err != nil {
+			logger.Error(err, "failed to update Calico NetworkPolicies status in NimbusPolicy")
+		}
+	}
+}
+
+func logNetpolsToDelete(ctx context.Context, deletedNp *unstructured.Unstructured) {
+	logger := log.FromContext(ctx)
+
+	var netpols calicov3.NetworkPolicyList
+	if err := k8sClient.List(ctx, &netpols, &client.ListOptions{Namespace: deletedNp.GetNamespace()}); err != nil {
+		logger.Error(err, "failed to list Calico NetworkPolicies")
+		return
+	}
+
+	// Kubernetes GC automatically deletes the child when the parent/owner is
+	// deleted. So, we don't need to delete the policy because NimbusPolicy is the
+	// owner and when it gets deleted all the corresponding policies will be
+	// automatically deleted.
+	for _, netpol := range netpols.Items {
+		for _, ownerRef := range netpol.OwnerReferences {
+			if ownerRef.Name == deletedNp.GetName() && ownerRef.UID == deletedNp.GetUID() {
+				logger.Info("Calico NetworkPolicy already deleted due to NimbusPolicy deletion",
+					"NetworkPolicy.Name", netpol.Name, "NetworkPolicy.Namespace", netpol.Namespace,
+					"NimbusPolicy.Name", deletedNp.GetName(), "NimbusPolicy.Namespace", deletedNp.GetNamespace(),
+				)
+				break
+			}
+		}
+	}
+}
+
+// deleteDanglingNetpols deletes the Calico NetworkPolicies owned by the given
+// NimbusPolicy that aren't built from it anymore.
+func deleteDanglingNetpols(ctx context.Context, np v1alpha1.NimbusPolicy, netpols []calicov3.NetworkPolicy, logger logr.Logger) {
+	var existingNetpols calicov3.NetworkPolicyList
+	if err := k8sClient.List(ctx, &existingNetpols, client.InNamespace(np.Namespace)); err != nil {
+		logger.Error(err, "failed to list Calico NetworkPolicies for cleanup")
+		return
+	}
+
+	for idx := range existingNetpols.Items {
+		netpol := &existingNetpols.Items[idx]
+		if !slices.ContainsFunc(netpol.OwnerReferences, func(ownerRef metav1.OwnerReference) bool { return ownerRef.UID == np.UID }) {
+			continue
+		}
+		if slices.ContainsFunc(netpols, func(built calicov3.NetworkPolicy) bool { return built.Name == netpol.Name }) {
+			continue
+		}
+
+		if err := k8sClient.Delete(ctx, netpol); err != nil {
+			logger.Error(err, "failed to delete dangling Calico NetworkPolicy", "NetworkPolicy.Name", netpol.Name, "NetworkPolicy.Namespace", netpol.Namespace)
+			continue
+		}
+		if err := adapterutil.UpdateNpStatus(ctx, k8sClient, netpolKind+"/"+netpol.Name, np.Name, np.Namespace, true); err != nil {
+			logger.Error(err, "failed to update Calico NetworkPolicy status in NimbusPolicy")
+		}
+		logger.Info("Dangling Calico NetworkPolicy deleted", "NetworkPolicy.Name", netpol.Name, "NetworkPolicy.Namespace", netpol.Namespace)
+		adapterutil.RecordDanglingPolicyDeleted(recorder, &np, netpolKind+"/"+netpol.Name)
+	}
+}
@@ -0,0 +1,49 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package manager
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-calico/processor"
+)
+
+// ensureTier creates or restores the tier of the policies of the adapter and
+// its pass policy. They aren't owned by any NimbusPolicy, so they stay in
+// place when the adapter is uninstalled.
+func ensureTier(ctx context.Context) {
+	logger := log.FromContext(ctx)
+
+	tier := processor.BuildTier()
+	if err := createOrUpdate(ctx, &tier, tier.DeepCopy()); err != nil {
+		logger.Error(err, "failed to configure Calico Tier", "Tier.Name", tier.Name)
+		return
+	}
+
+	passPolicy := processor.BuildPassPolicy()
+	if err := createOrUpdate(ctx, &passPolicy, passPolicy.DeepCopy()); err != nil {
+		logger.Error(err, "failed to configure GlobalNetworkPolicy", "GlobalNetworkPolicy.Name", passPolicy.Name)
+		return
+	}
+	logger.V(2).Info("Calico Tier configured", "Tier.Name", tier.Name, "GlobalNetworkPolicy.Name", passPolicy.Name)
+}
+
+// createOrUpdate creates the given cluster-scoped object, or updates it when
+// it already exists, using existing to get its current version.
+func createOrUpdate(ctx context.Context, obj, existing client.Object) error {
+	err := k8sClient.Get(ctx, types.NamespacedName{Name: obj.GetName()}, existing)
+	if errors.IsNotFound(err) {
+		return k8sClient.Create(ctx, obj)
+	}
+	if err != nil {
+		return err
+	}
+	obj.SetResourceVersion(existing.GetResourceVersion())
+	return k8sClient.Update(ctx, obj)
+}
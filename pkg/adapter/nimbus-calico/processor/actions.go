@@ -0,0 +1,26 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package processor
+
+import (
+	v1alpha1 "github.com/5GSEC/nimbus/api/v1alpha1"
+
+	calicov3 "github.com/5GSEC/nimbus/pkg/adapter/nimbus-calico/api/v3"
+)
+
+// supportedActions maps the Nimbus actions that Calico policies can enforce
+// to the action of the rules matching the traffic the intents forbid.
+//
+//	| Nimbus | Calico |
+//	|--------|--------|
+//	| Block  | Deny   |
+//	| Audit  | Log    |
+//
+// Log rules log the traffic and go on with the next rules, so that audited
+// traffic is still subject to the other policies. Warn, Allow and Mutate
+// aren't supported.
+var supportedActions = map[string]calicov3.Action{
+	v1alpha1.ActionBlock: calicov3.ActionDeny,
+	v1alpha1.ActionAudit: calicov3.ActionLog,
+}
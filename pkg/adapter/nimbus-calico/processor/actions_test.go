@@ -0,0 +1,33 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package processor
+
+import (
+	"testing"
+
+	v1alpha1 "github.com/5GSEC/nimbus/api/v1alpha1"
+	calicov3 "github.com/5GSEC/nimbus/pkg/adapter/nimbus-calico/api/v3"
+)
+
+func TestSupportedActions(t *testing.T) {
+	tests := []struct {
+		action       string
+		calicoAction calicov3.Action
+		supported    bool
+	}{
+		{v1alpha1.ActionAudit, calicov3.ActionLog, true},
+		{v1alpha1.ActionBlock, calicov3.ActionDeny, true},
+		{v1alpha1.ActionWarn, "", false},
+		{v1alpha1.ActionAllow, "", false},
+		{v1alpha1.ActionMutate, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			calicoAction, ok := supportedActions[tt.action]
+			if calicoAction != tt.calicoAction || ok != tt.supported {
+				t.Errorf("supportedActions[%q] = %q, %v, want %q, %v", tt.action, calicoAction, ok, tt.calicoAction, tt.supported)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package processor
+
+import (
+	"context"
+	"slices"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1alpha1 "github.com/5GSEC/nimbus/api/v1alpha1"
+	"github.com/5GSEC/nimbus/pkg/adapter/clustercidrs"
+	"github.com/5GSEC/nimbus/pkg/adapter/clusterdns"
+	"github.com/5GSEC/nimbus/pkg/adapter/idpool"
+	"github.com/5GSEC/nimbus/pkg/adapter/metrics"
+	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
+
+	calicov3 "github.com/5GSEC/nimbus/pkg/adapter/nimbus-calico/api/v3"
+)
+
+// nsBlackList lists the namespaces never selected by the GlobalNetworkPolicies,
+// as the controller never generates NimbusPolicies for them either.
+var nsBlackList = []string{"kube-system"}
+
+// BuildGlobalNetPolsFrom builds the GlobalNetworkPolicies enforcing the
+// intents of the given ClusterNimbusPolicy in the namespaces it selects, one
+// per intent.
+func BuildGlobalNetPolsFrom(logger logr.Logger, cwnp v1alpha1.ClusterNimbusPolicy, k8sClient client.Client) []calicov3.GlobalNetworkPolicy {
+	clusterCIDRs, err := clustercidrs.Discover(context.Background(), k8sClient)
+	if err != nil {
+		logger.Error(err, "Failed to discover some of the cluster CIDRs")
+	}
+	clusterDNS, err := clusterdns.Discover(context.Background(), k8sClient)
+	if err != nil {
+		logger.Error(err, "Failed to discover some of the cluster DNS servers")
+	}
+
+	var gnps []calicov3.GlobalNetworkPolicy
+	for _, nimbusRule := range cwnp.Spec.NimbusRules {
+		id := nimbusRule.ID
+		if !idpool.IsIdSupportedBy(id, "calico") {
+			logger.Info("Calico adapter does not support this ID", "ID", id, "ClusterNimbusPolicy.Name", cwnp.Name)
+			continue
+		}
+		action, ok := supportedActions[nimbusRule.Rule.RuleAction]
+		if !ok {
+			logger.Info("Calico adapter does not support this action", "ID", id, "Action", nimbusRule.Rule.RuleAction,
+				"ClusterNimbusPolicy.Name", cwnp.Name)
+			continue
+		}
+
+		// Leave the intent to the conflicting NimbusPolicies taking precedence
+		// in some of the namespaces.
+		nsSelector, ok := adapterutil.RuleNsSelector(cwnp.Spec, id)
+		if !ok {
+			logger.Info("Conflicting NimbusPolicies take precedence over the intent in all the selected namespaces",
+				"ID", id, "ClusterNimbusPolicy.Name", cwnp.Name)
+			continue
+		}
+
+		cidrs, err := clusterCIDRs.WithParams(nimbusRule.Rule.Params)
+		if err != nil {
+			logger.Error(err, "Ignoring invalid CIDR params", "ID", id, "ClusterNimbusPolicy.Name", cwnp.Name)
+			metrics.TranslationFailed(adapterName, id)
+		}
+		dns, err := clusterDNS.WithParams(nimbusRule.Rule.Params)
+		if err != nil {
+			logger.Error(err, "Ignoring invalid DNS params", "ID", id, "ClusterNimbusPolicy.Name", cwnp.Name)
+			metrics.TranslationFailed(adapterName, id)
+		}
+		rules, ok := rulesFor(id, action, cidrs, dns)
+		if !ok {
+			continue
+		}
+
+		gnp := calicov3.GlobalNetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        PolicyName(cwnp.Name, id),
+				Annotations: map[string]string{"app.kubernetes.io/managed-by": "nimbus-calico"},
+			},
+			Spec: calicov3.GlobalNetworkPolicySpec{
+				Tier:              TierName,
+				Order:             ptr.To(policyOrders[id]),
+				Selector:          selectorOf(cwnp.Spec.WorkloadSelector),
+				NamespaceSelector: namespaceSelectorFor(nsSelector),
+				Ingress:           rules.ingress,
+				Egress:            rules.egress,
+				Types:             rules.types,
+			},
+		}
+		adapterutil.AddIntents(gnp.Annotations, id)
+		gnps = append(gnps, gnp)
+	}
+	return gnps
+}
+
+// namespaceSelectorFor translates the namespace selector of a
+// ClusterNimbusPolicy into the namespace selector of a GlobalNetworkPolicy:
+//
+//   - MatchNames: ["*"] selects all the namespaces but the blacklisted ones.
+//   - MatchNames selects the listed namespaces.
+//   - ExcludeNames selects all the namespaces but the listed and the
+//     blacklisted ones.
+//
+// Selecting namespaces also keeps the policy from applying to host endpoints.
+func namespaceSelectorFor(nsSelector v1alpha1.NamespaceSelector) string {
+	matchNames, excludeNames := nsSelector.MatchNames, nsSelector.ExcludeNames
+	switch {
+	case len(excludeNames) > 0:
+		return inSelector(append(slices.Clone(nsBlackList), excludeNames...), false)
+	case len(matchNames) > 0 && !(len(matchNames) == 1 && matchNames[0] == "*"):
+		return inSelector(slices.DeleteFunc(slices.Clone(matchNames), func(ns string) bool { return slices.Contains(nsBlackList, ns) }), true)
+	default:
+		return inSelector(nsBlackList, false)
+	}
+}
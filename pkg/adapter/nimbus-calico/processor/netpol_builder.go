@@ -0,0 +1,95 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package processor
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1alpha1 "github.com/5GSEC/nimbus/api/v1alpha1"
+	"github.com/5GSEC/nimbus/pkg/adapter/clustercidrs"
+	"github.com/5GSEC/nimbus/pkg/adapter/clusterdns"
+	"github.com/5GSEC/nimbus/pkg/adapter/idpool"
+	"github.com/5GSEC/nimbus/pkg/adapter/metrics"
+	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
+
+	calicov3 "github.com/5GSEC/nimbus/pkg/adapter/nimbus-calico/api/v3"
+)
+
+// adapterName labels the metrics of the adapter.
+const adapterName = "nimbus-calico"
+
+// PolicyName returns the name of the policy of the given intent of a
+// NimbusPolicy or ClusterNimbusPolicy, prefixed by the tier as Calico requires.
+func PolicyName(npName, id string) string {
+	return TierName + "." + npName + "-" + strings.ToLower(id)
+}
+
+// BuildNetPolsFrom builds the Calico NetworkPolicies enforcing the intents of
+// the given NimbusPolicy, one per intent.
+func BuildNetPolsFrom(logger logr.Logger, np v1alpha1.NimbusPolicy, k8sClient client.Client) []calicov3.NetworkPolicy {
+	clusterCIDRs, err := clustercidrs.Discover(context.Background(), k8sClient)
+	if err != nil {
+		logger.Error(err, "Failed to discover some of the cluster CIDRs")
+	}
+	clusterDNS, err := clusterdns.Discover(context.Background(), k8sClient)
+	if err != nil {
+		logger.Error(err, "Failed to discover some of the cluster DNS servers")
+	}
+
+	var netpols []calicov3.NetworkPolicy
+	for _, nimbusRule := range np.Spec.NimbusRules {
+		id := nimbusRule.ID
+		if !idpool.IsIdSupportedBy(id, "calico") {
+			logger.Info("Calico adapter does not support this ID", "ID", id,
+				"NimbusPolicy.Name", np.Name, "NimbusPolicy.Namespace", np.Namespace)
+			continue
+		}
+		action, ok := supportedActions[nimbusRule.Rule.RuleAction]
+		if !ok {
+			logger.Info("Calico adapter does not support this action", "ID", id, "Action", nimbusRule.Rule.RuleAction,
+				"NimbusPolicy.Name", np.Name, "NimbusPolicy.Namespace", np.Namespace)
+			continue
+		}
+
+		cidrs, err := clusterCIDRs.WithParams(nimbusRule.Rule.Params)
+		if err != nil {
+			logger.Error(err, "Ignoring invalid CIDR params", "ID", id, "NimbusPolicy.Name", np.Name, "NimbusPolicy.Namespace", np.Namespace)
+			metrics.TranslationFailed(adapterName, id)
+		}
+		dns, err := clusterDNS.WithParams(nimbusRule.Rule.Params)
+		if err != nil {
+			logger.Error(err, "Ignoring invalid DNS params", "ID", id, "NimbusPolicy.Name", np.Name, "NimbusPolicy.Namespace", np.Namespace)
+			metrics.TranslationFailed(adapterName, id)
+		}
+		rules, ok := rulesFor(id, action, cidrs, dns)
+		if !ok {
+			continue
+		}
+
+		netpol := calicov3.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        PolicyName(np.Name, id),
+				Namespace:   np.Namespace,
+				Annotations: map[string]string{"app.kubernetes.io/managed-by": "nimbus-calico"},
+			},
+			Spec: calicov3.NetworkPolicySpec{
+				Tier:     TierName,
+				Order:    ptr.To(policyOrders[id]),
+				Selector: selectorOf(np.Spec.Selector),
+				Ingress:  rules.ingress,
+				Egress:   rules.egress,
+				Types:    rules.types,
+			},
+		}
+		adapterutil.AddIntents(netpol.Annotations, id)
+		netpols = append(netpols, netpol)
+	}
+	return netpols
+}
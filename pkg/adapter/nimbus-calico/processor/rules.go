@@ -0,0 +1,225 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package processor
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+
+	v1alpha1 "github.com/5GSEC/nimbus/api/v1alpha1"
+	"github.com/5GSEC/nimbus/pkg/adapter/clustercidrs"
+	"github.com/5GSEC/nimbus/pkg/adapter/clusterdns"
+	"github.com/5GSEC/nimbus/pkg/adapter/idpool"
+
+	calicov3 "github.com/5GSEC/nimbus/pkg/adapter/nimbus-calico/api/v3"
+)
+
+// namespaceNameLabel is the label Calico sets on the namespaces with their
+// name.
+const namespaceNameLabel = "projectcalico.org/name"
+
+// policyOrders are the orders of the policies of the intents in the tier. The
+// DNS intent comes first, so that the DNS traffic it denies isn't passed on by
+// the cluster-internal traffic denyExternalNetworkAccess passes.
+var policyOrders = map[string]float64{
+	idpool.DNSManipulation: 40,
+	idpool.DenyENAccess:    50,
+}
+
+// rules are the rules of the policy of an intent.
+type rules struct {
+	ingress []calicov3.Rule
+	egress  []calicov3.Rule
+	types   []calicov3.PolicyType
+}
+
+// rulesFor builds the rules enforcing the given intent ID. The traffic the
+// intent permits is passed on to the next tier, so that namespace owners may
+// still restrict it with their policies, and the traffic it forbids matches
+// rules with the given action.
+func rulesFor(id string, action calicov3.Action, cidrs clustercidrs.CIDRs, dns clusterdns.DNS) (rules, bool) {
+	switch id {
+	case idpool.DNSManipulation:
+		return dnsManipulationRules(action, dns), true
+	case idpool.DenyENAccess:
+		return denyExternalNetworkAccessRules(action, cidrs, dns), true
+	default:
+		return rules{}, false
+	}
+}
+
+// dnsManipulationRules only allow the DNS queries to the DNS servers of the
+// cluster. The other traffic goes on with the next policies.
+func dnsManipulationRules(action calicov3.Action, dns clusterdns.DNS) rules {
+	egress := clusterDNSRules(dns)
+	for _, protocol := range []string{"UDP", "TCP"} {
+		egress = append(egress, calicov3.Rule{
+			Action:      action,
+			Protocol:    protocol,
+			Destination: calicov3.EntityRule{Ports: ports([]int32{clusterdns.Port})},
+		})
+	}
+	return rules{
+		egress: egress,
+		types:  []calicov3.PolicyType{calicov3.PolicyTypeEgress},
+	}
+}
+
+// denyExternalNetworkAccessRules only allow the traffic from and to the
+// networks of the cluster, and the DNS queries to its DNS servers.
+func denyExternalNetworkAccessRules(action calicov3.Action, cidrs clustercidrs.CIDRs, dns clusterdns.DNS) rules {
+	internal := cidrs.Internal()
+
+	var ingress []calicov3.Rule
+	for _, nets := range byIPVersion(internal) {
+		ingress = append(ingress, calicov3.Rule{
+			Action:    calicov3.ActionPass,
+			IPVersion: nets.version,
+			Source:    calicov3.EntityRule{Nets: nets.cidrs},
+		})
+	}
+	ingress = append(ingress, calicov3.Rule{Action: action})
+
+	var egress []calicov3.Rule
+	for _, nets := range byIPVersion(internal) {
+		egress = append(egress, calicov3.Rule{
+			Action:      calicov3.ActionPass,
+			IPVersion:   nets.version,
+			Destination: calicov3.EntityRule{Nets: nets.cidrs},
+		})
+	}
+	egress = append(egress, clusterDNSRules(dns)...)
+	egress = append(egress, calicov3.Rule{Action: action})
+
+	return rules{
+		ingress: ingress,
+		egress:  egress,
+		types:   []calicov3.PolicyType{calicov3.PolicyTypeIngress, calicov3.PolicyTypeEgress},
+	}
+}
+
+// clusterDNSRules pass the DNS queries to the DNS servers of the cluster on
+// to the next tier.
+func clusterDNSRules(dns clusterdns.DNS) []calicov3.Rule {
+	var dnsRules []calicov3.Rule
+	for _, protocol := range []string{"UDP", "TCP"} {
+		if len(dns.Selector) > 0 {
+			dnsRules = append(dnsRules, calicov3.Rule{
+				Action:   calicov3.ActionPass,
+				Protocol: protocol,
+				Destination: calicov3.EntityRule{
+					Selector:          selectorOf(v1alpha1.LabelSelector{MatchLabels: dns.Selector}),
+					NamespaceSelector: fmt.Sprintf("%s == '%s'", namespaceNameLabel, dns.Namespace),
+					Ports:             ports(dns.Ports),
+				},
+			})
+		}
+		for _, nets := range byIPVersion(dns.IPs) {
+			dnsRules = append(dnsRules, calicov3.Rule{
+				Action:    calicov3.ActionPass,
+				IPVersion: nets.version,
+				Protocol:  protocol,
+				Destination: calicov3.EntityRule{
+					Nets:  nets.cidrs,
+					Ports: ports(dns.Ports),
+				},
+			})
+		}
+	}
+	return dnsRules
+}
+
+// ipNets are CIDRs of the same IP version.
+type ipNets struct {
+	version *int
+	cidrs   []string
+}
+
+// byIPVersion groups the given CIDRs by IP version, as Calico rules only match
+// a single one.
+func byIPVersion(cidrs []string) []ipNets {
+	var ipv4, ipv6 []string
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			continue
+		}
+		if prefix.Addr().Is4() {
+			ipv4 = append(ipv4, cidr)
+		} else {
+			ipv6 = append(ipv6, cidr)
+		}
+	}
+
+	var nets []ipNets
+	if len(ipv4) > 0 {
+		nets = append(nets, ipNets{version: ptr.To(4), cidrs: ipv4})
+	}
+	if len(ipv6) > 0 {
+		nets = append(nets, ipNets{version: ptr.To(6), cidrs: ipv6})
+	}
+	return nets
+}
+
+func ports(numbers []int32) []intstr.IntOrString {
+	var ports []intstr.IntOrString
+	for _, number := range numbers {
+		ports = append(ports, intstr.FromInt32(number))
+	}
+	return ports
+}
+
+// selectorOf returns the Calico selector matching all the labels and
+// expressions of the given workload selector, or all the endpoints when there
+// are none.
+func selectorOf(selector v1alpha1.LabelSelector) string {
+	if len(selector.MatchLabels) == 0 && len(selector.MatchExpressions) == 0 {
+		return "all()"
+	}
+
+	var terms []string
+	for key, value := range selector.MatchLabels {
+		terms = append(terms, fmt.Sprintf("%s == '%s'", key, value))
+	}
+	slices.Sort(terms)
+	for _, expression := range selector.MatchExpressions {
+		switch expression.Operator {
+		case metav1.LabelSelectorOpIn:
+			terms = append(terms, inTerm(expression.Key, expression.Values, true))
+		case metav1.LabelSelectorOpNotIn:
+			terms = append(terms, inTerm(expression.Key, expression.Values, false))
+		case metav1.LabelSelectorOpExists:
+			terms = append(terms, fmt.Sprintf("has(%s)", expression.Key))
+		case metav1.LabelSelectorOpDoesNotExist:
+			terms = append(terms, fmt.Sprintf("!has(%s)", expression.Key))
+		}
+	}
+	return strings.Join(terms, " && ")
+}
+
+// inSelector returns the Calico selector matching the namespaces with the given
+// names, or with none of them.
+func inSelector(names []string, in bool) string {
+	return inTerm(namespaceNameLabel, names, in)
+}
+
+// inTerm returns the Calico selector matching the endpoints whose given label
+// has one of the given values, or none of them.
+func inTerm(key string, values []string, in bool) string {
+	operator := "in"
+	if !in {
+		operator = "not in"
+	}
+	quoted := make([]string, 0, len(values))
+	for _, value := range values {
+		quoted = append(quoted, "'"+value+"'")
+	}
+	return fmt.Sprintf("%s %s {%s}", key, operator, strings.Join(quoted, ", "))
+}
@@ -0,0 +1,62 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package processor
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	calicov3 "github.com/5GSEC/nimbus/pkg/adapter/nimbus-calico/api/v3"
+)
+
+const (
+	// TierName is the name of the tier of the policies of the adapter. Its
+	// policies are evaluated before the ones of the namespaces in the default
+	// tier, so that namespace owners can't override them.
+	TierName = "nimbus"
+	// tierOrder puts the tier before the ones of the AdminNetworkPolicies and
+	// BaselineAdminNetworkPolicies, with orders 1000 and 10000000.
+	tierOrder = 100
+	// passOrder puts the pass policy after the policies of the intents.
+	passOrder = 10000
+)
+
+// PassPolicyName is the name of the policy passing the traffic no policy of
+// the intents matched on to the next tier.
+const PassPolicyName = TierName + ".pass"
+
+// BuildTier builds the tier of the policies of the adapter.
+func BuildTier() calicov3.Tier {
+	return calicov3.Tier{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        TierName,
+			Annotations: map[string]string{"app.kubernetes.io/managed-by": "nimbus-calico"},
+		},
+		Spec: calicov3.TierSpec{
+			Order: ptr.To[float64](tierOrder),
+		},
+	}
+}
+
+// BuildPassPolicy builds the last policy of the tier, which passes all the
+// traffic on to the next tier. Calico denies the traffic of the endpoints
+// selected by the policies of a tier that none of its rules match, so without
+// it the policy of an intent would deny all the traffic it doesn't pass
+// itself.
+func BuildPassPolicy() calicov3.GlobalNetworkPolicy {
+	return calicov3.GlobalNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        PassPolicyName,
+			Annotations: map[string]string{"app.kubernetes.io/managed-by": "nimbus-calico"},
+		},
+		Spec: calicov3.GlobalNetworkPolicySpec{
+			Tier:     TierName,
+			Order:    ptr.To[float64](passOrder),
+			Selector: "all()",
+			Ingress:  []calicov3.Rule{{Action: calicov3.ActionPass}},
+			Egress:   []calicov3.Rule{{Action: calicov3.ActionPass}},
+			Types:    []calicov3.PolicyType{calicov3.PolicyTypeIngress, calicov3.PolicyTypeEgress},
+		},
+	}
+}
@@ -0,0 +1,106 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package watcher
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
+)
+
+// WatchGlobalNetpols watches update and delete events for GlobalNetworkPolicies
+// owned by ClusterNimbusPolicy and put the name of their owner on respective
+// channels.
+func WatchGlobalNetpols(ctx context.Context, updatedGnpCh, deletedGnpCh chan string) {
+	logger := log.FromContext(ctx)
+	informer := calicoInformer("globalnetworkpolicies")
+	handlers := cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldU := oldObj.(*unstructured.Unstructured)
+			newU := newObj.(*unstructured.Unstructured)
+
+			if adapterutil.IsOrphan(newU.GetOwnerReferences(), "ClusterNimbusPolicy") {
+				logger.V(4).Info("Ignoring orphan GlobalNetworkPolicy", "GlobalNetworkPolicy.Name", oldU.GetName(), "Operation", "Update")
+				return
+			}
+
+			if specUnchanged(oldU, newU) {
+				return
+			}
+
+			updatedGnpCh <- ownerName(newU, "ClusterNimbusPolicy")
+		},
+		DeleteFunc: func(obj interface{}) {
+			u := obj.(*unstructured.Unstructured)
+			if adapterutil.IsOrphan(u.GetOwnerReferences(), "ClusterNimbusPolicy") {
+				logger.V(4).Info("Ignoring orphan GlobalNetworkPolicy", "GlobalNetworkPolicy.Name", u.GetName(), "Operation", "Delete")
+				return
+			}
+			deletedGnpCh <- ownerName(u, "ClusterNimbusPolicy")
+		},
+	}
+	_, err := informer.AddEventHandler(handlers)
+	if err != nil {
+		logger.Error(err, "failed to add event handlers")
+		return
+	}
+	logger.Info("GlobalNetworkPolicy watcher started")
+	informer.Run(ctx.Done())
+}
+
+// WatchPassPolicy watches update and delete events for the GlobalNetworkPolicy
+// with the given name, which passes the traffic of the tier of the adapter on to
+// the next tier, and signals them on the given channel, whose buffer coalesces
+// the events not handled yet.
+func WatchPassPolicy(ctx context.Context, name string, passPolicyChangedCh chan struct{}) {
+	logger := log.FromContext(ctx)
+	informer := calicoInformer("globalnetworkpolicies")
+
+	signal := func(u *unstructured.Unstructured, operation string) {
+		if u.GetName() != name {
+			return
+		}
+		logger.V(2).Info("Pass GlobalNetworkPolicy changed", "GlobalNetworkPolicy.Name", name, "Operation", operation)
+		select {
+		case passPolicyChangedCh <- struct{}{}:
+		default:
+		}
+	}
+
+	handlers := cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldU := oldObj.(*unstructured.Unstructured)
+			newU := newObj.(*unstructured.Unstructured)
+			if specUnchanged(oldU, newU) {
+				return
+			}
+			signal(newU, "Update")
+		},
+		DeleteFunc: func(obj interface{}) {
+			u, ok := obj.(*unstructured.Unstructured)
+			if !ok {
+				tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+				if !ok {
+					return
+				}
+				if u, ok = tombstone.Obj.(*unstructured.Unstructured); !ok {
+					return
+				}
+			}
+			signal(u, "Delete")
+		},
+	}
+	_, err := informer.AddEventHandler(handlers)
+	if err != nil {
+		logger.Error(err, "failed to add event handlers")
+		return
+	}
+	logger.Info("Pass GlobalNetworkPolicy watcher started")
+	// The informer is shared with WatchGlobalNetpols, and only runs once.
+	informer.Run(ctx.Done())
+}
@@ -0,0 +1,101 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package watcher
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/5GSEC/nimbus/pkg/adapter/common"
+	"github.com/5GSEC/nimbus/pkg/adapter/k8s"
+	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
+)
+
+var (
+	factory dynamicinformer.DynamicSharedInformerFactory
+)
+
+func init() {
+	factory = dynamicinformer.NewDynamicSharedInformerFactory(k8s.NewDynamicClient(), time.Minute)
+}
+
+func calicoInformer(resource string) cache.SharedIndexInformer {
+	calicoGvr := schema.GroupVersionResource{
+		Group:    "projectcalico.org",
+		Version:  "v3",
+		Resource: resource,
+	}
+	informer := factory.ForResource(calicoGvr).Informer()
+	return informer
+}
+
+// WatchNetpols watches update and delete events for Calico NetworkPolicies
+// owned by NimbusPolicy and put the name of their owner on respective
+// channels.
+func WatchNetpols(ctx context.Context, updatedNetpolCh, deletedNetpolCh chan common.Request) {
+	logger := log.FromContext(ctx)
+	informer := calicoInformer("networkpolicies")
+	handlers := cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldU := oldObj.(*unstructured.Unstructured)
+			newU := newObj.(*unstructured.Unstructured)
+
+			if adapterutil.IsOrphan(newU.GetOwnerReferences(), "NimbusPolicy") {
+				logger.V(4).Info("Ignoring orphan Calico NetworkPolicy", "NetworkPolicy.Name", oldU.GetName(), "NetworkPolicy.Namespace", oldU.GetNamespace(), "Operation", "Update")
+				return
+			}
+
+			if specUnchanged(oldU, newU) {
+				return
+			}
+
+			updatedNetpolCh <- common.Request{
+				Name:      ownerName(newU, "NimbusPolicy"),
+				Namespace: newU.GetNamespace(),
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			u := obj.(*unstructured.Unstructured)
+			if adapterutil.IsOrphan(u.GetOwnerReferences(), "NimbusPolicy") {
+				logger.V(4).Info("Ignoring orphan Calico NetworkPolicy", "NetworkPolicy.Name", u.GetName(), "NetworkPolicy.Namespace", u.GetNamespace(), "Operation", "Delete")
+				return
+			}
+			deletedNetpolCh <- common.Request{
+				Name:      ownerName(u, "NimbusPolicy"),
+				Namespace: u.GetNamespace(),
+			}
+		},
+	}
+	_, err := informer.AddEventHandler(handlers)
+	if err != nil {
+		logger.Error(err, "failed to add event handlers")
+		return
+	}
+	logger.Info("Calico NetworkPolicy watcher started")
+	informer.Run(ctx.Done())
+}
+
+// specUnchanged reports whether the spec of a policy is unchanged by an update.
+// The Calico API server doesn't track the generation of its resources, so
+// their specs are compared instead.
+func specUnchanged(oldU, newU *unstructured.Unstructured) bool {
+	return equality.Semantic.DeepEqual(oldU.Object["spec"], newU.Object["spec"])
+}
+
+// ownerName returns the name of the owner of the given kind of a policy.
+func ownerName(u *unstructured.Unstructured, kind string) string {
+	for _, ownerRef := range u.GetOwnerReferences() {
+		if ownerRef.Kind == kind {
+			return ownerRef.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,199 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package v2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Entity selects the peers of a rule by their role in the cluster.
+type Entity string
+
+const (
+	// EntityWorld selects the peers outside the cluster.
+	EntityWorld Entity = "world"
+	// EntityCluster selects the peers inside the cluster, including the
+	// nodes.
+	EntityCluster Entity = "cluster"
+)
+
+// CIDR is an IPv4 or IPv6 CIDR, e.g. "10.0.0.0/8".
+type CIDR string
+
+// L4Proto is the L4 protocol of a port.
+// +kubebuilder:validation:Enum=TCP;UDP;SCTP;ANY
+type L4Proto string
+
+const (
+	ProtoTCP L4Proto = "TCP"
+	ProtoUDP L4Proto = "UDP"
+	ProtoAny L4Proto = "ANY"
+)
+
+// PortProtocol is a port and its protocol.
+type PortProtocol struct {
+	// Port is a port number or name.
+	Port string `json:"port"`
+	// +optional
+	Protocol L4Proto `json:"protocol,omitempty"`
+}
+
+// FQDNSelector matches domain names, either exactly or with a pattern whose
+// "*" matches any characters allowed in a domain name.
+type FQDNSelector struct {
+	// +optional
+	MatchName string `json:"matchName,omitempty"`
+	// +optional
+	MatchPattern string `json:"matchPattern,omitempty"`
+}
+
+// PortRuleHTTP matches HTTP requests. All the given fields must match, the
+// path and method as extended POSIX regular expressions.
+type PortRuleHTTP struct {
+	// +optional
+	Path string `json:"path,omitempty"`
+	// +optional
+	Method string `json:"method,omitempty"`
+	// +optional
+	Host string `json:"host,omitempty"`
+}
+
+// L7Rules restricts the L7 traffic allowed on the ports of a PortRule.
+type L7Rules struct {
+	// +optional
+	HTTP []PortRuleHTTP `json:"http,omitempty"`
+	// DNS restricts the domain names that may be looked up, and makes Cilium
+	// proxy the DNS traffic so that the rules with FQDNs learn their IPs.
+	// +optional
+	DNS []FQDNSelector `json:"dns,omitempty"`
+}
+
+// PortRule selects the ports a rule allows, and their L7 traffic.
+type PortRule struct {
+	// +optional
+	Ports []PortProtocol `json:"ports,omitempty"`
+	// +optional
+	Rules *L7Rules `json:"rules,omitempty"`
+}
+
+// PortDenyRule selects the ports a rule denies.
+type PortDenyRule struct {
+	// +optional
+	Ports []PortProtocol `json:"ports,omitempty"`
+}
+
+// IngressDenyRule denies the ingress traffic from the peers it selects, on
+// the given ports or all of them.
+type IngressDenyRule struct {
+	// +optional
+	FromEntities []Entity `json:"fromEntities,omitempty"`
+	// +optional
+	FromCIDR []CIDR `json:"fromCIDR,omitempty"`
+	// +optional
+	ToPorts []PortDenyRule `json:"toPorts,omitempty"`
+}
+
+// EgressRule allows the egress traffic to the peers it selects, on the given
+// ports or all of them.
+type EgressRule struct {
+	// +optional
+	ToEndpoints []metav1.LabelSelector `json:"toEndpoints,omitempty"`
+	// +optional
+	ToEntities []Entity `json:"toEntities,omitempty"`
+	// +optional
+	ToCIDR []CIDR `json:"toCIDR,omitempty"`
+	// +optional
+	ToFQDNs []FQDNSelector `json:"toFQDNs,omitempty"`
+	// +optional
+	ToPorts []PortRule `json:"toPorts,omitempty"`
+}
+
+// EgressDenyRule denies the egress traffic to the peers it selects, on the
+// given ports or all of them.
+type EgressDenyRule struct {
+	// +optional
+	ToEntities []Entity `json:"toEntities,omitempty"`
+	// +optional
+	ToCIDR []CIDR `json:"toCIDR,omitempty"`
+	// +optional
+	ToPorts []PortDenyRule `json:"toPorts,omitempty"`
+}
+
+// DefaultDenyConfig sets whether the endpoints selected by a rule deny the
+// traffic no rule allows, which they do by default in the directions with
+// rules.
+type DefaultDenyConfig struct {
+	// +optional
+	Ingress *bool `json:"ingress,omitempty"`
+	// +optional
+	Egress *bool `json:"egress,omitempty"`
+}
+
+// Rule selects endpoints and the traffic they allow or deny. Deny rules take
+// precedence over the allow ones.
+type Rule struct {
+	// EndpointSelector selects the endpoints the rule applies to. Their
+	// namespace is matched by the "k8s:io.kubernetes.pod.namespace" label.
+	EndpointSelector metav1.LabelSelector `json:"endpointSelector"`
+
+	// +optional
+	IngressDeny []IngressDenyRule `json:"ingressDeny,omitempty"`
+	// +optional
+	Egress []EgressRule `json:"egress,omitempty"`
+	// +optional
+	EgressDeny []EgressDenyRule `json:"egressDeny,omitempty"`
+
+	// +optional
+	EnableDefaultDeny *DefaultDenyConfig `json:"enableDefaultDeny,omitempty"`
+
+	// +optional
+	Description string `json:"description,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:shortName="cnp"
+
+// CiliumNetworkPolicy is the Schema for the ciliumnetworkpolicies API
+type CiliumNetworkPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec Rule `json:"spec"`
+}
+
+//+kubebuilder:object:root=true
+
+// CiliumNetworkPolicyList contains a list of CiliumNetworkPolicy
+type CiliumNetworkPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CiliumNetworkPolicy `json:"items"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster,shortName="ccnp"
+
+// CiliumClusterwideNetworkPolicy is the Schema for the
+// ciliumclusterwidenetworkpolicies API
+type CiliumClusterwideNetworkPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec Rule `json:"spec"`
+}
+
+//+kubebuilder:object:root=true
+
+// CiliumClusterwideNetworkPolicyList contains a list of
+// CiliumClusterwideNetworkPolicy
+type CiliumClusterwideNetworkPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CiliumClusterwideNetworkPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&CiliumNetworkPolicy{}, &CiliumNetworkPolicyList{},
+		&CiliumClusterwideNetworkPolicy{}, &CiliumClusterwideNetworkPolicyList{})
+}
@@ -0,0 +1,419 @@
+//go:build !ignore_autogenerated
+
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+// Code generated by controller-gen. DO NOT EDIT.
+
+package v2
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CiliumClusterwideNetworkPolicy) DeepCopyInto(out *CiliumClusterwideNetworkPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CiliumClusterwideNetworkPolicy.
+func (in *CiliumClusterwideNetworkPolicy) DeepCopy() *CiliumClusterwideNetworkPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(CiliumClusterwideNetworkPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CiliumClusterwideNetworkPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CiliumClusterwideNetworkPolicyList) DeepCopyInto(out *CiliumClusterwideNetworkPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]CiliumClusterwideNetworkPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CiliumClusterwideNetworkPolicyList.
+func (in *CiliumClusterwideNetworkPolicyList) DeepCopy() *CiliumClusterwideNetworkPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(CiliumClusterwideNetworkPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CiliumClusterwideNetworkPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CiliumNetworkPolicy) DeepCopyInto(out *CiliumNetworkPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CiliumNetworkPolicy.
+func (in *CiliumNetworkPolicy) DeepCopy() *CiliumNetworkPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(CiliumNetworkPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CiliumNetworkPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CiliumNetworkPolicyList) DeepCopyInto(out *CiliumNetworkPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]CiliumNetworkPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CiliumNetworkPolicyList.
+func (in *CiliumNetworkPolicyList) DeepCopy() *CiliumNetworkPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(CiliumNetworkPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CiliumNetworkPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DefaultDenyConfig) DeepCopyInto(out *DefaultDenyConfig) {
+	*out = *in
+	if in.Ingress != nil {
+		in, out := &in.Ingress, &out.Ingress
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Egress != nil {
+		in, out := &in.Egress, &out.Egress
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DefaultDenyConfig.
+func (in *DefaultDenyConfig) DeepCopy() *DefaultDenyConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(DefaultDenyConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EgressDenyRule) DeepCopyInto(out *EgressDenyRule) {
+	*out = *in
+	if in.ToEntities != nil {
+		in, out := &in.ToEntities, &out.ToEntities
+		*out = make([]Entity, len(*in))
+		copy(*out, *in)
+	}
+	if in.ToCIDR != nil {
+		in, out := &in.ToCIDR, &out.ToCIDR
+		*out = make([]CIDR, len(*in))
+		copy(*out, *in)
+	}
+	if in.ToPorts != nil {
+		in, out := &in.ToPorts, &out.ToPorts
+		*out = make([]PortDenyRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EgressDenyRule.
+func (in *EgressDenyRule) DeepCopy() *EgressDenyRule {
+	if in == nil {
+		return nil
+	}
+	out := new(EgressDenyRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EgressRule) DeepCopyInto(out *EgressRule) {
+	*out = *in
+	if in.ToEndpoints != nil {
+		in, out := &in.ToEndpoints, &out.ToEndpoints
+		*out = make([]v1.LabelSelector, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ToEntities != nil {
+		in, out := &in.ToEntities, &out.ToEntities
+		*out = make([]Entity, len(*in))
+		copy(*out, *in)
+	}
+	if in.ToCIDR != nil {
+		in, out := &in.ToCIDR, &out.ToCIDR
+		*out = make([]CIDR, len(*in))
+		copy(*out, *in)
+	}
+	if in.ToFQDNs != nil {
+		in, out := &in.ToFQDNs, &out.ToFQDNs
+		*out = make([]FQDNSelector, len(*in))
+		copy(*out, *in)
+	}
+	if in.ToPorts != nil {
+		in, out := &in.ToPorts, &out.ToPorts
+		*out = make([]PortRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EgressRule.
+func (in *EgressRule) DeepCopy() *EgressRule {
+	if in == nil {
+		return nil
+	}
+	out := new(EgressRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FQDNSelector) DeepCopyInto(out *FQDNSelector) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FQDNSelector.
+func (in *FQDNSelector) DeepCopy() *FQDNSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(FQDNSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IngressDenyRule) DeepCopyInto(out *IngressDenyRule) {
+	*out = *in
+	if in.FromEntities != nil {
+		in, out := &in.FromEntities, &out.FromEntities
+		*out = make([]Entity, len(*in))
+		copy(*out, *in)
+	}
+	if in.FromCIDR != nil {
+		in, out := &in.FromCIDR, &out.FromCIDR
+		*out = make([]CIDR, len(*in))
+		copy(*out, *in)
+	}
+	if in.ToPorts != nil {
+		in, out := &in.ToPorts, &out.ToPorts
+		*out = make([]PortDenyRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressDenyRule.
+func (in *IngressDenyRule) DeepCopy() *IngressDenyRule {
+	if in == nil {
+		return nil
+	}
+	out := new(IngressDenyRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *L7Rules) DeepCopyInto(out *L7Rules) {
+	*out = *in
+	if in.HTTP != nil {
+		in, out := &in.HTTP, &out.HTTP
+		*out = make([]PortRuleHTTP, len(*in))
+		copy(*out, *in)
+	}
+	if in.DNS != nil {
+		in, out := &in.DNS, &out.DNS
+		*out = make([]FQDNSelector, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new L7Rules.
+func (in *L7Rules) DeepCopy() *L7Rules {
+	if in == nil {
+		return nil
+	}
+	out := new(L7Rules)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PortDenyRule) DeepCopyInto(out *PortDenyRule) {
+	*out = *in
+	if in.Ports != nil {
+		in, out := &in.Ports, &out.Ports
+		*out = make([]PortProtocol, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PortDenyRule.
+func (in *PortDenyRule) DeepCopy() *PortDenyRule {
+	if in == nil {
+		return nil
+	}
+	out := new(PortDenyRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PortProtocol) DeepCopyInto(out *PortProtocol) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PortProtocol.
+func (in *PortProtocol) DeepCopy() *PortProtocol {
+	if in == nil {
+		return nil
+	}
+	out := new(PortProtocol)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PortRule) DeepCopyInto(out *PortRule) {
+	*out = *in
+	if in.Ports != nil {
+		in, out := &in.Ports, &out.Ports
+		*out = make([]PortProtocol, len(*in))
+		copy(*out, *in)
+	}
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = new(L7Rules)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PortRule.
+func (in *PortRule) DeepCopy() *PortRule {
+	if in == nil {
+		return nil
+	}
+	out := new(PortRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PortRuleHTTP) DeepCopyInto(out *PortRuleHTTP) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PortRuleHTTP.
+func (in *PortRuleHTTP) DeepCopy() *PortRuleHTTP {
+	if in == nil {
+		return nil
+	}
+	out := new(PortRuleHTTP)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Rule) DeepCopyInto(out *Rule) {
+	*out = *in
+	in.EndpointSelector.DeepCopyInto(&out.EndpointSelector)
+	if in.IngressDeny != nil {
+		in, out := &in.IngressDeny, &out.IngressDeny
+		*out = make([]IngressDenyRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Egress != nil {
+		in, out := &in.Egress, &out.Egress
+		*out = make([]EgressRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.EgressDeny != nil {
+		in, out := &in.EgressDeny, &out.EgressDeny
+		*out = make([]EgressDenyRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.EnableDefaultDeny != nil {
+		in, out := &in.EnableDefaultDeny, &out.EnableDefaultDeny
+		*out = new(DefaultDenyConfig)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Rule.
+func (in *Rule) DeepCopy() *Rule {
+	if in == nil {
+		return nil
+	}
+	out := new(Rule)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,69 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package manager
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/5GSEC/nimbus/api/v1alpha1"
+	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
+
+	ciliumv2 "github.com/5GSEC/nimbus/pkg/adapter/nimbus-cilium/api/v2"
+	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-cilium/processor"
+)
+
+func reconcileCcnp(ctx context.Context, cwnpName string, deleted bool) {
+	logger := log.FromContext(ctx)
+	if cwnpName == "" {
+		return
+	}
+	if deleted {
+		logger.V(2).Info("Reconciling deleted CiliumClusterwideNetworkPolicy", "ClusterNimbusPolicy.Name", cwnpName)
+	} else {
+		logger.V(2).Info("Reconciling modified CiliumClusterwideNetworkPolicy", "ClusterNimbusPolicy.Name", cwnpName)
+	}
+	createOrUpdateCcnp(ctx, cwnpName)
+}
+
+func createOrUpdateCcnp(ctx context.Context, cwnpName string) {
+	adapterutil.ReconcileClusterPolicies(ctx, k8sClient, scheme, recorder, adapterName, "cilium", cwnpName, adapterutil.ClusterPolicies[ciliumv2.CiliumClusterwideNetworkPolicy]{
+		Kind: "CiliumClusterwideNetworkPolicy",
+		Build: func(ctx context.Context, cwnp *v1alpha1.ClusterNimbusPolicy) []ciliumv2.CiliumClusterwideNetworkPolicy {
+			return processor.BuildCcnpsFrom(log.FromContext(ctx), *cwnp, k8sClient)
+		},
+		List: func(ctx context.Context) ([]ciliumv2.CiliumClusterwideNetworkPolicy, error) {
+			var ccnps ciliumv2.CiliumClusterwideNetworkPolicyList
+			err := k8sClient.List(ctx, &ccnps)
+			return ccnps.Items, err
+		},
+	})
+}
+
+func logCcnpToDelete(ctx context.Context, deletedCwnp *unstructured.Unstructured) {
+	logger := log.FromContext(ctx)
+	var ccnps ciliumv2.CiliumClusterwideNetworkPolicyList
+
+	if err := k8sClient.List(ctx, &ccnps); err != nil {
+		logger.Error(err, "failed to list CiliumClusterwideNetworkPolicies")
+		return
+	}
+
+	// Kubernetes GC automatically deletes the child when the parent/owner is
+	// deleted. So, we don't need to delete the policy because ClusterNimbusPolicy
+	// is the owner and when it gets deleted all the corresponding policies will be
+	// automatically deleted.
+	for _, ccnp := range ccnps.Items {
+		for _, ownerRef := range ccnp.OwnerReferences {
+			if ownerRef.Name == deletedCwnp.GetName() && ownerRef.UID == deletedCwnp.GetUID() {
+				logger.Info("CiliumClusterwideNetworkPolicy already deleted due to ClusterNimbusPolicy deletion",
+					"CiliumClusterwideNetworkPolicy.Name", ccnp.Name, "ClusterNimbusPolicy.Name", deletedCwnp.GetName(),
+				)
+				break
+			}
+		}
+	}
+}
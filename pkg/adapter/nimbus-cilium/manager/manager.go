@@ -0,0 +1,288 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package manager
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/5GSEC/nimbus/api/v1alpha1"
+	"github.com/5GSEC/nimbus/pkg/adapter/common"
+	"github.com/5GSEC/nimbus/pkg/adapter/k8s"
+	"github.com/5GSEC/nimbus/pkg/adapter/metrics"
+	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
+	globalwatcher "github.com/5GSEC/nimbus/pkg/adapter/watcher"
+	"github.com/5GSEC/nimbus/pkg/tracing"
+
+	ciliumv2 "github.com/5GSEC/nimbus/pkg/adapter/nimbus-cilium/api/v2"
+	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-cilium/processor"
+	ciliumwatcher "github.com/5GSEC/nimbus/pkg/adapter/nimbus-cilium/watcher"
+)
+
+const adapterName = "nimbus-cilium"
+
+var (
+	scheme    = runtime.NewScheme()
+	k8sClient client.Client
+	recorder  record.EventRecorder
+)
+
+func init() {
+	utilruntime.Must(v1alpha1.AddToScheme(scheme))
+	utilruntime.Must(ciliumv2.AddToScheme(scheme))
+	utilruntime.Must(corev1.AddToScheme(scheme))
+	utilruntime.Must(appsv1.AddToScheme(scheme))
+	utilruntime.Must(discoveryv1.AddToScheme(scheme))
+	k8sClient = k8s.NewOrDie(scheme)
+	recorder = k8s.NewEventRecorderOrDie(scheme, adapterName)
+}
+
+func Run(ctx context.Context) {
+	npCh := make(chan common.Request)
+	deletedNpCh := make(chan *unstructured.Unstructured)
+	go globalwatcher.WatchNimbusPolicies(ctx, npCh, deletedNpCh, "SecurityIntentBinding", "ClusterSecurityIntentBinding")
+
+	clusterNpCh := make(chan string)
+	deletedClusterNpCh := make(chan *unstructured.Unstructured)
+	go globalwatcher.WatchClusterNimbusPolicies(ctx, clusterNpCh, deletedClusterNpCh)
+
+	updatedCnpCh := make(chan common.Request)
+	deletedCnpCh := make(chan common.Request)
+	go ciliumwatcher.WatchCnps(ctx, updatedCnpCh, deletedCnpCh)
+
+	updatedCcnpCh := make(chan string)
+	deletedCcnpCh := make(chan string)
+	go ciliumwatcher.WatchCcnps(ctx, updatedCcnpCh, deletedCcnpCh)
+
+	for {
+		select {
+		case <-ctx.Done():
+			close(npCh)
+			close(deletedNpCh)
+			close(clusterNpCh)
+			close(deletedClusterNpCh)
+			close(updatedCnpCh)
+			close(deletedCnpCh)
+			close(updatedCcnpCh)
+			close(deletedCcnpCh)
+			return
+		case createdNp := <-npCh:
+			createOrUpdateCnp(ctx, createdNp.Name, createdNp.Namespace)
+		case createdCwnp := <-clusterNpCh:
+			createOrUpdateCcnp(ctx, createdCwnp)
+		case deletedNp := <-deletedNpCh:
+			logCnpToDelete(ctx, deletedNp)
+		case deletedCwnp := <-deletedClusterNpCh:
+			logCcnpToDelete(ctx, deletedCwnp)
+		case updatedCnp := <-updatedCnpCh:
+			reconcileCnp(ctx, updatedCnp.Name, updatedCnp.Namespace, false)
+		case deletedCnp := <-deletedCnpCh:
+			reconcileCnp(ctx, deletedCnp.Name, deletedCnp.Namespace, true)
+		case updatedCcnp := <-updatedCcnpCh:
+			reconcileCcnp(ctx, updatedCcnp, false)
+		case deletedCcnp := <-deletedCcnpCh:
+			reconcileCcnp(ctx, deletedCcnp, true)
+		}
+	}
+}
+
+func reconcileCnp(ctx context.Context, cnpName, namespace string, deleted bool) {
+	logger := log.FromContext(ctx)
+	npName := adapterutil.ExtractAnyNimbusPolicyName(cnpName)
+	var np v1alpha1.NimbusPolicy
+	err := k8sClient.Get(ctx, types.NamespacedName{Name: npName, Namespace: namespace}, &np)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			logger.Error(err, "failed to get NimbusPolicy", "NimbusPolicy.Name", npName, "NimbusPolicy.Namespace", namespace)
+		}
+		return
+	}
+	if deleted {
+		logger.V(2).Info("Reconciling deleted CiliumNetworkPolicy", "CiliumNetworkPolicy.Name", cnpName, "CiliumNetworkPolicy.Namespace", namespace)
+	} else {
+		logger.V(2).Info("Reconciling modified CiliumNetworkPolicy", "CiliumNetworkPolicy.Name", cnpName, "CiliumNetworkPolicy.Namespace", namespace)
+	}
+	createOrUpdateCnp(ctx, npName, namespace)
+}
+
+func createOrUpdateCnp(ctx context.Context, npName, npNamespace string) {
+	defer metrics.ObserveReconcile(adapterName, time.Now())
+	logger := log.FromContext(ctx)
+	var np v1alpha1.NimbusPolicy
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: npName, Namespace: npNamespace}, &np); err != nil {
+		if !errors.IsNotFound(err) {
+			logger.Error(err, "failed to get NimbusPolicy", "NimbusPolicy.Name", npName, "NimbusPolicy.Namespace", npNamespace)
+		}
+		return
+	}
+
+	if adapterutil.IsOrphan(np.GetOwnerReferences(), "SecurityIntentBinding", "ClusterSecurityIntentBinding") {
+		logger.V(4).Info("Ignoring orphan NimbusPolicy", "NimbusPolicy.Name", npName, "NimbusPolicy.Namespace", npNamespace)
+		return
+	}
+
+	// The ClusterSecurityIntentBindings are enforced by
+	// CiliumClusterwideNetworkPolicies built from their ClusterNimbusPolicy, so
+	// only drop the CiliumNetworkPolicies built from the NimbusPolicies generated
+	// for them.
+	if adapterutil.IsOrphan(np.GetOwnerReferences(), "SecurityIntentBinding") {
+		logger.V(4).Info("Ignoring NimbusPolicy of a ClusterSecurityIntentBinding", "NimbusPolicy.Name", npName, "NimbusPolicy.Namespace", npNamespace)
+		np.Spec.NimbusRules = nil
+		deleteDanglingCnps(ctx, np, logger)
+		return
+	}
+
+	// Continue the trace of the reconciliation that changed the NimbusPolicy.
+	ctx, span := tracing.Start(tracing.Extract(ctx, &np), adapterName+".Reconcile", tracing.WithObject("NimbusPolicy", &np))
+	defer span.End()
+
+	deleteDanglingCnps(ctx, np, logger)
+	adapterutil.RecordUnsupportedIntents(recorder, &np, np.Spec.NimbusRules, "cilium")
+	adapterutil.RecordUnsupportedActions(recorder, &np, np.Spec.NimbusRules, "cilium")
+	if err := adapterutil.UpdateUnsupportedActions(ctx, k8sClient, "NimbusPolicy", np.Name, np.Namespace, "cilium", np.Spec.NimbusRules); err != nil {
+		logger.Error(err, "failed to update unsupported actions status in NimbusPolicy", "NimbusPolicy.Name", np.Name, "NimbusPolicy.Namespace", np.Namespace)
+	}
+	metrics.UnsupportedIntents(adapterName, &np, np.Spec.NimbusRules, "cilium")
+	_, translateSpan := tracing.Start(ctx, "CiliumNetworkPolicy.Translate")
+	cnps := processor.BuildCnpsFrom(logger, np, k8sClient)
+	translateSpan.End()
+	// Iterate using a separate index variable to avoid aliasing
+	for idx := range cnps {
+		cnp := cnps[idx]
+
+		// Set NimbusPolicy as the owner of the CNP
+		if err := ctrl.SetControllerReference(&np, &cnp, scheme); err != nil {
+			logger.Error(err, "failed to set OwnerReference on CiliumNetworkPolicy", "CiliumNetworkPolicy.Name", cnp.Name, "CiliumNetworkPolicy.Namespace", cnp.Namespace)
+			return
+		}
+
+		var existingCnp ciliumv2.CiliumNetworkPolicy
+		err := k8sClient.Get(ctx, types.NamespacedName{Name: cnp.Name, Namespace: cnp.Namespace}, &existingCnp)
+		if err != nil && !errors.IsNotFound(err) {
+			logger.Error(err, "failed to get existing CiliumNetworkPolicy", "CiliumNetworkPolicy.Name", cnp.Name, "CiliumNetworkPolicy.Namespace", cnp.Namespace)
+			return
+		}
+		if err != nil {
+			if err = tracing.Trace(ctx, "CiliumNetworkPolicy.Create", func(ctx context.Context) error {
+				return k8sClient.Create(ctx, &cnp)
+			}, tracing.WithObject("CiliumNetworkPolicy", &cnp)); err != nil {
+				logger.Error(err, "failed to create CiliumNetworkPolicy", "CiliumNetworkPolicy.Name", cnp.Name, "CiliumNetworkPolicy.Namespace", cnp.Namespace)
+				adapterutil.RecordPolicyFailed(recorder, &np, "CiliumNetworkPolicy/"+cnp.Name, err)
+				metrics.PolicyFailed(adapterName, "CiliumNetworkPolicy")
+				return
+			}
+			logger.Info("CiliumNetworkPolicy created", "CiliumNetworkPolicy.Name", cnp.Name, "CiliumNetworkPolicy.Namespace", cnp.Namespace)
+			adapterutil.RecordPolicyCreated(recorder, &np, "CiliumNetworkPolicy/"+cnp.Name)
+			metrics.PolicyGenerated(adapterName, "CiliumNetworkPolicy", &cnp)
+		} else {
+			cnp.ObjectMeta.ResourceVersion = existingCnp.ObjectMeta.ResourceVersion
+			if err = tracing.Trace(ctx, "CiliumNetworkPolicy.Update", func(ctx context.Context) error {
+				return k8sClient.Update(ctx, &cnp)
+			}, tracing.WithObject("CiliumNetworkPolicy", &cnp)); err != nil {
+				logger.Error(err, "failed to configure existing CiliumNetworkPolicy", "CiliumNetworkPolicy.Name", cnp.Name, "CiliumNetworkPolicy.Namespace", cnp.Namespace)
+				adapterutil.RecordPolicyFailed(recorder, &np, "CiliumNetworkPolicy/"+cnp.Name, err)
+				metrics.PolicyFailed(adapterName, "CiliumNetworkPolicy")
+				return
+			}
+			logger.Info("CiliumNetworkPolicy configured", "CiliumNetworkPolicy.Name", cnp.Name, "CiliumNetworkPolicy.Namespace", cnp.Namespace)
+			if cnp.GetGeneration() != existingCnp.GetGeneration() {
+				adapterutil.RecordPolicyConfigured(recorder, &np, "CiliumNetworkPolicy/"+cnp.Name)
+				metrics.PolicyGenerated(adapterName, "CiliumNetworkPolicy", &cnp)
+			}
+		}
+
+		if err = adapterutil.UpdateNpStatus(ctx, k8sClient, "CiliumNetworkPolicy/"+cnp.Name, np.Name, np.Namespace, false); err != nil {
+			logger.Error(err, "failed to update CiliumNetworkPolicies status in NimbusPolicy")
+		}
+	}
+}
+
+func logCnpToDelete(ctx context.Context, deletedNp *unstructured.Unstructured) {
+	logger := log.FromContext(ctx)
+
+	var cnps ciliumv2.CiliumNetworkPolicyList
+	if err := k8sClient.List(ctx, &cnps, &client.ListOptions{Namespace: deletedNp.GetNamespace()}); err != nil {
+		logger.Error(err, "failed to list CiliumNetworkPolicies")
+		return
+	}
+
+	// Kubernetes GC automatically deletes the child when the parent/owner is
+	// deleted. So, we don't need to delete the policy because NimbusPolicy is the
+	// owner and when it gets deleted all the corresponding policies will be
+	// automatically deleted.
+	for _, cnp := range cnps.Items {
+		for _, ownerRef := range cnp.OwnerReferences {
+			if ownerRef.Name == deletedNp.GetName() && ownerRef.UID == deletedNp.GetUID() {
+				logger.Info("CiliumNetworkPolicy already deleted due to NimbusPolicy deletion",
+					"CiliumNetworkPolicy.Name", cnp.Name, "CiliumNetworkPolicy.Namespace", cnp.Namespace,
+					"NimbusPolicy.Name", deletedNp.GetName(), "NimbusPolicy.Namespace", deletedNp.GetNamespace(),
+				)
+				break
+			}
+		}
+	}
+}
+
+func deleteDanglingCnps(ctx context.Context, np v1alpha1.NimbusPolicy, logger logr.Logger) {
+	var existingCnps ciliumv2.CiliumNetworkPolicyList
+	if err := k8sClient.List(ctx, &existingCnps, client.InNamespace(np.Namespace)); err != nil {
+		logger.Error(err, "failed to list CiliumNetworkPolicies for cleanup")
+		return
+	}
+
+	var cnpsOwnedByNp []ciliumv2.CiliumNetworkPolicy
+	for _, cnp := range existingCnps.Items {
+		for _, ownerRef := range cnp.OwnerReferences {
+			if ownerRef.Name == np.Name && ownerRef.UID == np.UID {
+				cnpsOwnedByNp = append(cnpsOwnedByNp, cnp)
+				break
+			}
+		}
+	}
+
+	if len(cnpsOwnedByNp) == 0 {
+		return
+	}
+
+	cnpsToDelete := make(map[string]ciliumv2.CiliumNetworkPolicy)
+
+	// Populate owned CNPs
+	for _, cnpOwnedByNp := range cnpsOwnedByNp {
+		cnpsToDelete[cnpOwnedByNp.Name] = cnpOwnedByNp
+	}
+
+	for _, nimbusRule := range np.Spec.NimbusRules {
+		cnpName := np.Name + "-" + strings.ToLower(nimbusRule.ID)
+		delete(cnpsToDelete, cnpName)
+	}
+
+	for cnpName := range cnpsToDelete {
+		cnp := cnpsToDelete[cnpName]
+		if err := k8sClient.Delete(ctx, &cnp); err != nil {
+			logger.Error(err, "failed to delete dangling CiliumNetworkPolicy", "CiliumNetworkPolicy.Name", cnp.Name, "CiliumNetworkPolicy.Namespace", cnp.Namespace)
+			continue
+		}
+
+		if err := adapterutil.UpdateNpStatus(ctx, k8sClient, "CiliumNetworkPolicy/"+cnp.Name, np.Name, np.Namespace, true); err != nil {
+			logger.Error(err, "failed to update CiliumNetworkPolicy status in NimbusPolicy")
+		}
+		logger.Info("Dangling CiliumNetworkPolicy deleted", "CiliumNetworkPolicy.Name", cnp.Name, "CiliumNetworkPolicy.Namespace", cnp.Namespace)
+		adapterutil.RecordDanglingPolicyDeleted(recorder, &np, "CiliumNetworkPolicy/"+cnp.Name)
+	}
+}
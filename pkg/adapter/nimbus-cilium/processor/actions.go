@@ -0,0 +1,22 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package processor
+
+import (
+	"github.com/5GSEC/nimbus/pkg/adapter/idpool"
+)
+
+// actionSupported reports whether Cilium policies can enforce the given ID with
+// the given Nimbus action, as listed in the idpool.
+//
+//	| Nimbus | Cilium                                   |
+//	|--------|------------------------------------------|
+//	| Block  | traffic denied or not allowed is dropped |
+//
+// Cilium only audits the traffic its policies would drop in the policy audit
+// mode of whole endpoints, not per policy, so Audit, Warn, Allow and Mutate
+// aren't supported.
+func actionSupported(id, action string) bool {
+	return idpool.IsActionSupportedBy(id, action, "cilium")
+}
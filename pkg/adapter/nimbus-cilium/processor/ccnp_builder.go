@@ -0,0 +1,122 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package processor
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1alpha1 "github.com/5GSEC/nimbus/api/v1alpha1"
+	"github.com/5GSEC/nimbus/pkg/adapter/clusterdns"
+	"github.com/5GSEC/nimbus/pkg/adapter/idpool"
+	"github.com/5GSEC/nimbus/pkg/adapter/metrics"
+	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
+
+	ciliumv2 "github.com/5GSEC/nimbus/pkg/adapter/nimbus-cilium/api/v2"
+)
+
+// nsBlackList lists the namespaces never selected by the
+// CiliumClusterwideNetworkPolicies, as the controller never generates
+// NimbusPolicies for them either.
+var nsBlackList = []string{"kube-system"}
+
+// BuildCcnpsFrom builds the CiliumClusterwideNetworkPolicies enforcing the
+// intents of the given ClusterNimbusPolicy in the namespaces it selects, one
+// per intent.
+func BuildCcnpsFrom(logger logr.Logger, cwnp v1alpha1.ClusterNimbusPolicy, k8sClient client.Client) []ciliumv2.CiliumClusterwideNetworkPolicy {
+	clusterDNS, err := clusterdns.Discover(context.Background(), k8sClient)
+	if err != nil {
+		logger.Error(err, "Failed to discover some of the cluster DNS servers")
+	}
+
+	var ccnps []ciliumv2.CiliumClusterwideNetworkPolicy
+	for _, nimbusRule := range cwnp.Spec.NimbusRules {
+		id := nimbusRule.ID
+		if !idpool.IsIdSupportedBy(id, "cilium") {
+			logger.Info("Cilium adapter does not support this ID", "ID", id, "ClusterNimbusPolicy.Name", cwnp.Name)
+			continue
+		}
+		if !actionSupported(id, nimbusRule.Rule.RuleAction) {
+			logger.Info("Cilium adapter does not support this action", "ID", id, "Action", nimbusRule.Rule.RuleAction,
+				"ClusterNimbusPolicy.Name", cwnp.Name)
+			continue
+		}
+
+		// Leave the intent to the conflicting NimbusPolicies taking precedence
+		// in some of the namespaces.
+		spec := cwnp.Spec
+		var ok bool
+		spec.NsSelector, ok = adapterutil.RuleNsSelector(cwnp.Spec, id)
+		if !ok {
+			logger.Info("Conflicting NimbusPolicies take precedence over the intent in all the selected namespaces",
+				"ID", id, "ClusterNimbusPolicy.Name", cwnp.Name)
+			continue
+		}
+
+		dns, err := clusterDNS.WithParams(nimbusRule.Rule.Params)
+		if err != nil {
+			logger.Error(err, "Ignoring invalid DNS params", "ID", id, "ClusterNimbusPolicy.Name", cwnp.Name)
+			metrics.TranslationFailed(adapterName, id)
+		}
+		rule, ok, err := buildRuleFor(id, nimbusRule.Rule.Params, dns)
+		if err != nil {
+			logger.Error(err, "Ignoring invalid params", "ID", id, "ClusterNimbusPolicy.Name", cwnp.Name)
+			metrics.TranslationFailed(adapterName, id)
+		}
+		if !ok {
+			continue
+		}
+
+		endpointSelector := endpointSelectorFor(spec)
+		rule.EndpointSelector = *endpointSelector.DeepCopy()
+		rule.Description = nimbusRule.Description
+		ccnp := ciliumv2.CiliumClusterwideNetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        cwnp.Name + "-" + strings.ToLower(id),
+				Annotations: map[string]string{"app.kubernetes.io/managed-by": "nimbus-cilium"},
+			},
+			Spec: rule,
+		}
+		adapterutil.AddIntents(ccnp.Annotations, id)
+		ccnps = append(ccnps, ccnp)
+	}
+	return ccnps
+}
+
+// endpointSelectorFor translates the namespace and workload selectors of a
+// ClusterNimbusPolicy into the endpoint selector of a
+// CiliumClusterwideNetworkPolicy:
+//
+//   - MatchNames: ["*"] selects all the namespaces but the blacklisted ones.
+//   - MatchNames selects the listed namespaces.
+//   - ExcludeNames selects all the namespaces but the listed and the
+//     blacklisted ones.
+//   - The MatchLabels of the workload selector select the pods having all of
+//     them in these namespaces.
+func endpointSelectorFor(spec v1alpha1.ClusterNimbusPolicySpec) metav1.LabelSelector {
+	requirement := metav1.LabelSelectorRequirement{
+		Key:      namespaceLabel,
+		Operator: metav1.LabelSelectorOpNotIn,
+		Values:   slices.Clone(nsBlackList),
+	}
+
+	matchNames, excludeNames := spec.NsSelector.MatchNames, spec.NsSelector.ExcludeNames
+	switch {
+	case len(excludeNames) > 0:
+		requirement.Values = append(requirement.Values, excludeNames...)
+	case len(matchNames) > 0 && !(len(matchNames) == 1 && matchNames[0] == "*"):
+		requirement.Operator = metav1.LabelSelectorOpIn
+		requirement.Values = slices.DeleteFunc(slices.Clone(matchNames), func(ns string) bool { return slices.Contains(nsBlackList, ns) })
+	}
+
+	return metav1.LabelSelector{
+		MatchLabels:      spec.WorkloadSelector.MatchLabels,
+		MatchExpressions: append([]metav1.LabelSelectorRequirement{requirement}, spec.WorkloadSelector.MatchExpressions...),
+	}
+}
@@ -0,0 +1,79 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package processor
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1alpha1 "github.com/5GSEC/nimbus/api/v1alpha1"
+	"github.com/5GSEC/nimbus/pkg/adapter/clusterdns"
+	"github.com/5GSEC/nimbus/pkg/adapter/idpool"
+	"github.com/5GSEC/nimbus/pkg/adapter/metrics"
+	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
+
+	ciliumv2 "github.com/5GSEC/nimbus/pkg/adapter/nimbus-cilium/api/v2"
+)
+
+// adapterName labels the metrics of the adapter.
+const adapterName = "nimbus-cilium"
+
+// BuildCnpsFrom builds the CiliumNetworkPolicies enforcing the intents of the
+// given NimbusPolicy, one per intent.
+func BuildCnpsFrom(logger logr.Logger, np v1alpha1.NimbusPolicy, k8sClient client.Client) []ciliumv2.CiliumNetworkPolicy {
+	clusterDNS, err := clusterdns.Discover(context.Background(), k8sClient)
+	if err != nil {
+		logger.Error(err, "Failed to discover some of the cluster DNS servers")
+	}
+
+	var cnps []ciliumv2.CiliumNetworkPolicy
+	for _, nimbusRule := range np.Spec.NimbusRules {
+		id := nimbusRule.ID
+		if !idpool.IsIdSupportedBy(id, "cilium") {
+			logger.Info("Cilium adapter does not support this ID", "ID", id,
+				"NimbusPolicy.Name", np.Name, "NimbusPolicy.Namespace", np.Namespace)
+			continue
+		}
+		if !actionSupported(id, nimbusRule.Rule.RuleAction) {
+			logger.Info("Cilium adapter does not support this action", "ID", id, "Action", nimbusRule.Rule.RuleAction,
+				"NimbusPolicy.Name", np.Name, "NimbusPolicy.Namespace", np.Namespace)
+			continue
+		}
+
+		dns, err := clusterDNS.WithParams(nimbusRule.Rule.Params)
+		if err != nil {
+			logger.Error(err, "Ignoring invalid DNS params", "ID", id, "NimbusPolicy.Name", np.Name, "NimbusPolicy.Namespace", np.Namespace)
+			metrics.TranslationFailed(adapterName, id)
+		}
+		rule, ok, err := buildRuleFor(id, nimbusRule.Rule.Params, dns)
+		if err != nil {
+			logger.Error(err, "Ignoring invalid params", "ID", id, "NimbusPolicy.Name", np.Name, "NimbusPolicy.Namespace", np.Namespace)
+			metrics.TranslationFailed(adapterName, id)
+		}
+		if !ok {
+			continue
+		}
+
+		rule.EndpointSelector = metav1.LabelSelector{
+			MatchLabels:      np.Spec.Selector.MatchLabels,
+			MatchExpressions: np.Spec.Selector.MatchExpressions,
+		}
+		rule.Description = nimbusRule.Description
+		cnp := ciliumv2.CiliumNetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        np.Name + "-" + strings.ToLower(id),
+				Namespace:   np.Namespace,
+				Annotations: map[string]string{"app.kubernetes.io/managed-by": "nimbus-cilium"},
+			},
+			Spec: rule,
+		}
+		adapterutil.AddIntents(cnp.Annotations, id)
+		cnps = append(cnps, cnp)
+	}
+	return cnps
+}
@@ -0,0 +1,259 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	"github.com/5GSEC/nimbus/pkg/adapter/clusterdns"
+	"github.com/5GSEC/nimbus/pkg/adapter/idpool"
+
+	ciliumv2 "github.com/5GSEC/nimbus/pkg/adapter/nimbus-cilium/api/v2"
+)
+
+// Params of egressAllowList.
+const (
+	// paramFQDNs lists the domain names the workloads may connect to, where
+	// "*" matches any characters allowed in a domain name, e.g.
+	// "*.github.com".
+	paramFQDNs = "fqdns"
+	// paramPorts lists the ports allowed to these domains, as "443" or
+	// "8443/TCP". It defaults to 80 and 443 over TCP.
+	paramPorts = "ports"
+	// paramHTTPMethods restricts the HTTP requests to these domains on the TCP
+	// ports to the given methods, e.g. "GET". Cilium can only inspect plain
+	// HTTP, so these ports must not carry TLS.
+	paramHTTPMethods = "httpMethods"
+	// paramHTTPPaths restricts the HTTP requests to these domains to the
+	// paths matching the given regular expressions, e.g. "/api/.*".
+	paramHTTPPaths = "httpPaths"
+)
+
+// namespaceLabel is the label of the endpoints Cilium matches against their
+// namespace.
+const namespaceLabel = "k8s:io.kubernetes.pod.namespace"
+
+// defaultPorts are the ports egressAllowList allows by default.
+var defaultPorts = []ciliumv2.PortProtocol{
+	{Port: "80", Protocol: ciliumv2.ProtoTCP},
+	{Port: "443", Protocol: ciliumv2.ProtoTCP},
+}
+
+// errNoFQDNs is returned when egressAllowList has no valid domain names to
+// allow, in which case it would deny all the egress traffic to the world.
+var errNoFQDNs = errors.New("egressAllowList requires domain names in the fqdns param")
+
+// buildRuleFor builds the rule enforcing the given intent ID with its params.
+// The errors of invalid param values are returned along with the rule built
+// without them, unless it can't be built at all.
+func buildRuleFor(id string, params map[string][]string, dns clusterdns.DNS) (ciliumv2.Rule, bool, error) {
+	switch id {
+	case idpool.DNSManipulation:
+		return dnsManipulationRule(dns), true, nil
+	case idpool.DenyENAccess:
+		return denyExternalNetworkAccessRule(), true, nil
+	case idpool.EgressAllowList:
+		return egressAllowListRule(params, dns)
+	default:
+		return ciliumv2.Rule{}, false, nil
+	}
+}
+
+// dnsManipulationRule only allows the workloads to query the DNS servers of
+// the cluster, through the DNS proxy of Cilium, by denying DNS traffic to the
+// world. The other traffic isn't affected.
+func dnsManipulationRule(dns clusterdns.DNS) ciliumv2.Rule {
+	return ciliumv2.Rule{
+		Egress: clusterDNSRules(dns),
+		EgressDeny: []ciliumv2.EgressDenyRule{
+			{
+				ToEntities: []ciliumv2.Entity{ciliumv2.EntityWorld},
+				ToPorts:    []ciliumv2.PortDenyRule{{Ports: dnsPorts([]int32{clusterdns.Port})}},
+			},
+		},
+		EnableDefaultDeny: noDefaultDeny(),
+	}
+}
+
+// denyExternalNetworkAccessRule denies the traffic between the workloads and
+// the world. The traffic within the cluster isn't affected.
+func denyExternalNetworkAccessRule() ciliumv2.Rule {
+	return ciliumv2.Rule{
+		IngressDeny: []ciliumv2.IngressDenyRule{
+			{FromEntities: []ciliumv2.Entity{ciliumv2.EntityWorld}},
+		},
+		EgressDeny: []ciliumv2.EgressDenyRule{
+			{ToEntities: []ciliumv2.Entity{ciliumv2.EntityWorld}},
+		},
+		EnableDefaultDeny: noDefaultDeny(),
+	}
+}
+
+// egressAllowListRule only allows the egress traffic of the workloads to the
+// cluster and to the domain names of the fqdns param, on the ports and with
+// the HTTP requests of the other params. The DNS queries go through the DNS
+// proxy of Cilium, which learns the IPs of the domain names from them.
+func egressAllowListRule(params map[string][]string, dns clusterdns.DNS) (ciliumv2.Rule, bool, error) {
+	var errs []error
+
+	fqdns := fqdnSelectors(params[paramFQDNs])
+	if len(fqdns) == 0 {
+		return ciliumv2.Rule{}, false, errNoFQDNs
+	}
+
+	ports := defaultPorts
+	if len(params[paramPorts]) > 0 {
+		ports = portProtocols(params[paramPorts], &errs)
+	}
+	if len(ports) == 0 {
+		return ciliumv2.Rule{}, false, errors.Join(append(errs, errors.New("egressAllowList has no valid ports"))...)
+	}
+
+	fqdnPorts := []ciliumv2.PortRule{{Ports: ports}}
+	if http := httpRules(params[paramHTTPMethods], params[paramHTTPPaths]); len(http) > 0 {
+		// Cilium only inspects HTTP over TCP, the other ports are allowed as is.
+		tcpPorts := slices.DeleteFunc(slices.Clone(ports), func(port ciliumv2.PortProtocol) bool { return port.Protocol != ciliumv2.ProtoTCP })
+		otherPorts := slices.DeleteFunc(slices.Clone(ports), func(port ciliumv2.PortProtocol) bool { return port.Protocol == ciliumv2.ProtoTCP })
+		fqdnPorts = nil
+		if len(tcpPorts) > 0 {
+			fqdnPorts = append(fqdnPorts, ciliumv2.PortRule{Ports: tcpPorts, Rules: &ciliumv2.L7Rules{HTTP: http}})
+		}
+		if len(otherPorts) > 0 {
+			fqdnPorts = append(fqdnPorts, ciliumv2.PortRule{Ports: otherPorts})
+		}
+	}
+
+	egress := clusterDNSRules(dns)
+	egress = append(egress,
+		ciliumv2.EgressRule{ToEntities: []ciliumv2.Entity{ciliumv2.EntityCluster}},
+		ciliumv2.EgressRule{ToFQDNs: fqdns, ToPorts: fqdnPorts},
+	)
+	return ciliumv2.Rule{Egress: egress}, true, errors.Join(errs...)
+}
+
+// clusterDNSRules allow the DNS queries to the DNS servers of the cluster,
+// proxied by Cilium. Cilium doesn't support selecting both endpoints and CIDRs
+// in the same rule.
+func clusterDNSRules(dns clusterdns.DNS) []ciliumv2.EgressRule {
+	ports := []ciliumv2.PortRule{
+		{
+			Ports: dnsPorts(dns.Ports),
+			Rules: &ciliumv2.L7Rules{DNS: []ciliumv2.FQDNSelector{{MatchPattern: "*"}}},
+		},
+	}
+
+	var rules []ciliumv2.EgressRule
+	if len(dns.Selector) > 0 {
+		labels := maps.Clone(dns.Selector)
+		labels[namespaceLabel] = dns.Namespace
+		rules = append(rules, ciliumv2.EgressRule{
+			ToEndpoints: []metav1.LabelSelector{{MatchLabels: labels}},
+			ToPorts:     ports,
+		})
+	}
+	if len(dns.IPs) > 0 {
+		var cidrs []ciliumv2.CIDR
+		for _, ip := range dns.IPs {
+			cidrs = append(cidrs, ciliumv2.CIDR(ip))
+		}
+		rules = append(rules, ciliumv2.EgressRule{
+			ToCIDR:  cidrs,
+			ToPorts: ports,
+		})
+	}
+	return rules
+}
+
+// dnsPorts returns the given DNS ports over both UDP and TCP.
+func dnsPorts(ports []int32) []ciliumv2.PortProtocol {
+	var portProtocols []ciliumv2.PortProtocol
+	for _, port := range ports {
+		portProtocols = append(portProtocols,
+			ciliumv2.PortProtocol{Port: strconv.Itoa(int(port)), Protocol: ciliumv2.ProtoUDP},
+			ciliumv2.PortProtocol{Port: strconv.Itoa(int(port)), Protocol: ciliumv2.ProtoTCP},
+		)
+	}
+	return portProtocols
+}
+
+// noDefaultDeny keeps the workloads allowing the traffic the deny rules don't
+// match.
+func noDefaultDeny() *ciliumv2.DefaultDenyConfig {
+	return &ciliumv2.DefaultDenyConfig{
+		Ingress: ptr.To(false),
+		Egress:  ptr.To(false),
+	}
+}
+
+// fqdnSelectors returns the selectors of the given domain names, matching
+// them by pattern when they have a wildcard.
+func fqdnSelectors(fqdns []string) []ciliumv2.FQDNSelector {
+	var selectors []ciliumv2.FQDNSelector
+	for _, fqdn := range fqdns {
+		fqdn = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(fqdn)), ".")
+		switch {
+		case fqdn == "":
+		case strings.Contains(fqdn, "*"):
+			selectors = append(selectors, ciliumv2.FQDNSelector{MatchPattern: fqdn})
+		default:
+			selectors = append(selectors, ciliumv2.FQDNSelector{MatchName: fqdn})
+		}
+	}
+	return selectors
+}
+
+// portProtocols parses the given ports, given as "443" for TCP or as
+// "53/UDP", appending an error for each invalid one.
+func portProtocols(ports []string, errs *[]error) []ciliumv2.PortProtocol {
+	var portProtocols []ciliumv2.PortProtocol
+	for _, port := range ports {
+		number, protocol, found := strings.Cut(strings.TrimSpace(port), "/")
+		if !found {
+			protocol = string(ciliumv2.ProtoTCP)
+		}
+		protocol = strings.ToUpper(protocol)
+		if n, err := strconv.Atoi(number); err != nil || n < 1 || n > 65535 {
+			*errs = append(*errs, fmt.Errorf("invalid port %q", port))
+			continue
+		}
+		switch ciliumv2.L4Proto(protocol) {
+		case ciliumv2.ProtoTCP, ciliumv2.ProtoUDP, ciliumv2.ProtoAny:
+		default:
+			*errs = append(*errs, fmt.Errorf("invalid protocol of port %q", port))
+			continue
+		}
+		portProtocols = append(portProtocols, ciliumv2.PortProtocol{Port: number, Protocol: ciliumv2.L4Proto(protocol)})
+	}
+	return portProtocols
+}
+
+// httpRules returns the HTTP rules allowing every combination of the given
+// methods and paths, none when neither is given.
+func httpRules(methods, paths []string) []ciliumv2.PortRuleHTTP {
+	if len(methods) == 0 && len(paths) == 0 {
+		return nil
+	}
+	if len(methods) == 0 {
+		methods = []string{""}
+	}
+	if len(paths) == 0 {
+		paths = []string{""}
+	}
+
+	var rules []ciliumv2.PortRuleHTTP
+	for _, method := range methods {
+		for _, path := range paths {
+			rules = append(rules, ciliumv2.PortRuleHTTP{Method: strings.ToUpper(method), Path: path})
+		}
+	}
+	return rules
+}
@@ -0,0 +1,65 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package watcher
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
+)
+
+// WatchCcnps watches update and delete events for
+// CiliumClusterwideNetworkPolicies owned by ClusterNimbusPolicy and put the
+// name of their owner on respective channels.
+func WatchCcnps(ctx context.Context, updatedCcnpCh, deletedCcnpCh chan string) {
+	logger := log.FromContext(ctx)
+	informer := ciliumInformer("ciliumclusterwidenetworkpolicies")
+	handlers := cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldU := oldObj.(*unstructured.Unstructured)
+			newU := newObj.(*unstructured.Unstructured)
+
+			if adapterutil.IsOrphan(newU.GetOwnerReferences(), "ClusterNimbusPolicy") {
+				logger.V(4).Info("Ignoring orphan CiliumClusterwideNetworkPolicy", "CiliumClusterwideNetworkPolicy.Name", oldU.GetName(), "Operation", "Update")
+				return
+			}
+
+			if oldU.GetGeneration() == newU.GetGeneration() {
+				return
+			}
+
+			updatedCcnpCh <- ownerName(newU)
+		},
+		DeleteFunc: func(obj interface{}) {
+			u := obj.(*unstructured.Unstructured)
+			if adapterutil.IsOrphan(u.GetOwnerReferences(), "ClusterNimbusPolicy") {
+				logger.V(4).Info("Ignoring orphan CiliumClusterwideNetworkPolicy", "CiliumClusterwideNetworkPolicy.Name", u.GetName(), "Operation", "Delete")
+				return
+			}
+			deletedCcnpCh <- ownerName(u)
+		},
+	}
+	_, err := informer.AddEventHandler(handlers)
+	if err != nil {
+		logger.Error(err, "failed to add event handlers")
+		return
+	}
+	logger.Info("CiliumClusterwideNetworkPolicy watcher started")
+	informer.Run(ctx.Done())
+}
+
+// ownerName returns the name of the ClusterNimbusPolicy owning the given
+// CiliumClusterwideNetworkPolicy.
+func ownerName(u *unstructured.Unstructured) string {
+	for _, ownerRef := range u.GetOwnerReferences() {
+		if ownerRef.Kind == "ClusterNimbusPolicy" {
+			return ownerRef.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,84 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package watcher
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/5GSEC/nimbus/pkg/adapter/common"
+	"github.com/5GSEC/nimbus/pkg/adapter/k8s"
+	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
+)
+
+var (
+	factory dynamicinformer.DynamicSharedInformerFactory
+)
+
+func init() {
+	factory = dynamicinformer.NewDynamicSharedInformerFactory(k8s.NewDynamicClient(), time.Minute)
+}
+
+func ciliumInformer(resource string) cache.SharedIndexInformer {
+	ciliumGvr := schema.GroupVersionResource{
+		Group:    "cilium.io",
+		Version:  "v2",
+		Resource: resource,
+	}
+	informer := factory.ForResource(ciliumGvr).Informer()
+	return informer
+}
+
+// WatchCnps watches update and delete events for CiliumNetworkPolicies owned by
+// NimbusPolicy and put their info on respective channels.
+func WatchCnps(ctx context.Context, updatedCnpCh, deletedCnpCh chan common.Request) {
+	logger := log.FromContext(ctx)
+	informer := ciliumInformer("ciliumnetworkpolicies")
+	handlers := cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldU := oldObj.(*unstructured.Unstructured)
+			newU := newObj.(*unstructured.Unstructured)
+
+			if adapterutil.IsOrphan(newU.GetOwnerReferences(), "NimbusPolicy") {
+				logger.V(4).Info("Ignoring orphan CiliumNetworkPolicy", "CiliumNetworkPolicy.Name", oldU.GetName(), "CiliumNetworkPolicy.Namespace", oldU.GetNamespace(), "Operation", "Update")
+				return
+			}
+
+			if oldU.GetGeneration() == newU.GetGeneration() {
+				return
+			}
+
+			cnpNamespacedName := common.Request{
+				Name:      newU.GetName(),
+				Namespace: newU.GetNamespace(),
+			}
+			updatedCnpCh <- cnpNamespacedName
+		},
+		DeleteFunc: func(obj interface{}) {
+			u := obj.(*unstructured.Unstructured)
+			if adapterutil.IsOrphan(u.GetOwnerReferences(), "NimbusPolicy") {
+				logger.V(4).Info("Ignoring orphan CiliumNetworkPolicy", "CiliumNetworkPolicy.Name", u.GetName(), "CiliumNetworkPolicy.Namespace", u.GetNamespace(), "Operation", "Delete")
+				return
+			}
+			cnpNamespacedName := common.Request{
+				Name:      u.GetName(),
+				Namespace: u.GetNamespace(),
+			}
+			deletedCnpCh <- cnpNamespacedName
+		},
+	}
+	_, err := informer.AddEventHandler(handlers)
+	if err != nil {
+		logger.Error(err, "failed to add event handlers")
+		return
+	}
+	logger.Info("CiliumNetworkPolicy watcher started")
+	informer.Run(ctx.Done())
+}
@@ -0,0 +1,21 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package builder
+
+import (
+	"github.com/5GSEC/nimbus/pkg/adapter/idpool"
+)
+
+// actionSupported reports whether the k8tls CronJobs can enforce the given ID
+// with the given Nimbus action, as listed in the idpool.
+//
+//	| Nimbus | k8tls                                     |
+//	|--------|-------------------------------------------|
+//	| Audit  | TLS configurations are assessed on a cron |
+//
+// The assessment only reports on the TLS configurations it finds, so Block,
+// Warn, Allow and Mutate aren't supported.
+func actionSupported(id, action string) bool {
+	return idpool.IsActionSupportedBy(id, action, "k8tls")
+}
@@ -19,6 +19,7 @@ import (
 	"github.com/5GSEC/nimbus/api/v1alpha1"
 	"github.com/5GSEC/nimbus/pkg/adapter/common"
 	"github.com/5GSEC/nimbus/pkg/adapter/idpool"
+	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
 )
 
 var (
@@ -31,11 +32,16 @@ func BuildCronJob(ctx context.Context, cwnp v1alpha1.ClusterNimbusPolicy) (*batc
 	for _, nimbusRule := range cwnp.Spec.NimbusRules {
 		id := nimbusRule.ID
 		if idpool.IsIdSupportedBy(id, "k8tls") {
+			if !actionSupported(id, nimbusRule.Rule.RuleAction) {
+				logger.Info("K8TLS adapter doesn't support this action", "ID", id, "Action", nimbusRule.Rule.RuleAction)
+				continue
+			}
 			cronJob, configMap := cronJobFor(ctx, id, nimbusRule)
 			cronJob.SetName(cwnp.Name + "-" + strings.ToLower(id))
 			cronJob.SetAnnotations(map[string]string{
 				"app.kubernetes.io/managed-by": "nimbus-k8tls",
 			})
+			adapterutil.AddIntents(cronJob.Annotations, id)
 			cronJob.SetLabels(cwnp.Labels)
 			return cronJob, configMap
 		}
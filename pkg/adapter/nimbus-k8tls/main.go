@@ -13,7 +13,9 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
+	"github.com/5GSEC/nimbus/pkg/adapter/metrics"
 	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-k8tls/manager"
+	"github.com/5GSEC/nimbus/pkg/tracing"
 )
 
 func main() {
@@ -34,5 +36,16 @@ func main() {
 	}()
 
 	logger.Info("K8TLS adapter started")
+	shutdownTracing, err := tracing.Setup(ctx, "nimbus-k8tls")
+	if err != nil {
+		logger.Error(err, "failed to set up tracing")
+		os.Exit(1)
+	}
+
+	go metrics.Serve(ctx)
 	manager.Run(ctx)
+
+	if err := shutdownTracing(context.Background()); err != nil {
+		logger.Error(err, "failed to flush traces")
+	}
 }
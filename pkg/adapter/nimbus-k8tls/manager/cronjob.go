@@ -18,7 +18,9 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	"github.com/5GSEC/nimbus/api/v1alpha1"
+	"github.com/5GSEC/nimbus/pkg/adapter/metrics"
 	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
+	"github.com/5GSEC/nimbus/pkg/tracing"
 )
 
 func createOrUpdateCj(ctx context.Context, logger logr.Logger, cwnp v1alpha1.ClusterNimbusPolicy, cronJob *batchv1.CronJob) {
@@ -38,20 +40,34 @@ func createOrUpdateCj(ctx context.Context, logger logr.Logger, cwnp v1alpha1.Clu
 
 	if err != nil {
 		if errors.IsNotFound(err) {
-			if err = k8sClient.Create(ctx, cronJob); err != nil {
+			if err = tracing.Trace(ctx, "CronJob.Create", func(ctx context.Context) error {
+				return k8sClient.Create(ctx, cronJob)
+			}, tracing.WithObject("CronJob", cronJob)); err != nil {
 				logger.Error(err, "failed to create Kubernetes CronJob", "CronJob.Name", cronJob.Name, "CronJob.Namespace", cronJob.Namespace)
+				adapterutil.RecordPolicyFailed(recorder, &cwnp, cronJob.Namespace+"/CronJob/"+cronJob.Name, err)
+				metrics.PolicyFailed(adapterName, "CronJob")
 				return
 			}
 			logger.Info("created Kubernetes CronJob", "CronJob.Name", cronJob.Name, "CronJob.Namespace", cronJob.Namespace)
+			adapterutil.RecordPolicyCreated(recorder, &cwnp, cronJob.Namespace+"/CronJob/"+cronJob.Name)
+			metrics.PolicyGenerated(adapterName, "CronJob", cronJob)
 		}
 	} else {
 		cronJob.ResourceVersion = existingCronJob.ResourceVersion
 
-		if err = k8sClient.Update(ctx, cronJob); err != nil {
+		if err = tracing.Trace(ctx, "CronJob.Update", func(ctx context.Context) error {
+			return k8sClient.Update(ctx, cronJob)
+		}, tracing.WithObject("CronJob", cronJob)); err != nil {
 			logger.Error(err, "failed to configure existing Kubernetes CronJob", "CronJob.Name", cronJob.Name, "CronJob.Namespace", cronJob.Namespace)
+			adapterutil.RecordPolicyFailed(recorder, &cwnp, cronJob.Namespace+"/CronJob/"+cronJob.Name, err)
+			metrics.PolicyFailed(adapterName, "CronJob")
 			return
 		}
 		logger.Info("configured Kubernetes CronJob", "CronJob.Name", cronJob.Name, "CronJob.Namespace", cronJob.Namespace)
+		if cronJob.GetGeneration() != existingCronJob.GetGeneration() {
+			adapterutil.RecordPolicyConfigured(recorder, &cwnp, cronJob.Namespace+"/CronJob/"+cronJob.Name)
+			metrics.PolicyGenerated(adapterName, "CronJob", cronJob)
+		}
 	}
 
 	if err = adapterutil.UpdateCwnpStatus(ctx, k8sClient, cronJob.Namespace+"/CronJob/"+cronJob.Name, cwnp.Name, false); err != nil {
@@ -59,7 +75,7 @@ func createOrUpdateCj(ctx context.Context, logger logr.Logger, cwnp v1alpha1.Clu
 	}
 }
 
-func deleteCronJobs(ctx context.Context, logger logr.Logger, cwnpName string, cronJobsToDelete map[string]batchv1.CronJob) {
+func deleteCronJobs(ctx context.Context, logger logr.Logger, cwnp v1alpha1.ClusterNimbusPolicy, cronJobsToDelete map[string]batchv1.CronJob) {
 	for cronJobName := range cronJobsToDelete {
 		cronJob := cronJobsToDelete[cronJobName]
 		if err := k8sClient.Delete(ctx, &cronJob); err != nil {
@@ -67,10 +83,11 @@ func deleteCronJobs(ctx context.Context, logger logr.Logger, cwnpName string, cr
 			continue
 		}
 
-		if err := adapterutil.UpdateCwnpStatus(ctx, k8sClient, cronJob.Namespace+"/CronJob/"+cronJob.Name, cwnpName, true); err != nil {
+		if err := adapterutil.UpdateCwnpStatus(ctx, k8sClient, cronJob.Namespace+"/CronJob/"+cronJob.Name, cwnp.Name, true); err != nil {
 			logger.Error(err, "failed to update ClusterNimbusPolicy status")
 		}
 		logger.Info("Dangling Kubernetes CronJob deleted", "CronJobJob.Name", cronJob.Name, "CronJob.Namespace", cronJob.Namespace)
+		adapterutil.RecordDanglingPolicyDeleted(recorder, &cwnp, cronJob.Namespace+"/CronJob/"+cronJob.Name)
 	}
 }
 
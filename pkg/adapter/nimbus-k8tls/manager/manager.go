@@ -6,6 +6,7 @@ package manager
 import (
 	"context"
 	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	batchv1 "k8s.io/api/batch/v1"
@@ -16,21 +17,27 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	"github.com/5GSEC/nimbus/api/v1alpha1"
 	"github.com/5GSEC/nimbus/pkg/adapter/common"
 	"github.com/5GSEC/nimbus/pkg/adapter/k8s"
+	"github.com/5GSEC/nimbus/pkg/adapter/metrics"
 	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-k8tls/builder"
 	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-k8tls/watcher"
 	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
 	globalwatcher "github.com/5GSEC/nimbus/pkg/adapter/watcher"
+	"github.com/5GSEC/nimbus/pkg/tracing"
 )
 
+const adapterName = "nimbus-k8tls"
+
 var (
 	scheme         = runtime.NewScheme()
 	k8sClient      client.Client
+	recorder       record.EventRecorder
 	K8tlsNamespace = "nimbus-k8tls-env"
 	k8tls          = "k8tls"
 )
@@ -41,13 +48,16 @@ func init() {
 	utilruntime.Must(rbacv1.AddToScheme(scheme))
 	utilruntime.Must(v1alpha1.AddToScheme(scheme))
 	k8sClient = k8s.NewOrDie(scheme)
+	recorder = k8s.NewEventRecorderOrDie(scheme, adapterName)
 }
 
 //+kubebuilder:rbac:groups=intent.security.nimbus.com,resources=clusternimbuspolicies,verbs=get;list;watch
 //+kubebuilder:rbac:groups=intent.security.nimbus.com,resources=clusternimbuspolicies/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;create;delete;list;watch;update
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch
 //+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;create;delete;update
 //+kubebuilder:rbac:groups="",resources=namespaces;serviceaccounts,verbs=get
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 func Run(ctx context.Context) {
 	cwnpCh := make(chan string)
@@ -57,6 +67,7 @@ func Run(ctx context.Context) {
 	updateCronJobCh := make(chan common.Request)
 	deletedCronJobCh := make(chan common.Request)
 	go watcher.WatchCronJobs(ctx, updateCronJobCh, deletedCronJobCh)
+	go watcher.WatchScanJobs(ctx)
 
 	// Get the namespace name within which the k8tls environment needs to be set
 	for {
@@ -97,6 +108,7 @@ func reconcileCronJob(ctx context.Context, name string) {
 }
 
 func createOrUpdateCronJob(ctx context.Context, cwnpName string) {
+	defer metrics.ObserveReconcile(adapterName, time.Now())
 	logger := log.FromContext(ctx)
 	var cwnp v1alpha1.ClusterNimbusPolicy
 	if err := k8sClient.Get(ctx, types.NamespacedName{Name: cwnpName}, &cwnp); err != nil {
@@ -109,10 +121,23 @@ func createOrUpdateCronJob(ctx context.Context, cwnpName string) {
 		return
 	}
 
+	// Continue the trace of the reconciliation that changed the
+	// ClusterNimbusPolicy.
+	ctx, span := tracing.Start(tracing.Extract(ctx, &cwnp), adapterName+".Reconcile", tracing.WithObject("ClusterNimbusPolicy", &cwnp))
+	defer span.End()
+
 	deleteDanglingCj(ctx, logger, cwnp)
+	adapterutil.RecordUnsupportedIntents(recorder, &cwnp, cwnp.Spec.NimbusRules, "k8tls")
+	adapterutil.RecordUnsupportedActions(recorder, &cwnp, cwnp.Spec.NimbusRules, "k8tls")
+	if err := adapterutil.UpdateUnsupportedActions(ctx, k8sClient, "ClusterNimbusPolicy", cwnp.Name, cwnp.Namespace, "k8tls", cwnp.Spec.NimbusRules); err != nil {
+		logger.Error(err, "failed to update unsupported actions status in ClusterNimbusPolicy", "ClusterNimbusPolicy.Name", cwnp.Name)
+	}
+	metrics.UnsupportedIntents(adapterName, &cwnp, cwnp.Spec.NimbusRules, "k8tls")
 	newCtx := context.WithValue(ctx, common.K8sClientKey, k8sClient)
 	newCtx = context.WithValue(newCtx, common.NamespaceNameKey, K8tlsNamespace)
+	newCtx, translateSpan := tracing.Start(newCtx, "CronJob.Translate")
 	cronJob, configMap := builder.BuildCronJob(newCtx, cwnp)
+	translateSpan.End()
 
 	if cronJob != nil {
 		if !k8tlsEnvExist(ctx, k8sClient) {
@@ -178,5 +203,5 @@ func deleteDanglingCj(ctx context.Context, logger logr.Logger, cwnp v1alpha1.Clu
 		delete(cronJobsToDelete, cjName)
 	}
 
-	deleteCronJobs(ctx, logger, cwnp.Name, cronJobsToDelete)
+	deleteCronJobs(ctx, logger, cwnp, cronJobsToDelete)
 }
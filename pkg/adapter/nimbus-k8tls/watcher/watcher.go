@@ -8,12 +8,14 @@ import (
 	"time"
 
 	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/tools/cache"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	"github.com/5GSEC/nimbus/pkg/adapter/common"
 	"github.com/5GSEC/nimbus/pkg/adapter/k8s"
+	"github.com/5GSEC/nimbus/pkg/adapter/metrics"
 	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
 )
 
@@ -29,6 +31,10 @@ func cronJobInformer() cache.SharedIndexInformer {
 	return factory.Batch().V1().CronJobs().Informer()
 }
 
+func jobInformer() cache.SharedIndexInformer {
+	return factory.Batch().V1().Jobs().Informer()
+}
+
 // WatchCronJobs watches for update and delete events for Kubernetes CronJobs
 // owned by ClusterNimbusPolicy and put their info on corresponding channels.
 func WatchCronJobs(ctx context.Context, updatedCronJobCh, deletedCronJobCh chan common.Request) {
@@ -74,3 +80,65 @@ func WatchCronJobs(ctx context.Context, updatedCronJobCh, deletedCronJobCh chan
 	logger.Info("Kubernetes CronJob watcher started")
 	informer.Run(ctx.Done())
 }
+
+// WatchScanJobs watches for the Kubernetes Jobs spawned by the CronJobs owned
+// by ClusterNimbusPolicy and counts the outcome of the scans they run.
+func WatchScanJobs(ctx context.Context) {
+	logger := log.FromContext(ctx)
+	cronJobs := cronJobInformer().GetStore()
+	informer := jobInformer()
+	handlers := cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldJob := oldObj.(*batchv1.Job)
+			newJob := newObj.(*batchv1.Job)
+
+			if _, finished := jobOutcome(oldJob); finished {
+				return
+			}
+			outcome, finished := jobOutcome(newJob)
+			if !finished {
+				return
+			}
+
+			var cronJobName string
+			for _, ownerRef := range newJob.GetOwnerReferences() {
+				if ownerRef.Kind == "CronJob" {
+					cronJobName = ownerRef.Name
+					break
+				}
+			}
+			obj, exists, err := cronJobs.GetByKey(newJob.GetNamespace() + "/" + cronJobName)
+			if cronJobName == "" || err != nil || !exists {
+				return
+			}
+			if adapterutil.IsOrphan(obj.(*batchv1.CronJob).GetOwnerReferences(), "ClusterNimbusPolicy") {
+				return
+			}
+
+			logger.Info("k8tls scan finished", "Job.Name", newJob.GetName(), "Job.Namespace", newJob.GetNamespace(), "Outcome", outcome)
+			metrics.ScanFinished(cronJobName, outcome)
+		},
+	}
+	if _, err := informer.AddEventHandler(handlers); err != nil {
+		logger.Error(err, "failed to add event handler")
+		return
+	}
+	logger.Info("Kubernetes Job watcher started")
+	informer.Run(ctx.Done())
+}
+
+// jobOutcome returns the outcome of the given Job, and whether it finished.
+func jobOutcome(job *batchv1.Job) (string, bool) {
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			return metrics.ScanSucceeded, true
+		case batchv1.JobFailed:
+			return metrics.ScanFailed, true
+		}
+	}
+	return "", false
+}
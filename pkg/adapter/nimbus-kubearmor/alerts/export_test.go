@@ -0,0 +1,48 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package alerts
+
+import (
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// SetFlushInterval sets how often the violations are flushed, and returns a
+// function restoring it.
+func SetFlushInterval(interval time.Duration) func() {
+	previous := flushInterval
+	flushInterval = interval
+	return func() {
+		flushInterval = previous
+	}
+}
+
+// AlertFields maps the fields of the Alert message of the KubeArmor protobuf
+// API the adapter relies on to the field numbers it decodes them with.
+var AlertFields = map[string]protowire.Number{
+	"Timestamp":     fieldTimestamp,
+	"UpdatedTime":   fieldUpdatedTime,
+	"ClusterName":   fieldClusterName,
+	"HostName":      fieldHostName,
+	"NamespaceName": fieldNamespaceName,
+	"PodName":       fieldPodName,
+	"ContainerName": fieldContainerName,
+	"PolicyName":    fieldPolicyName,
+	"Severity":      fieldSeverity,
+	"Tags":          fieldTags,
+	"Message":       fieldMessage,
+	"Type":          fieldType,
+	"Source":        fieldSource,
+	"Operation":     fieldOperation,
+	"Resource":      fieldResource,
+	"Result":        fieldResult,
+	"Action":        fieldAction,
+}
+
+// RequestFields maps the fields of the RequestMessage of the KubeArmor
+// protobuf API to the field numbers the adapter encodes them with.
+var RequestFields = map[string]protowire.Number{
+	"Filter": fieldFilter,
+}
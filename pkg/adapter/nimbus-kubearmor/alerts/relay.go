@@ -0,0 +1,256 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The KubeArmor relay serves the alerts through the WatchAlerts streaming RPC
+// of its feeder.LogService.
+const (
+	ServiceName      = "feeder.LogService"
+	WatchAlertsName  = "WatchAlerts"
+	watchAlertsRoute = "/" + ServiceName + "/" + WatchAlertsName
+
+	// policyFilter makes the relay only stream the alerts raised by policies.
+	policyFilter = "policy"
+)
+
+// Alert is the subset of the KubeArmor alert fields the adapter relies on.
+type Alert struct {
+	Timestamp     int64
+	UpdatedTime   string
+	ClusterName   string
+	HostName      string
+	NamespaceName string
+	PodName       string
+	ContainerName string
+	PolicyName    string
+	Severity      string
+	Tags          string
+	Message       string
+	Type          string
+	Source        string
+	Operation     string
+	Resource      string
+	Result        string
+	Action        string
+}
+
+// Field numbers of the Alert message of the KubeArmor protobuf API.
+const (
+	fieldTimestamp     protowire.Number = 1
+	fieldUpdatedTime   protowire.Number = 2
+	fieldClusterName   protowire.Number = 3
+	fieldHostName      protowire.Number = 4
+	fieldNamespaceName protowire.Number = 5
+	fieldPodName       protowire.Number = 6
+	fieldContainerName protowire.Number = 8
+	fieldPolicyName    protowire.Number = 13
+	fieldSeverity      protowire.Number = 14
+	fieldTags          protowire.Number = 15
+	fieldMessage       protowire.Number = 16
+	fieldType          protowire.Number = 17
+	fieldSource        protowire.Number = 18
+	fieldOperation     protowire.Number = 19
+	fieldResource      protowire.Number = 20
+	fieldAction        protowire.Number = 22
+	fieldResult        protowire.Number = 23
+
+	// fieldFilter is the field number of the Filter of the RequestMessage.
+	fieldFilter protowire.Number = 1
+)
+
+func (a *Alert) stringFields() map[protowire.Number]*string {
+	return map[protowire.Number]*string{
+		fieldUpdatedTime:   &a.UpdatedTime,
+		fieldClusterName:   &a.ClusterName,
+		fieldHostName:      &a.HostName,
+		fieldNamespaceName: &a.NamespaceName,
+		fieldPodName:       &a.PodName,
+		fieldContainerName: &a.ContainerName,
+		fieldPolicyName:    &a.PolicyName,
+		fieldSeverity:      &a.Severity,
+		fieldTags:          &a.Tags,
+		fieldMessage:       &a.Message,
+		fieldType:          &a.Type,
+		fieldSource:        &a.Source,
+		fieldOperation:     &a.Operation,
+		fieldResource:      &a.Resource,
+		fieldResult:        &a.Result,
+		fieldAction:        &a.Action,
+	}
+}
+
+// Marshal encodes the alert in the protobuf wire format.
+func (a *Alert) Marshal() []byte {
+	var b []byte
+	if a.Timestamp != 0 {
+		b = protowire.AppendTag(b, fieldTimestamp, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(a.Timestamp))
+	}
+	fields := a.stringFields()
+	nums := make([]protowire.Number, 0, len(fields))
+	for num := range fields {
+		nums = append(nums, num)
+	}
+	slices.Sort(nums)
+	for _, num := range nums {
+		if value := fields[num]; *value != "" {
+			b = protowire.AppendTag(b, num, protowire.BytesType)
+			b = protowire.AppendString(b, *value)
+		}
+	}
+	return b
+}
+
+// Unmarshal decodes an alert from the protobuf wire format, skipping the
+// fields the adapter doesn't rely on.
+func (a *Alert) Unmarshal(b []byte) error {
+	fields := a.stringFields()
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		switch {
+		case num == fieldTimestamp && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			a.Timestamp = int64(v)
+			b = b[n:]
+		case fields[num] != nil && typ == protowire.BytesType:
+			v, n := protowire.ConsumeString(b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			*fields[num] = v
+			b = b[n:]
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			b = b[n:]
+		}
+	}
+	return nil
+}
+
+// request is the RequestMessage of the WatchAlerts RPC.
+type request struct {
+	Filter string
+}
+
+func (r *request) Marshal() []byte {
+	var b []byte
+	if r.Filter != "" {
+		b = protowire.AppendTag(b, fieldFilter, protowire.BytesType)
+		b = protowire.AppendString(b, r.Filter)
+	}
+	return b
+}
+
+func (r *request) Unmarshal(b []byte) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		if num == fieldFilter && typ == protowire.BytesType {
+			v, n := protowire.ConsumeString(b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			r.Filter = v
+			b = b[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+type message interface {
+	Marshal() []byte
+	Unmarshal([]byte) error
+}
+
+// Codec encodes the messages of the WatchAlerts RPC, so that the adapter
+// doesn't need the generated code of the whole KubeArmor protobuf API.
+type Codec struct{}
+
+func (Codec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(message)
+	if !ok {
+		return nil, fmt.Errorf("unexpected message type %T", v)
+	}
+	return m.Marshal(), nil
+}
+
+func (Codec) Unmarshal(data []byte, v any) error {
+	m, ok := v.(message)
+	if !ok {
+		return fmt.Errorf("unexpected message type %T", v)
+	}
+	return m.Unmarshal(data)
+}
+
+func (Codec) Name() string {
+	return "proto"
+}
+
+// NewRequest returns the RequestMessage the adapter sends to watch the alerts
+// of the policies.
+func NewRequest() any {
+	return &request{Filter: policyFilter}
+}
+
+// watchAlerts streams the alerts of the policies from the relay at the given
+// address to the handler until the stream breaks or the context is done.
+// established is called once the stream is set up.
+func watchAlerts(ctx context.Context, address string, established func(), handle func(*Alert)) error {
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{StreamName: WatchAlertsName, ServerStreams: true},
+		watchAlertsRoute, grpc.ForceCodec(Codec{}))
+	if err != nil {
+		return err
+	}
+	if err := stream.SendMsg(NewRequest()); err != nil {
+		return err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+	established()
+
+	for {
+		alert := &Alert{}
+		if err := stream.RecvMsg(alert); err != nil {
+			return err
+		}
+		handle(alert)
+	}
+}
@@ -0,0 +1,116 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package alerts_test
+
+import (
+	"os"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-kubearmor/alerts"
+)
+
+// protoField matches the scalar fields of a protobuf message.
+var protoField = regexp.MustCompile(`(?m)^\s*(?:repeated\s+)?(\w+)\s+(\w+)\s*=\s*(\d+);`)
+
+// messageFields returns the type and number of the fields of the given message
+// of the given protobuf schema.
+func messageFields(t *testing.T, schema, message string) map[string]struct {
+	typ string
+	num protowire.Number
+} {
+	t.Helper()
+	body := regexp.MustCompile(`(?s)message ` + message + ` \{(.*?)\n\}`).FindStringSubmatch(schema)
+	if body == nil {
+		t.Fatalf("message %s not found in the schema", message)
+	}
+
+	fields := make(map[string]struct {
+		typ string
+		num protowire.Number
+	})
+	for _, match := range protoField.FindAllStringSubmatch(body[1], -1) {
+		num, err := strconv.Atoi(match[3])
+		if err != nil {
+			t.Fatal(err)
+		}
+		fields[match[2]] = struct {
+			typ string
+			num protowire.Number
+		}{typ: match[1], num: protowire.Number(num)}
+	}
+	return fields
+}
+
+func TestFieldNumbers(t *testing.T) {
+	schema, err := os.ReadFile("testdata/kubearmor.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for message, numbers := range map[string]map[string]protowire.Number{
+		"Alert":          alerts.AlertFields,
+		"RequestMessage": alerts.RequestFields,
+	} {
+		fields := messageFields(t, string(schema), message)
+		for name, num := range numbers {
+			field, ok := fields[name]
+			if !ok {
+				t.Errorf("field %s.%s not found in the schema", message, name)
+				continue
+			}
+			if num != field.num {
+				t.Errorf("field %s.%s has number %d, want %d", message, name, num, field.num)
+			}
+			want := "string"
+			if name == "Timestamp" {
+				want = "int64"
+			}
+			if field.typ != want {
+				t.Errorf("field %s.%s is a %s, want %s", message, name, field.typ, want)
+			}
+		}
+	}
+}
+
+func TestCodec(t *testing.T) {
+	alert := &alerts.Alert{
+		Timestamp:     1714557600,
+		NamespaceName: "default",
+		PodName:       "nginx",
+		PolicyName:    "pkg-mgr-execution-binding-swdeploymenttools",
+		Tags:          "swDeploymentTools",
+		Operation:     "Process",
+		Resource:      "/usr/bin/apt",
+		Result:        "Permission denied",
+		Action:        "Block",
+	}
+
+	data, err := alerts.Codec{}.Marshal(alert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := &alerts.Alert{}
+	if err := (alerts.Codec{}).Unmarshal(data, decoded); err != nil {
+		t.Fatal(err)
+	}
+	if *decoded != *alert {
+		t.Errorf("decoded %+v, want %+v", decoded, alert)
+	}
+
+	// The fields the adapter doesn't rely on, e.g. the ContainerImage, are
+	// skipped.
+	data = protowire.AppendTag(data, 24, protowire.BytesType)
+	data = protowire.AppendString(data, "nginx:latest")
+	decoded = &alerts.Alert{}
+	if err := (alerts.Codec{}).Unmarshal(data, decoded); err != nil {
+		t.Fatal(err)
+	}
+	if *decoded != *alert {
+		t.Errorf("decoded %+v, want %+v", decoded, alert)
+	}
+}
@@ -0,0 +1,83 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+// Package relaytest provides a fake KubeArmor relay, streaming the given
+// alerts to the adapter, for testing the relaying of the alerts without
+// KubeArmor.
+package relaytest
+
+import (
+	"net"
+
+	"google.golang.org/grpc"
+
+	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-kubearmor/alerts"
+)
+
+// Server is a fake KubeArmor relay serving the WatchAlerts RPC.
+type Server struct {
+	// Address is the address the server listens on, to set in the
+	// KUBEARMOR_RELAY_ADDRESS environment variable of the adapter.
+	Address string
+
+	alertCh chan *alerts.Alert
+	server  *grpc.Server
+}
+
+// NewServer starts a fake KubeArmor relay listening on the given address,
+// e.g. "127.0.0.1:0" for a random port.
+func NewServer(address string) (*Server, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		Address: listener.Addr().String(),
+		alertCh: make(chan *alerts.Alert),
+		server:  grpc.NewServer(grpc.ForceServerCodec(alerts.Codec{})),
+	}
+	s.server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: alerts.ServiceName,
+		HandlerType: (*any)(nil),
+		Streams: []grpc.StreamDesc{
+			{
+				StreamName:    alerts.WatchAlertsName,
+				Handler:       s.watchAlerts,
+				ServerStreams: true,
+			},
+		},
+	}, s)
+
+	go func() {
+		_ = s.server.Serve(listener)
+	}()
+	return s, nil
+}
+
+// Send streams the given alert to a watching adapter, blocking until one
+// receives it.
+func (s *Server) Send(alert *alerts.Alert) {
+	s.alertCh <- alert
+}
+
+// Close stops the server, breaking the streams of the watching adapters.
+func (s *Server) Close() {
+	s.server.Stop()
+}
+
+func (s *Server) watchAlerts(_ any, stream grpc.ServerStream) error {
+	if err := stream.RecvMsg(alerts.NewRequest()); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case alert := <-s.alertCh:
+			if err := stream.SendMsg(alert); err != nil {
+				return err
+			}
+		}
+	}
+}
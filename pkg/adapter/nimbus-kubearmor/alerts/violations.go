@@ -0,0 +1,352 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+// Package alerts relays the alerts of the KubeArmor policies generated by the
+// adapter to the bindings of the intents they enforce, as violations.
+package alerts
+
+import (
+	"context"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	kubearmorv1 "github.com/kubearmor/KubeArmor/pkg/KubeArmorController/api/security.kubearmor.com/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/5GSEC/nimbus/api/v1alpha1"
+	"github.com/5GSEC/nimbus/pkg/adapter/metrics"
+	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
+
+	kubearmorclusterv1 "github.com/5GSEC/nimbus/pkg/adapter/nimbus-kubearmor/api/v1"
+)
+
+const (
+	// RelayAddressEnv is the environment variable holding the address of the
+	// KubeArmor relay gRPC server, e.g. "kubearmor.kubearmor:32767". The
+	// alerts aren't relayed when it's empty.
+	RelayAddressEnv = "KUBEARMOR_RELAY_ADDRESS"
+
+	// PodNamespaceEnv is the environment variable holding the namespace of
+	// the adapter, where it renews the Lease advertising that it relays the
+	// KubeArmor alerts.
+	PodNamespaceEnv = "POD_NAMESPACE"
+
+	adapterName = "nimbus-kubearmor"
+	engine      = "kubearmor"
+
+	minRetryDelay = time.Second
+	maxRetryDelay = time.Minute
+)
+
+// flushInterval is how often the observed violations are added to the status
+// of the bindings, shortened by the tests.
+var flushInterval = 15 * time.Second
+
+// bindingRef identifies a SecurityIntentBinding or a
+// ClusterSecurityIntentBinding.
+type bindingRef struct {
+	Kind      string
+	Name      string
+	Namespace string
+}
+
+// target is a bound intent a KubeArmor policy enforces.
+type target struct {
+	binding bindingRef
+	id      string
+}
+
+// resolved are the targets of a KubeArmor policy, along with the policy name
+// formatted as "<Kind>/<name>".
+type resolved struct {
+	policy  string
+	targets []target
+}
+
+// Watch relays the alerts of the KubeArmor policies generated by the adapter
+// from the KubeArmor relay at the address set in the KUBEARMOR_RELAY_ADDRESS
+// environment variable to the bindings, until the context is done. The
+// violations are added to the status of the bindings every 15 seconds, along
+// with an IntentViolated warning Event. While the relay streams the alerts,
+// the adapter renews a Lease in its namespace so that the controller knows the
+// KubeArmor policies are audited when rolling them out.
+func Watch(ctx context.Context, k8sClient client.Client, recorder record.EventRecorder) {
+	logger := log.FromContext(ctx).WithName("alerts")
+
+	address := os.Getenv(RelayAddressEnv)
+	if address == "" {
+		logger.Info("Relaying of the KubeArmor alerts disabled")
+		return
+	}
+
+	namespace := os.Getenv(PodNamespaceEnv)
+	if namespace == "" {
+		logger.Info("Namespace of the adapter unknown, not advertising the relaying of the KubeArmor alerts",
+			"Env", PodNamespaceEnv)
+	}
+
+	alertCh := make(chan *Alert)
+	// connectedCh receives the time the stream of the alerts was set up, or
+	// the zero time when it broke.
+	connectedCh := make(chan time.Time)
+	go relay(ctx, logger, address, alertCh, connectedCh)
+	var connectedSince time.Time
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	// The targets of the policies are cached until the next flush, so that
+	// a burst of alerts doesn't result in a burst of requests.
+	cache := make(map[string]resolved)
+	violations := make(map[bindingRef][]v1alpha1.IntentViolation)
+
+	for {
+		select {
+		case <-ctx.Done():
+			flush(context.Background(), logger, k8sClient, recorder, violations)
+			return
+		case connectedSince = <-connectedCh:
+			renew(ctx, logger, k8sClient, namespace, connectedSince)
+		case alert := <-alertCh:
+			key := alert.NamespaceName + "/" + alert.PolicyName + "/" + alert.Tags
+			policy, ok := cache[key]
+			if !ok {
+				policy = resolve(ctx, k8sClient, alert)
+				cache[key] = policy
+			}
+			for _, t := range policy.targets {
+				violations[t.binding] = observe(violations[t.binding], t.id, policy.policy, alert)
+				metrics.IntentViolated(adapterName, t.id, alert.Action)
+			}
+		case <-ticker.C:
+			flush(ctx, logger, k8sClient, recorder, violations)
+			renew(ctx, logger, k8sClient, namespace, connectedSince)
+			clear(cache)
+			clear(violations)
+		}
+	}
+}
+
+// relay streams the alerts from the relay to the given channel, reconnecting
+// with an exponential backoff whenever the stream breaks. The times the stream
+// is set up and breaks are sent to connectedCh.
+func relay(ctx context.Context, logger logr.Logger, address string, alertCh chan<- *Alert, connectedCh chan<- time.Time) {
+	notify := func(since time.Time) {
+		select {
+		case connectedCh <- since:
+		case <-ctx.Done():
+		}
+	}
+
+	delay := minRetryDelay
+	for {
+		logger.Info("Watching KubeArmor alerts", "Address", address)
+		connected := false
+		err := watchAlerts(ctx, address, func() {
+			connected = true
+			notify(time.Now())
+		}, func(alert *Alert) {
+			delay = minRetryDelay
+			select {
+			case alertCh <- alert:
+			case <-ctx.Done():
+			}
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			notify(time.Time{})
+		}
+
+		logger.Error(err, "failed to watch KubeArmor alerts, retrying", "Address", address, "Delay", delay)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+		delay = min(2*delay, maxRetryDelay)
+	}
+}
+
+// renew renews the Lease advertising that the adapter relays the KubeArmor
+// alerts since the given time, unless the stream of the alerts is broken or
+// the namespace of the adapter is unknown.
+func renew(ctx context.Context, logger logr.Logger, k8sClient client.Client, namespace string, connectedSince time.Time) {
+	if connectedSince.IsZero() || namespace == "" {
+		return
+	}
+	if err := adapterutil.RenewViolationSource(ctx, k8sClient, namespace, engine, connectedSince); err != nil {
+		logger.Error(err, "failed to renew the violation source Lease", "Namespace", namespace)
+	}
+}
+
+// resolve maps the KubeArmorPolicy or KubeArmorClusterPolicy that raised the
+// alert back to the bindings and intents it enforces, through the
+// NimbusPolicies or ClusterNimbusPolicy owning it. Alerts of policies not
+// generated by the adapter don't have any target.
+func resolve(ctx context.Context, k8sClient client.Client, alert *Alert) resolved {
+	if alert.PolicyName == "" {
+		return resolved{}
+	}
+
+	var policy client.Object
+	var policyFullName string
+
+	ksp := &kubearmorv1.KubeArmorPolicy{}
+	kcsp := &kubearmorclusterv1.KubeArmorClusterPolicy{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: alert.PolicyName, Namespace: alert.NamespaceName}, ksp); err == nil {
+		policy, policyFullName = ksp, "KubeArmorPolicy/"+ksp.Name
+	} else if err := k8sClient.Get(ctx, types.NamespacedName{Name: alert.PolicyName}, kcsp); err == nil {
+		policy, policyFullName = kcsp, "KubeArmorClusterPolicy/"+kcsp.Name
+	} else {
+		return resolved{}
+	}
+
+	if policy.GetAnnotations()["app.kubernetes.io/managed-by"] != adapterName {
+		return resolved{}
+	}
+
+	ids := intentsOf(policy, alert)
+	var targets []target
+	for _, ownerRef := range policy.GetOwnerReferences() {
+		switch ownerRef.Kind {
+		case "NimbusPolicy":
+			np := &v1alpha1.NimbusPolicy{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{Name: ownerRef.Name, Namespace: policy.GetNamespace()}, np); err != nil {
+				continue
+			}
+			targets = append(targets, targetsOf(np, np.Spec.NimbusRules, ids)...)
+		case "ClusterNimbusPolicy":
+			cwnp := &v1alpha1.ClusterNimbusPolicy{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{Name: ownerRef.Name}, cwnp); err != nil {
+				continue
+			}
+			targets = append(targets, targetsOf(cwnp, cwnp.Spec.NimbusRules, ids)...)
+		}
+	}
+
+	return resolved{policy: policyFullName, targets: targets}
+}
+
+// intentsOf returns the IDs of the intents the policy enforces. Consolidated
+// policies enforce several intents, in which case only the ones the rule that
+// raised the alert is tagged with are returned.
+func intentsOf(policy client.Object, alert *Alert) []string {
+	intents := policy.GetAnnotations()[adapterutil.IntentsAnnotation]
+	if intents == "" {
+		return nil
+	}
+	ids := strings.Split(intents, ",")
+	if len(ids) == 1 {
+		return ids
+	}
+
+	tags := strings.Split(alert.Tags, ",")
+	tagged := slices.DeleteFunc(slices.Clone(ids), func(id string) bool {
+		return !slices.Contains(tags, id)
+	})
+	if len(tagged) == 0 {
+		return ids
+	}
+	return tagged
+}
+
+// targetsOf returns the given intents of the NimbusPolicy or
+// ClusterNimbusPolicy, along with the binding that owns it.
+func targetsOf(nimbusPolicy client.Object, rules []v1alpha1.NimbusRules, ids []string) []target {
+	ownerRefs := nimbusPolicy.GetOwnerReferences()
+	if len(ownerRefs) == 0 {
+		return nil
+	}
+
+	binding := bindingRef{Kind: ownerRefs[0].Kind, Name: ownerRefs[0].Name}
+	switch binding.Kind {
+	case "SecurityIntentBinding":
+		binding.Namespace = nimbusPolicy.GetNamespace()
+	case "ClusterSecurityIntentBinding":
+	default:
+		return nil
+	}
+
+	var targets []target
+	for _, rule := range rules {
+		if slices.Contains(ids, rule.ID) {
+			targets = append(targets, target{binding: binding, id: rule.ID})
+		}
+	}
+	return targets
+}
+
+// observe counts the alert as a violation of the given intent.
+func observe(violations []v1alpha1.IntentViolation, id, policy string, alert *Alert) []v1alpha1.IntentViolation {
+	seen := metav1.Now()
+	if alert.Timestamp > 0 {
+		seen = metav1.NewTime(time.Unix(alert.Timestamp, 0))
+	}
+	sample := v1alpha1.ViolationSample{
+		Time:      seen,
+		Policy:    policy,
+		Namespace: alert.NamespaceName,
+		Pod:       alert.PodName,
+		Container: alert.ContainerName,
+		Operation: alert.Operation,
+		Resource:  alert.Resource,
+		Source:    alert.Source,
+		Action:    alert.Action,
+	}
+
+	idx := slices.IndexFunc(violations, func(v v1alpha1.IntentViolation) bool {
+		return v.ID == id
+	})
+	if idx < 0 {
+		return append(violations, v1alpha1.IntentViolation{
+			ID:       id,
+			Engine:   engine,
+			Count:    1,
+			LastSeen: seen,
+			Samples:  []v1alpha1.ViolationSample{sample},
+		})
+	}
+
+	violation := &violations[idx]
+	violation.Count++
+	if seen.After(violation.LastSeen.Time) {
+		violation.LastSeen = seen
+	}
+	violation.Samples = append([]v1alpha1.ViolationSample{sample}, violation.Samples...)
+	if len(violation.Samples) > v1alpha1.MaxViolationSamples {
+		violation.Samples = violation.Samples[:v1alpha1.MaxViolationSamples]
+	}
+	return violations
+}
+
+// flush adds the violations observed since the previous flush to the status
+// of the bindings.
+func flush(ctx context.Context, logger logr.Logger, k8sClient client.Client, recorder record.EventRecorder, violations map[bindingRef][]v1alpha1.IntentViolation) {
+	for binding, observed := range violations {
+		if err := adapterutil.UpdateBindingViolations(ctx, k8sClient, binding.Kind, binding.Name, binding.Namespace, observed); err != nil {
+			logger.Error(err, "failed to report violations", "Binding.Kind", binding.Kind, "Binding.Name", binding.Name,
+				"Binding.Namespace", binding.Namespace)
+			continue
+		}
+
+		var object client.Object = &v1alpha1.SecurityIntentBinding{}
+		if binding.Kind == "ClusterSecurityIntentBinding" {
+			object = &v1alpha1.ClusterSecurityIntentBinding{}
+		}
+		if err := k8sClient.Get(ctx, types.NamespacedName{Name: binding.Name, Namespace: binding.Namespace}, object); err != nil {
+			continue
+		}
+		for _, violation := range observed {
+			adapterutil.RecordIntentViolated(recorder, object, violation)
+		}
+	}
+}
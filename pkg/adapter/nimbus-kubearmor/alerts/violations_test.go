@@ -0,0 +1,141 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package alerts_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	kubearmorv1 "github.com/kubearmor/KubeArmor/pkg/KubeArmorController/api/security.kubearmor.com/v1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/5GSEC/nimbus/api/v1alpha1"
+	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
+
+	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-kubearmor/alerts"
+	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-kubearmor/alerts/relaytest"
+)
+
+func TestWatch(t *testing.T) {
+	defer alerts.SetFlushInterval(100 * time.Millisecond)()
+
+	scheme := runtime.NewScheme()
+	utilruntime.Must(v1alpha1.AddToScheme(scheme))
+	utilruntime.Must(kubearmorv1.AddToScheme(scheme))
+	utilruntime.Must(coordinationv1.AddToScheme(scheme))
+
+	sib := &v1alpha1.SecurityIntentBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "binding", Namespace: "default", UID: "sib-uid"},
+	}
+	np := &v1alpha1.NimbusPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "binding", Namespace: "default", UID: "np-uid",
+			OwnerReferences: []metav1.OwnerReference{{Kind: "SecurityIntentBinding", Name: sib.Name, UID: sib.UID}}},
+		Spec: v1alpha1.NimbusPolicySpec{NimbusRules: []v1alpha1.NimbusRules{
+			{ID: "escapeToHost", Rule: v1alpha1.Rule{RuleAction: v1alpha1.ActionBlock}},
+			{ID: "swDeploymentTools", Rule: v1alpha1.Rule{RuleAction: v1alpha1.ActionBlock}},
+		}},
+	}
+	// A consolidated policy, enforcing both intents.
+	ksp := &kubearmorv1.KubeArmorPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "nimbus-consolidated-block-12345678", Namespace: "default",
+			Annotations: map[string]string{
+				"app.kubernetes.io/managed-by": "nimbus-kubearmor",
+				adapterutil.IntentsAnnotation:  "escapeToHost,swDeploymentTools",
+			},
+			OwnerReferences: []metav1.OwnerReference{{Kind: "NimbusPolicy", Name: np.Name, UID: np.UID}}},
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(sib, np, ksp).
+		WithStatusSubresource(&v1alpha1.SecurityIntentBinding{}).Build()
+	recorder := record.NewFakeRecorder(10)
+
+	server, err := relaytest.NewServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	t.Setenv(alerts.RelayAddressEnv, server.Address)
+	t.Setenv(alerts.PodNamespaceEnv, "nimbus")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		alerts.Watch(ctx, k8sClient, recorder)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// The rule that raised the alert is tagged with the intent it enforces.
+	seen := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	server.Send(&alerts.Alert{
+		Timestamp:     seen.Unix(),
+		NamespaceName: "default",
+		PodName:       "nginx",
+		ContainerName: "nginx",
+		PolicyName:    ksp.Name,
+		Tags:          "swDeploymentTools",
+		Operation:     "Process",
+		Resource:      "/usr/bin/apt",
+		Action:        "Block",
+	})
+
+	var violations []v1alpha1.IntentViolation
+	deadline := time.Now().Add(10 * time.Second)
+	for len(violations) == 0 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+		latestSib := &v1alpha1.SecurityIntentBinding{}
+		if err := k8sClient.Get(ctx, types.NamespacedName{Name: sib.Name, Namespace: sib.Namespace}, latestSib); err != nil {
+			t.Fatal(err)
+		}
+		violations = latestSib.Status.Violations
+	}
+
+	if len(violations) != 1 {
+		t.Fatalf("violations = %+v, want one of swDeploymentTools", violations)
+	}
+	violation := violations[0]
+	if violation.ID != "swDeploymentTools" || violation.Engine != "kubearmor" || violation.Count != 1 {
+		t.Errorf("violation = %s by %s %d time(s), want swDeploymentTools by kubearmor once",
+			violation.ID, violation.Engine, violation.Count)
+	}
+	if !violation.LastSeen.Time.Equal(seen) {
+		t.Errorf("last seen = %s, want %s", violation.LastSeen.Time, seen)
+	}
+	if len(violation.Samples) != 1 {
+		t.Fatalf("samples = %+v, want one", violation.Samples)
+	}
+	sample := violation.Samples[0]
+	if sample.Policy != "KubeArmorPolicy/"+ksp.Name || sample.Pod != "nginx" || sample.Resource != "/usr/bin/apt" {
+		t.Errorf("sample = %+v, want the one of the alert", sample)
+	}
+
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, adapterutil.ReasonIntentViolated) {
+			t.Errorf("event = %q, want an %s one", event, adapterutil.ReasonIntentViolated)
+		}
+	default:
+		t.Errorf("no %s event recorded", adapterutil.ReasonIntentViolated)
+	}
+
+	// The adapter advertises it relays the alerts of KubeArmor.
+	sources, err := adapterutil.ActiveViolationSources(ctx, k8sClient, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := sources["kubearmor"]; !ok {
+		t.Errorf("violation sources = %v, want kubearmor", sources)
+	}
+}
+
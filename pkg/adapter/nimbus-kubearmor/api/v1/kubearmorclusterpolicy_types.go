@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package v1
+
+import (
+	kubearmorv1 "github.com/kubearmor/KubeArmor/pkg/KubeArmorController/api/security.kubearmor.com/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// MatchNamespace matches the namespaces by name.
+	MatchNamespace = "namespace"
+	// MatchLabel matches the workloads by label, as "key=value".
+	MatchLabel = "label"
+
+	OperatorIn    = "In"
+	OperatorNotIn = "NotIn"
+)
+
+// MatchExpressionsType selects the namespaces or the workloads a
+// KubeArmorClusterPolicy applies to.
+type MatchExpressionsType struct {
+	// +kubebuilder:validation:Enum=namespace;label
+	Key string `json:"key,omitempty"`
+
+	// +kubebuilder:validation:Enum=In;NotIn
+	Operator string `json:"operator,omitempty"`
+
+	Values []string `json:"values,omitempty"`
+}
+
+// NsSelectorType selects the workloads a KubeArmorClusterPolicy applies to
+// across namespaces. All the expressions must match.
+type NsSelectorType struct {
+	MatchExpressions []MatchExpressionsType `json:"matchExpressions,omitempty"`
+}
+
+// KubeArmorClusterPolicySpec defines the desired state of KubeArmorClusterPolicy
+type KubeArmorClusterPolicySpec struct {
+	Selector NsSelectorType `json:"selector,omitempty"`
+
+	Process      kubearmorv1.ProcessType      `json:"process,omitempty"`
+	File         kubearmorv1.FileType         `json:"file,omitempty"`
+	Network      kubearmorv1.NetworkType      `json:"network,omitempty"`
+	Capabilities kubearmorv1.CapabilitiesType `json:"capabilities,omitempty"`
+	Syscalls     kubearmorv1.SyscallsType     `json:"syscalls,omitempty"`
+
+	// +kubebuilder:validation:optional
+	Severity kubearmorv1.SeverityType `json:"severity,omitempty"`
+	// +kubebuilder:validation:optional
+	Tags []string `json:"tags,omitempty"`
+	// +kubebuilder:validation:optional
+	Message string `json:"message,omitempty"`
+	// +kubebuilder:validation:optional
+	Action kubearmorv1.ActionType `json:"action,omitempty"`
+}
+
+// KubeArmorClusterPolicyStatus defines the observed state of KubeArmorClusterPolicy
+type KubeArmorClusterPolicyStatus struct {
+	PolicyStatus string `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster,shortName="csp"
+//+kubebuilder:subresource:status
+
+// KubeArmorClusterPolicy is the Schema for the kubearmorclusterpolicies API
+type KubeArmorClusterPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   KubeArmorClusterPolicySpec   `json:"spec,omitempty"`
+	Status KubeArmorClusterPolicyStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// KubeArmorClusterPolicyList contains a list of KubeArmorClusterPolicy
+type KubeArmorClusterPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []KubeArmorClusterPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&KubeArmorClusterPolicy{}, &KubeArmorClusterPolicyList{})
+}
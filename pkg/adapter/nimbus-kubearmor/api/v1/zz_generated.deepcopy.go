@@ -0,0 +1,154 @@
+//go:build !ignore_autogenerated
+
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+// Code generated by controller-gen. DO NOT EDIT.
+
+package v1
+
+import (
+	runtime "k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeArmorClusterPolicy) DeepCopyInto(out *KubeArmorClusterPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeArmorClusterPolicy.
+func (in *KubeArmorClusterPolicy) DeepCopy() *KubeArmorClusterPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeArmorClusterPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KubeArmorClusterPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeArmorClusterPolicyList) DeepCopyInto(out *KubeArmorClusterPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]KubeArmorClusterPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeArmorClusterPolicyList.
+func (in *KubeArmorClusterPolicyList) DeepCopy() *KubeArmorClusterPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeArmorClusterPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KubeArmorClusterPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeArmorClusterPolicySpec) DeepCopyInto(out *KubeArmorClusterPolicySpec) {
+	*out = *in
+	in.Selector.DeepCopyInto(&out.Selector)
+	in.Process.DeepCopyInto(&out.Process)
+	in.File.DeepCopyInto(&out.File)
+	in.Network.DeepCopyInto(&out.Network)
+	in.Capabilities.DeepCopyInto(&out.Capabilities)
+	in.Syscalls.DeepCopyInto(&out.Syscalls)
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeArmorClusterPolicySpec.
+func (in *KubeArmorClusterPolicySpec) DeepCopy() *KubeArmorClusterPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeArmorClusterPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeArmorClusterPolicyStatus) DeepCopyInto(out *KubeArmorClusterPolicyStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeArmorClusterPolicyStatus.
+func (in *KubeArmorClusterPolicyStatus) DeepCopy() *KubeArmorClusterPolicyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeArmorClusterPolicyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MatchExpressionsType) DeepCopyInto(out *MatchExpressionsType) {
+	*out = *in
+	if in.Values != nil {
+		in, out := &in.Values, &out.Values
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MatchExpressionsType.
+func (in *MatchExpressionsType) DeepCopy() *MatchExpressionsType {
+	if in == nil {
+		return nil
+	}
+	out := new(MatchExpressionsType)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NsSelectorType) DeepCopyInto(out *NsSelectorType) {
+	*out = *in
+	if in.MatchExpressions != nil {
+		in, out := &in.MatchExpressions, &out.MatchExpressions
+		*out = make([]MatchExpressionsType, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NsSelectorType.
+func (in *NsSelectorType) DeepCopy() *NsSelectorType {
+	if in == nil {
+		return nil
+	}
+	out := new(NsSelectorType)
+	in.DeepCopyInto(out)
+	return out
+}
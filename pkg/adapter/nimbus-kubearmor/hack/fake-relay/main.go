@@ -0,0 +1,55 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+// fake-relay is a fake KubeArmor relay streaming the alerts read from the
+// standard input, one JSON object per line, to the adapter. Run the adapter
+// with KUBEARMOR_RELAY_ADDRESS set to the address it listens on, e.g.
+//
+//	echo '{"NamespaceName":"default","PodName":"nginx","PolicyName":"pkg-mgr-execution-binding-swdeploymenttools","Operation":"Process","Resource":"/usr/bin/apt","Action":"Block"}' \
+//	  | go run ./hack/fake-relay -address 127.0.0.1:32767
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-kubearmor/alerts"
+	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-kubearmor/alerts/relaytest"
+)
+
+func main() {
+	address := flag.String("address", "127.0.0.1:32767", "Address to listen on")
+	flag.Parse()
+
+	server, err := relaytest.NewServer(*address)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer server.Close()
+	fmt.Fprintln(os.Stderr, "Fake KubeArmor relay listening on", server.Address)
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		alert := &alerts.Alert{}
+		if err := json.Unmarshal(scanner.Bytes(), alert); err != nil {
+			fmt.Fprintln(os.Stderr, "Skipping invalid alert:", err)
+			continue
+		}
+		if alert.Timestamp == 0 {
+			alert.Timestamp = time.Now().Unix()
+		}
+		server.Send(alert)
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// Let the adapter receive the last alert before breaking the stream.
+	time.Sleep(time.Second)
+}
@@ -0,0 +1,76 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package manager
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/5GSEC/nimbus/api/v1alpha1"
+	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
+
+	kubearmorclusterv1 "github.com/5GSEC/nimbus/pkg/adapter/nimbus-kubearmor/api/v1"
+	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-kubearmor/processor"
+)
+
+func reconcileKcsp(ctx context.Context, cwnpName string, deleted bool) {
+	logger := log.FromContext(ctx)
+	if cwnpName == "" {
+		return
+	}
+	if deleted {
+		logger.V(2).Info("Reconciling deleted KubeArmorClusterPolicy", "ClusterNimbusPolicy.Name", cwnpName)
+	} else {
+		logger.V(2).Info("Reconciling modified KubeArmorClusterPolicy", "ClusterNimbusPolicy.Name", cwnpName)
+	}
+	createOrUpdateKcsp(ctx, cwnpName)
+}
+
+func createOrUpdateKcsp(ctx context.Context, cwnpName string) {
+	adapterutil.ReconcileClusterPolicies(ctx, k8sClient, scheme, recorder, adapterName, "kubearmor", cwnpName, adapterutil.ClusterPolicies[kubearmorclusterv1.KubeArmorClusterPolicy]{
+		Kind: "KubeArmorClusterPolicy",
+		Prepare: func(ctx context.Context, cwnp *v1alpha1.ClusterNimbusPolicy) {
+			selectorSupported := processor.SelectsClusterWorkloads(cwnp.Spec.WorkloadSelector)
+			adapterutil.RecordUnsupportedSelector(recorder, cwnp, "kubearmor", selectorSupported)
+			if err := adapterutil.UpdateUnsupportedSelector(ctx, k8sClient, "ClusterNimbusPolicy", cwnp.Name, cwnp.Namespace, "kubearmor", selectorSupported); err != nil {
+				log.FromContext(ctx).Error(err, "failed to update unsupported selectors status in ClusterNimbusPolicy", "ClusterNimbusPolicy.Name", cwnp.Name)
+			}
+		},
+		Build: func(ctx context.Context, cwnp *v1alpha1.ClusterNimbusPolicy) []kubearmorclusterv1.KubeArmorClusterPolicy {
+			return processor.BuildKcspsFrom(log.FromContext(ctx), cwnp)
+		},
+		List: func(ctx context.Context) ([]kubearmorclusterv1.KubeArmorClusterPolicy, error) {
+			var kcsps kubearmorclusterv1.KubeArmorClusterPolicyList
+			err := k8sClient.List(ctx, &kcsps)
+			return kcsps.Items, err
+		},
+	})
+}
+
+func logKcspToDelete(ctx context.Context, deletedCwnp *unstructured.Unstructured) {
+	logger := log.FromContext(ctx)
+	var kcsps kubearmorclusterv1.KubeArmorClusterPolicyList
+
+	if err := k8sClient.List(ctx, &kcsps); err != nil {
+		logger.Error(err, "failed to list KubeArmorClusterPolicies")
+		return
+	}
+
+	// Kubernetes GC automatically deletes the child when the parent/owner is
+	// deleted. So, we don't need to delete the policy because ClusterNimbusPolicy
+	// is the owner and when it gets deleted all the corresponding policies will be
+	// automatically deleted.
+	for _, kcsp := range kcsps.Items {
+		for _, ownerRef := range kcsp.OwnerReferences {
+			if ownerRef.Name == deletedCwnp.GetName() && ownerRef.UID == deletedCwnp.GetUID() {
+				logger.Info("KubeArmorClusterPolicy already deleted due to ClusterNimbusPolicy deletion",
+					"KubeArmorClusterPolicy.Name", kcsp.Name, "ClusterNimbusPolicy.Name", deletedCwnp.GetName(),
+				)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package manager
+
+import (
+	"context"
+
+	kubearmorv1 "github.com/kubearmor/KubeArmor/pkg/KubeArmorController/api/security.kubearmor.com/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/5GSEC/nimbus/api/v1alpha1"
+	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
+
+	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-kubearmor/processor"
+)
+
+// consolidateKsps reconciles the KubeArmorPolicies of all the NimbusPolicies in
+// the given namespace in consolidation mode, see
+// adapterutil.ConsolidatePolicies.
+func consolidateKsps(ctx context.Context, namespace string) {
+	adapterutil.ConsolidatePolicies(ctx, k8sClient, scheme, recorder, adapterName, "kubearmor", namespace, kspConsolidator{})
+}
+
+// kspConsolidator consolidates the KubeArmorPolicies. The NimbusPolicies of the
+// ClusterSecurityIntentBindings are enforced by KubeArmorClusterPolicies
+// instead.
+type kspConsolidator struct{}
+
+func (kspConsolidator) Kind() string {
+	return "KubeArmorPolicy"
+}
+
+func (kspConsolidator) Prepare(ctx context.Context, np *v1alpha1.NimbusPolicy, consolidated bool) {
+	if !consolidated {
+		return
+	}
+	selectorSupported := processor.SelectsNamespacedWorkloads(np.Spec.Selector)
+	adapterutil.RecordUnsupportedSelector(recorder, np, "kubearmor", selectorSupported)
+	if err := adapterutil.UpdateUnsupportedSelector(ctx, k8sClient, "NimbusPolicy", np.Name, np.Namespace, "kubearmor", selectorSupported); err != nil {
+		log.FromContext(ctx).Error(err, "failed to update unsupported selectors status in NimbusPolicy", "NimbusPolicy.Name", np.Name, "NimbusPolicy.Namespace", np.Namespace)
+	}
+}
+
+func (kspConsolidator) Consolidate(ctx context.Context, nps []v1alpha1.NimbusPolicy) []adapterutil.ConsolidatedPolicy {
+	var consolidated []adapterutil.ConsolidatedPolicy
+	for _, c := range processor.ConsolidateKsps(log.FromContext(ctx), nps) {
+		ksp := c.Ksp
+		consolidated = append(consolidated, adapterutil.ConsolidatedPolicy{Policy: &ksp, Owners: c.Owners})
+	}
+	return consolidated
+}
+
+func (kspConsolidator) NewPolicy() client.Object {
+	return &kubearmorv1.KubeArmorPolicy{}
+}
+
+func (kspConsolidator) ListPolicies(ctx context.Context, namespace string) ([]client.Object, error) {
+	var ksps kubearmorv1.KubeArmorPolicyList
+	if err := k8sClient.List(ctx, &ksps, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	policies := make([]client.Object, 0, len(ksps.Items))
+	for idx := range ksps.Items {
+		policies = append(policies, &ksps.Items[idx])
+	}
+	return policies, nil
+}
+
+func (kspConsolidator) Changed(existing, built client.Object) (string, bool) {
+	existingKsp, builtKsp := existing.(*kubearmorv1.KubeArmorPolicy), built.(*kubearmorv1.KubeArmorPolicy)
+	switch {
+	case !equality.Semantic.DeepEqual(existingKsp.Spec, builtKsp.Spec):
+		return "diff: Spec", true
+	case !equality.Semantic.DeepEqual(existingKsp.Labels, builtKsp.Labels):
+		return "diff: Labels", true
+	case !equality.Semantic.DeepEqual(existingKsp.Annotations, builtKsp.Annotations):
+		return "diff: Annotations", true
+	case !equality.Semantic.DeepEqual(existingKsp.OwnerReferences, builtKsp.OwnerReferences):
+		return "diff: OwnerReferences", true
+	}
+	return "", false
+}
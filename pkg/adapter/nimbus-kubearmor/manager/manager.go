@@ -6,6 +6,7 @@ package manager
 import (
 	"context"
 	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	kubearmorv1 "github.com/kubearmor/KubeArmor/pkg/KubeArmorController/api/security.kubearmor.com/v1"
@@ -14,6 +15,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
@@ -21,22 +23,31 @@ import (
 	"github.com/5GSEC/nimbus/api/v1alpha1"
 	"github.com/5GSEC/nimbus/pkg/adapter/common"
 	"github.com/5GSEC/nimbus/pkg/adapter/k8s"
+	"github.com/5GSEC/nimbus/pkg/adapter/metrics"
 	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
 	globalwatcher "github.com/5GSEC/nimbus/pkg/adapter/watcher"
+	"github.com/5GSEC/nimbus/pkg/tracing"
 
+	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-kubearmor/alerts"
+	kubearmorclusterv1 "github.com/5GSEC/nimbus/pkg/adapter/nimbus-kubearmor/api/v1"
 	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-kubearmor/processor"
 	kspwatcher "github.com/5GSEC/nimbus/pkg/adapter/nimbus-kubearmor/watcher"
 )
 
+const adapterName = "nimbus-kubearmor"
+
 var (
 	scheme    = runtime.NewScheme()
 	k8sClient client.Client
+	recorder  record.EventRecorder
 )
 
 func init() {
 	utilruntime.Must(v1alpha1.AddToScheme(scheme))
 	utilruntime.Must(kubearmorv1.AddToScheme(scheme))
+	utilruntime.Must(kubearmorclusterv1.AddToScheme(scheme))
 	k8sClient = k8s.NewOrDie(scheme)
+	recorder = k8s.NewEventRecorderOrDie(scheme, adapterName)
 }
 
 func Run(ctx context.Context) {
@@ -44,32 +55,65 @@ func Run(ctx context.Context) {
 	deletedNpCh := make(chan *unstructured.Unstructured)
 	go globalwatcher.WatchNimbusPolicies(ctx, npCh, deletedNpCh, "SecurityIntentBinding", "ClusterSecurityIntentBinding")
 
+	clusterNpCh := make(chan string)
+	deletedClusterNpCh := make(chan *unstructured.Unstructured)
+	go globalwatcher.WatchClusterNimbusPolicies(ctx, clusterNpCh, deletedClusterNpCh)
+
 	updatedKspCh := make(chan common.Request)
 	deletedKspCh := make(chan common.Request)
 	go kspwatcher.WatchKsps(ctx, updatedKspCh, deletedKspCh)
 
+	updatedKcspCh := make(chan string)
+	deletedKcspCh := make(chan string)
+	go kspwatcher.WatchKcsps(ctx, updatedKcspCh, deletedKcspCh)
+
+	go alerts.Watch(ctx, k8sClient, recorder)
+
 	for {
 		select {
 		case <-ctx.Done():
 			close(npCh)
 			close(deletedNpCh)
+			close(clusterNpCh)
+			close(deletedClusterNpCh)
 			close(updatedKspCh)
 			close(deletedKspCh)
+			close(updatedKcspCh)
+			close(deletedKcspCh)
 			return
 		case createdNp := <-npCh:
 			createOrUpdateKsp(ctx, createdNp.Name, createdNp.Namespace)
+		case createdCwnp := <-clusterNpCh:
+			createOrUpdateKcsp(ctx, createdCwnp)
+		case deletedCwnp := <-deletedClusterNpCh:
+			logKcspToDelete(ctx, deletedCwnp)
 		case deletedNp := <-deletedNpCh:
+			if adapterutil.ConsolidationEnabled() {
+				// The KubeArmorPolicies shared with other NimbusPolicies aren't
+				// garbage collected, so drop the rules of the deleted one.
+				consolidateKsps(ctx, deletedNp.GetNamespace())
+				continue
+			}
 			logKspToDelete(ctx, deletedNp)
 		case updatedKsp := <-updatedKspCh:
 			reconcileKsp(ctx, updatedKsp.Name, updatedKsp.Namespace, false)
 		case deletedKsp := <-deletedKspCh:
 			reconcileKsp(ctx, deletedKsp.Name, deletedKsp.Namespace, true)
+		case updatedKcsp := <-updatedKcspCh:
+			reconcileKcsp(ctx, updatedKcsp, false)
+		case deletedKcsp := <-deletedKcspCh:
+			reconcileKcsp(ctx, deletedKcsp, true)
 		}
 	}
 }
 
 func reconcileKsp(ctx context.Context, kspName, namespace string, deleted bool) {
 	logger := log.FromContext(ctx)
+	if adapterutil.ConsolidationEnabled() {
+		logger.V(2).Info("Reconciling consolidated KubeArmorPolicies", "KubeArmorPolicy.Name", kspName, "KubeArmorPolicy.Namespace", namespace)
+		consolidateKsps(ctx, namespace)
+		return
+	}
 	npName := adapterutil.ExtractAnyNimbusPolicyName(kspName)
 	var np v1alpha1.NimbusPolicy
 	err := k8sClient.Get(ctx, types.NamespacedName{Name: npName, Namespace: namespace}, &np)
@@ -88,7 +132,13 @@ func reconcileKsp(ctx context.Context, kspName, namespace string, deleted bool)
 }
 
 func createOrUpdateKsp(ctx context.Context, npName, npNamespace string) {
+	defer metrics.ObserveReconcile(adapterName, time.Now())
 	logger := log.FromContext(ctx)
+	if adapterutil.ConsolidationEnabled() {
+		consolidateKsps(ctx, npNamespace)
+		return
+	}
+
 	var np v1alpha1.NimbusPolicy
 	if err := k8sClient.Get(ctx, types.NamespacedName{Name: npName, Namespace: npNamespace}, &np); err != nil {
 		logger.Error(err, "failed to get NimbusPolicy", "NimbusPolicy.Name", npName, "NimbusPolicy.Namespace", npNamespace)
@@ -100,8 +150,35 @@ func createOrUpdateKsp(ctx context.Context, npName, npNamespace string) {
 		return
 	}
 
+	// The ClusterSecurityIntentBindings are enforced by KubeArmorClusterPolicies
+	// built from their ClusterNimbusPolicy, so only drop the KSPs built from the
+	// NimbusPolicies generated for them by former versions.
+	if adapterutil.IsOrphan(np.GetOwnerReferences(), "SecurityIntentBinding") {
+		logger.V(4).Info("Ignoring NimbusPolicy of a ClusterSecurityIntentBinding", "NimbusPolicy.Name", npName, "NimbusPolicy.Namespace", npNamespace)
+		np.Spec.NimbusRules = nil
+		deleteDanglingKsps(ctx, np, logger)
+		return
+	}
+
+	// Continue the trace of the reconciliation that changed the NimbusPolicy.
+	ctx, span := tracing.Start(tracing.Extract(ctx, &np), adapterName+".Reconcile", tracing.WithObject("NimbusPolicy", &np))
+	defer span.End()
+
 	deleteDanglingKsps(ctx, np, logger)
+	selectorSupported := processor.SelectsNamespacedWorkloads(np.Spec.Selector)
+	adapterutil.RecordUnsupportedSelector(recorder, &np, "kubearmor", selectorSupported)
+	if err := adapterutil.UpdateUnsupportedSelector(ctx, k8sClient, "NimbusPolicy", np.Name, np.Namespace, "kubearmor", selectorSupported); err != nil {
+		logger.Error(err, "failed to update unsupported selectors status in NimbusPolicy", "NimbusPolicy.Name", np.Name, "NimbusPolicy.Namespace", np.Namespace)
+	}
+	adapterutil.RecordUnsupportedIntents(recorder, &np, np.Spec.NimbusRules, "kubearmor")
+	adapterutil.RecordUnsupportedActions(recorder, &np, np.Spec.NimbusRules, "kubearmor")
+	if err := adapterutil.UpdateUnsupportedActions(ctx, k8sClient, "NimbusPolicy", np.Name, np.Namespace, "kubearmor", np.Spec.NimbusRules); err != nil {
+		logger.Error(err, "failed to update unsupported actions status in NimbusPolicy", "NimbusPolicy.Name", np.Name, "NimbusPolicy.Namespace", np.Namespace)
+	}
+	metrics.UnsupportedIntents(adapterName, &np, np.Spec.NimbusRules, "kubearmor")
+	_, translateSpan := tracing.Start(ctx, "KubeArmorPolicy.Translate")
 	ksps := processor.BuildKspsFrom(logger, &np)
+	translateSpan.End()
 
 	// Iterate using a separate index variable to avoid aliasing
 	for idx := range ksps {
@@ -121,19 +198,33 @@ func createOrUpdateKsp(ctx context.Context, npName, npNamespace string) {
 		}
 		if err != nil {
 			if errors.IsNotFound(err) {
-				if err = k8sClient.Create(ctx, &ksp); err != nil {
+				if err = tracing.Trace(ctx, "KubeArmorPolicy.Create", func(ctx context.Context) error {
+					return k8sClient.Create(ctx, &ksp)
+				}, tracing.WithObject("KubeArmorPolicy", &ksp)); err != nil {
 					logger.Error(err, "failed to create KubeArmorPolicy", "KubeArmorPolicy.Name", ksp.Name, "KubeArmorPolicy.Namespace", ksp.Namespace)
+					adapterutil.RecordPolicyFailed(recorder, &np, "KubeArmorPolicy/"+ksp.Name, err)
+					metrics.PolicyFailed(adapterName, "KubeArmorPolicy")
 					return
 				}
 				logger.Info("KubeArmorPolicy created", "KubeArmorPolicy.Name", ksp.Name, "KubeArmorPolicy.Namespace", ksp.Namespace)
+				adapterutil.RecordPolicyCreated(recorder, &np, "KubeArmorPolicy/"+ksp.Name)
+				metrics.PolicyGenerated(adapterName, "KubeArmorPolicy", &ksp)
 			}
 		} else {
 			ksp.ObjectMeta.ResourceVersion = existingKsp.ObjectMeta.ResourceVersion
-			if err = k8sClient.Update(ctx, &ksp); err != nil {
+			if err = tracing.Trace(ctx, "KubeArmorPolicy.Update", func(ctx context.Context) error {
+				return k8sClient.Update(ctx, &ksp)
+			}, tracing.WithObject("KubeArmorPolicy", &ksp)); err != nil {
 				logger.Error(err, "failed to configure existing KubeArmorPolicy", "KubeArmorPolicy.Name", existingKsp.Name, "KubeArmorPolicy.Namespace", existingKsp.Namespace)
+				adapterutil.RecordPolicyFailed(recorder, &np, "KubeArmorPolicy/"+ksp.Name, err)
+				metrics.PolicyFailed(adapterName, "KubeArmorPolicy")
 				return
 			}
 			logger.Info("KubeArmorPolicy configured", "KubeArmorPolicy.Name", existingKsp.Name, "KubeArmorPolicy.Namespace", existingKsp.Namespace)
+			if ksp.GetGeneration() != existingKsp.GetGeneration() {
+				adapterutil.RecordPolicyConfigured(recorder, &np, "KubeArmorPolicy/"+ksp.Name)
+				metrics.PolicyGenerated(adapterName, "KubeArmorPolicy", &ksp)
+			}
 		}
 
 		if err = adapterutil.UpdateNpStatus(ctx, k8sClient, "KubeArmorPolicy/"+ksp.Name, np.Name, np.Namespace, false); err != nil {
@@ -206,5 +297,6 @@ func deleteDanglingKsps(ctx context.Context, np v1alpha1.NimbusPolicy, logger lo
 			logger.Error(err, "failed to update KubeArmorPolicy status in NimbusPolicy")
 		}
 		logger.Info("Dangling KubeArmorPolicy deleted", "KubeArmorPolicy.Name", ksp.Name, "KubeArmorPolicy.Namespace", ksp.Namespace)
+		adapterutil.RecordDanglingPolicyDeleted(recorder, &np, "KubeArmorPolicy/"+ksp.Name)
 	}
 }
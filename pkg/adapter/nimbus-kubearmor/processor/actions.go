@@ -0,0 +1,32 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package processor
+
+import (
+	kubearmorv1 "github.com/kubearmor/KubeArmor/pkg/KubeArmorController/api/security.kubearmor.com/v1"
+
+	v1alpha1 "github.com/5GSEC/nimbus/api/v1alpha1"
+)
+
+// kspActions maps the Nimbus actions to the KubeArmorPolicy actions.
+//
+//	| Nimbus | KubeArmor |
+//	|--------|-----------|
+//	| Audit  | Audit     |
+//	| Block  | Block     |
+//
+// The intents supported by KubeArmor describe behaviors to deny, so Allow,
+// which would turn them into an allow list, isn't supported. Neither are Warn
+// and Mutate, which KubeArmor has no equivalent for.
+var kspActions = map[string]kubearmorv1.ActionType{
+	v1alpha1.ActionAudit: "Audit",
+	v1alpha1.ActionBlock: "Block",
+}
+
+// kspActionFor returns the KubeArmorPolicy action for the given Nimbus action,
+// and whether KubeArmor supports it.
+func kspActionFor(action string) (kubearmorv1.ActionType, bool) {
+	kspAction, ok := kspActions[action]
+	return kspAction, ok
+}
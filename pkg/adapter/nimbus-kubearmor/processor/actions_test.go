@@ -0,0 +1,34 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package processor
+
+import (
+	"testing"
+
+	kubearmorv1 "github.com/kubearmor/KubeArmor/pkg/KubeArmorController/api/security.kubearmor.com/v1"
+
+	v1alpha1 "github.com/5GSEC/nimbus/api/v1alpha1"
+)
+
+func TestKspActionFor(t *testing.T) {
+	tests := []struct {
+		action    string
+		kspAction kubearmorv1.ActionType
+		supported bool
+	}{
+		{v1alpha1.ActionAudit, "Audit", true},
+		{v1alpha1.ActionBlock, "Block", true},
+		{v1alpha1.ActionWarn, "", false},
+		{v1alpha1.ActionAllow, "", false},
+		{v1alpha1.ActionMutate, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			kspAction, ok := kspActionFor(tt.action)
+			if kspAction != tt.kspAction || ok != tt.supported {
+				t.Errorf("kspActionFor(%q) = %q, %v, want %q, %v", tt.action, kspAction, ok, tt.kspAction, tt.supported)
+			}
+		})
+	}
+}
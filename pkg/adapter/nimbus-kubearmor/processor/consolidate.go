@@ -0,0 +1,123 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package processor
+
+import (
+	"strings"
+
+	"github.com/go-logr/logr"
+	kubearmorv1 "github.com/kubearmor/KubeArmor/pkg/KubeArmorController/api/security.kubearmor.com/v1"
+
+	v1alpha1 "github.com/5GSEC/nimbus/api/v1alpha1"
+	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
+)
+
+// ConsolidatedKsp is a KubeArmorPolicy built for one or more NimbusPolicies,
+// along with these NimbusPolicies.
+type ConsolidatedKsp struct {
+	Ksp    kubearmorv1.KubeArmorPolicy
+	Owners []v1alpha1.NimbusPolicy
+}
+
+// ConsolidateKsps builds the KubeArmorPolicies of the given NimbusPolicies of a
+// namespace, merging the process, file and capability rules of the ones that
+// select the same workloads with the same action into a single
+// KubeArmorPolicy. Every merged rule is tagged with the ID of the intent it
+// enforces and carries its description as message, and the merged
+// KubeArmorPolicy lists the intents it enforces in its annotations.
+// KubeArmorPolicies with network or syscall rules are kept as they are.
+func ConsolidateKsps(logger logr.Logger, nps []v1alpha1.NimbusPolicy) []ConsolidatedKsp {
+	var consolidated []ConsolidatedKsp
+	indexByName := make(map[string]int)
+
+	for idx := range nps {
+		np := nps[idx]
+		for _, ksp := range BuildKspsFrom(logger, &np) {
+			if !mergeable(ksp) {
+				consolidated = append(consolidated, ConsolidatedKsp{Ksp: ksp, Owners: []v1alpha1.NimbusPolicy{np}})
+				continue
+			}
+
+			id := ksp.Annotations[adapterutil.IntentsAnnotation]
+			traceRules(&ksp, id)
+
+			name := adapterutil.ConsolidatedPolicyName(string(ksp.Spec.Action), ksp.Spec.Selector.MatchLabels, nil)
+			if existing, ok := indexByName[name]; ok {
+				mergeKsp(&consolidated[existing], ksp, np, id)
+				continue
+			}
+
+			ksp.Name = name
+			ksp.Spec.Message = ""
+			indexByName[name] = len(consolidated)
+			consolidated = append(consolidated, ConsolidatedKsp{Ksp: ksp, Owners: []v1alpha1.NimbusPolicy{np}})
+		}
+	}
+
+	return consolidated
+}
+
+func mergeable(ksp kubearmorv1.KubeArmorPolicy) bool {
+	return len(ksp.Spec.Network.MatchProtocols) == 0 &&
+		len(ksp.Spec.Syscalls.MatchSyscalls) == 0 &&
+		len(ksp.Spec.Syscalls.MatchPaths) == 0
+}
+
+// traceRules tags the rules of a KubeArmorPolicy with the given intent ID and
+// moves the policy message to the rules, so that they can be traced back to
+// the intent once merged with other rules.
+func traceRules(ksp *kubearmorv1.KubeArmorPolicy, id string) {
+	message := ksp.Spec.Message
+
+	for i := range ksp.Spec.Process.MatchPaths {
+		ksp.Spec.Process.MatchPaths[i].Tags = append(ksp.Spec.Process.MatchPaths[i].Tags, id)
+		ksp.Spec.Process.MatchPaths[i].Message = message
+	}
+	for i := range ksp.Spec.Process.MatchDirectories {
+		ksp.Spec.Process.MatchDirectories[i].Tags = append(ksp.Spec.Process.MatchDirectories[i].Tags, id)
+		ksp.Spec.Process.MatchDirectories[i].Message = message
+	}
+	for i := range ksp.Spec.Process.MatchPatterns {
+		ksp.Spec.Process.MatchPatterns[i].Tags = append(ksp.Spec.Process.MatchPatterns[i].Tags, id)
+		ksp.Spec.Process.MatchPatterns[i].Message = message
+	}
+	for i := range ksp.Spec.File.MatchPaths {
+		ksp.Spec.File.MatchPaths[i].Tags = append(ksp.Spec.File.MatchPaths[i].Tags, id)
+		ksp.Spec.File.MatchPaths[i].Message = message
+	}
+	for i := range ksp.Spec.File.MatchDirectories {
+		ksp.Spec.File.MatchDirectories[i].Tags = append(ksp.Spec.File.MatchDirectories[i].Tags, id)
+		ksp.Spec.File.MatchDirectories[i].Message = message
+	}
+	for i := range ksp.Spec.File.MatchPatterns {
+		ksp.Spec.File.MatchPatterns[i].Tags = append(ksp.Spec.File.MatchPatterns[i].Tags, id)
+		ksp.Spec.File.MatchPatterns[i].Message = message
+	}
+	for i := range ksp.Spec.Capabilities.MatchCapabilities {
+		ksp.Spec.Capabilities.MatchCapabilities[i].Tags = append(ksp.Spec.Capabilities.MatchCapabilities[i].Tags, id)
+		ksp.Spec.Capabilities.MatchCapabilities[i].Message = message
+	}
+}
+
+// mergeKsp merges the rules of the KubeArmorPolicy built for the given intent
+// of a NimbusPolicy into a consolidated KubeArmorPolicy.
+func mergeKsp(consolidated *ConsolidatedKsp, ksp kubearmorv1.KubeArmorPolicy, np v1alpha1.NimbusPolicy, id string) {
+	merged := &consolidated.Ksp.Spec
+	merged.Process.MatchPaths = append(merged.Process.MatchPaths, ksp.Spec.Process.MatchPaths...)
+	merged.Process.MatchDirectories = append(merged.Process.MatchDirectories, ksp.Spec.Process.MatchDirectories...)
+	merged.Process.MatchPatterns = append(merged.Process.MatchPatterns, ksp.Spec.Process.MatchPatterns...)
+	merged.File.MatchPaths = append(merged.File.MatchPaths, ksp.Spec.File.MatchPaths...)
+	merged.File.MatchDirectories = append(merged.File.MatchDirectories, ksp.Spec.File.MatchDirectories...)
+	merged.File.MatchPatterns = append(merged.File.MatchPatterns, ksp.Spec.File.MatchPatterns...)
+	merged.Capabilities.MatchCapabilities = append(merged.Capabilities.MatchCapabilities, ksp.Spec.Capabilities.MatchCapabilities...)
+
+	adapterutil.AddIntents(consolidated.Ksp.Annotations, strings.Split(id, ",")...)
+
+	for _, owner := range consolidated.Owners {
+		if owner.UID == np.UID {
+			return
+		}
+	}
+	consolidated.Owners = append(consolidated.Owners, np)
+}
@@ -0,0 +1,160 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package processor
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/go-logr/logr"
+
+	v1alpha1 "github.com/5GSEC/nimbus/api/v1alpha1"
+	"github.com/5GSEC/nimbus/pkg/adapter/idpool"
+	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
+
+	kubearmorclusterv1 "github.com/5GSEC/nimbus/pkg/adapter/nimbus-kubearmor/api/v1"
+)
+
+// nsBlackList lists the namespaces never selected by the
+// KubeArmorClusterPolicies, as the controller never generates NimbusPolicies
+// for them either.
+var nsBlackList = []string{"kube-system"}
+
+// BuildKcspsFrom builds the KubeArmorClusterPolicies enforcing the intents of
+// the given ClusterNimbusPolicy in the namespaces it selects, including the
+// ones created afterward.
+func BuildKcspsFrom(logger logr.Logger, cwnp *v1alpha1.ClusterNimbusPolicy) []kubearmorclusterv1.KubeArmorClusterPolicy {
+	if !SelectsClusterWorkloads(cwnp.Spec.WorkloadSelector) {
+		logger.Info("KubeArmorClusterPolicies can't select workloads by whether they have a label, skipping the ClusterNimbusPolicy",
+			"ClusterNimbusPolicy", cwnp.Name)
+		return nil
+	}
+
+	var kcsps []kubearmorclusterv1.KubeArmorClusterPolicy
+	for _, nimbusRule := range cwnp.Spec.NimbusRules {
+		id := nimbusRule.ID
+		if !idpool.IsIdSupportedBy(id, "kubearmor") {
+			logger.Info("KubeArmor does not support this ID", "ID", id, "ClusterNimbusPolicy", cwnp.Name)
+			continue
+		}
+		action, ok := kspActionFor(nimbusRule.Rule.RuleAction)
+		if !ok {
+			logger.Info("KubeArmor does not support this action", "ID", id, "Action", nimbusRule.Rule.RuleAction,
+				"ClusterNimbusPolicy", cwnp.Name)
+			continue
+		}
+
+		if len(nimbusRule.Rule.Params[paramContainers]) > 0 {
+			logger.Info("KubeArmorClusterPolicies can't select containers, ignoring the containers param", "ID", id,
+				"ClusterNimbusPolicy", cwnp.Name)
+		}
+
+		// Leave the intent to the conflicting NimbusPolicies taking precedence
+		// in some of the namespaces.
+		spec := cwnp.Spec
+		spec.NsSelector, ok = adapterutil.RuleNsSelector(cwnp.Spec, id)
+		if !ok {
+			logger.Info("Conflicting NimbusPolicies take precedence over the intent in all the selected namespaces",
+				"ID", id, "ClusterNimbusPolicy", cwnp.Name)
+			continue
+		}
+
+		policyNames, ok := idpool.KaIDPolicies[id]
+		if !ok {
+			policyNames = []string{id}
+		}
+		for _, policyName := range policyNames {
+			kcsp := buildKcspFor(policyName, nimbusRule.Rule.Params)
+			kcsp.Name = cwnp.Name + "-" + strings.ToLower(id)
+			if policyName != id {
+				kcsp.Name += "-" + strings.ToLower(policyName)
+			}
+			kcsp.Spec.Message = nimbusRule.Description
+			kcsp.Spec.Selector = nsSelectorFor(spec)
+			kcsp.Spec.Action = action
+			kcsp.Annotations = map[string]string{"app.kubernetes.io/managed-by": "nimbus-kubearmor"}
+			adapterutil.AddIntents(kcsp.Annotations, id)
+			kcsps = append(kcsps, kcsp)
+		}
+	}
+	return kcsps
+}
+
+// buildKcspFor builds a KubeArmorClusterPolicy with the same rules as the
+// KubeArmorPolicy of the given intent ID.
+func buildKcspFor(id string, params map[string][]string) kubearmorclusterv1.KubeArmorClusterPolicy {
+	ksp := buildKspFor(id, params)
+	return kubearmorclusterv1.KubeArmorClusterPolicy{
+		Spec: kubearmorclusterv1.KubeArmorClusterPolicySpec{
+			Process:      ksp.Spec.Process,
+			File:         ksp.Spec.File,
+			Network:      ksp.Spec.Network,
+			Capabilities: ksp.Spec.Capabilities,
+			Syscalls:     ksp.Spec.Syscalls,
+		},
+	}
+}
+
+// nsSelectorFor translates the namespace and workload selectors of a
+// ClusterNimbusPolicy into the selector of a KubeArmorClusterPolicy:
+//
+//   - MatchNames: ["*"] selects all the namespaces but the blacklisted ones.
+//   - MatchNames selects the listed namespaces.
+//   - ExcludeNames selects all the namespaces but the listed and the
+//     blacklisted ones.
+//   - The MatchLabels of the workload selector select the workloads having all
+//     of them.
+//   - The In and NotIn MatchExpressions of the workload selector select the
+//     workloads having, or not, one of their labels.
+func nsSelectorFor(spec v1alpha1.ClusterNimbusPolicySpec) kubearmorclusterv1.NsSelectorType {
+	var selector kubearmorclusterv1.NsSelectorType
+
+	matchNames, excludeNames := spec.NsSelector.MatchNames, spec.NsSelector.ExcludeNames
+	switch {
+	case len(excludeNames) > 0:
+		selector.MatchExpressions = append(selector.MatchExpressions, kubearmorclusterv1.MatchExpressionsType{
+			Key:      kubearmorclusterv1.MatchNamespace,
+			Operator: kubearmorclusterv1.OperatorNotIn,
+			Values:   append(slices.Clone(nsBlackList), excludeNames...),
+		})
+	case len(matchNames) == 1 && matchNames[0] == "*":
+		selector.MatchExpressions = append(selector.MatchExpressions, kubearmorclusterv1.MatchExpressionsType{
+			Key:      kubearmorclusterv1.MatchNamespace,
+			Operator: kubearmorclusterv1.OperatorNotIn,
+			Values:   slices.Clone(nsBlackList),
+		})
+	case len(matchNames) > 0:
+		selector.MatchExpressions = append(selector.MatchExpressions, kubearmorclusterv1.MatchExpressionsType{
+			Key:      kubearmorclusterv1.MatchNamespace,
+			Operator: kubearmorclusterv1.OperatorIn,
+			Values:   slices.DeleteFunc(slices.Clone(matchNames), func(ns string) bool { return slices.Contains(nsBlackList, ns) }),
+		})
+	}
+
+	// Sort the labels to keep the selector stable across reconciliations.
+	keys := make([]string, 0, len(spec.WorkloadSelector.MatchLabels))
+	for key := range spec.WorkloadSelector.MatchLabels {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		selector.MatchExpressions = append(selector.MatchExpressions, kubearmorclusterv1.MatchExpressionsType{
+			Key:      kubearmorclusterv1.MatchLabel,
+			Operator: kubearmorclusterv1.OperatorIn,
+			Values:   []string{key + "=" + spec.WorkloadSelector.MatchLabels[key]},
+		})
+	}
+	for _, expression := range spec.WorkloadSelector.MatchExpressions {
+		labels := make([]string, 0, len(expression.Values))
+		for _, value := range expression.Values {
+			labels = append(labels, expression.Key+"="+value)
+		}
+		selector.MatchExpressions = append(selector.MatchExpressions, kubearmorclusterv1.MatchExpressionsType{
+			Key:      kubearmorclusterv1.MatchLabel,
+			Operator: string(expression.Operator),
+			Values:   labels,
+		})
+	}
+	return selector
+}
@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package processor
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/go-logr/logr"
+
+	v1alpha1 "github.com/5GSEC/nimbus/api/v1alpha1"
+	"github.com/5GSEC/nimbus/pkg/adapter/idpool"
+
+	kubearmorclusterv1 "github.com/5GSEC/nimbus/pkg/adapter/nimbus-kubearmor/api/v1"
+)
+
+func TestBuildKcspsFromExcludedNamespaces(t *testing.T) {
+	tests := []struct {
+		name       string
+		nsSelector v1alpha1.NamespaceSelector
+		// namespaces is the expected namespace expression of the
+		// dnsManipulation policy, nil when it isn't built.
+		namespaces *kubearmorclusterv1.MatchExpressionsType
+	}{
+		{
+			name:       "all namespaces",
+			nsSelector: v1alpha1.NamespaceSelector{MatchNames: []string{"*"}},
+			namespaces: &kubearmorclusterv1.MatchExpressionsType{Key: kubearmorclusterv1.MatchNamespace,
+				Operator: kubearmorclusterv1.OperatorNotIn, Values: []string{"kube-system", "dev"}},
+		},
+		{
+			name:       "excluded namespaces",
+			nsSelector: v1alpha1.NamespaceSelector{ExcludeNames: []string{"staging"}},
+			namespaces: &kubearmorclusterv1.MatchExpressionsType{Key: kubearmorclusterv1.MatchNamespace,
+				Operator: kubearmorclusterv1.OperatorNotIn, Values: []string{"kube-system", "staging", "dev"}},
+		},
+		{
+			name:       "matched namespaces",
+			nsSelector: v1alpha1.NamespaceSelector{MatchNames: []string{"dev", "prod"}},
+			namespaces: &kubearmorclusterv1.MatchExpressionsType{Key: kubearmorclusterv1.MatchNamespace,
+				Operator: kubearmorclusterv1.OperatorIn, Values: []string{"prod"}},
+		},
+		{
+			name:       "no namespace left",
+			nsSelector: v1alpha1.NamespaceSelector{MatchNames: []string{"dev"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cwnp := &v1alpha1.ClusterNimbusPolicy{
+				Spec: v1alpha1.ClusterNimbusPolicySpec{
+					NsSelector: tt.nsSelector,
+					NimbusRules: []v1alpha1.NimbusRules{
+						{ID: idpool.DNSManipulation, Rule: v1alpha1.Rule{RuleAction: v1alpha1.ActionBlock}},
+						{ID: idpool.UnAuthorizedSaTokenAccess, Rule: v1alpha1.Rule{RuleAction: v1alpha1.ActionBlock}},
+					},
+					// A conflicting NimbusPolicy takes precedence over
+					// dnsManipulation in the dev namespace.
+					ExcludedNamespaces: map[string][]string{idpool.DNSManipulation: {"dev"}},
+				},
+			}
+			cwnp.Name = "csib"
+
+			kcsps := BuildKcspsFrom(logr.Discard(), cwnp)
+			idx := slices.IndexFunc(kcsps, func(kcsp kubearmorclusterv1.KubeArmorClusterPolicy) bool {
+				return kcsp.Name == "csib-dnsmanipulation"
+			})
+			if tt.namespaces == nil {
+				if idx >= 0 {
+					t.Errorf("built the dnsManipulation policy, excluded from all the namespaces")
+				}
+			} else if idx < 0 {
+				t.Errorf("didn't build the dnsManipulation policy")
+			} else if got := kcsps[idx].Spec.Selector.MatchExpressions[0]; !equalExpressions(got, *tt.namespaces) {
+				t.Errorf("dnsManipulation namespaces = %+v, want %+v", got, *tt.namespaces)
+			}
+
+			// The namespace isn't excluded for the other intents.
+			idx = slices.IndexFunc(kcsps, func(kcsp kubearmorclusterv1.KubeArmorClusterPolicy) bool {
+				return kcsp.Name == "csib-unauthorizedsatokenaccess"
+			})
+			if idx < 0 {
+				t.Fatalf("didn't build the unAuthorizedSaTokenAccess policy")
+			}
+			if values := kcsps[idx].Spec.Selector.MatchExpressions[0].Values; slices.Contains(values, "dev") !=
+				(kcsps[idx].Spec.Selector.MatchExpressions[0].Operator == kubearmorclusterv1.OperatorIn) {
+				t.Errorf("unAuthorizedSaTokenAccess namespaces = %v, want dev selected", values)
+			}
+		})
+	}
+}
+
+func equalExpressions(a, b kubearmorclusterv1.MatchExpressionsType) bool {
+	return a.Key == b.Key && a.Operator == b.Operator && slices.Equal(a.Values, b.Values)
+}
@@ -4,6 +4,7 @@
 package processor
 
 import (
+	"slices"
 	"strings"
 
 	"github.com/go-logr/logr"
@@ -11,34 +12,51 @@ import (
 
 	v1alpha1 "github.com/5GSEC/nimbus/api/v1alpha1"
 	"github.com/5GSEC/nimbus/pkg/adapter/idpool"
+	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
 )
 
 func BuildKspsFrom(logger logr.Logger, np *v1alpha1.NimbusPolicy) []kubearmorv1.KubeArmorPolicy {
+	// Selecting the workloads by their labels only would enforce the intents
+	// on more workloads than the NimbusPolicy selects.
+	if !SelectsNamespacedWorkloads(np.Spec.Selector) {
+		logger.Info("KubeArmorPolicies can't select workloads by expressions, skipping the NimbusPolicy",
+			"NimbusPolicy", np.Name, "NimbusPolicy.Namespace", np.Namespace)
+		return nil
+	}
+
 	// Build KSPs based on given IDs
 	var ksps []kubearmorv1.KubeArmorPolicy
 	var ksp kubearmorv1.KubeArmorPolicy
 	for _, nimbusRule := range np.Spec.NimbusRules {
 		id := nimbusRule.ID
 		if idpool.IsIdSupportedBy(id, "kubearmor") {
+			action, ok := kspActionFor(nimbusRule.Rule.RuleAction)
+			if !ok {
+				logger.Info("KubeArmor does not support this action", "ID", id, "Action", nimbusRule.Rule.RuleAction,
+					"NimbusPolicy", np.Name, "NimbusPolicy.Namespace", np.Namespace)
+				continue
+			}
 			if _, ok := idpool.KaIDPolicies[id]; ok {
 				for _, policyName := range idpool.KaIDPolicies[id] {
-					ksp = buildKspFor(policyName)
+					ksp = buildKspFor(policyName, nimbusRule.Rule.Params)
 					ksp.Name = np.Name + "-" + strings.ToLower(id) + "-" + strings.ToLower(policyName)
 					ksp.Namespace = np.Namespace
 					ksp.Spec.Message = nimbusRule.Description
-					ksp.Spec.Selector.MatchLabels = np.Spec.Selector.MatchLabels
-					ksp.Spec.Action = kubearmorv1.ActionType(nimbusRule.Rule.RuleAction)
+					ksp.Spec.Selector.MatchLabels = selectorLabelsFor(np.Spec.Selector.MatchLabels, nimbusRule.Rule.Params)
+					ksp.Spec.Action = action
 					addManagedByAnnotation(&ksp)
+					adapterutil.AddIntents(ksp.Annotations, id)
 					ksps = append(ksps, ksp)
 				}
 			} else {
-				ksp = buildKspFor(id)
+				ksp = buildKspFor(id, nimbusRule.Rule.Params)
 				ksp.Name = np.Name + "-" + strings.ToLower(id)
 				ksp.Namespace = np.Namespace
 				ksp.Spec.Message = nimbusRule.Description
-				ksp.Spec.Selector.MatchLabels = np.Spec.Selector.MatchLabels
-				ksp.Spec.Action = kubearmorv1.ActionType(nimbusRule.Rule.RuleAction)
+				ksp.Spec.Selector.MatchLabels = selectorLabelsFor(np.Spec.Selector.MatchLabels, nimbusRule.Rule.Params)
+				ksp.Spec.Action = action
 				addManagedByAnnotation(&ksp)
+				adapterutil.AddIntents(ksp.Annotations, id)
 				ksps = append(ksps, ksp)
 			}
 		} else {
@@ -49,36 +67,54 @@ func BuildKspsFrom(logger logr.Logger, np *v1alpha1.NimbusPolicy) []kubearmorv1.
 	return ksps
 }
 
-// buildKspFor builds a KubeArmorPolicy based on intent ID supported by KubeArmor Security Engine.
-func buildKspFor(id string) kubearmorv1.KubeArmorPolicy {
+// Default binaries, files, capabilities and directories of the intents, which
+// their params tune.
+var (
+	swDeploymentTools = []string{
+		"/usr/bin/apt", "/usr/bin/apt-get", "/sbin/apk", "/bin/apt-get", "/bin/apt", "/usr/bin/dpkg", "/bin/dpkg",
+		"/usr/bin/gdebi", "/bin/gdebi", "/usr/bin/make", "/bin/make", "/usr/bin/yum", "/bin/yum", "/usr/bin/rpm", "/bin/rpm",
+		"/usr/bin/dnf", "/bin/dnf", "/usr/bin/pacman", "/usr/sbin/pacman", "/bin/pacman", "/sbin/pacman", "/usr/bin/makepkg",
+		"/usr/sbin/makepkg", "/bin/makepkg", "/sbin/makepkg", "/usr/bin/yaourt", "/usr/sbin/yaourt", "/bin/yaourt",
+		"/sbin/yaourt", "/usr/bin/zypper", "/bin/zypper", "/usr/bin/curl", "/bin/curl", "/usr/local/bin/curl", "/usr/bin/wget",
+		"/bin/wget", "/usr/local/bin/curl",
+	}
+	chRootBinaries     = []string{"/usr/sbin/chroot", "/sbin/chroot"}
+	dnsFiles           = []string{"/etc/resolv.conf"}
+	deniedCapabilities = []string{"sys_admin", "sys_ptrace", "sys_module", "dac_read_search", "dac_override"}
+	// ref: https://www.tenable.com/audits/items/search?q=noexec&sort=&page=1
+	pfaDirectories = []string{"/var/tmp/", "/tmp/", "/var/log/", "/app/logs/", "/logs/", "/etc/"}
+)
+
+// buildKspFor builds a KubeArmorPolicy based on intent ID supported by KubeArmor Security Engine,
+// tuned with the params of the intent.
+func buildKspFor(id string, params map[string][]string) kubearmorv1.KubeArmorPolicy {
 	switch id {
 	case idpool.SwDeploymentTools:
-		return swDeploymentToolsKsp()
+		return swDeploymentToolsKsp(params)
 	case idpool.UnAuthorizedSaTokenAccess:
 		return unAuthorizedSaTokenAccessKsp()
 	case idpool.DNSManipulation:
-		return dnsManipulationKsp()
+		return dnsManipulationKsp(params)
 	case idpool.DisallowChRoot:
-		return disallowChRoot()
+		return disallowChRoot(params)
 	case idpool.DisallowCapabilities:
-		return disallowCapabilities()
+		return disallowCapabilities(params)
 	case idpool.ExploitPFA:
-		return disallowBinaries()
+		return disallowBinaries(params)
 	default:
 		return kubearmorv1.KubeArmorPolicy{}
 	}
 }
 
-func dnsManipulationKsp() kubearmorv1.KubeArmorPolicy {
+func dnsManipulationKsp(params map[string][]string) kubearmorv1.KubeArmorPolicy {
+	files := dnsFiles
+	if len(params[paramResolverFiles]) > 0 {
+		files = params[paramResolverFiles]
+	}
 	return kubearmorv1.KubeArmorPolicy{
 		Spec: kubearmorv1.KubeArmorPolicySpec{
 			File: kubearmorv1.FileType{
-				MatchPaths: []kubearmorv1.FilePathType{
-					{
-						Path:     "/etc/resolv.conf",
-						ReadOnly: true,
-					},
-				},
+				MatchPaths: readOnlyFilePaths(withParams(files, params, paramExtraPaths, paramExcludePaths), params),
 			},
 		},
 	}
@@ -99,209 +135,58 @@ func unAuthorizedSaTokenAccessKsp() kubearmorv1.KubeArmorPolicy {
 	}
 }
 
-func swDeploymentToolsKsp() kubearmorv1.KubeArmorPolicy {
+func swDeploymentToolsKsp(params map[string][]string) kubearmorv1.KubeArmorPolicy {
 	return kubearmorv1.KubeArmorPolicy{
 		Spec: kubearmorv1.KubeArmorPolicySpec{
 			Process: kubearmorv1.ProcessType{
-				MatchPaths: []kubearmorv1.ProcessPathType{
-					{
-						Path: "/usr/bin/apt",
-					},
-					{
-						Path: "/usr/bin/apt-get",
-					},
-					{
-						Path: "/sbin/apk",
-					},
-					{
-						Path: "/bin/apt-get",
-					},
-					{
-						Path: "/bin/apt",
-					},
-					{
-						Path: "/usr/bin/dpkg",
-					},
-					{
-						Path: "/bin/dpkg",
-					},
-					{
-						Path: "/usr/bin/gdebi",
-					},
-					{
-						Path: "/bin/gdebi",
-					},
-					{
-						Path: "/usr/bin/make",
-					},
-					{
-						Path: "/bin/make",
-					},
-					{
-						Path: "/usr/bin/yum",
-					},
-					{
-						Path: "/bin/yum",
-					},
-					{
-						Path: "/usr/bin/rpm",
-					},
-					{
-						Path: "/bin/rpm",
-					},
-					{
-						Path: "/usr/bin/dnf",
-					},
-					{
-						Path: "/bin/dnf",
-					},
-					{
-						Path: "/usr/bin/pacman",
-					},
-					{
-						Path: "/usr/sbin/pacman",
-					},
-					{
-						Path: "/bin/pacman",
-					},
-					{
-						Path: "/sbin/pacman",
-					},
-					{
-						Path: "/usr/bin/makepkg",
-					},
-					{
-						Path: "/usr/sbin/makepkg",
-					},
-					{
-						Path: "/bin/makepkg",
-					},
-					{
-						Path: "/sbin/makepkg",
-					},
-					{
-						Path: "/usr/bin/yaourt",
-					},
-					{
-						Path: "/usr/sbin/yaourt",
-					},
-					{
-						Path: "/bin/yaourt",
-					},
-					{
-						Path: "/sbin/yaourt",
-					},
-					{
-						Path: "/usr/bin/zypper",
-					},
-					{
-						Path: "/bin/zypper",
-					},
-					{
-						Path: "/usr/bin/curl",
-					},
-					{
-						Path: "/bin/curl",
-					},
-					{
-						Path: "/usr/local/bin/curl",
-					},
-					{
-						Path: "/usr/bin/wget",
-					},
-					{
-						Path: "/bin/wget",
-					},
-					{
-						Path: "/usr/local/bin/curl",
-					},
-				},
+				MatchPaths: processPaths(withParams(swDeploymentTools, params, paramExtraPaths, paramExcludePaths), params),
 			},
 		},
 	}
 }
 
-func disallowCapabilities() kubearmorv1.KubeArmorPolicy {
+func disallowCapabilities(params map[string][]string) kubearmorv1.KubeArmorPolicy {
+	capabilities := deniedCapabilities
+	if len(params[paramCapabilities]) > 0 {
+		capabilities = capabilityNames(params[paramCapabilities])
+	}
+	excluded := capabilityNames(params[paramExcludeCapabilities])
+	capabilities = slices.DeleteFunc(slices.Clone(capabilities), func(capability string) bool {
+		return slices.Contains(excluded, capability)
+	})
 	return kubearmorv1.KubeArmorPolicy{
 		Spec: kubearmorv1.KubeArmorPolicySpec{
 			Capabilities: kubearmorv1.CapabilitiesType{
-				MatchCapabilities: []kubearmorv1.MatchCapabilitiesType{
-					{
-						Capability: "sys_admin",
-					},
-					{
-						Capability: "sys_ptrace",
-					},
-					{
-						Capability: "sys_module",
-					},
-					{
-						Capability: "dac_read_search",
-					},
-					{
-						Capability: "dac_override",
-					},
-				},
+				MatchCapabilities: matchCapabilities(capabilities, params),
 			},
 		},
 	}
 }
 
-func disallowChRoot() kubearmorv1.KubeArmorPolicy {
+func disallowChRoot(params map[string][]string) kubearmorv1.KubeArmorPolicy {
 	return kubearmorv1.KubeArmorPolicy{
 		Spec: kubearmorv1.KubeArmorPolicySpec{
 			Process: kubearmorv1.ProcessType{
-				MatchPaths: []kubearmorv1.ProcessPathType{
-					{
-						Path: "/usr/sbin/chroot",
-					},
-					{
-						Path: "/sbin/chroot",
-					},
-				},
+				MatchPaths: processPaths(withParams(chRootBinaries, params, paramExtraPaths, paramExcludePaths), params),
 			},
 		},
 	}
 }
 
-
-func disallowBinaries() kubearmorv1.KubeArmorPolicy { // ref: https://www.tenable.com/audits/items/search?q=noexec&sort=&page=1
+func disallowBinaries(params map[string][]string) kubearmorv1.KubeArmorPolicy {
+	directories := pfaDirectories
+	if len(params[paramDirectories]) > 0 {
+		directories = params[paramDirectories]
+	}
 	return kubearmorv1.KubeArmorPolicy{
 		Spec: kubearmorv1.KubeArmorPolicySpec{
 			Process: kubearmorv1.ProcessType{
-				MatchDirectories: []kubearmorv1.ProcessDirectoryType{
-					{
-						Directory: "/var/tmp/",
-						Recursive: true,
-					},
-					{
-						Directory: "/tmp/",
-						Recursive: true,
-					},
-					{
-						Directory: "/var/log/",
-						Recursive: true,
-					},
-					{
-						Directory: "/app/logs/",
-						Recursive: true,
-					},
-					{
-						Directory: "/logs/",
-						Recursive: true,
-					},
-					{
-						Directory: "/etc/",
-						Recursive: true,
-					},
-				},
-				Action: kubearmorv1.ActionType("Block"),
+				MatchDirectories: processDirectories(directories, params),
 			},
 		},
 	}
 }
 
-
 func addManagedByAnnotation(ksp *kubearmorv1.KubeArmorPolicy) {
 	ksp.Annotations = make(map[string]string)
 	ksp.Annotations["app.kubernetes.io/managed-by"] = "nimbus-kubearmor"
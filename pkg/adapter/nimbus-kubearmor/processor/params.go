@@ -0,0 +1,193 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package processor
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	kubearmorv1 "github.com/kubearmor/KubeArmor/pkg/KubeArmorController/api/security.kubearmor.com/v1"
+)
+
+// Params of the intents supported by KubeArmor. They apply to every
+// KubeArmorPolicy built for an intent, e.g. to the three policies of
+// escapeToHost.
+const (
+	// paramExtraPaths lists more binaries (swDeploymentTools, disallowChRoot)
+	// or files (dnsManipulation) to protect.
+	paramExtraPaths = "extraPaths"
+	// paramExcludePaths lists binaries or files to remove from the protected
+	// ones.
+	paramExcludePaths = "excludePaths"
+	// paramResolverFiles replaces the resolver files dnsManipulation protects,
+	// for workloads whose resolver isn't configured in /etc/resolv.conf.
+	paramResolverFiles = "resolverFiles"
+	// paramCapabilities replaces the capabilities denied by
+	// disallowCapabilities.
+	paramCapabilities = "capabilities"
+	// paramExcludeCapabilities lists capabilities to remove from the denied
+	// ones.
+	paramExcludeCapabilities = "excludeCapabilities"
+	// paramDirectories replaces the directories exploitPFA denies executing
+	// binaries from.
+	paramDirectories = "directories"
+	// paramAllowFromSource lists the binaries still allowed to run the
+	// protected binaries, write the protected files or use the denied
+	// capabilities.
+	paramAllowFromSource = "allowFromSource"
+	// paramContainers restricts the policies to the given containers of the
+	// selected workloads.
+	paramContainers = "containers"
+)
+
+// containerNameLabel is the selector label KubeArmor matches against the
+// container names.
+const containerNameLabel = "kubearmor.io/container.name"
+
+// kspActionAllow is the action of the rules excepting the sources listed in
+// the allowFromSource param. KubeArmor matches rules with a source before the
+// ones without.
+const kspActionAllow kubearmorv1.ActionType = "Allow"
+
+// withParams returns the defaults with the values of the extra param appended
+// and the values of the exclude param removed.
+func withParams(defaults []string, params map[string][]string, extraKey, excludeKey string) []string {
+	values := slices.Clone(defaults)
+	for _, value := range params[extraKey] {
+		if value != "" && !slices.Contains(values, value) {
+			values = append(values, value)
+		}
+	}
+	return slices.DeleteFunc(values, func(value string) bool {
+		return slices.Contains(params[excludeKey], value)
+	})
+}
+
+// fromSources returns the sources of the allowFromSource param.
+func fromSources(params map[string][]string) []kubearmorv1.MatchSourceType {
+	var sources []kubearmorv1.MatchSourceType
+	for _, source := range params[paramAllowFromSource] {
+		if source != "" {
+			sources = append(sources, kubearmorv1.MatchSourceType{Path: kubearmorv1.MatchPathType(source)})
+		}
+	}
+	return sources
+}
+
+// processPaths builds the rules denying the execution of the given binaries,
+// except from the sources of the allowFromSource param.
+func processPaths(paths []string, params map[string][]string) []kubearmorv1.ProcessPathType {
+	sources := fromSources(params)
+
+	var rules []kubearmorv1.ProcessPathType
+	for _, path := range paths {
+		rules = append(rules, kubearmorv1.ProcessPathType{Path: kubearmorv1.MatchPathType(path)})
+		if len(sources) > 0 {
+			rules = append(rules, kubearmorv1.ProcessPathType{
+				Path:       kubearmorv1.MatchPathType(path),
+				FromSource: sources,
+				Action:     kspActionAllow,
+			})
+		}
+	}
+	return rules
+}
+
+// processDirectories builds the rules denying the execution of binaries from
+// the given directories, except from the sources of the allowFromSource param.
+func processDirectories(directories []string, params map[string][]string) []kubearmorv1.ProcessDirectoryType {
+	sources := fromSources(params)
+
+	var rules []kubearmorv1.ProcessDirectoryType
+	for _, directory := range directories {
+		if !strings.HasSuffix(directory, "/") {
+			directory += "/"
+		}
+		rules = append(rules, kubearmorv1.ProcessDirectoryType{
+			Directory: kubearmorv1.MatchDirectoryType(directory),
+			Recursive: true,
+		})
+		if len(sources) > 0 {
+			rules = append(rules, kubearmorv1.ProcessDirectoryType{
+				Directory:  kubearmorv1.MatchDirectoryType(directory),
+				Recursive:  true,
+				FromSource: sources,
+				Action:     kspActionAllow,
+			})
+		}
+	}
+	return rules
+}
+
+// readOnlyFilePaths builds the rules denying writes to the given files,
+// except from the sources of the allowFromSource param.
+func readOnlyFilePaths(paths []string, params map[string][]string) []kubearmorv1.FilePathType {
+	sources := fromSources(params)
+
+	var rules []kubearmorv1.FilePathType
+	for _, path := range paths {
+		rules = append(rules, kubearmorv1.FilePathType{
+			Path:     kubearmorv1.MatchPathType(path),
+			ReadOnly: true,
+		})
+		if len(sources) > 0 {
+			rules = append(rules, kubearmorv1.FilePathType{
+				Path:       kubearmorv1.MatchPathType(path),
+				FromSource: sources,
+				Action:     kspActionAllow,
+			})
+		}
+	}
+	return rules
+}
+
+// capabilityNames returns the given capabilities the way KubeArmor names
+// them, so that they may be given with their CAP_ prefix and in any case.
+func capabilityNames(capabilities []string) []string {
+	var names []string
+	for _, capability := range capabilities {
+		if capability != "" {
+			names = append(names, strings.TrimPrefix(strings.ToLower(capability), "cap_"))
+		}
+	}
+	return names
+}
+
+// matchCapabilities builds the rules denying the given capabilities, except
+// from the sources of the allowFromSource param.
+func matchCapabilities(capabilities []string, params map[string][]string) []kubearmorv1.MatchCapabilitiesType {
+	sources := fromSources(params)
+
+	var rules []kubearmorv1.MatchCapabilitiesType
+	for _, capability := range capabilities {
+		rules = append(rules, kubearmorv1.MatchCapabilitiesType{
+			Capability: kubearmorv1.MatchCapabilitiesStringType(capability),
+		})
+		if len(sources) > 0 {
+			rules = append(rules, kubearmorv1.MatchCapabilitiesType{
+				Capability: kubearmorv1.MatchCapabilitiesStringType(capability),
+				FromSource: sources,
+				Action:     kspActionAllow,
+			})
+		}
+	}
+	return rules
+}
+
+// selectorLabelsFor returns the match labels of a KubeArmorPolicy selecting
+// the given workloads, restricted to the containers of the containers param.
+func selectorLabelsFor(matchLabels map[string]string, params map[string][]string) map[string]string {
+	containers := params[paramContainers]
+	if len(containers) == 0 {
+		return matchLabels
+	}
+
+	labels := maps.Clone(matchLabels)
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	labels[containerNameLabel] = "[" + strings.Join(containers, ",") + "]"
+	return labels
+}
@@ -0,0 +1,30 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package processor
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1alpha1 "github.com/5GSEC/nimbus/api/v1alpha1"
+)
+
+// SelectsNamespacedWorkloads reports whether the KubeArmorPolicies can select
+// the workloads of the given selector of a NimbusPolicy. They only select the
+// workloads by labels.
+func SelectsNamespacedWorkloads(selector v1alpha1.LabelSelector) bool {
+	return len(selector.MatchExpressions) == 0
+}
+
+// SelectsClusterWorkloads reports whether the KubeArmorClusterPolicies can
+// select the workloads of the given workload selector of a
+// ClusterNimbusPolicy. They select the workloads having, or not, one of the
+// given labels, so not by whether they have a label at all.
+func SelectsClusterWorkloads(selector v1alpha1.LabelSelector) bool {
+	for _, expression := range selector.MatchExpressions {
+		if expression.Operator != metav1.LabelSelectorOpIn && expression.Operator != metav1.LabelSelectorOpNotIn {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package watcher
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
+)
+
+func kcspInformer() cache.SharedIndexInformer {
+	kcspGvr := schema.GroupVersionResource{
+		Group:    "security.kubearmor.com",
+		Version:  "v1",
+		Resource: "kubearmorclusterpolicies",
+	}
+	informer := factory.ForResource(kcspGvr).Informer()
+	return informer
+}
+
+// WatchKcsps watches update and delete event for KubeArmorClusterPolicies owned
+// by ClusterNimbusPolicy and put the name of their owner on respective channels.
+func WatchKcsps(ctx context.Context, updatedKcspCh, deletedKcspCh chan string) {
+	logger := log.FromContext(ctx)
+	informer := kcspInformer()
+	handlers := cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldU := oldObj.(*unstructured.Unstructured)
+			newU := newObj.(*unstructured.Unstructured)
+
+			if adapterutil.IsOrphan(newU.GetOwnerReferences(), "ClusterNimbusPolicy") {
+				logger.V(4).Info("Ignoring orphan KubeArmorClusterPolicy", "KubeArmorClusterPolicy.Name", oldU.GetName(), "Operation", "Update")
+				return
+			}
+
+			if oldU.GetGeneration() == newU.GetGeneration() {
+				return
+			}
+
+			updatedKcspCh <- ownerName(newU)
+		},
+		DeleteFunc: func(obj interface{}) {
+			u := obj.(*unstructured.Unstructured)
+			if adapterutil.IsOrphan(u.GetOwnerReferences(), "ClusterNimbusPolicy") {
+				logger.V(4).Info("Ignoring orphan KubeArmorClusterPolicy", "KubeArmorClusterPolicy.Name", u.GetName(), "Operation", "Delete")
+				return
+			}
+			deletedKcspCh <- ownerName(u)
+		},
+	}
+	_, err := informer.AddEventHandler(handlers)
+	if err != nil {
+		logger.Error(err, "failed to add event handlers")
+		return
+	}
+	logger.Info("KubeArmorClusterPolicy watcher started")
+	informer.Run(ctx.Done())
+}
+
+// ownerName returns the name of the ClusterNimbusPolicy owning the given
+// KubeArmorClusterPolicy.
+func ownerName(u *unstructured.Unstructured) string {
+	for _, ownerRef := range u.GetOwnerReferences() {
+		if ownerRef.Kind == "ClusterNimbusPolicy" {
+			return ownerRef.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,189 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+// vp-ingest builds the virtual patch feed of the nimbus-kyverno adapter from
+// OSV and CSAF VEX advisories, the mapping of the images to their packages,
+// and the curated templates mitigating the advisories, e.g.
+//
+//	go run ./cmd/vp-ingest -osv advisories/osv -csaf advisories/vex.json \
+//	  -images images.yaml -templates cmd/vp-ingest/templates.yaml -o vp.json
+//
+// With -interval, the feed is rebuilt periodically, and the output file only
+// rewritten when the feed changes.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-kyverno/feed"
+	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-kyverno/feed/ingest"
+)
+
+// paths is a flag that can be set multiple times.
+type paths []string
+
+func (p *paths) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *paths) Set(value string) error {
+	*p = append(*p, value)
+	return nil
+}
+
+type options struct {
+	osv       paths
+	csaf      paths
+	images    string
+	templates string
+	base      string
+	output    string
+}
+
+func main() {
+	var opts options
+	flag.Var(&opts.osv, "osv", "OSV JSON file, or directory of them, to ingest (repeatable)")
+	flag.Var(&opts.csaf, "csaf", "CSAF VEX JSON file, or directory of them, to ingest (repeatable)")
+	flag.StringVar(&opts.images, "images", "", "YAML file mapping the images to their packages")
+	flag.StringVar(&opts.templates, "templates", "", "YAML file of the templates mitigating the advisories")
+	flag.StringVar(&opts.base, "base", "", "Feed whose images and CVEs are kept, e.g. curated by hand")
+	flag.StringVar(&opts.output, "o", "-", "Output feed file, or - for the standard output")
+	interval := flag.Duration("interval", 0, "Rebuild the feed periodically with the given interval, e.g. 1h")
+	flag.Parse()
+
+	if opts.images == "" || opts.templates == "" || len(opts.osv)+len(opts.csaf) == 0 {
+		fmt.Fprintln(os.Stderr, "-images, -templates and at least one -osv or -csaf are required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *interval <= 0 {
+		if _, err := run(opts, nil); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	var last []byte
+	for {
+		// A failed run keeps the last feed written, until the next run.
+		if written, err := run(opts, last); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		} else {
+			last = written
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// run builds the feed and writes it, unless it's the same as the given last
+// feed written. It returns the feed written.
+func run(opts options, last []byte) ([]byte, error) {
+	mapping, err := ingest.LoadMapping(opts.images)
+	if err != nil {
+		return nil, err
+	}
+	templates, err := ingest.LoadTemplates(opts.templates)
+	if err != nil {
+		return nil, err
+	}
+	var base *feed.Feed
+	if opts.base != "" {
+		document, err := os.ReadFile(opts.base)
+		if err != nil {
+			return nil, err
+		}
+		if base, err = feed.Parse(document); err != nil {
+			return nil, fmt.Errorf("invalid base feed %s: %w", opts.base, err)
+		}
+	}
+
+	var advisories []ingest.Advisory
+	for _, path := range opts.osv {
+		osvAdvisories, err := ingest.ReadAdvisories(path, ingest.ParseOSV)
+		if err != nil {
+			return nil, err
+		}
+		advisories = append(advisories, osvAdvisories...)
+	}
+	for _, path := range opts.csaf {
+		csafAdvisories, err := ingest.ReadAdvisories(path, ingest.ParseCSAF)
+		if err != nil {
+			return nil, err
+		}
+		advisories = append(advisories, csafAdvisories...)
+	}
+
+	result, err := ingest.Ingest(advisories, mapping, templates, base)
+	if err != nil {
+		return nil, err
+	}
+	for _, unmitigated := range result.Unmitigated {
+		fmt.Fprintln(os.Stderr, "No template mitigates", unmitigated)
+	}
+
+	document, err := json.MarshalIndent(result.Feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	document = append(document, '\n')
+	if bytes.Equal(document, last) {
+		return document, nil
+	}
+	if err := write(opts.output, document); err != nil {
+		return nil, err
+	}
+
+	var cves int
+	for _, image := range result.Feed.Images {
+		cves += len(image.CVEs)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote the feed of %d CVEs of %d images from %d advisories\n", cves, len(result.Feed.Images), len(advisories))
+	return document, nil
+}
+
+// write writes the feed to the given file, replacing it atomically so that it
+// is never read half written, or to the standard output.
+func write(output string, document []byte) error {
+	if output == "-" {
+		_, err := os.Stdout.Write(document)
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(output), "."+filepath.Base(output)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(document); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), output)
+}
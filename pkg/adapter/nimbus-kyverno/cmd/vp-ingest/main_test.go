@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-kyverno/feed"
+)
+
+const testdata = "../../feed/ingest/testdata"
+
+func TestRun(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "vp.json")
+	opts := options{
+		osv:       paths{filepath.Join(testdata, "osv.json"), filepath.Join(testdata, "query.json")},
+		csaf:      paths{filepath.Join(testdata, "csaf.json")},
+		images:    filepath.Join(testdata, "images.yaml"),
+		templates: "templates.yaml",
+		output:    output,
+	}
+
+	written, err := run(opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	document, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(document, written) {
+		t.Errorf("run() returned a feed other than the one written")
+	}
+	// The shipped templates mitigate the advisories of the fixtures with
+	// CWEs.
+	result, err := feed.Parse(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var images []string
+	for _, image := range result.Images {
+		images = append(images, image.Image)
+	}
+	if want := []string{"nginx:1.25.3", "node:20"}; !slices.Equal(images, want) {
+		t.Errorf("feed images = %v, want %v", images, want)
+	}
+
+	// An unchanged feed isn't rewritten.
+	if err := os.Remove(output); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := run(opts, written); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(output); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("unchanged feed rewritten, stat error = %v", err)
+	}
+
+	// The base feed must be valid.
+	base := filepath.Join(t.TempDir(), "base.json")
+	if err := os.WriteFile(base, []byte(`{"schemaVersion": "v0"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	opts.base = base
+	if _, err := run(opts, nil); err == nil {
+		t.Error("run() with an invalid base feed succeeded")
+	}
+}
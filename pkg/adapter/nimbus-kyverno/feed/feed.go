@@ -0,0 +1,235 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+// Package feed loads the feed of virtual patches the virtualPatch intent is
+// enforced with, from a ConfigMap, a file, an HTTP(S) endpoint or an OCI
+// registry, and verifies and validates it.
+package feed
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// SchemaVersion is the version of the feed schema the adapter reads.
+const SchemaVersion = "v1"
+
+// supportedSchemaVersions are the versions of the feed schema the adapter can
+// read.
+var supportedSchemaVersions = []string{SchemaVersion}
+
+// Engines of the policies of a virtual patch.
+const (
+	EngineKubeArmor = "karmor"
+	EngineKyverno   = "kyverno"
+	EngineNetPol    = "netpol"
+)
+
+// Feed lists the virtual patches mitigating the CVEs of container images.
+type Feed struct {
+	// SchemaVersion is the version of the schema of the feed.
+	SchemaVersion string `json:"schemaVersion"`
+	// Images are the container images with known CVEs.
+	Images []Image `json:"images"`
+}
+
+// Image lists the CVEs of the images of a repository and their virtual
+// patches.
+type Image struct {
+	// Image is the reference of the images, matched by repository, with the
+	// registry normalized, and by tag and digest when given. The tag may be a
+	// glob, e.g. "nginx:1.25.*", and "nginx" matches all the images of the
+	// repository, see ParseImageReference.
+	Image string `json:"image"`
+	// TagRange restricts the matched images to those whose tag is a semantic
+	// version in the given range, e.g. ">=1.25.0 <1.26.0".
+	TagRange string `json:"tagRange,omitempty"`
+	CVEs     []CVE  `json:"cves"`
+}
+
+// Reference returns the parsed reference of the images.
+func (i Image) Reference() (ImageReference, error) {
+	return ParseImageReference(i.Image)
+}
+
+// CVE lists the virtual patches mitigating a CVE.
+type CVE struct {
+	// ID of the CVE, e.g. "CVE-2024-4439".
+	ID           string  `json:"cve"`
+	VirtualPatch []Patch `json:"virtual_patch"`
+}
+
+// Patch holds the manifests of the policies of a virtual patch, per engine.
+// The adapter generates Kyverno policies creating them for the pods running
+// the image.
+type Patch struct {
+	KubeArmor map[string]any `json:"karmor,omitempty"`
+	Kyverno   map[string]any `json:"kyverno,omitempty"`
+	NetPol    map[string]any `json:"netpol,omitempty"`
+}
+
+// Policies returns the manifests of the policies of the patch by engine.
+func (p Patch) Policies() map[string]map[string]any {
+	policies := make(map[string]map[string]any)
+	if p.KubeArmor != nil {
+		policies[EngineKubeArmor] = p.KubeArmor
+	}
+	if p.Kyverno != nil {
+		policies[EngineKyverno] = p.Kyverno
+	}
+	if p.NetPol != nil {
+		policies[EngineNetPol] = p.NetPol
+	}
+	return policies
+}
+
+// Parse decodes and validates the given feed document. A bare array of images,
+// the format of the feeds written before the schema was versioned, is read as a
+// feed of the current version.
+func Parse(document []byte) (*Feed, error) {
+	document = bytes.TrimSpace(document)
+	if len(document) == 0 {
+		return nil, errors.New("empty feed")
+	}
+
+	var feed Feed
+	if document[0] == '[' {
+		if err := decodeStrict(document, &feed.Images); err != nil {
+			return nil, fmt.Errorf("failed to decode feed: %w", err)
+		}
+		feed.SchemaVersion = SchemaVersion
+	} else {
+		var version struct {
+			SchemaVersion string `json:"schemaVersion"`
+		}
+		if err := json.Unmarshal(document, &version); err != nil {
+			return nil, fmt.Errorf("failed to decode feed: %w", err)
+		}
+		if !slices.Contains(supportedSchemaVersions, version.SchemaVersion) {
+			return nil, fmt.Errorf("unsupported feed schema version %q, supported versions are %s",
+				version.SchemaVersion, strings.Join(supportedSchemaVersions, ", "))
+		}
+		if err := decodeStrict(document, &feed); err != nil {
+			return nil, fmt.Errorf("failed to decode feed: %w", err)
+		}
+	}
+
+	if err := feed.Validate(); err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+// decodeStrict decodes the given JSON document, rejecting the fields the
+// schema doesn't define.
+func decodeStrict(document []byte, out any) error {
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(out)
+}
+
+// Validate checks that every virtual patch of the feed has the fields the
+// adapter relies on to generate its policies.
+func (f *Feed) Validate() error {
+	var errs []error
+	for i, image := range f.Images {
+		path := fmt.Sprintf("images[%d]", i)
+		if strings.TrimSpace(image.Image) == "" {
+			errs = append(errs, fmt.Errorf("%s: missing image", path))
+		} else if _, err := image.Reference(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+		}
+		if image.TagRange != "" {
+			if err := ValidateTagRange(image.TagRange); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			}
+		}
+		for j, cve := range image.CVEs {
+			path := fmt.Sprintf("%s.cves[%d]", path, j)
+			if strings.TrimSpace(cve.ID) == "" {
+				errs = append(errs, fmt.Errorf("%s: missing cve", path))
+			}
+			for k, patch := range cve.VirtualPatch {
+				errs = append(errs, patch.validate(fmt.Sprintf("%s.virtual_patch[%d]", path, k))...)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks that the patch has a policy for at least one engine, with
+// the fields the adapter relies on to generate it.
+func (p Patch) Validate() error {
+	return errors.Join(p.validate("")...)
+}
+
+// validate returns the errors of the patch, prefixed with the given path.
+func (p Patch) validate(path string) []error {
+	policies := p.Policies()
+	if len(policies) == 0 {
+		err := fmt.Errorf("no policy for any of the %s, %s or %s engines", EngineKubeArmor, EngineKyverno, EngineNetPol)
+		if path != "" {
+			err = fmt.Errorf("%s: %w", path, err)
+		}
+		return []error{err}
+	}
+
+	var errs []error
+	for _, engine := range []string{EngineKubeArmor, EngineKyverno, EngineNetPol} {
+		if policy, ok := policies[engine]; ok {
+			if err := validatePolicy(engine, policy); err != nil {
+				if path != "" {
+					engine = path + "." + engine
+				}
+				errs = append(errs, fmt.Errorf("%s: %w", engine, err))
+			}
+		}
+	}
+	return errs
+}
+
+// validatePolicy checks the fields of the manifest of a policy that are
+// rewritten for the pods running the image.
+func validatePolicy(engine string, policy map[string]any) error {
+	metadata, ok := policy["metadata"].(map[string]any)
+	if !ok {
+		return errors.New("missing metadata")
+	}
+	if name, _ := metadata["name"].(string); name == "" {
+		return errors.New("missing metadata.name")
+	}
+	spec, ok := policy["spec"].(map[string]any)
+	if !ok {
+		return errors.New("missing spec")
+	}
+
+	switch engine {
+	case EngineKubeArmor:
+		if _, ok := spec["selector"].(map[string]any); !ok {
+			return errors.New("missing spec.selector")
+		}
+	case EngineNetPol:
+		if _, ok := spec["podSelector"].(map[string]any); !ok {
+			return errors.New("missing spec.podSelector")
+		}
+	case EngineKyverno:
+		rules, _ := spec["rules"].([]any)
+		if len(rules) == 0 {
+			return errors.New("missing spec.rules")
+		}
+		rule, ok := rules[0].(map[string]any)
+		if !ok {
+			return errors.New("invalid spec.rules[0]")
+		}
+		if preconditions, ok := rule["preconditions"]; ok {
+			if _, ok := preconditions.(map[string]any); !ok {
+				return errors.New("invalid spec.rules[0].preconditions")
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+// Package feedtest provides a stand-in for the endpoints serving the virtual
+// patch feed, both over HTTP and from an OCI registry, for testing the loading
+// of the feed without them.
+package feedtest
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-kyverno/feed"
+)
+
+const (
+	// Repository is the repository of the OCI artifact holding the feed.
+	Repository = "nimbus/virtual-patch-feed"
+	// Tag is the tag of the OCI artifact holding the feed.
+	Tag = "latest"
+
+	// token is granted to the anonymous pulls when the server requires one.
+	token = "feedtest"
+)
+
+// Server serves the feed and its detached signature at /vp.json and
+// /vp.json.sig, and as the layers of the Repository:Tag OCI artifact through
+// the distribution API.
+type Server struct {
+	// Address is the address the server listens on.
+	Address string
+
+	mu           sync.Mutex
+	document     []byte
+	signature    []byte
+	requireToken bool
+
+	server *http.Server
+}
+
+// NewServer starts a server listening on the given address, e.g.
+// "127.0.0.1:0" for a random port, serving the given feed and signature. The
+// signature isn't served when nil.
+func NewServer(address string, document, signature []byte) (*Server, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		Address:   listener.Addr().String(),
+		document:  document,
+		signature: signature,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/vp.json", s.serveDocument)
+	mux.HandleFunc("/vp.json.sig", s.serveSignature)
+	mux.HandleFunc("/token", s.serveToken)
+	mux.HandleFunc("/v2/", s.serveRegistry)
+	s.server = &http.Server{Handler: mux}
+
+	go func() {
+		_ = s.server.Serve(listener)
+	}()
+	return s, nil
+}
+
+// HTTPLocation returns the location of the feed served over HTTP.
+func (s *Server) HTTPLocation() string {
+	return "http://" + s.Address + "/vp.json"
+}
+
+// OCILocation returns the location of the feed served from the registry.
+func (s *Server) OCILocation() string {
+	return "oci+http://" + s.Address + "/" + Repository + ":" + Tag
+}
+
+// SetFeed replaces the served feed and signature.
+func (s *Server) SetFeed(document, signature []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.document, s.signature = document, signature
+}
+
+// RequireToken makes the registry require the token it grants to anonymous
+// pulls, like e.g. Docker Hub or GHCR.
+func (s *Server) RequireToken(require bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requireToken = require
+}
+
+// Close stops the server.
+func (s *Server) Close() {
+	_ = s.server.Close()
+}
+
+func (s *Server) feed() (document, signature []byte, requireToken bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.document, s.signature, s.requireToken
+}
+
+func (s *Server) serveDocument(w http.ResponseWriter, _ *http.Request) {
+	document, _, _ := s.feed()
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(document)
+}
+
+func (s *Server) serveSignature(w http.ResponseWriter, r *http.Request) {
+	_, signature, _ := s.feed()
+	if signature == nil {
+		http.NotFound(w, r)
+		return
+	}
+	_, _ = w.Write(signature)
+}
+
+func (s *Server) serveToken(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"token": token})
+}
+
+// serveRegistry serves the manifest and blobs of the artifact holding the
+// feed, whose layers are the feed and its signature.
+func (s *Server) serveRegistry(w http.ResponseWriter, r *http.Request) {
+	document, signature, requireToken := s.feed()
+	if requireToken && r.Header.Get("Authorization") != "Bearer "+token {
+		w.Header().Set("WWW-Authenticate",
+			fmt.Sprintf(`Bearer realm="http://%s/token",service="feedtest"`, s.Address))
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	layers := []map[string]any{layer(feed.FeedMediaType, document)}
+	blobs := map[string][]byte{digest(document): document}
+	if signature != nil {
+		layers = append(layers, layer(feed.SignatureMediaType, signature))
+		blobs[digest(signature)] = signature
+	}
+
+	prefix := "/v2/" + Repository
+	switch {
+	case r.URL.Path == prefix+"/manifests/"+Tag:
+		w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"schemaVersion": 2,
+			"mediaType":     "application/vnd.oci.image.manifest.v1+json",
+			"config":        layer("application/vnd.oci.empty.v1+json", []byte("{}")),
+			"layers":        layers,
+		})
+	case strings.HasPrefix(r.URL.Path, prefix+"/blobs/"):
+		blob, ok := blobs[strings.TrimPrefix(r.URL.Path, prefix+"/blobs/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_, _ = w.Write(blob)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func layer(mediaType string, data []byte) map[string]any {
+	return map[string]any{
+		"mediaType": mediaType,
+		"digest":    digest(data),
+		"size":      len(data),
+	}
+}
+
+func digest(data []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+}
@@ -0,0 +1,164 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package feed
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// DockerHubRegistry is the registry of the images without registry.
+	DockerHubRegistry = "docker.io"
+	// dockerHubOfficialRepository is the namespace of the official Docker Hub
+	// images, e.g. "library/nginx" for "nginx".
+	dockerHubOfficialRepository = "library/"
+)
+
+// dockerHubHosts are the hosts of the Docker Hub registry.
+var dockerHubHosts = []string{DockerHubRegistry, "index.docker.io", "registry-1.docker.io", "registry.hub.docker.com"}
+
+var (
+	// repositoryPattern matches the path components of a repository.
+	repositoryPattern = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*$`)
+	// tagPattern matches a tag, possibly with * and ? globs.
+	tagPattern = regexp.MustCompile(`^[\w*?][\w.*?-]{0,127}$`)
+	// digestPattern matches a digest.
+	digestPattern = regexp.MustCompile(`^[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[a-fA-F0-9]{32,}$`)
+	// tagRangePattern matches a semantic version range, e.g.
+	// ">=1.25.0 <1.26.0 || >=1.27.0".
+	tagRangePattern = regexp.MustCompile(`^(?:(?:<|<=|>|>=|=|==|!=)?\d+\.\d+\.\d+\S*)(?:\s+(?:\|\|\s+)?(?:<|<=|>|>=|=|==|!=)?\d+\.\d+\.\d+\S*)*$`)
+)
+
+// ImageReference is the reference of the images an image of the feed matches:
+// the images of a repository, with any tag, the tags matching a glob, or a
+// digest.
+type ImageReference struct {
+	// Registry is the normalized host of the registry of the repository, e.g.
+	// "docker.io" for Docker Hub.
+	Registry string
+	// Repository is the normalized path of the repository, e.g.
+	// "library/nginx" for the official nginx image of Docker Hub.
+	Repository string
+	// Tag is the tag of the images, possibly with * and ? globs. Any tag
+	// matches when empty.
+	Tag string
+	// Digest is the digest of the images, e.g. "sha256:...". Any digest
+	// matches when empty.
+	Digest string
+}
+
+// ParseImageReference parses the given image, as
+// [<registry>/]<repository>[:<tag>][@<digest>]. The registry is the first
+// component of the repository if it's a host, i.e. contains a "." or a ":",
+// or is "localhost", and Docker Hub otherwise. The official Docker Hub images
+// are in the "library" namespace.
+func ParseImageReference(image string) (ImageReference, error) {
+	var ref ImageReference
+	name := strings.TrimSpace(image)
+	if name == "" {
+		return ref, errors.New("empty image")
+	}
+
+	if before, digest, found := strings.Cut(name, "@"); found {
+		name, ref.Digest = before, digest
+		if !digestPattern.MatchString(ref.Digest) {
+			return ref, fmt.Errorf("invalid digest %q of image %q", ref.Digest, image)
+		}
+	}
+	// The tag follows the last ":" after the last "/", the other ":" being
+	// the one of the port of the registry.
+	if idx := strings.LastIndex(name, ":"); idx > strings.LastIndex(name, "/") {
+		name, ref.Tag = name[:idx], name[idx+1:]
+		if !tagPattern.MatchString(ref.Tag) {
+			return ref, fmt.Errorf("invalid tag %q of image %q", ref.Tag, image)
+		}
+	}
+
+	ref.Registry = DockerHubRegistry
+	if first, rest, found := strings.Cut(name, "/"); found && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		ref.Registry, name = strings.ToLower(first), rest
+	}
+	for _, host := range dockerHubHosts {
+		if ref.Registry == host {
+			ref.Registry = DockerHubRegistry
+		}
+	}
+	if ref.Registry == DockerHubRegistry && !strings.Contains(name, "/") {
+		name = dockerHubOfficialRepository + name
+	}
+	if !repositoryPattern.MatchString(name) {
+		return ref, fmt.Errorf("invalid repository %q of image %q", name, image)
+	}
+	ref.Repository = name
+	return ref, nil
+}
+
+// Names returns the names the images of the repository may be referred to by
+// in pods, e.g. "nginx", "library/nginx", "docker.io/nginx" and
+// "docker.io/library/nginx" for the official nginx image of Docker Hub.
+func (r ImageReference) Names() []string {
+	if r.Registry != DockerHubRegistry {
+		return []string{r.Registry + "/" + r.Repository}
+	}
+
+	paths := []string{r.Repository}
+	if official, found := strings.CutPrefix(r.Repository, dockerHubOfficialRepository); found {
+		paths = []string{official, r.Repository}
+	}
+	var names []string
+	for _, prefix := range append([]string{""}, dockerHubHosts...) {
+		if prefix != "" {
+			prefix += "/"
+		}
+		for _, path := range paths {
+			names = append(names, prefix+path)
+		}
+	}
+	return names
+}
+
+// Patterns returns the wildcard patterns, with * and ? globs, matching the
+// images of the reference as referred to in pods, e.g. "nginx:1.25.*",
+// "nginx:1.25.*@*" or "docker.io/library/nginx:1.25.*" for "nginx:1.25.*".
+// The images without tag have the "latest" tag.
+func (r ImageReference) Patterns() []string {
+	tag := r.Tag
+	if tag == "" {
+		tag = "*"
+	}
+	var patterns []string
+	for _, name := range r.Names() {
+		switch {
+		case r.Digest != "" && r.Tag == "":
+			patterns = append(patterns, name+"@"+r.Digest, name+":*@"+r.Digest)
+		case r.Digest != "":
+			patterns = append(patterns, name+":"+tag+"@"+r.Digest)
+		default:
+			patterns = append(patterns, name+":"+tag, name+":"+tag+"@*")
+			if matchesLatest(tag) {
+				patterns = append(patterns, name, name+"@*")
+			}
+		}
+	}
+	return patterns
+}
+
+// matchesLatest reports whether the given tag glob matches the "latest" tag.
+func matchesLatest(tag string) bool {
+	pattern := "^" + strings.NewReplacer(".", `\.`, "*", ".*", "?", ".").Replace(tag) + "$"
+	matched, _ := regexp.MatchString(pattern, "latest")
+	return matched
+}
+
+// ValidateTagRange checks that the given range of tags is a semantic version
+// range, e.g. ">=1.25.0 <1.26.0".
+func ValidateTagRange(tagRange string) error {
+	if !tagRangePattern.MatchString(strings.TrimSpace(tagRange)) {
+		return fmt.Errorf("invalid tagRange %q, expected a semantic version range, e.g. \">=1.25.0 <1.26.0\"", tagRange)
+	}
+	return nil
+}
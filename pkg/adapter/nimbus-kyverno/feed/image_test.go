@@ -0,0 +1,165 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package feed
+
+import (
+	"slices"
+	"testing"
+)
+
+const digest = "sha256:0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31"
+
+func TestParseImageReference(t *testing.T) {
+	tests := []struct {
+		image   string
+		want    ImageReference
+		wantErr bool
+	}{
+		{image: "nginx",
+			want: ImageReference{Registry: "docker.io", Repository: "library/nginx"}},
+		{image: "docker.io/library/nginx",
+			want: ImageReference{Registry: "docker.io", Repository: "library/nginx"}},
+		{image: "index.docker.io/nginx:1.25",
+			want: ImageReference{Registry: "docker.io", Repository: "library/nginx", Tag: "1.25"}},
+		{image: "bitnami/redis:7.2",
+			want: ImageReference{Registry: "docker.io", Repository: "bitnami/redis", Tag: "7.2"}},
+		{image: "localhost:5000/app:1.0",
+			want: ImageReference{Registry: "localhost:5000", Repository: "app", Tag: "1.0"}},
+		{image: "localhost/app",
+			want: ImageReference{Registry: "localhost", Repository: "app"}},
+		{image: "Registry.Example.com:443/team/app:v1",
+			want: ImageReference{Registry: "registry.example.com:443", Repository: "team/app", Tag: "v1"}},
+		{image: "ghcr.io/org/app@" + digest,
+			want: ImageReference{Registry: "ghcr.io", Repository: "org/app", Digest: digest}},
+		{image: "nginx:1.25.3@" + digest,
+			want: ImageReference{Registry: "docker.io", Repository: "library/nginx", Tag: "1.25.3", Digest: digest}},
+		{image: "nginx:1.25.*",
+			want: ImageReference{Registry: "docker.io", Repository: "library/nginx", Tag: "1.25.*"}},
+		{image: " ", wantErr: true},
+		{image: "nginx:", wantErr: true},
+		{image: "nginx:-1", wantErr: true},
+		{image: "nginx@sha256:abc", wantErr: true},
+		{image: "Nginx", wantErr: true},
+		{image: "localhost:5000/", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseImageReference(tt.image)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseImageReference(%q) error = %v, wantErr %t", tt.image, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParseImageReference(%q) = %+v, want %+v", tt.image, got, tt.want)
+		}
+	}
+}
+
+func TestPatterns(t *testing.T) {
+	tests := []struct {
+		image string
+		// want are all the patterns, or some of them when contains is set.
+		want     []string
+		contains bool
+		// wantNot are patterns that must not be returned.
+		wantNot []string
+	}{
+		{
+			image: "localhost:5000/app:1.0",
+			want:  []string{"localhost:5000/app:1.0", "localhost:5000/app:1.0@*"},
+		},
+		{
+			image: "ghcr.io/org/app",
+			want:  []string{"ghcr.io/org/app:*", "ghcr.io/org/app:*@*", "ghcr.io/org/app", "ghcr.io/org/app@*"},
+		},
+		{
+			image: "ghcr.io/org/app@" + digest,
+			want:  []string{"ghcr.io/org/app@" + digest, "ghcr.io/org/app:*@" + digest},
+		},
+		{
+			image: "ghcr.io/org/app:1.0@" + digest,
+			want:  []string{"ghcr.io/org/app:1.0@" + digest},
+		},
+		{
+			image:    "nginx:1.25.*",
+			want:     []string{"nginx:1.25.*", "nginx:1.25.*@*", "library/nginx:1.25.*", "docker.io/nginx:1.25.*", "docker.io/library/nginx:1.25.*@*", "index.docker.io/library/nginx:1.25.*"},
+			contains: true,
+			wantNot:  []string{"nginx", "nginx@*"},
+		},
+		{
+			image:    "nginx",
+			want:     []string{"nginx", "nginx@*", "nginx:*", "docker.io/library/nginx", "registry-1.docker.io/nginx:*@*"},
+			contains: true,
+		},
+		{
+			image:    "bitnami/redis:lat*",
+			want:     []string{"bitnami/redis:lat*", "bitnami/redis", "docker.io/bitnami/redis@*"},
+			contains: true,
+			wantNot:  []string{"redis:lat*", "library/bitnami/redis:lat*"},
+		},
+	}
+
+	for _, tt := range tests {
+		ref, err := ParseImageReference(tt.image)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := ref.Patterns()
+		if tt.contains {
+			for _, pattern := range tt.want {
+				if !slices.Contains(got, pattern) {
+					t.Errorf("Patterns() of %q = %v, missing %q", tt.image, got, pattern)
+				}
+			}
+		} else if !slices.Equal(got, tt.want) {
+			t.Errorf("Patterns() of %q = %v, want %v", tt.image, got, tt.want)
+		}
+		for _, pattern := range tt.wantNot {
+			if slices.Contains(got, pattern) {
+				t.Errorf("Patterns() of %q = %v, unexpected %q", tt.image, got, pattern)
+			}
+		}
+	}
+}
+
+func TestMatchesLatest(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want bool
+	}{
+		{"latest", true},
+		{"*", true},
+		{"lat*", true},
+		{"l?test", true},
+		{"latest.*", false},
+		{"1.25.*", false},
+		{"latest-alpine", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchesLatest(tt.tag); got != tt.want {
+			t.Errorf("matchesLatest(%q) = %t, want %t", tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTagRange(t *testing.T) {
+	tests := []struct {
+		tagRange string
+		wantErr  bool
+	}{
+		{tagRange: ">=1.25.0 <1.26.0"},
+		{tagRange: ">=1.25.0 <1.26.0 || >=1.27.0-rc.1"},
+		{tagRange: "1.25.3"},
+		{tagRange: ">=1.25", wantErr: true},
+		{tagRange: "~1.25.0", wantErr: true},
+		{tagRange: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if err := ValidateTagRange(tt.tagRange); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateTagRange(%q) error = %v, wantErr %t", tt.tagRange, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package ingest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// csafDocument is a CSAF document, e.g. a VEX, see
+// https://docs.oasis-open.org/csaf/csaf/v2.0/csaf-v2.0.html.
+type csafDocument struct {
+	Document struct {
+		Category string `json:"category"`
+	} `json:"document"`
+	ProductTree struct {
+		Branches         []csafBranch      `json:"branches"`
+		FullProductNames []csafProductName `json:"full_product_names"`
+		Relationships    []struct {
+			ProductReference string          `json:"product_reference"`
+			FullProductName  csafProductName `json:"full_product_name"`
+		} `json:"relationships"`
+	} `json:"product_tree"`
+	Vulnerabilities []struct {
+		CVE string `json:"cve"`
+		IDs []struct {
+			Text string `json:"text"`
+		} `json:"ids"`
+		CWE  *csafCWE  `json:"cwe"`
+		CWEs []csafCWE `json:"cwes"`
+		// ProductStatus lists the IDs of the products by status.
+		ProductStatus map[string][]string `json:"product_status"`
+	} `json:"vulnerabilities"`
+}
+
+type csafBranch struct {
+	Category string           `json:"category"`
+	Name     string           `json:"name"`
+	Branches []csafBranch     `json:"branches"`
+	Product  *csafProductName `json:"product"`
+}
+
+type csafProductName struct {
+	ProductID string `json:"product_id"`
+	Name      string `json:"name"`
+	Helper    struct {
+		PURL string `json:"purl"`
+	} `json:"product_identification_helper"`
+}
+
+type csafCWE struct {
+	ID string `json:"id"`
+}
+
+// csafAffectedStatuses are the product statuses of the affected products.
+var csafAffectedStatuses = []string{"known_affected", "first_affected", "last_affected"}
+
+// ParseCSAF reads the advisories of a CSAF document, e.g. a VEX. The products
+// with the known_affected, first_affected and last_affected statuses are
+// affected, identified by their PURL, or by the product name and version, or
+// version range, of their branches.
+func ParseCSAF(document []byte, source string) ([]Advisory, error) {
+	var doc csafDocument
+	if err := json.Unmarshal(document, &doc); err != nil {
+		return nil, fmt.Errorf("invalid CSAF document %s: %w", source, err)
+	}
+	if doc.Document.Category == "" {
+		return nil, fmt.Errorf("invalid CSAF document %s: missing document.category", source)
+	}
+
+	products := make(map[string]AffectedPackage)
+	for _, branch := range doc.ProductTree.Branches {
+		collectCSAFProducts(branch, AffectedPackage{}, products)
+	}
+	for _, product := range doc.ProductTree.FullProductNames {
+		products[product.ProductID] = csafProduct(product, AffectedPackage{Name: product.Name})
+	}
+	// The products of the relationships are components of other products,
+	// identified by the component.
+	for _, relationship := range doc.ProductTree.Relationships {
+		if component, ok := products[relationship.ProductReference]; ok {
+			products[relationship.FullProductName.ProductID] = component
+		}
+	}
+
+	var advisories []Advisory
+	for _, vuln := range doc.Vulnerabilities {
+		var aliases []string
+		for _, id := range vuln.IDs {
+			aliases = append(aliases, id.Text)
+		}
+		id := vuln.CVE
+		if id == "" {
+			if len(aliases) == 0 {
+				continue
+			}
+			id = cveID(aliases[0], aliases)
+		}
+
+		advisory := Advisory{ID: id, Source: source}
+		if vuln.CWE != nil {
+			advisory.CWEs = append(advisory.CWEs, vuln.CWE.ID)
+		}
+		for _, cwe := range vuln.CWEs {
+			advisory.CWEs = append(advisory.CWEs, cwe.ID)
+		}
+		for _, status := range csafAffectedStatuses {
+			for _, productID := range vuln.ProductStatus[status] {
+				if product, ok := products[productID]; ok && product.Name != "" {
+					advisory.Affected = append(advisory.Affected, product)
+				}
+			}
+		}
+		if len(advisory.Affected) > 0 {
+			advisories = append(advisories, advisory)
+		}
+	}
+	return advisories, nil
+}
+
+// collectCSAFProducts collects the products of the given branch, named after
+// the product_name branch and versioned after the product_version or
+// product_version_range branch they are in.
+func collectCSAFProducts(branch csafBranch, parent AffectedPackage, products map[string]AffectedPackage) {
+	current := parent
+	switch branch.Category {
+	case "product_name":
+		current.Name = branch.Name
+	case "product_version":
+		current.Versions = []string{branch.Name}
+	case "product_version_range":
+		current.Versions, current.Ranges = parseVers(branch.Name)
+	}
+
+	if branch.Product != nil {
+		products[branch.Product.ProductID] = csafProduct(*branch.Product, current)
+	}
+	for _, child := range branch.Branches {
+		collectCSAFProducts(child, current, products)
+	}
+}
+
+// csafProduct returns the package of the given product, identified by its
+// PURL if it has one.
+func csafProduct(product csafProductName, pkg AffectedPackage) AffectedPackage {
+	if product.Helper.PURL == "" {
+		return pkg
+	}
+	p, err := parsePURL(product.Helper.PURL)
+	if err != nil {
+		return pkg
+	}
+	pkg.Name = p.name
+	pkg.PURL = p.withoutVersion()
+	pkg.Ecosystem = p.ecosystem()
+	if p.version != "" {
+		pkg.Versions, pkg.Ranges = []string{p.version}, nil
+	}
+	return pkg
+}
+
+// parseVers parses a version range specifier, e.g.
+// "vers:deb/>=1.0|<1.2|1.4", into versions and ranges. The ">" constraints
+// are read as ">=", and the "!=" ones ignored. The ranges of the semver, npm,
+// golang and cargo versioning schemes are SEMVER ones, the others ECOSYSTEM
+// ones.
+func parseVers(vers string) ([]string, []VersionRange) {
+	rangeType := EcosystemRange
+	if scheme, constraints, found := strings.Cut(vers, "/"); found && strings.HasPrefix(scheme, "vers:") {
+		switch strings.ToLower(strings.TrimPrefix(scheme, "vers:")) {
+		case "semver", "npm", "golang", "cargo":
+			rangeType = SemverRange
+		}
+		vers = constraints
+	}
+
+	var versions []string
+	var ranges []VersionRange
+	var current *VersionRange
+	for _, constraint := range strings.Split(vers, "|") {
+		constraint = strings.TrimSpace(constraint)
+		switch {
+		case constraint == "*":
+			ranges = append(ranges, VersionRange{Type: rangeType})
+		case strings.HasPrefix(constraint, ">="), strings.HasPrefix(constraint, ">"):
+			if current != nil {
+				ranges = append(ranges, *current)
+			}
+			current = &VersionRange{Type: rangeType, Introduced: strings.TrimLeft(constraint, ">=")}
+		case strings.HasPrefix(constraint, "<="):
+			if current == nil {
+				current = &VersionRange{Type: rangeType}
+			}
+			current.LastAffected = strings.TrimPrefix(constraint, "<=")
+			ranges = append(ranges, *current)
+			current = nil
+		case strings.HasPrefix(constraint, "<"):
+			if current == nil {
+				current = &VersionRange{Type: rangeType}
+			}
+			current.Fixed = strings.TrimPrefix(constraint, "<")
+			ranges = append(ranges, *current)
+			current = nil
+		case strings.HasPrefix(constraint, "!="):
+		case constraint != "":
+			versions = append(versions, strings.TrimPrefix(constraint, "="))
+		}
+	}
+	if current != nil {
+		ranges = append(ranges, *current)
+	}
+	return versions, ranges
+}
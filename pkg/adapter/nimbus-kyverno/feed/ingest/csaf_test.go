@@ -0,0 +1,120 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package ingest
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParseCSAF(t *testing.T) {
+	document, err := os.ReadFile("testdata/csaf.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		document []byte
+		want     []Advisory
+		wantErr  bool
+	}{
+		{
+			name:     "vex",
+			document: document,
+			want: []Advisory{
+				{
+					ID:   "CVE-2024-0010",
+					CWEs: []string{"CWE-22"},
+					Affected: []AffectedPackage{
+						{
+							Name:   "libxml2",
+							Ranges: []VersionRange{{Type: EcosystemRange, Introduced: "2.9.0", Fixed: "2.9.14+dfsg-1.3~deb12u1"}},
+						},
+						// The component of the relationship, identified by
+						// its PURL.
+						{
+							Ecosystem: "Debian",
+							Name:      "curl",
+							PURL:      "pkg:deb/debian/curl",
+							Versions:  []string{"8.5.0-2"},
+						},
+					},
+				},
+				{
+					ID:   "CVE-2024-0011",
+					CWEs: []string{"CWE-94"},
+					Affected: []AffectedPackage{{
+						Name:   "express",
+						Ranges: []VersionRange{{Type: SemverRange, Introduced: "4.0.0-beta.1", Fixed: "4.19.2"}},
+					}},
+				},
+				// CVE-2024-0012 affects no product.
+			},
+		},
+		{
+			name:     "missing category",
+			document: []byte(`{"document": {}}`),
+			wantErr:  true,
+		},
+		{
+			name:     "invalid",
+			document: []byte(`[]`),
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := range tt.want {
+				tt.want[i].Source = "csaf.json"
+			}
+			got, err := ParseCSAF(tt.document, "csaf.json")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCSAF() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseCSAF() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseVers(t *testing.T) {
+	tests := []struct {
+		vers         string
+		wantVersions []string
+		wantRanges   []VersionRange
+	}{
+		{
+			vers:       "vers:deb/>=1.0|<1.2",
+			wantRanges: []VersionRange{{Type: EcosystemRange, Introduced: "1.0", Fixed: "1.2"}},
+		},
+		{
+			vers:       "vers:semver/>1.0.0-rc1|<=1.0.0",
+			wantRanges: []VersionRange{{Type: SemverRange, Introduced: "1.0.0-rc1", LastAffected: "1.0.0"}},
+		},
+		{
+			vers:         "vers:rpm/1:1.0|!=1:1.1|>=2:0.1",
+			wantVersions: []string{"1:1.0"},
+			wantRanges:   []VersionRange{{Type: EcosystemRange, Introduced: "2:0.1"}},
+		},
+		{
+			vers:       "vers:npm/*",
+			wantRanges: []VersionRange{{Type: SemverRange}},
+		},
+		{
+			vers:         "1.4",
+			wantVersions: []string{"1.4"},
+		},
+	}
+
+	for _, tt := range tests {
+		versions, ranges := parseVers(tt.vers)
+		if !reflect.DeepEqual(versions, tt.wantVersions) || !reflect.DeepEqual(ranges, tt.wantRanges) {
+			t.Errorf("parseVers(%q) = %v, %+v, want %v, %+v", tt.vers, versions, ranges, tt.wantVersions, tt.wantRanges)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+// Package ingest converts OSV and CSAF VEX advisories into a virtual patch
+// feed. The advisories affecting the packages of the images of a mapping are
+// mitigated with the curated templates matching their CWEs or packages.
+package ingest
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-kyverno/feed"
+)
+
+// Advisory is a vulnerability of packages, read from an OSV or CSAF VEX
+// document.
+type Advisory struct {
+	// ID of the vulnerability, its CVE ID when it has one.
+	ID string
+	// CWEs are the weaknesses of the vulnerability, e.g. "CWE-78".
+	CWEs []string
+	// Affected are the affected packages.
+	Affected []AffectedPackage
+	// Source is the document the advisory was read from.
+	Source string
+}
+
+// AffectedPackage is a package affected by an advisory.
+type AffectedPackage struct {
+	// Ecosystem of the package, e.g. "Debian:12" or "npm". Empty when
+	// unknown.
+	Ecosystem string
+	// Name of the package.
+	Name string
+	// PURL of the package, without version. Empty when unknown.
+	PURL string
+	// Versions are the affected versions.
+	Versions []string
+	// Ranges are the affected version ranges.
+	Ranges []VersionRange
+}
+
+// VersionRange is the range of versions from Introduced, included, to Fixed,
+// excluded, or LastAffected, included. A range without upper bound includes
+// all the versions from Introduced.
+type VersionRange struct {
+	// Type of the range, SemverRange or EcosystemRange, the default, telling
+	// how to compare its versions.
+	Type         string
+	Introduced   string
+	Fixed        string
+	LastAffected string
+}
+
+// Affects reports whether the given package of an image is affected.
+func (a AffectedPackage) Affects(pkg Package) bool {
+	if pkg.purl.name != "" && a.PURL != "" {
+		if pkg.purl.withoutVersion() != a.PURL {
+			return false
+		}
+	} else {
+		if !strings.EqualFold(a.Name, pkg.name()) {
+			return false
+		}
+		if ecosystem := pkg.ecosystem(); a.Ecosystem != "" && ecosystem != "" && !sameEcosystem(a.Ecosystem, ecosystem) {
+			return false
+		}
+	}
+
+	version := pkg.version()
+	if version == "" || (len(a.Versions) == 0 && len(a.Ranges) == 0) {
+		return true
+	}
+	if slices.Contains(a.Versions, version) {
+		return true
+	}
+	for _, r := range a.Ranges {
+		if r.contains(version) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r VersionRange) contains(version string) bool {
+	if r.Introduced != "" && r.Introduced != "0" && compareVersions(r.Type, version, r.Introduced) < 0 {
+		return false
+	}
+	switch {
+	case r.Fixed != "":
+		return compareVersions(r.Type, version, r.Fixed) < 0
+	case r.LastAffected != "":
+		return compareVersions(r.Type, version, r.LastAffected) <= 0
+	default:
+		return true
+	}
+}
+
+// sameEcosystem reports whether the given ecosystems are the same, ignoring
+// their releases, e.g. "Debian:12" and "Debian".
+func sameEcosystem(a, b string) bool {
+	a, _, _ = strings.Cut(a, ":")
+	b, _, _ = strings.Cut(b, ":")
+	return strings.EqualFold(a, b)
+}
+
+// Result is the feed built from advisories, along with the advisories that
+// couldn't be mitigated.
+type Result struct {
+	Feed *feed.Feed
+	// Unmitigated lists the advisories affecting the images that no template
+	// mitigates, as "<image>: <advisory ID>".
+	Unmitigated []string
+}
+
+// Ingest builds the feed of the virtual patches mitigating the given
+// advisories affecting the packages of the images of the mapping. The images
+// and CVEs of the base feed, e.g. curated by hand, are kept and take
+// precedence over the ingested ones.
+func Ingest(advisories []Advisory, mapping *Mapping, templates *Templates, base *feed.Feed) (*Result, error) {
+	result := &Result{Feed: &feed.Feed{SchemaVersion: feed.SchemaVersion}}
+	images := make(map[string]*feed.Image)
+	if base != nil {
+		for _, image := range base.Images {
+			baseImage := image
+			baseImage.CVEs = slices.Clone(image.CVEs)
+			images[image.Image] = &baseImage
+		}
+	}
+
+	for _, image := range mapping.Images {
+		for _, advisory := range advisories {
+			pkg, affected := advisory.affects(image.Packages)
+			if !affected {
+				continue
+			}
+			feedImage, ok := images[image.Image]
+			if !ok {
+				feedImage = &feed.Image{Image: image.Image}
+				images[image.Image] = feedImage
+			}
+			if slices.ContainsFunc(feedImage.CVEs, func(cve feed.CVE) bool { return cve.ID == advisory.ID }) {
+				continue
+			}
+
+			patches := templates.patchesFor(advisory, pkg)
+			if len(patches) == 0 {
+				result.Unmitigated = append(result.Unmitigated, image.Image+": "+advisory.ID)
+				continue
+			}
+			feedImage.CVEs = append(feedImage.CVEs, feed.CVE{ID: advisory.ID, VirtualPatch: patches})
+		}
+	}
+
+	for _, image := range images {
+		if len(image.CVEs) == 0 {
+			continue
+		}
+		slices.SortFunc(image.CVEs, func(a, b feed.CVE) int {
+			return cmp.Compare(a.ID, b.ID)
+		})
+		result.Feed.Images = append(result.Feed.Images, *image)
+	}
+	slices.SortFunc(result.Feed.Images, func(a, b feed.Image) int {
+		return cmp.Compare(a.Image, b.Image)
+	})
+	slices.Sort(result.Unmitigated)
+	result.Unmitigated = slices.Compact(result.Unmitigated)
+
+	if err := result.Feed.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid feed: %w", err)
+	}
+	return result, nil
+}
+
+// affects returns the first of the given packages the advisory affects.
+func (a Advisory) affects(pkgs []Package) (Package, bool) {
+	for _, pkg := range pkgs {
+		for _, affected := range a.Affected {
+			if affected.Affects(pkg) {
+				return pkg, true
+			}
+		}
+	}
+	return Package{}, false
+}
+
+// ReadAdvisories reads the advisories of the given JSON file, or of the JSON
+// files of the given directory, with the given parser, e.g. ParseOSV.
+func ReadAdvisories(path string, parse func(document []byte, source string) ([]Advisory, error)) ([]Advisory, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if info.IsDir() {
+		if files, err = filepath.Glob(filepath.Join(path, "*.json")); err != nil {
+			return nil, err
+		}
+	}
+
+	var advisories []Advisory
+	for _, file := range files {
+		document, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		fileAdvisories, err := parse(document, file)
+		if err != nil {
+			return nil, err
+		}
+		advisories = append(advisories, fileAdvisories...)
+	}
+	return advisories, nil
+}
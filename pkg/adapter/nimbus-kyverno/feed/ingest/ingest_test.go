@@ -0,0 +1,114 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package ingest
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-kyverno/feed"
+)
+
+func TestIngest(t *testing.T) {
+	osv, err := ReadAdvisories("testdata/osv.json", ParseOSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query, err := ReadAdvisories("testdata/query.json", ParseOSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	csaf, err := ReadAdvisories("testdata/csaf.json", ParseCSAF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	advisories := slices.Concat(osv, query, csaf)
+
+	mapping, err := LoadMapping("testdata/images.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates, err := LoadTemplates("testdata/templates.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	curated := feed.CVE{ID: "CVE-2024-0002", VirtualPatch: []feed.Patch{{
+		NetPol: map[string]any{
+			"metadata": map[string]any{"name": "curated"},
+			"spec":     map[string]any{"podSelector": map[string]any{}},
+		},
+	}}}
+	tests := []struct {
+		name string
+		base *feed.Feed
+		// want are the CVEs of the images, by image, and the names of the
+		// first policy of their patches, by CVE.
+		want            map[string]map[string]string
+		wantUnmitigated []string
+	}{
+		{
+			name: "advisories",
+			want: map[string]map[string]string{
+				"alpine:3.19": {"CVE-2024-0005": "cve-2024-0005-block-busybox-file-access"},
+				"nginx:1.25.3": {
+					"CVE-2024-0002": "cve-2024-0002-block-openssl-file-access",
+					"CVE-2024-0010": "cve-2024-0010-block-curl-file-access",
+				},
+				"node:20": {"CVE-2024-0001": "cve-2024-0001-block-shell-exec"},
+			},
+			// CVE-2024-0011 doesn't affect express 4.19.2, and OSV-0004 has
+			// no CWE.
+			wantUnmitigated: []string{"alpine:3.19: OSV-0004"},
+		},
+		{
+			name: "base feed",
+			base: &feed.Feed{SchemaVersion: feed.SchemaVersion, Images: []feed.Image{
+				{Image: "nginx:1.25.3", CVEs: []feed.CVE{curated}},
+				{Image: "redis:7", CVEs: []feed.CVE{{ID: "CVE-2024-0100", VirtualPatch: curated.VirtualPatch}}},
+			}},
+			want: map[string]map[string]string{
+				"alpine:3.19": {"CVE-2024-0005": "cve-2024-0005-block-busybox-file-access"},
+				"nginx:1.25.3": {
+					"CVE-2024-0002": "curated",
+					"CVE-2024-0010": "cve-2024-0010-block-curl-file-access",
+				},
+				"node:20": {"CVE-2024-0001": "cve-2024-0001-block-shell-exec"},
+				"redis:7": {"CVE-2024-0100": "curated"},
+			},
+			wantUnmitigated: []string{"alpine:3.19: OSV-0004"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Ingest(advisories, mapping, templates, tt.base)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make(map[string]map[string]string)
+			var images []string
+			for _, image := range result.Feed.Images {
+				images = append(images, image.Image)
+				got[image.Image] = make(map[string]string)
+				for _, cve := range image.CVEs {
+					for _, policy := range cve.VirtualPatch[0].Policies() {
+						got[image.Image][cve.ID] = policy["metadata"].(map[string]any)["name"].(string)
+					}
+				}
+			}
+			if !slices.IsSorted(images) {
+				t.Errorf("images %v aren't sorted", images)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Ingest() = %v, want %v", got, tt.want)
+			}
+			if !slices.Equal(result.Unmitigated, tt.wantUnmitigated) {
+				t.Errorf("Ingest() unmitigated = %v, want %v", result.Unmitigated, tt.wantUnmitigated)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package ingest
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Mapping lists the packages of the images, e.g. from their SBOMs:
+//
+//	images:
+//	  - image: nginx:1.25.3
+//	    packages:
+//	      - purl: pkg:deb/debian/openssl@3.0.11-1~deb12u2
+//	      - ecosystem: Debian
+//	        name: libxml2
+//	        version: 2.9.14+dfsg-1.3~deb12u1
+type Mapping struct {
+	Images []ImagePackages `json:"images"`
+}
+
+// ImagePackages lists the packages of an image.
+type ImagePackages struct {
+	// Image is the container image, as set in the feed, e.g. "nginx:latest".
+	Image    string    `json:"image"`
+	Packages []Package `json:"packages"`
+}
+
+// Package is a package of an image, identified by its PURL, or by its
+// ecosystem and name. All the versions of the package are considered affected
+// by its advisories when its version isn't set.
+type Package struct {
+	// PURL of the package, e.g. "pkg:deb/debian/openssl@3.0.11-1~deb12u2".
+	PURL string `json:"purl,omitempty"`
+	// Ecosystem of the package, as named by OSV, e.g. "Debian" or "npm".
+	Ecosystem string `json:"ecosystem,omitempty"`
+	// Name of the package, e.g. "openssl".
+	Name string `json:"name,omitempty"`
+	// Version of the package.
+	Version string `json:"version,omitempty"`
+
+	purl purl
+}
+
+func (p Package) name() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return p.purl.name
+}
+
+func (p Package) version() string {
+	if p.Version != "" {
+		return p.Version
+	}
+	return p.purl.version
+}
+
+func (p Package) ecosystem() string {
+	if p.Ecosystem != "" {
+		return p.Ecosystem
+	}
+	return p.purl.ecosystem()
+}
+
+// LoadMapping reads the mapping of the images to their packages from the
+// given YAML or JSON file.
+func LoadMapping(path string) (*Mapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var mapping Mapping
+	if err := yaml.UnmarshalStrict(data, &mapping); err != nil {
+		return nil, fmt.Errorf("invalid mapping %s: %w", path, err)
+	}
+
+	var errs []error
+	for i := range mapping.Images {
+		image := &mapping.Images[i]
+		if image.Image == "" {
+			errs = append(errs, fmt.Errorf("images[%d]: missing image", i))
+		}
+		for j := range image.Packages {
+			pkg := &image.Packages[j]
+			if pkg.PURL != "" {
+				if pkg.purl, err = parsePURL(pkg.PURL); err != nil {
+					errs = append(errs, fmt.Errorf("images[%d].packages[%d]: %w", i, j, err))
+				}
+			} else if pkg.Name == "" {
+				errs = append(errs, fmt.Errorf("images[%d].packages[%d]: missing purl or name", i, j))
+			}
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("invalid mapping %s: %w", path, err)
+	}
+	return &mapping, nil
+}
+
+// purl is a parsed package URL, see https://github.com/package-url/purl-spec.
+type purl struct {
+	typ       string
+	namespace string
+	name      string
+	version   string
+}
+
+// parsePURL parses the given package URL, ignoring its qualifiers and subpath.
+func parsePURL(s string) (purl, error) {
+	rest, found := strings.CutPrefix(s, "pkg:")
+	if !found {
+		return purl{}, fmt.Errorf("invalid purl %q", s)
+	}
+	rest, _, _ = strings.Cut(rest, "#")
+	rest, _, _ = strings.Cut(rest, "?")
+
+	var p purl
+	if idx := strings.LastIndex(rest, "@"); idx >= 0 {
+		rest, p.version = rest[:idx], rest[idx+1:]
+	}
+	parts := strings.Split(strings.Trim(rest, "/"), "/")
+	if len(parts) < 2 {
+		return purl{}, fmt.Errorf("invalid purl %q", s)
+	}
+	p.typ = strings.ToLower(parts[0])
+	p.namespace = strings.Join(parts[1:len(parts)-1], "/")
+	p.name = parts[len(parts)-1]
+
+	var err error
+	if p.namespace, err = url.PathUnescape(p.namespace); err != nil {
+		return purl{}, fmt.Errorf("invalid purl %q: %w", s, err)
+	}
+	if p.name, err = url.PathUnescape(p.name); err != nil {
+		return purl{}, fmt.Errorf("invalid purl %q: %w", s, err)
+	}
+	if p.version, err = url.PathUnescape(p.version); err != nil {
+		return purl{}, fmt.Errorf("invalid purl %q: %w", s, err)
+	}
+	if p.name == "" {
+		return purl{}, fmt.Errorf("invalid purl %q", s)
+	}
+	return p, nil
+}
+
+// withoutVersion returns the package URL without version, qualifiers and
+// subpath.
+func (p purl) withoutVersion() string {
+	if p.namespace == "" {
+		return "pkg:" + p.typ + "/" + p.name
+	}
+	return "pkg:" + p.typ + "/" + p.namespace + "/" + p.name
+}
+
+// ecosystem returns the OSV ecosystem of the package type, or an empty string
+// if unknown.
+func (p purl) ecosystem() string {
+	switch p.typ {
+	case "deb":
+		if strings.EqualFold(p.namespace, "ubuntu") {
+			return "Ubuntu"
+		}
+		return "Debian"
+	case "apk":
+		return "Alpine"
+	case "npm":
+		return "npm"
+	case "pypi":
+		return "PyPI"
+	case "golang":
+		return "Go"
+	case "maven":
+		return "Maven"
+	case "cargo":
+		return "crates.io"
+	case "gem":
+		return "RubyGems"
+	case "nuget":
+		return "NuGet"
+	case "composer":
+		return "Packagist"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,142 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package ingest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadMapping(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping string
+		wantErr bool
+	}{
+		{
+			name:    "purl and name",
+			mapping: "images:\n  - image: nginx\n    packages:\n      - purl: pkg:deb/debian/openssl@3.0.11\n      - name: libxml2\n",
+		},
+		{
+			name:    "missing image",
+			mapping: "images:\n  - packages:\n      - name: libxml2\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid purl",
+			mapping: "images:\n  - image: nginx\n    packages:\n      - purl: deb/openssl\n",
+			wantErr: true,
+		},
+		{
+			name:    "missing purl or name",
+			mapping: "images:\n  - image: nginx\n    packages:\n      - version: 1.0\n",
+			wantErr: true,
+		},
+		{
+			name:    "unknown field",
+			mapping: "images:\n  - image: nginx\n    pkgs: []\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "images.yaml")
+			if err := os.WriteFile(path, []byte(tt.mapping), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadMapping(path); (err != nil) != tt.wantErr {
+				t.Errorf("LoadMapping() error = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParsePURL(t *testing.T) {
+	tests := []struct {
+		purl      string
+		want      purl
+		ecosystem string
+		wantErr   bool
+	}{
+		{purl: "pkg:deb/debian/openssl@3.0.11-1~deb12u2?arch=amd64",
+			want:      purl{typ: "deb", namespace: "debian", name: "openssl", version: "3.0.11-1~deb12u2"},
+			ecosystem: "Debian"},
+		{purl: "pkg:deb/ubuntu/curl@8.5.0",
+			want:      purl{typ: "deb", namespace: "ubuntu", name: "curl", version: "8.5.0"},
+			ecosystem: "Ubuntu"},
+		{purl: "pkg:npm/%40angular/core@17.0.0",
+			want:      purl{typ: "npm", namespace: "@angular", name: "core", version: "17.0.0"},
+			ecosystem: "npm"},
+		{purl: "pkg:golang/example.com/mod/pkg#sub",
+			want:      purl{typ: "golang", namespace: "example.com/mod", name: "pkg"},
+			ecosystem: "Go"},
+		{purl: "pkg:generic/thing", want: purl{typ: "generic", name: "thing"}},
+		{purl: "deb/debian/openssl", wantErr: true},
+		{purl: "pkg:deb", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parsePURL(tt.purl)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parsePURL(%q) error = %v, wantErr %t", tt.purl, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want || got.ecosystem() != tt.ecosystem {
+			t.Errorf("parsePURL(%q) = %+v of ecosystem %q, want %+v of ecosystem %q", tt.purl, got, got.ecosystem(), tt.want, tt.ecosystem)
+		}
+	}
+}
+
+func TestAffects(t *testing.T) {
+	openssl := AffectedPackage{
+		Ecosystem: "Debian:12",
+		Name:      "openssl",
+		PURL:      "pkg:deb/debian/openssl",
+		Ranges:    []VersionRange{{Type: EcosystemRange, Introduced: "0", Fixed: "3.0.11-1~deb12u2"}},
+	}
+	leftPad := AffectedPackage{
+		Ecosystem: "npm",
+		Name:      "left-pad",
+		Versions:  []string{"0.9.0"},
+		Ranges:    []VersionRange{{Type: SemverRange, Introduced: "1.2.0-rc1", Fixed: "1.2.0"}},
+	}
+
+	tests := []struct {
+		name     string
+		affected AffectedPackage
+		pkg      Package
+		want     bool
+	}{
+		{"purl in range", openssl, purlPackage(t, "pkg:deb/debian/openssl@3.0.11-1~deb12u1"), true},
+		{"purl fixed", openssl, purlPackage(t, "pkg:deb/debian/openssl@3.0.11-1~deb12u2"), false},
+		{"purl other namespace", openssl, purlPackage(t, "pkg:deb/ubuntu/openssl@3.0.0"), false},
+		{"name and ecosystem", openssl, Package{Ecosystem: "Debian", Name: "OpenSSL", Version: "3.0.0-1"}, true},
+		{"other ecosystem", openssl, Package{Ecosystem: "Alpine", Name: "openssl", Version: "3.0.0-r1"}, false},
+		{"unversioned", openssl, Package{Name: "openssl"}, true},
+		{"pre-release in range", leftPad, Package{Ecosystem: "npm", Name: "left-pad", Version: "1.2.0-rc2"}, true},
+		{"release fixed", leftPad, Package{Ecosystem: "npm", Name: "left-pad", Version: "1.2.0"}, false},
+		{"listed version", leftPad, Package{Ecosystem: "npm", Name: "left-pad", Version: "0.9.0"}, true},
+		{"other name", leftPad, Package{Ecosystem: "npm", Name: "right-pad", Version: "1.2.0-rc2"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.affected.Affects(tt.pkg); got != tt.want {
+				t.Errorf("Affects() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+// purlPackage returns the package of the given PURL, as read from a mapping.
+func purlPackage(t *testing.T, s string) Package {
+	t.Helper()
+	p, err := parsePURL(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Package{PURL: s, purl: p}
+}
@@ -0,0 +1,143 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package ingest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// osvVulnerability is an OSV vulnerability, see https://ossf.github.io/osv-schema.
+type osvVulnerability struct {
+	ID               string        `json:"id"`
+	Aliases          []string      `json:"aliases"`
+	Withdrawn        string        `json:"withdrawn"`
+	Affected         []osvAffected `json:"affected"`
+	DatabaseSpecific struct {
+		CWEIDs []string `json:"cwe_ids"`
+	} `json:"database_specific"`
+}
+
+type osvAffected struct {
+	Package struct {
+		Ecosystem string `json:"ecosystem"`
+		Name      string `json:"name"`
+		PURL      string `json:"purl"`
+	} `json:"package"`
+	Ranges []struct {
+		Type   string `json:"type"`
+		Events []struct {
+			Introduced   string `json:"introduced"`
+			Fixed        string `json:"fixed"`
+			LastAffected string `json:"last_affected"`
+		} `json:"events"`
+	} `json:"ranges"`
+	Versions []string `json:"versions"`
+}
+
+// ParseOSV reads the advisories of an OSV document, holding a vulnerability,
+// an array of them, or the "vulns" of an osv.dev query response. The withdrawn
+// vulnerabilities are skipped, and the GIT ranges ignored.
+func ParseOSV(document []byte, source string) ([]Advisory, error) {
+	document = bytes.TrimSpace(document)
+	var vulns []osvVulnerability
+	switch {
+	case len(document) > 0 && document[0] == '[':
+		if err := json.Unmarshal(document, &vulns); err != nil {
+			return nil, fmt.Errorf("invalid OSV document %s: %w", source, err)
+		}
+	default:
+		var query struct {
+			Vulns []osvVulnerability `json:"vulns"`
+		}
+		if err := json.Unmarshal(document, &query); err != nil {
+			return nil, fmt.Errorf("invalid OSV document %s: %w", source, err)
+		}
+		if query.Vulns != nil {
+			vulns = query.Vulns
+			break
+		}
+		var vuln osvVulnerability
+		if err := json.Unmarshal(document, &vuln); err != nil {
+			return nil, fmt.Errorf("invalid OSV document %s: %w", source, err)
+		}
+		vulns = []osvVulnerability{vuln}
+	}
+
+	var advisories []Advisory
+	for _, vuln := range vulns {
+		if vuln.ID == "" {
+			return nil, fmt.Errorf("invalid OSV document %s: missing id", source)
+		}
+		if vuln.Withdrawn != "" {
+			continue
+		}
+		advisory := Advisory{
+			ID:     cveID(vuln.ID, vuln.Aliases),
+			CWEs:   vuln.DatabaseSpecific.CWEIDs,
+			Source: source,
+		}
+		for _, affected := range vuln.Affected {
+			pkg := AffectedPackage{
+				Ecosystem: affected.Package.Ecosystem,
+				Name:      affected.Package.Name,
+				Versions:  affected.Versions,
+			}
+			if affected.Package.PURL != "" {
+				if p, err := parsePURL(affected.Package.PURL); err == nil {
+					pkg.PURL = p.withoutVersion()
+				}
+			}
+			for _, r := range affected.Ranges {
+				if r.Type == "GIT" {
+					continue
+				}
+				var current *VersionRange
+				for _, event := range r.Events {
+					switch {
+					case event.Introduced != "":
+						if current != nil {
+							pkg.Ranges = append(pkg.Ranges, *current)
+						}
+						current = &VersionRange{Type: r.Type, Introduced: event.Introduced}
+					case event.Fixed != "" && current != nil:
+						current.Fixed = event.Fixed
+						pkg.Ranges = append(pkg.Ranges, *current)
+						current = nil
+					case event.LastAffected != "" && current != nil:
+						current.LastAffected = event.LastAffected
+						pkg.Ranges = append(pkg.Ranges, *current)
+						current = nil
+					}
+				}
+				if current != nil {
+					pkg.Ranges = append(pkg.Ranges, *current)
+				}
+			}
+			if len(affected.Ranges) > 0 && len(pkg.Ranges) == 0 && len(pkg.Versions) == 0 {
+				// Only GIT ranges, the affected versions are unknown.
+				continue
+			}
+			advisory.Affected = append(advisory.Affected, pkg)
+		}
+		advisories = append(advisories, advisory)
+	}
+	return advisories, nil
+}
+
+// cveID returns the CVE ID of a vulnerability, the given ID or the first CVE
+// alias, or the given ID if it has none.
+func cveID(id string, aliases []string) string {
+	if strings.HasPrefix(id, "CVE-") {
+		return id
+	}
+	for _, alias := range aliases {
+		if strings.HasPrefix(alias, "CVE-") {
+			return alias
+		}
+	}
+	return id
+}
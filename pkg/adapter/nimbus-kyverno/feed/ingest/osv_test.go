@@ -0,0 +1,121 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package ingest
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParseOSV(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		// file, when set, is the fixture read instead of the document.
+		file    string
+		want    []Advisory
+		wantErr bool
+	}{
+		{
+			name: "array",
+			file: "testdata/osv.json",
+			want: []Advisory{
+				{
+					ID:   "CVE-2024-0001",
+					CWEs: []string{"CWE-78"},
+					Affected: []AffectedPackage{{
+						Ecosystem: "npm",
+						Name:      "left-pad",
+						PURL:      "pkg:npm/left-pad",
+						Ranges: []VersionRange{
+							{Type: SemverRange, Introduced: "1.2.0-rc1", Fixed: "1.2.0"},
+							{Type: SemverRange, Introduced: "2.0.0", LastAffected: "2.1.0"},
+						},
+					}},
+				},
+				{
+					ID:   "CVE-2024-0002",
+					CWEs: []string{"CWE-22"},
+					Affected: []AffectedPackage{{
+						Ecosystem: "Debian:12",
+						Name:      "openssl",
+						Ranges:    []VersionRange{{Type: EcosystemRange, Introduced: "0", Fixed: "3.0.11-1~deb12u2"}},
+					}},
+				},
+				// The withdrawn GHSA-xxxx-0003 is skipped, and the package
+				// with GIT ranges only of OSV-0004.
+				{
+					ID: "OSV-0004",
+					Affected: []AffectedPackage{{
+						Ecosystem: "PyPI",
+						Name:      "requests",
+						Versions:  []string{"2.31.0"},
+					}},
+				},
+			},
+		},
+		{
+			name: "query response",
+			file: "testdata/query.json",
+			want: []Advisory{{
+				ID: "CVE-2024-0005",
+				Affected: []AffectedPackage{{
+					Ecosystem: "Alpine:v3.19",
+					Name:      "busybox",
+					Ranges:    []VersionRange{{Type: EcosystemRange, Introduced: "0", Fixed: "1.36.1-r16"}},
+				}},
+			}},
+		},
+		{
+			name:     "single vulnerability",
+			document: `{"id": "GHSA-xxxx-0006", "aliases": ["CVE-2024-0006"], "affected": [{"package": {"ecosystem": "Go", "name": "example.com/mod"}, "ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}, {"fixed": "1.0.1"}, {"introduced": "1.1.0"}]}]}]}`,
+			want: []Advisory{{
+				ID: "CVE-2024-0006",
+				Affected: []AffectedPackage{{
+					Ecosystem: "Go",
+					Name:      "example.com/mod",
+					Ranges: []VersionRange{
+						{Type: SemverRange, Introduced: "0", Fixed: "1.0.1"},
+						{Type: SemverRange, Introduced: "1.1.0"},
+					},
+				}},
+			}},
+		},
+		{
+			name:     "missing id",
+			document: `[{"affected": []}]`,
+			wantErr:  true,
+		},
+		{
+			name:     "invalid",
+			document: `{"id": 1}`,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			document, source := []byte(tt.document), "test.json"
+			if tt.file != "" {
+				var err error
+				if document, err = os.ReadFile(tt.file); err != nil {
+					t.Fatal(err)
+				}
+				source = tt.file
+			}
+			for i := range tt.want {
+				tt.want[i].Source = source
+			}
+
+			got, err := ParseOSV(document, source)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseOSV() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseOSV() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
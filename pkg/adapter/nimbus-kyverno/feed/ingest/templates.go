@@ -0,0 +1,146 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package ingest
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-kyverno/feed"
+)
+
+// Templates are the curated mitigations of the advisories, by CWE or package:
+//
+//	templates:
+//	  - name: os-command-injection
+//	    cwes: ["CWE-77", "CWE-78"]
+//	    virtual_patch:
+//	      - karmor:
+//	          apiVersion: security.kubearmor.com/v1
+//	          kind: KubeArmorPolicy
+//	          metadata:
+//	            name: ${cve}-block-shell-exec
+//	          spec: ...
+//
+// The "${CVE}", "${cve}" (lowercase) and "${PACKAGE}" placeholders of the
+// strings of the policies are replaced with the ID of the advisory and the
+// name of the affected package.
+type Templates struct {
+	Templates []Template `json:"templates"`
+}
+
+// Template is the mitigation of the advisories of some CWEs or packages.
+type Template struct {
+	// Name of the template.
+	Name string `json:"name"`
+	// CWEs are the weaknesses mitigated by the template, e.g. "CWE-78".
+	CWEs []string `json:"cwes,omitempty"`
+	// Packages are the names of the packages whose advisories are mitigated
+	// by the template, e.g. "openssl".
+	Packages []string `json:"packages,omitempty"`
+	// VirtualPatch are the policies mitigating the advisories.
+	VirtualPatch []feed.Patch `json:"virtual_patch"`
+}
+
+// LoadTemplates reads the templates from the given YAML or JSON file.
+func LoadTemplates(path string) (*Templates, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var templates Templates
+	if err := yaml.UnmarshalStrict(data, &templates); err != nil {
+		return nil, fmt.Errorf("invalid templates %s: %w", path, err)
+	}
+
+	var errs []error
+	for i, template := range templates.Templates {
+		name := fmt.Sprintf("templates[%d]", i)
+		if template.Name == "" {
+			errs = append(errs, fmt.Errorf("%s: missing name", name))
+		} else {
+			name = template.Name
+		}
+		if len(template.CWEs) == 0 && len(template.Packages) == 0 {
+			errs = append(errs, fmt.Errorf("%s: no cwes or packages", name))
+		}
+		if len(template.VirtualPatch) == 0 {
+			errs = append(errs, fmt.Errorf("%s: missing virtual_patch", name))
+		}
+		for j, patch := range template.VirtualPatch {
+			if err := patch.Validate(); err != nil {
+				errs = append(errs, fmt.Errorf("%s.virtual_patch[%d]: %w", name, j, err))
+			}
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("invalid templates %s: %w", path, err)
+	}
+	return &templates, nil
+}
+
+// patchesFor returns the patches of the templates matching the CWEs of the
+// given advisory or the given affected package.
+func (t *Templates) patchesFor(advisory Advisory, pkg Package) []feed.Patch {
+	replacer := strings.NewReplacer(
+		"${CVE}", advisory.ID,
+		"${cve}", strings.ToLower(advisory.ID),
+		"${PACKAGE}", pkg.name(),
+	)
+
+	var patches []feed.Patch
+	for _, template := range t.Templates {
+		matches := slices.ContainsFunc(template.CWEs, func(cwe string) bool {
+			return slices.Contains(advisory.CWEs, cwe)
+		}) || slices.ContainsFunc(template.Packages, func(name string) bool {
+			return strings.EqualFold(name, pkg.name())
+		})
+		if !matches {
+			continue
+		}
+		for _, patch := range template.VirtualPatch {
+			patches = append(patches, feed.Patch{
+				KubeArmor: substitute(patch.KubeArmor, replacer),
+				Kyverno:   substitute(patch.Kyverno, replacer),
+				NetPol:    substitute(patch.NetPol, replacer),
+			})
+		}
+	}
+	return patches
+}
+
+// substitute returns a copy of the given policy with the placeholders of its
+// strings replaced.
+func substitute(policy map[string]any, replacer *strings.Replacer) map[string]any {
+	if policy == nil {
+		return nil
+	}
+	return substituteValue(policy, replacer).(map[string]any)
+}
+
+func substituteValue(value any, replacer *strings.Replacer) any {
+	switch value := value.(type) {
+	case string:
+		return replacer.Replace(value)
+	case map[string]any:
+		out := make(map[string]any, len(value))
+		for key, v := range value {
+			out[key] = substituteValue(v, replacer)
+		}
+		return out
+	case []any:
+		out := make([]any, len(value))
+		for idx, v := range value {
+			out[idx] = substituteValue(v, replacer)
+		}
+		return out
+	default:
+		return value
+	}
+}
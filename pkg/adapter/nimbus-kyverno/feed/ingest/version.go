@@ -0,0 +1,211 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package ingest
+
+import (
+	"cmp"
+	"strings"
+)
+
+// The types of the version ranges, as named by OSV.
+const (
+	// SemverRange ranges compare the versions per Semantic Versioning 2.0.0.
+	SemverRange = "SEMVER"
+	// EcosystemRange ranges compare the versions like dpkg and rpm do.
+	EcosystemRange = "ECOSYSTEM"
+)
+
+// compareVersions compares the given versions per the given range type, with
+// compareSemver for SEMVER ranges, and compareEcosystemVersions otherwise. It
+// returns -1, 0 or 1.
+func compareVersions(rangeType, a, b string) int {
+	if rangeType == SemverRange {
+		return compareSemver(a, b)
+	}
+	return compareEcosystemVersions(a, b)
+}
+
+// compareSemver compares the given versions per Semantic Versioning 2.0.0: the
+// major, minor and patch versions numerically, a missing one being 0, then the
+// pre-release identifiers, a pre-release sorting before its release, e.g.
+// 1.2.0-rc1 < 1.2.0. The build metadata and a "v" prefix are ignored.
+func compareSemver(a, b string) int {
+	coreA, preA := splitSemver(a)
+	coreB, preB := splitSemver(b)
+
+	partsA, partsB := strings.Split(coreA, "."), strings.Split(coreB, ".")
+	for i := 0; i < len(partsA) || i < len(partsB); i++ {
+		partA, partB := "0", "0"
+		if i < len(partsA) {
+			partA = partsA[i]
+		}
+		if i < len(partsB) {
+			partB = partsB[i]
+		}
+		if c := compareIdentifiers(partA, partB); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case preA == preB:
+		return 0
+	case preA == "":
+		return 1
+	case preB == "":
+		return -1
+	}
+	idsA, idsB := strings.Split(preA, "."), strings.Split(preB, ".")
+	for i := 0; i < len(idsA) && i < len(idsB); i++ {
+		if c := compareIdentifiers(idsA[i], idsB[i]); c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(idsA), len(idsB))
+}
+
+// splitSemver returns the core version, e.g. "1.2.0", and the pre-release,
+// e.g. "rc.1", of the given semantic version.
+func splitSemver(version string) (string, string) {
+	version = strings.TrimPrefix(version, "v")
+	version, _, _ = strings.Cut(version, "+")
+	core, pre, _ := strings.Cut(version, "-")
+	return core, pre
+}
+
+// compareIdentifiers compares the given semver identifiers, the numeric ones
+// numerically and before the alphanumeric ones, compared lexically.
+func compareIdentifiers(a, b string) int {
+	numericA, numericB := isNumeric(a), isNumeric(b)
+	switch {
+	case numericA && numericB:
+		return compareNumbers(a, b)
+	case numericA:
+		return -1
+	case numericB:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+// compareEcosystemVersions compares the given versions of an OS package like
+// dpkg and rpm do: by epoch, e.g. "2:", a missing one being 0, then by upstream
+// version and revision, split at the last "-". A "v" prefix is ignored.
+func compareEcosystemVersions(a, b string) int {
+	epochA, a := splitEpoch(a)
+	epochB, b := splitEpoch(b)
+	if c := compareNumbers(epochA, epochB); c != 0 {
+		return c
+	}
+
+	upstreamA, revisionA := splitRevision(a)
+	upstreamB, revisionB := splitRevision(b)
+	if c := compareSegments(upstreamA, upstreamB); c != 0 {
+		return c
+	}
+	return compareSegments(revisionA, revisionB)
+}
+
+// splitEpoch returns the epoch, "0" when missing, and the rest of the given
+// version.
+func splitEpoch(version string) (string, string) {
+	epoch := "0"
+	if e, rest, found := strings.Cut(version, ":"); found && isNumeric(e) {
+		epoch, version = e, rest
+	}
+	return epoch, strings.TrimPrefix(version, "v")
+}
+
+// splitRevision returns the upstream version and the revision, or release, of
+// the given version.
+func splitRevision(version string) (string, string) {
+	if idx := strings.LastIndex(version, "-"); idx >= 0 {
+		return version[:idx], version[idx+1:]
+	}
+	return version, ""
+}
+
+// compareSegments compares the given versions segment by segment, the digit
+// segments numerically and the others lexically. "~" sorts before anything,
+// even the end of the version, so that pre-releases sort before releases, e.g.
+// 1.0~rc1 < 1.0.
+func compareSegments(a, b string) int {
+	for a != "" || b != "" {
+		if c := compareNonDigits(&a, &b); c != 0 {
+			return c
+		}
+		if c := compareNumbers(leading(&a, true), leading(&b, true)); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// compareNonDigits compares and consumes the leading non-digit segments of the
+// given versions.
+func compareNonDigits(a, b *string) int {
+	segA, segB := leading(a, false), leading(b, false)
+	for i := 0; i < len(segA) || i < len(segB); i++ {
+		if c := cmp.Compare(order(segA, i), order(segB, i)); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// order returns the weight of the ith character of the given segment: "~"
+// sorts before the end of the segment, the end before the letters, and the
+// letters before the other characters.
+func order(segment string, i int) int {
+	if i >= len(segment) {
+		return 0
+	}
+	switch c := segment[i]; {
+	case c == '~':
+		return -1
+	case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		return int(c)
+	default:
+		return int(c) + 256
+	}
+}
+
+// compareNumbers compares the given digit strings numerically, whatever their
+// length.
+func compareNumbers(a, b string) int {
+	a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+	if c := cmp.Compare(len(a), len(b)); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+// leading consumes and returns the leading digits, or non-digits, of the
+// given version.
+func leading(version *string, digits bool) string {
+	end := 0
+	for end < len(*version) && isDigit((*version)[end]) == digits {
+		end++
+	}
+	segment := (*version)[:end]
+	*version = (*version)[end:]
+	return segment
+}
+
+func isNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,80 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package ingest
+
+import (
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		rangeType string
+		a, b      string
+		want      int
+	}{
+		// Semantic versions.
+		{SemverRange, "1.2.0", "1.2.0", 0},
+		{SemverRange, "v1.2.0", "1.2.0", 0},
+		{SemverRange, "1.2", "1.2.0", 0},
+		{SemverRange, "1.2.0+build.5", "1.2.0", 0},
+		{SemverRange, "1.2.0", "1.10.0", -1},
+		{SemverRange, "1.2.0-rc1", "1.2.0", -1},
+		{SemverRange, "1.2.0", "1.2.0-rc1", 1},
+		{SemverRange, "1.2.0-rc1", "1.2.0-rc2", -1},
+		{SemverRange, "1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{SemverRange, "1.0.0-alpha.1", "1.0.0-alpha.beta", -1},
+		{SemverRange, "1.0.0-beta.2", "1.0.0-beta.11", -1},
+		{SemverRange, "1.0.0-rc.1", "1.0.0", -1},
+
+		// dpkg and rpm versions.
+		{EcosystemRange, "1.0", "1.0", 0},
+		{EcosystemRange, "0:1.0", "1.0", 0},
+		{EcosystemRange, "2:1.0", "1.9", 1},
+		{EcosystemRange, "1.9", "1:1.0", -1},
+		{EcosystemRange, "1:1.0", "2:0.1", -1},
+		{EcosystemRange, "1.0~rc1", "1.0", -1},
+		{EcosystemRange, "1.0", "1.0a", -1},
+		{EcosystemRange, "1.0-1", "1.0.1-1", -1},
+		{EcosystemRange, "1.0-10", "1.0-9", 1},
+		{EcosystemRange, "3.0.11-1~deb12u1", "3.0.11-1~deb12u2", -1},
+		{EcosystemRange, "3.0.11-1~deb12u2", "3.0.11-1", -1},
+		{EcosystemRange, "2.9.14+dfsg-1.3~deb12u1", "2.9.14+dfsg-1.3", -1},
+		{EcosystemRange, "1.36.1-r15", "1.36.1-r16", -1},
+		{"", "2:1.0", "1.9", 1},
+	}
+
+	for _, tt := range tests {
+		if got := compareVersions(tt.rangeType, tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q, %q) = %d, want %d", tt.rangeType, tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestVersionRangeContains(t *testing.T) {
+	tests := []struct {
+		name    string
+		r       VersionRange
+		version string
+		want    bool
+	}{
+		{"unbounded", VersionRange{}, "1.0", true},
+		{"introduced 0", VersionRange{Introduced: "0", Fixed: "1.0"}, "0.1", true},
+		{"fixed", VersionRange{Introduced: "1.0", Fixed: "1.2"}, "1.2", false},
+		{"before introduced", VersionRange{Introduced: "1.0", Fixed: "1.2"}, "0.9", false},
+		{"last affected", VersionRange{Introduced: "1.0", LastAffected: "1.2"}, "1.2", true},
+		{"after last affected", VersionRange{Introduced: "1.0", LastAffected: "1.2"}, "1.2.1", false},
+		{"semver pre-release", VersionRange{Type: SemverRange, Introduced: "1.2.0-rc1", Fixed: "1.2.0"}, "1.2.0-rc2", true},
+		{"semver release", VersionRange{Type: SemverRange, Introduced: "1.2.0-rc1", Fixed: "1.2.0"}, "1.2.0", false},
+		{"epoch fixed", VersionRange{Type: EcosystemRange, Introduced: "0", Fixed: "1:1.0"}, "1.9", true},
+		{"epoch introduced", VersionRange{Type: EcosystemRange, Introduced: "1:1.0"}, "2.0", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.r.contains(tt.version); got != tt.want {
+				t.Errorf("contains(%q) = %t, want %t", tt.version, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package feed
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+
+	"github.com/5GSEC/nimbus/api/v1alpha1"
+)
+
+const (
+	// LocationEnv is the environment variable setting the location of the
+	// feed, see NewSource.
+	LocationEnv = "VIRTUAL_PATCH_FEED"
+	// ChecksumEnv is the environment variable setting the expected SHA-256
+	// digest of the feed.
+	ChecksumEnv = "VIRTUAL_PATCH_FEED_SHA256"
+	// PublicKeyEnv is the environment variable setting the path of the PEM
+	// encoded public key verifying the signature of the feed.
+	PublicKeyEnv = "VIRTUAL_PATCH_FEED_PUBLIC_KEY"
+)
+
+// Loader loads the feed from its source. The last feed loaded successfully is
+// kept, so that the policies aren't torn down when the source is temporarily
+// unavailable or serves an invalid feed, and served by Cached between the
+// loads.
+type Loader struct {
+	location string
+	source   Source
+	verifier Verifier
+	// err is the configuration error of the loader, reported on every load.
+	err error
+
+	// loading serializes the loads, so that concurrent refreshes fetch the
+	// feed once.
+	loading sync.Mutex
+
+	mu     sync.Mutex
+	loaded bool
+	last   *Feed
+	status v1alpha1.VirtualPatchFeedStatus
+}
+
+// NewLoaderFromEnv returns the loader of the feed configured with the
+// VIRTUAL_PATCH_FEED, VIRTUAL_PATCH_FEED_SHA256 and
+// VIRTUAL_PATCH_FEED_PUBLIC_KEY environment variables.
+func NewLoaderFromEnv(client dynamic.Interface) *Loader {
+	loader := &Loader{
+		location: os.Getenv(LocationEnv),
+		verifier: Verifier{Checksum: os.Getenv(ChecksumEnv)},
+	}
+	if loader.location == "" {
+		loader.err = fmt.Errorf("the virtual patch feed is disabled, set its location in the %s environment variable", LocationEnv)
+		return loader
+	}
+
+	var errs []error
+	source, err := NewSource(loader.location, client)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	loader.source = source
+
+	if path := os.Getenv(PublicKeyEnv); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to read the feed public key: %w", err))
+		} else if loader.verifier.PublicKey, err = ParsePublicKey(data); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	loader.err = errors.Join(errs...)
+	return loader
+}
+
+// Load fetches, verifies and validates the feed, and returns it along with
+// the status of the load. When the feed fails to load, the last feed loaded
+// successfully, if any, is returned along with the failure.
+func (l *Loader) Load(ctx context.Context) (*Feed, v1alpha1.VirtualPatchFeedStatus) {
+	l.loading.Lock()
+	defer l.loading.Unlock()
+	return l.reload(ctx)
+}
+
+// Refresh loads the feed, unless it was loaded less than maxAge ago, e.g. by a
+// refresh running concurrently, and returns the status of the last load.
+func (l *Loader) Refresh(ctx context.Context, maxAge time.Duration) v1alpha1.VirtualPatchFeedStatus {
+	l.loading.Lock()
+	defer l.loading.Unlock()
+
+	if _, status, loaded := l.Cached(); loaded && time.Since(status.LastLoaded.Time) < maxAge {
+		return status
+	}
+	_, status := l.reload(ctx)
+	return status
+}
+
+// Cached returns the feed Load returned last time, along with the status of
+// that load, without fetching the feed. It returns false if the feed was never
+// loaded.
+func (l *Loader) Cached() (*Feed, v1alpha1.VirtualPatchFeedStatus, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.last, l.status, l.loaded
+}
+
+// reload loads the feed and keeps it, along with the status of the load.
+func (l *Loader) reload(ctx context.Context) (*Feed, v1alpha1.VirtualPatchFeedStatus) {
+	feed, status, err := l.load(ctx)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.loaded = true
+	if err != nil {
+		status = l.status
+		status.Source = l.location
+		status.State = v1alpha1.VirtualPatchFeedFailed
+		status.Message = err.Error()
+		status.LastLoaded = metav1.Now()
+		l.status = status
+		return l.last, status
+	}
+	l.last, l.status = feed, status
+	return feed, status
+}
+
+func (l *Loader) load(ctx context.Context) (*Feed, v1alpha1.VirtualPatchFeedStatus, error) {
+	var status v1alpha1.VirtualPatchFeedStatus
+	if l.err != nil {
+		return nil, status, l.err
+	}
+
+	document, signature, err := l.source.Fetch(ctx)
+	if err != nil {
+		return nil, status, fmt.Errorf("failed to fetch the feed from %s: %w", l.source, err)
+	}
+	verification, err := l.verifier.Verify(document, signature)
+	if err != nil {
+		return nil, status, err
+	}
+	feed, err := Parse(document)
+	if err != nil {
+		return nil, status, err
+	}
+
+	status = v1alpha1.VirtualPatchFeedStatus{
+		Source:        l.location,
+		State:         v1alpha1.VirtualPatchFeedLoaded,
+		SchemaVersion: feed.SchemaVersion,
+		Digest:        fmt.Sprintf("sha256:%x", sha256.Sum256(document)),
+		Verification:  verification,
+		LastLoaded:    metav1.Now(),
+	}
+	return feed, status, nil
+}
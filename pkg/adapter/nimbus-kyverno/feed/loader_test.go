@@ -0,0 +1,194 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package feed_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/5GSEC/nimbus/api/v1alpha1"
+	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-kyverno/feed"
+	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-kyverno/feed/feedtest"
+)
+
+func TestLoad(t *testing.T) {
+	document, err := os.ReadFile("../../../../vp.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(document)
+	checksum := hex.EncodeToString(sum[:])
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, document)))
+	publicKeyPath := writePublicKey(t, publicKey)
+
+	server, err := feedtest.NewServer("127.0.0.1:0", document, signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	tampered := append([]byte(" "), document...)
+	tests := []struct {
+		name         string
+		location     string
+		checksum     string
+		publicKey    string
+		requireToken bool
+		// served replaces the served feed when set.
+		served []byte
+		// state and verification are the expected state and verification
+		// of the loaded feed.
+		state        string
+		verification string
+	}{
+		{name: "http", location: server.HTTPLocation(),
+			state: v1alpha1.VirtualPatchFeedLoaded, verification: feed.VerificationNone},
+		{name: "oci", location: server.OCILocation(),
+			state: v1alpha1.VirtualPatchFeedLoaded, verification: feed.VerificationNone},
+		{name: "oci with token", location: server.OCILocation(), requireToken: true,
+			state: v1alpha1.VirtualPatchFeedLoaded, verification: feed.VerificationNone},
+		{name: "http checksum", location: server.HTTPLocation(), checksum: "sha256:" + checksum,
+			state: v1alpha1.VirtualPatchFeedLoaded, verification: feed.VerificationChecksum},
+		{name: "oci checksum mismatch", location: server.OCILocation(), checksum: checksum, served: tampered,
+			state: v1alpha1.VirtualPatchFeedFailed},
+		{name: "http signature", location: server.HTTPLocation(), publicKey: publicKeyPath,
+			state: v1alpha1.VirtualPatchFeedLoaded, verification: feed.VerificationSignature},
+		{name: "oci signature", location: server.OCILocation(), checksum: checksum, publicKey: publicKeyPath,
+			state: v1alpha1.VirtualPatchFeedLoaded, verification: feed.VerificationSignature},
+		{name: "http signature mismatch", location: server.HTTPLocation(), publicKey: publicKeyPath, served: tampered,
+			state: v1alpha1.VirtualPatchFeedFailed},
+		{name: "oci signature mismatch", location: server.OCILocation(), publicKey: publicKeyPath, served: tampered,
+			state: v1alpha1.VirtualPatchFeedFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			served := document
+			if tt.served != nil {
+				served = tt.served
+			}
+			server.SetFeed(served, signature)
+			server.RequireToken(tt.requireToken)
+
+			t.Setenv(feed.LocationEnv, tt.location)
+			t.Setenv(feed.ChecksumEnv, tt.checksum)
+			t.Setenv(feed.PublicKeyEnv, tt.publicKey)
+
+			loaded, status := feed.NewLoaderFromEnv(nil).Load(context.Background())
+			if status.State != tt.state {
+				t.Fatalf("state = %s (%s), want %s", status.State, status.Message, tt.state)
+			}
+			if tt.state == v1alpha1.VirtualPatchFeedFailed {
+				if loaded != nil {
+					t.Errorf("loaded a feed failing verification")
+				}
+				return
+			}
+			if status.Verification != tt.verification {
+				t.Errorf("verification = %s, want %s", status.Verification, tt.verification)
+			}
+			if status.Digest != "sha256:"+checksum {
+				t.Errorf("digest = %s, want sha256:%s", status.Digest, checksum)
+			}
+			if loaded == nil || len(loaded.Images) == 0 {
+				t.Errorf("no images loaded")
+			}
+		})
+	}
+}
+
+func TestLoadKeepsLastFeed(t *testing.T) {
+	document, err := os.ReadFile("../../../../vp.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := feedtest.NewServer("127.0.0.1:0", document, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	t.Setenv(feed.LocationEnv, server.HTTPLocation())
+	loader := feed.NewLoaderFromEnv(nil)
+	first, status := loader.Load(context.Background())
+	if status.State != v1alpha1.VirtualPatchFeedLoaded {
+		t.Fatalf("state = %s (%s), want %s", status.State, status.Message, v1alpha1.VirtualPatchFeedLoaded)
+	}
+
+	server.SetFeed([]byte(`{"schemaVersion": "v0"}`), nil)
+	last, status := loader.Load(context.Background())
+	if status.State != v1alpha1.VirtualPatchFeedFailed {
+		t.Fatalf("state = %s, want %s", status.State, v1alpha1.VirtualPatchFeedFailed)
+	}
+	if last != first {
+		t.Errorf("the last loaded feed wasn't kept")
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	document, err := os.ReadFile("../../../../vp.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := feedtest.NewServer("127.0.0.1:0", document, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	t.Setenv(feed.LocationEnv, server.HTTPLocation())
+	loader := feed.NewLoaderFromEnv(nil)
+	if _, _, loaded := loader.Cached(); loaded {
+		t.Fatal("feed cached before being loaded")
+	}
+	status := loader.Refresh(context.Background(), time.Hour)
+	if status.State != v1alpha1.VirtualPatchFeedLoaded {
+		t.Fatalf("state = %s (%s), want %s", status.State, status.Message, v1alpha1.VirtualPatchFeedLoaded)
+	}
+	first, cached, loaded := loader.Cached()
+	if !loaded || first == nil || cached.Digest != status.Digest {
+		t.Fatalf("Cached() = %v, %+v, %t, want the loaded feed", first, cached, loaded)
+	}
+
+	// A feed loaded less than maxAge ago isn't fetched again.
+	server.SetFeed(append(document, ' '), nil)
+	if refreshed := loader.Refresh(context.Background(), time.Hour); refreshed.Digest != status.Digest {
+		t.Errorf("feed loaded again before maxAge")
+	}
+	refreshed := loader.Refresh(context.Background(), 0)
+	if refreshed.State != v1alpha1.VirtualPatchFeedLoaded || refreshed.Digest == status.Digest {
+		t.Errorf("feed not loaded again after maxAge, status = %+v", refreshed)
+	}
+	if last, _, _ := loader.Cached(); last == first {
+		t.Errorf("the refreshed feed isn't cached")
+	}
+}
+
+func writePublicKey(t *testing.T, key ed25519.PublicKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "public-key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
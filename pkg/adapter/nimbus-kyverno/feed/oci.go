@@ -0,0 +1,225 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package feed
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Media types of the layers of the OCI artifacts holding a feed, e.g. pushed
+// with:
+//
+//	oras push <registry>/<repository>:<tag> \
+//	  vp.json:application/vnd.nimbus.virtualpatch.feed.v1+json \
+//	  vp.json.sig:application/vnd.nimbus.virtualpatch.signature.v1
+const (
+	FeedMediaType      = "application/vnd.nimbus.virtualpatch.feed.v1+json"
+	SignatureMediaType = "application/vnd.nimbus.virtualpatch.signature.v1"
+)
+
+// manifestMediaTypes are the media types of the manifests of the artifacts.
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// ociSource pulls the feed from the layers of an OCI artifact, anonymously or
+// with the token the registry grants to anonymous pulls.
+type ociSource struct {
+	location   string
+	baseURL    string
+	repository string
+	reference  string
+	client     *http.Client
+}
+
+type ociDescriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+}
+
+type ociManifest struct {
+	Layers []ociDescriptor `json:"layers"`
+}
+
+// newOCISource parses the given reference, as
+// <registry>/<repository>:<tag> or <registry>/<repository>@<digest>.
+func newOCISource(location, ref string, plainHTTP bool) (Source, error) {
+	registry, repository, found := strings.Cut(ref, "/")
+	if !found || registry == "" || repository == "" {
+		return nil, fmt.Errorf("invalid OCI feed source %q, expected oci://<registry>/<repository>:<tag>", location)
+	}
+
+	reference := "latest"
+	if name, digest, found := strings.Cut(repository, "@"); found {
+		repository, reference = name, digest
+	} else if idx := strings.LastIndex(repository, ":"); idx > strings.LastIndex(repository, "/") {
+		repository, reference = repository[:idx], repository[idx+1:]
+	}
+	if repository == "" || reference == "" {
+		return nil, fmt.Errorf("invalid OCI feed source %q, expected oci://<registry>/<repository>:<tag>", location)
+	}
+
+	scheme := "https"
+	if plainHTTP {
+		scheme = "http"
+	}
+	return ociSource{
+		location:   location,
+		baseURL:    scheme + "://" + registry + "/v2/" + repository,
+		repository: repository,
+		reference:  reference,
+		client:     &http.Client{Timeout: time.Minute},
+	}, nil
+}
+
+func (s ociSource) Fetch(ctx context.Context) ([]byte, []byte, error) {
+	var token string
+	manifestBytes, err := s.get(ctx, s.baseURL+"/manifests/"+s.reference, strings.Join(manifestMediaTypes, ", "), &token)
+	if err != nil {
+		return nil, nil, err
+	}
+	var manifest ociManifest
+	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
+		return nil, nil, fmt.Errorf("invalid manifest of %s: %w", s.location, err)
+	}
+
+	var feedLayer, signatureLayer *ociDescriptor
+	for idx := range manifest.Layers {
+		layer := &manifest.Layers[idx]
+		switch {
+		case layer.MediaType == FeedMediaType:
+			feedLayer = layer
+		case layer.MediaType == SignatureMediaType:
+			signatureLayer = layer
+		case feedLayer == nil:
+			// Artifacts pushed without media types have a single layer.
+			feedLayer = layer
+		}
+	}
+	if feedLayer == nil {
+		return nil, nil, fmt.Errorf("no feed layer in %s", s.location)
+	}
+
+	document, err := s.blob(ctx, *feedLayer, &token)
+	if err != nil {
+		return nil, nil, err
+	}
+	var signature []byte
+	if signatureLayer != nil {
+		if signature, err = s.blob(ctx, *signatureLayer, &token); err != nil {
+			return nil, nil, err
+		}
+	}
+	return document, signature, nil
+}
+
+// blob downloads the given layer and checks its digest.
+func (s ociSource) blob(ctx context.Context, layer ociDescriptor, token *string) ([]byte, error) {
+	algorithm, expected, found := strings.Cut(layer.Digest, ":")
+	if !found || algorithm != "sha256" {
+		return nil, fmt.Errorf("unsupported digest %q of a layer of %s", layer.Digest, s.location)
+	}
+	data, err := s.get(ctx, s.baseURL+"/blobs/"+layer.Digest, "", token)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != expected {
+		return nil, fmt.Errorf("digest mismatch of layer %s of %s", layer.Digest, s.location)
+	}
+	return data, nil
+}
+
+// get downloads the given registry URL, requesting a token for anonymous
+// pulls when the registry requires one.
+func (s ociSource) get(ctx context.Context, location, accept string, token *string) ([]byte, error) {
+	header := http.Header{}
+	if accept != "" {
+		header.Set("Accept", accept)
+	}
+	if *token != "" {
+		header.Set("Authorization", "Bearer "+*token)
+	}
+
+	resp, err := do(ctx, s.client, location, header)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized && *token == "" {
+		if *token, err = s.anonymousToken(ctx, resp.Header.Get("WWW-Authenticate")); err != nil {
+			return nil, err
+		}
+		return s.get(ctx, location, accept, token)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get %s: %s", location, resp.Status)
+	}
+	return readAll(resp.Body)
+}
+
+// challengeParam matches the parameters of a WWW-Authenticate challenge.
+var challengeParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// anonymousToken requests a token for pulling the repository from the realm
+// of the given Bearer challenge.
+func (s ociSource) anonymousToken(ctx context.Context, challenge string) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", fmt.Errorf("unsupported authentication %q required by %s", scheme, s.location)
+	}
+
+	values := url.Values{}
+	var realm string
+	for _, match := range challengeParam.FindAllStringSubmatch(params, -1) {
+		if match[1] == "realm" {
+			realm = match[2]
+		} else {
+			values.Set(match[1], match[2])
+		}
+	}
+	if realm == "" {
+		return "", fmt.Errorf("no token realm in the authentication challenge of %s", s.location)
+	}
+	if values.Get("scope") == "" {
+		values.Set("scope", "repository:"+s.repository+":pull")
+	}
+
+	body, found, err := get(ctx, s.client, realm+"?"+values.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", fmt.Errorf("token realm %s not found", realm)
+	}
+	var resp struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", fmt.Errorf("invalid token from %s: %w", realm, err)
+	}
+	if resp.Token == "" {
+		resp.Token = resp.AccessToken
+	}
+	if resp.Token == "" {
+		return "", fmt.Errorf("no token from %s", realm)
+	}
+	return resp.Token, nil
+}
+
+func (s ociSource) String() string {
+	return s.location
+}
@@ -0,0 +1,228 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package feed
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	// defaultConfigMapKey is the key of the feed in a ConfigMap source.
+	defaultConfigMapKey = "vp.json"
+	// signatureSuffix is appended to the location of the feed to get the
+	// location of its detached signature.
+	signatureSuffix = ".sig"
+	// maxFeedSize is the maximum size of a feed or signature document.
+	maxFeedSize = 32 << 20
+)
+
+// Source is a location the feed is loaded from.
+type Source interface {
+	// Fetch returns the feed document, along with its detached signature, or
+	// nil when the source has none.
+	Fetch(ctx context.Context) (document, signature []byte, err error)
+	// String returns the location of the source.
+	String() string
+}
+
+// NewSource returns the source of the feed at the given location:
+//
+//   - configmap://<namespace>/<name>[/<key>] reads the key of a ConfigMap,
+//     "vp.json" by default, and its signature from the "<key>.sig" key.
+//   - file:///<path>, or a plain path, reads a file, e.g. mounted from a
+//     volume, and its signature from "<path>.sig".
+//   - http://... and https://... download the feed, and its signature from
+//     the same URL with the ".sig" suffix.
+//   - oci://<registry>/<repository>:<tag> or @<digest> pulls the feed and its
+//     signature from the layers of an OCI artifact. oci+http:// pulls it from
+//     a registry served over plain HTTP.
+func NewSource(location string, client dynamic.Interface) (Source, error) {
+	scheme, rest, found := strings.Cut(location, "://")
+	if !found {
+		return fileSource{path: location}, nil
+	}
+
+	switch scheme {
+	case "configmap":
+		parts := strings.Split(rest, "/")
+		if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid ConfigMap feed source %q, expected configmap://<namespace>/<name>[/<key>]", location)
+		}
+		source := configMapSource{client: client, namespace: parts[0], name: parts[1], key: defaultConfigMapKey}
+		if len(parts) == 3 && parts[2] != "" {
+			source.key = parts[2]
+		}
+		return source, nil
+	case "file":
+		return fileSource{path: rest}, nil
+	case "http", "https":
+		if _, err := url.Parse(location); err != nil {
+			return nil, fmt.Errorf("invalid feed source %q: %w", location, err)
+		}
+		return httpSource{url: location, client: &http.Client{Timeout: time.Minute}}, nil
+	case "oci", "oci+http":
+		return newOCISource(location, rest, scheme == "oci+http")
+	default:
+		return nil, fmt.Errorf("unsupported feed source %q", location)
+	}
+}
+
+// configMapSource reads the feed from a key of a ConfigMap.
+type configMapSource struct {
+	client    dynamic.Interface
+	namespace string
+	name      string
+	key       string
+}
+
+func (s configMapSource) Fetch(ctx context.Context) ([]byte, []byte, error) {
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	cm, err := s.client.Resource(gvr).Namespace(s.namespace).Get(ctx, s.name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil, fmt.Errorf("ConfigMap %s/%s not found", s.namespace, s.name)
+		}
+		return nil, nil, err
+	}
+
+	document, found, err := configMapValue(cm, s.key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !found {
+		return nil, nil, fmt.Errorf("key %q not found in ConfigMap %s/%s", s.key, s.namespace, s.name)
+	}
+	signature, _, err := configMapValue(cm, s.key+signatureSuffix)
+	if err != nil {
+		return nil, nil, err
+	}
+	return document, signature, nil
+}
+
+// configMapValue returns the value of the given key of the data or binary
+// data of a ConfigMap.
+func configMapValue(cm *unstructured.Unstructured, key string) ([]byte, bool, error) {
+	if value, found, _ := unstructured.NestedString(cm.Object, "data", key); found {
+		return []byte(value), true, nil
+	}
+	if value, found, _ := unstructured.NestedString(cm.Object, "binaryData", key); found {
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, true, fmt.Errorf("invalid binary data of key %q: %w", key, err)
+		}
+		return decoded, true, nil
+	}
+	return nil, false, nil
+}
+
+func (s configMapSource) String() string {
+	return fmt.Sprintf("configmap://%s/%s/%s", s.namespace, s.name, s.key)
+}
+
+// fileSource reads the feed from a file.
+type fileSource struct {
+	path string
+}
+
+func (s fileSource) Fetch(context.Context) ([]byte, []byte, error) {
+	document, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, nil, err
+	}
+	signature, err := os.ReadFile(s.path + signatureSuffix)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, err
+	}
+	return document, signature, nil
+}
+
+func (s fileSource) String() string {
+	return "file://" + s.path
+}
+
+// httpSource downloads the feed from an HTTP(S) endpoint.
+type httpSource struct {
+	url    string
+	client *http.Client
+}
+
+func (s httpSource) Fetch(ctx context.Context) ([]byte, []byte, error) {
+	document, found, err := get(ctx, s.client, s.url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !found {
+		return nil, nil, fmt.Errorf("feed not found at %s", s.url)
+	}
+	signature, _, err := get(ctx, s.client, s.url+signatureSuffix, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return document, signature, nil
+}
+
+func (s httpSource) String() string {
+	return s.url
+}
+
+// get downloads the document at the given URL with the given headers. A
+// missing document isn't an error.
+func get(ctx context.Context, client *http.Client, location string, header http.Header) ([]byte, bool, error) {
+	resp, err := do(ctx, client, location, header)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("failed to get %s: %s", location, resp.Status)
+	}
+	body, err := readAll(resp.Body)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read %s: %w", location, err)
+	}
+	return body, true, nil
+}
+
+func do(ctx context.Context, client *http.Client, location string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	return client.Do(req)
+}
+
+// readAll reads the given body, up to the maximum size of a feed.
+func readAll(body io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(body, maxFeedSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxFeedSize {
+		return nil, fmt.Errorf("document larger than %d bytes", maxFeedSize)
+	}
+	return data, nil
+}
@@ -0,0 +1,112 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package feed
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// How a loaded feed was verified.
+const (
+	VerificationSignature = "Signature"
+	VerificationChecksum  = "Checksum"
+	VerificationNone      = "None"
+)
+
+// Verifier checks the integrity and authenticity of the feed documents.
+type Verifier struct {
+	// Checksum is the expected hex encoded SHA-256 digest of the document,
+	// optionally prefixed with "sha256:".
+	Checksum string
+	// PublicKey verifies the detached signature of the document. An ECDSA,
+	// Ed25519 or RSA (PKCS #1 v1.5) key, signing the SHA-256 digest of the
+	// document, except Ed25519 which signs the document itself.
+	PublicKey crypto.PublicKey
+}
+
+// Verify checks the given document against the checksum and signature, when
+// set, and returns how the document was verified.
+func (v Verifier) Verify(document, signature []byte) (string, error) {
+	verification := VerificationNone
+
+	if v.Checksum != "" {
+		expected, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(v.Checksum), "sha256:"))
+		if err != nil {
+			return "", fmt.Errorf("invalid feed checksum: %w", err)
+		}
+		sum := sha256.Sum256(document)
+		if !bytes.Equal(sum[:], expected) {
+			return "", fmt.Errorf("feed checksum mismatch, expected sha256:%x, got sha256:%x", expected, sum)
+		}
+		verification = VerificationChecksum
+	}
+
+	if v.PublicKey != nil {
+		if len(signature) == 0 {
+			return "", errors.New("feed signature not found")
+		}
+		if err := verifySignature(v.PublicKey, document, decodeSignature(signature)); err != nil {
+			return "", err
+		}
+		verification = VerificationSignature
+	}
+
+	return verification, nil
+}
+
+// decodeSignature returns the raw signature of a base64 encoded signature, as
+// written by e.g. `cosign sign-blob`, or the given signature otherwise.
+func decodeSignature(signature []byte) []byte {
+	decoded, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(signature)))
+	if err != nil {
+		return signature
+	}
+	return decoded
+}
+
+func verifySignature(key crypto.PublicKey, document, signature []byte) error {
+	digest := sha256.Sum256(document)
+	switch key := key.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, digest[:], signature) {
+			return errors.New("invalid feed signature")
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, document, signature) {
+			return errors.New("invalid feed signature")
+		}
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+			return errors.New("invalid feed signature")
+		}
+	default:
+		return fmt.Errorf("unsupported public key type %T", key)
+	}
+	return nil
+}
+
+// ParsePublicKey parses a PEM encoded PKIX public key.
+func ParsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM encoded public key")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	return key, nil
+}
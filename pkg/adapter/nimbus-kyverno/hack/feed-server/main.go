@@ -0,0 +1,85 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+// feed-server serves the virtual patch feed read from a file over HTTP and
+// from a stand-in OCI registry, signed with the given Ed25519 private key if
+// any. Run the adapter with VIRTUAL_PATCH_FEED set to one of the locations it
+// prints, e.g.
+//
+//	go run ./hack/feed-server -address 127.0.0.1:8080 -feed ../../../vp.json
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-kyverno/feed/feedtest"
+)
+
+func main() {
+	address := flag.String("address", "127.0.0.1:8080", "Address to listen on")
+	feedPath := flag.String("feed", "vp.json", "Path of the feed to serve")
+	keyPath := flag.String("key", "", "Path of the PEM encoded PKCS #8 Ed25519 private key signing the feed, not signed when empty")
+	flag.Parse()
+
+	if err := run(*address, *feedPath, *keyPath); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(address, feedPath, keyPath string) error {
+	document, err := os.ReadFile(feedPath)
+	if err != nil {
+		return err
+	}
+
+	var signature []byte
+	if keyPath != "" {
+		key, err := readPrivateKey(keyPath)
+		if err != nil {
+			return err
+		}
+		signature = []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(key, document)))
+	}
+
+	server, err := feedtest.NewServer(address, document, signature)
+	if err != nil {
+		return err
+	}
+	defer server.Close()
+	fmt.Fprintln(os.Stderr, "Serving the virtual patch feed at", server.HTTPLocation(), "and", server.OCILocation())
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	<-sigCh
+	return nil
+}
+
+func readPrivateKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM encoded private key")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	ed25519Key, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T, expected Ed25519", key)
+	}
+	return ed25519Key, nil
+}
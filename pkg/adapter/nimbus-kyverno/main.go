@@ -10,7 +10,9 @@ import (
 	"os/signal"
 	"syscall"
 
+	"github.com/5GSEC/nimbus/pkg/adapter/metrics"
 	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-kyverno/manager"
+	"github.com/5GSEC/nimbus/pkg/tracing"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 )
@@ -33,5 +35,16 @@ func main() {
 	}()
 
 	logger.Info("Kyverno adapter started")
+	shutdownTracing, err := tracing.Setup(ctx, "nimbus-kyverno")
+	if err != nil {
+		logger.Error(err, "failed to set up tracing")
+		os.Exit(1)
+	}
+
+	go metrics.Serve(ctx)
 	manager.Run(ctx)
+
+	if err := shutdownTracing(context.Background()); err != nil {
+		logger.Error(err, "failed to flush traces")
+	}
 }
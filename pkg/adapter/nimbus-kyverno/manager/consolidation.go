@@ -0,0 +1,74 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package manager
+
+import (
+	"context"
+
+	kyvernov1 "github.com/kyverno/kyverno/api/kyverno/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/5GSEC/nimbus/api/v1alpha1"
+	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-kyverno/processor"
+	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-kyverno/utils"
+	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
+)
+
+// consolidateKps reconciles the KyvernoPolicies of all the NimbusPolicies in
+// the given namespace in consolidation mode, see
+// adapterutil.ConsolidatePolicies.
+func consolidateKps(ctx context.Context, namespace string) {
+	adapterutil.ConsolidatePolicies(ctx, k8sClient, scheme, recorder, adapterName, "kyverno", namespace, kpConsolidator{})
+}
+
+// kpConsolidator consolidates the KyvernoPolicies, and schedules the refresh
+// of the ones of the virtualPatch intents.
+type kpConsolidator struct{}
+
+func (kpConsolidator) Kind() string {
+	return "KyvernoPolicy"
+}
+
+func (kpConsolidator) Prepare(ctx context.Context, np *v1alpha1.NimbusPolicy, consolidated bool) {
+	if !consolidated {
+		vpScheduler.unschedule(np.UID)
+		return
+	}
+	vpScheduler.schedule(ctx, np)
+}
+
+func (kpConsolidator) Consolidate(ctx context.Context, nps []v1alpha1.NimbusPolicy) []adapterutil.ConsolidatedPolicy {
+	var consolidated []adapterutil.ConsolidatedPolicy
+	for _, c := range processor.ConsolidateKps(ctx, log.FromContext(ctx), nps) {
+		kp := c.Kp
+		consolidated = append(consolidated, adapterutil.ConsolidatedPolicy{Policy: &kp, Owners: c.Owners})
+	}
+	// Building the policies of the virtualPatch intents loads the feed.
+	for idx := range nps {
+		updateVirtualPatchFeedStatus(ctx, &nps[idx])
+	}
+	return consolidated
+}
+
+func (kpConsolidator) NewPolicy() client.Object {
+	return &kyvernov1.Policy{}
+}
+
+func (kpConsolidator) ListPolicies(ctx context.Context, namespace string) ([]client.Object, error) {
+	var kps kyvernov1.PolicyList
+	if err := k8sClient.List(ctx, &kps, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	policies := make([]client.Object, 0, len(kps.Items))
+	for idx := range kps.Items {
+		policies = append(policies, &kps.Items[idx])
+	}
+	return policies, nil
+}
+
+func (kpConsolidator) Changed(existing, built client.Object) (string, bool) {
+	reason, isEqual := utils.PolEqual(*existing.(*kyvernov1.Policy), *built.(*kyvernov1.Policy))
+	return reason, !isEqual
+}
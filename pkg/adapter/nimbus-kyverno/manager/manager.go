@@ -6,6 +6,7 @@ package manager
 import (
 	"context"
 	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	kyvernov1 "github.com/kyverno/kyverno/api/kyverno/v1"
@@ -16,6 +17,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
@@ -23,16 +25,23 @@ import (
 	"github.com/5GSEC/nimbus/api/v1alpha1"
 	"github.com/5GSEC/nimbus/pkg/adapter/common"
 	"github.com/5GSEC/nimbus/pkg/adapter/k8s"
+	"github.com/5GSEC/nimbus/pkg/adapter/metrics"
 	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-kyverno/processor"
+	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-kyverno/reports"
 	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-kyverno/utils"
 	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-kyverno/watcher"
 	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
 	globalwatcher "github.com/5GSEC/nimbus/pkg/adapter/watcher"
+	"github.com/5GSEC/nimbus/pkg/tracing"
 )
 
+const adapterName = "nimbus-kyverno"
+
 var (
-	scheme    = runtime.NewScheme()
-	k8sClient client.Client
+	scheme      = runtime.NewScheme()
+	k8sClient   client.Client
+	recorder    record.EventRecorder
+	vpScheduler = newVirtualPatchScheduler()
 )
 
 func init() {
@@ -40,6 +49,7 @@ func init() {
 	utilruntime.Must(kyvernov1.AddToScheme(scheme))
 	utilruntime.Must(corev1.AddToScheme(scheme))
 	k8sClient = k8s.NewOrDie(scheme)
+	recorder = k8s.NewEventRecorderOrDie(scheme, adapterName)
 }
 
 func Run(ctx context.Context) {
@@ -59,6 +69,9 @@ func Run(ctx context.Context) {
 	deletedKpCh := make(chan common.Request)
 	go watcher.WatchKps(ctx, updatedKpCh, deletedKpCh)
 
+	go vpScheduler.run(ctx)
+
+	go reports.Watch(ctx, k8sClient)
 
 	for {
 		select {
@@ -79,7 +92,16 @@ func Run(ctx context.Context) {
 			createOrUpdateKp(ctx, createdNp.Name, createdNp.Namespace)
 		case createdCnp := <-clusterNpChan:
 			createOrUpdateKcp(ctx, createdCnp)
+		case refreshedNp := <-vpScheduler.refreshCh:
+			createOrUpdateKp(ctx, refreshedNp.Name, refreshedNp.Namespace)
 		case deletedNp := <-deletedNpCh:
+			vpScheduler.unschedule(deletedNp.GetUID())
+			if adapterutil.ConsolidationEnabled() {
+				// The KyvernoPolicies shared with other NimbusPolicies aren't
+				// garbage collected, so drop the rules of the deleted one.
+				consolidateKps(ctx, deletedNp.GetNamespace())
+				continue
+			}
 			logKpToDelete(ctx, deletedNp)
 		case deletedCnp := <-deletedClusterNpChan:
 			logKcpToDelete(ctx, deletedCnp)
@@ -98,6 +120,11 @@ func Run(ctx context.Context) {
 
 func reconcileKp(ctx context.Context, kpName, namespace string, deleted bool) {
 	logger := log.FromContext(ctx)
+	if adapterutil.ConsolidationEnabled() {
+		logger.V(2).Info("Reconciling consolidated KyvernoPolicies", "KyvernoPolicy.Name", kpName, "KyvernoPolicy.Namespace", namespace)
+		consolidateKps(ctx, namespace)
+		return
+	}
 	npName := adapterutil.ExtractAnyNimbusPolicyName(kpName)
 	var np v1alpha1.NimbusPolicy
 	err := k8sClient.Get(ctx, types.NamespacedName{Name: npName, Namespace: namespace}, &np)
@@ -135,7 +162,13 @@ func reconcileKcp(ctx context.Context, kcpName string, deleted bool) {
 }
 
 func createOrUpdateKp(ctx context.Context, npName, npNamespace string) {
+	defer metrics.ObserveReconcile(adapterName, time.Now())
 	logger := log.FromContext(ctx)
+	if adapterutil.ConsolidationEnabled() {
+		consolidateKps(ctx, npNamespace)
+		return
+	}
+
 	var np v1alpha1.NimbusPolicy
 	if err := k8sClient.Get(ctx, types.NamespacedName{Name: npName, Namespace: npNamespace}, &np); err != nil {
 		logger.Error(err, "failed to get NimbusPolicy", "NimbusPolicy.Name", npName, "NimbusPolicy.Namespace", npNamespace)
@@ -144,11 +177,27 @@ func createOrUpdateKp(ctx context.Context, npName, npNamespace string) {
 
 	if adapterutil.IsOrphan(np.GetOwnerReferences(), "SecurityIntentBinding") {
 		logger.V(4).Info("Ignoring orphan NimbusPolicy", "NimbusPolicy.Name", npName, "NimbusPolicy.Namespace", npNamespace)
+		vpScheduler.unschedule(np.UID)
 		return
 	}
 
+	// Continue the trace of the reconciliation that changed the NimbusPolicy.
+	ctx, span := tracing.Start(tracing.Extract(ctx, &np), adapterName+".Reconcile", tracing.WithObject("NimbusPolicy", &np))
+	defer span.End()
+
 	deleteDanglingkps(ctx, np, logger)
-	kps := processor.BuildKpsFrom(logger, &np)
+	adapterutil.RecordUnsupportedIntents(recorder, &np, np.Spec.NimbusRules, "kyverno")
+	adapterutil.RecordUnsupportedActions(recorder, &np, np.Spec.NimbusRules, "kyverno")
+	if err := adapterutil.UpdateUnsupportedActions(ctx, k8sClient, "NimbusPolicy", np.Name, np.Namespace, "kyverno", np.Spec.NimbusRules); err != nil {
+		logger.Error(err, "failed to update unsupported actions status in NimbusPolicy", "NimbusPolicy.Name", np.Name, "NimbusPolicy.Namespace", np.Namespace)
+	}
+	metrics.UnsupportedIntents(adapterName, &np, np.Spec.NimbusRules, "kyverno")
+	translateCtx, translateSpan := tracing.Start(ctx, "KyvernoPolicy.Translate")
+	kps := processor.BuildKpsFrom(translateCtx, logger, &np)
+	translateSpan.End()
+	updateVirtualPatchFeedStatus(ctx, &np)
+	vpScheduler.schedule(ctx, &np)
+	deleteStaleVirtualPatchKps(ctx, np, kps, logger)
 
 	// Iterate using a separate index variable to avoid aliasing
 	for idx := range kps {
@@ -169,27 +218,39 @@ func createOrUpdateKp(ctx context.Context, npName, npNamespace string) {
 
 		if err != nil {
 			if errors.IsNotFound(err) {
-				if err = k8sClient.Create(ctx, &kp); err != nil {
+				if err = tracing.Trace(ctx, "KyvernoPolicy.Create", func(ctx context.Context) error {
+					return k8sClient.Create(ctx, &kp)
+				}, tracing.WithObject("KyvernoPolicy", &kp)); err != nil {
 					logger.Error(err, "failed to create KyvernoPolicy", "KyvernoPolicy.Name", kp.Name, "KyvernoPolicy.Namespace", kp.Namespace)
+					adapterutil.RecordPolicyFailed(recorder, &np, "KyvernoPolicy/"+kp.Name, err)
+					metrics.PolicyFailed(adapterName, "KyvernoPolicy")
 					return
 				}
 				if err = adapterutil.UpdateNpStatus(ctx, k8sClient, "KyvernoPolicy/"+kp.Name, np.Name, np.Namespace, false); err != nil {
 					logger.Error(err, "failed to update KyvernoPolicies status in NimbusPolicy")
 				}
 				logger.Info("KyvernoPolicy created", "KyvernoPolicy.Name", kp.Name, "KyvernoPolicy.Namespace", kp.Namespace)
+				adapterutil.RecordPolicyCreated(recorder, &np, "KyvernoPolicy/"+kp.Name)
+				metrics.PolicyGenerated(adapterName, "KyvernoPolicy", &kp)
 			}
 		} else {
 			reason, isEqual := utils.PolEqual(existingKp, kp)
 			if !isEqual {
 				kp.ObjectMeta.ResourceVersion = existingKp.ObjectMeta.ResourceVersion
-				if err = k8sClient.Update(ctx, &kp); err != nil {
+				if err = tracing.Trace(ctx, "KyvernoPolicy.Update", func(ctx context.Context) error {
+					return k8sClient.Update(ctx, &kp)
+				}, tracing.WithObject("KyvernoPolicy", &kp)); err != nil {
 					logger.Error(err, "failed to configure existing KyvernoPolicy", "KyvernoPolicy.Name", existingKp.Name, "KyvernoPolicy.Namespace", existingKp.Namespace)
+					adapterutil.RecordPolicyFailed(recorder, &np, "KyvernoPolicy/"+kp.Name, err)
+					metrics.PolicyFailed(adapterName, "KyvernoPolicy")
 					return
 				}
 				if err = adapterutil.UpdateNpStatus(ctx, k8sClient, "KyvernoPolicy/"+kp.Name, np.Name, np.Namespace, false); err != nil {
 					logger.Error(err, "failed to update KyvernoPolicies status in NimbusPolicy")
 				}
 				logger.Info("KyvernoPolicy configured", "KyvernoPolicy.Name", existingKp.Name, "KyvernoPolicy.Namespace", existingKp.Namespace, "Reason", reason)
+				adapterutil.RecordPolicyConfigured(recorder, &np, "KyvernoPolicy/"+kp.Name)
+				metrics.PolicyGenerated(adapterName, "KyvernoPolicy", &kp)
 			} else {
 				continue
 			}
@@ -199,6 +260,7 @@ func createOrUpdateKp(ctx context.Context, npName, npNamespace string) {
 }
 
 func createOrUpdateKcp(ctx context.Context, cnpName string) {
+	defer metrics.ObserveReconcile(adapterName, time.Now())
 	logger := log.FromContext(ctx)
 	var cnp v1alpha1.ClusterNimbusPolicy
 	if err := k8sClient.Get(ctx, types.NamespacedName{Name: cnpName}, &cnp); err != nil {
@@ -211,8 +273,21 @@ func createOrUpdateKcp(ctx context.Context, cnpName string) {
 		return
 	}
 
+	// Continue the trace of the reconciliation that changed the
+	// ClusterNimbusPolicy.
+	ctx, span := tracing.Start(tracing.Extract(ctx, &cnp), adapterName+".Reconcile", tracing.WithObject("ClusterNimbusPolicy", &cnp))
+	defer span.End()
+
 	deleteDanglingkcps(ctx, cnp, logger)
+	adapterutil.RecordUnsupportedIntents(recorder, &cnp, cnp.Spec.NimbusRules, "kyverno")
+	adapterutil.RecordUnsupportedActions(recorder, &cnp, cnp.Spec.NimbusRules, "kyverno")
+	if err := adapterutil.UpdateUnsupportedActions(ctx, k8sClient, "ClusterNimbusPolicy", cnp.Name, cnp.Namespace, "kyverno", cnp.Spec.NimbusRules); err != nil {
+		logger.Error(err, "failed to update unsupported actions status in ClusterNimbusPolicy", "ClusterNimbusPolicy.Name", cnp.Name)
+	}
+	metrics.UnsupportedIntents(adapterName, &cnp, cnp.Spec.NimbusRules, "kyverno")
+	_, translateSpan := tracing.Start(ctx, "KyvernoClusterPolicy.Translate")
 	kcps := processor.BuildKcpsFrom(logger, &cnp)
+	translateSpan.End()
 
 	for idx := range kcps {
 		kcp := kcps[idx]
@@ -231,19 +306,33 @@ func createOrUpdateKcp(ctx context.Context, cnpName string) {
 		}
 		if err != nil {
 			if errors.IsNotFound(err) {
-				if err = k8sClient.Create(ctx, &kcp); err != nil {
+				if err = tracing.Trace(ctx, "KyvernoClusterPolicy.Create", func(ctx context.Context) error {
+					return k8sClient.Create(ctx, &kcp)
+				}, tracing.WithObject("KyvernoClusterPolicy", &kcp)); err != nil {
 					logger.Error(err, "failed to create KyvernoClusterPolicy", "KyvernoClusterPolicy.Name", kcp.Name)
+					adapterutil.RecordPolicyFailed(recorder, &cnp, "KyvernoClusterPolicy/"+kcp.Name, err)
+					metrics.PolicyFailed(adapterName, "KyvernoClusterPolicy")
 					return
 				}
 				logger.Info("KyvernoClusterPolicy created", "KyvernoClusterPolicy.Name", kcp.Name)
+				adapterutil.RecordPolicyCreated(recorder, &cnp, "KyvernoClusterPolicy/"+kcp.Name)
+				metrics.PolicyGenerated(adapterName, "KyvernoClusterPolicy", &kcp)
 			}
 		} else {
 			kcp.ObjectMeta.ResourceVersion = existingKcp.ObjectMeta.ResourceVersion
-			if err = k8sClient.Update(ctx, &kcp); err != nil {
+			if err = tracing.Trace(ctx, "KyvernoClusterPolicy.Update", func(ctx context.Context) error {
+				return k8sClient.Update(ctx, &kcp)
+			}, tracing.WithObject("KyvernoClusterPolicy", &kcp)); err != nil {
 				logger.Error(err, "failed to configure existing KyvernoClusterPolicy", "KyvernoClusterPolicy.Name", existingKcp.Name)
+				adapterutil.RecordPolicyFailed(recorder, &cnp, "KyvernoClusterPolicy/"+kcp.Name, err)
+				metrics.PolicyFailed(adapterName, "KyvernoClusterPolicy")
 				return
 			}
 			logger.Info("KyvernoClusterPolicy configured", "KyvernoClusterPolicy.Name", existingKcp.Name)
+			if kcp.GetGeneration() != existingKcp.GetGeneration() {
+				adapterutil.RecordPolicyConfigured(recorder, &cnp, "KyvernoClusterPolicy/"+kcp.Name)
+				metrics.PolicyGenerated(adapterName, "KyvernoClusterPolicy", &kcp)
+			}
 		}
 
 		if err = adapterutil.UpdateCwnpStatus(ctx, k8sClient, "KyvernoClusterPolicy/"+kcp.Name, cnp.Name, false); err != nil {
@@ -338,6 +427,7 @@ func deleteDanglingkps(ctx context.Context, np v1alpha1.NimbusPolicy, logger log
 		}
 
 		logger.Info("Dangling KyvernoPolicy deleted", "KyvernoPolicy.Name", kp.Name, "KyvernoPolicy.Namespace", kp.Namespace)
+		adapterutil.RecordDanglingPolicyDeleted(recorder, &np, "KyvernoPolicy/"+kp.Name)
 	}
 }
 
@@ -407,6 +497,7 @@ func deleteDanglingkcps(ctx context.Context, cnp v1alpha1.ClusterNimbusPolicy, l
 		}
 
 		logger.Info("Dangling KyvernoClusterPolicy deleted", "KyvernoClusterPolicy.Name", kcp.Name)
+		adapterutil.RecordDanglingPolicyDeleted(recorder, &cnp, "KyvernoClusterPolicy/"+kcp.Name)
 
 		if err := adapterutil.UpdateCwnpStatus(ctx, k8sClient, "KyvernoClusterPolicy/"+kcp.Name, cnp.Name, true); err != nil {
 			logger.Error(err, "failed to update KyvernoClusterPolicy statis in ClusterNimbusPolicy")
@@ -0,0 +1,195 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package manager
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/go-logr/logr"
+	kyvernov1 "github.com/kyverno/kyverno/api/kyverno/v1"
+	"github.com/robfig/cron/v3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/5GSEC/nimbus/api/v1alpha1"
+	"github.com/5GSEC/nimbus/pkg/adapter/common"
+	"github.com/5GSEC/nimbus/pkg/adapter/idpool"
+	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-kyverno/processor"
+	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
+)
+
+const (
+	// reasonVirtualPatchFeedFailed is the reason of the Event recorded when
+	// the virtual patch feed of a NimbusPolicy fails to load.
+	reasonVirtualPatchFeedFailed = "VirtualPatchFeedFailed"
+	// reasonInvalidVirtualPatchSchedule is the reason of the Event recorded
+	// when the schedule of the virtualPatch intent of a NimbusPolicy is
+	// invalid.
+	reasonInvalidVirtualPatchSchedule = "InvalidVirtualPatchSchedule"
+
+	// defaultVirtualPatchSchedule is the schedule of the virtualPatch intents
+	// without the schedule param, daily at midnight.
+	defaultVirtualPatchSchedule = "0 0 * * *"
+)
+
+// virtualPatchScheduler refreshes the KyvernoPolicies of the virtualPatch
+// intents on their schedule, so that they are rebuilt from the latest virtual
+// patch feed. A single cron runs the refreshes of all the NimbusPolicies,
+// keyed by their UID. The feed is loaded by the cron before the refreshes are
+// sent to the Run loop, which builds the policies from the cached feed. The
+// scheduler is only used from the Run loop.
+type virtualPatchScheduler struct {
+	cron      *cron.Cron
+	entries   map[types.UID]scheduledRefresh
+	refreshCh chan common.Request
+}
+
+type scheduledRefresh struct {
+	id       cron.EntryID
+	schedule string
+}
+
+func newVirtualPatchScheduler() *virtualPatchScheduler {
+	return &virtualPatchScheduler{
+		cron:      cron.New(),
+		entries:   make(map[types.UID]scheduledRefresh),
+		refreshCh: make(chan common.Request),
+	}
+}
+
+// run loads the virtual patch feed, and starts the cron until the given
+// context is done.
+func (s *virtualPatchScheduler) run(ctx context.Context) {
+	processor.RefreshVirtualPatchFeed(ctx)
+	s.cron.Start()
+	<-ctx.Done()
+	<-s.cron.Stop().Done()
+}
+
+// schedule schedules the refresh of the given NimbusPolicy on the schedule of
+// its virtualPatch intent, or stops it if the NimbusPolicy no longer has the
+// intent. A refresh already scheduled with the same schedule is kept.
+func (s *virtualPatchScheduler) schedule(ctx context.Context, np *v1alpha1.NimbusPolicy) {
+	logger := log.FromContext(ctx)
+
+	var vpRule *v1alpha1.NimbusRules
+	for idx := range np.Spec.NimbusRules {
+		if np.Spec.NimbusRules[idx].ID == idpool.VirtualPatch {
+			vpRule = &np.Spec.NimbusRules[idx]
+			break
+		}
+	}
+	if vpRule == nil {
+		s.unschedule(np.UID)
+		return
+	}
+
+	schedule := defaultVirtualPatchSchedule
+	if params := vpRule.Rule.Params["schedule"]; len(params) > 0 {
+		schedule = params[0]
+	}
+	if entry, ok := s.entries[np.UID]; ok && entry.schedule == schedule {
+		return
+	}
+	s.unschedule(np.UID)
+
+	request := common.Request{Name: np.Name, Namespace: np.Namespace}
+	id, err := s.cron.AddFunc(schedule, func() {
+		processor.RefreshVirtualPatchFeed(ctx)
+		select {
+		case s.refreshCh <- request:
+		case <-ctx.Done():
+		}
+	})
+	if err != nil {
+		logger.Error(err, "invalid virtualPatch schedule, the KyvernoPolicies won't be refreshed",
+			"Schedule", schedule, "NimbusPolicy.Name", np.Name, "NimbusPolicy.Namespace", np.Namespace)
+		recorder.Eventf(np, corev1.EventTypeWarning, reasonInvalidVirtualPatchSchedule,
+			"Invalid schedule %q of the virtualPatch intent, the policies won't be refreshed: %v", schedule, err)
+		return
+	}
+	s.entries[np.UID] = scheduledRefresh{id: id, schedule: schedule}
+	logger.V(2).Info("Scheduled the refresh of the virtualPatch KyvernoPolicies",
+		"Schedule", schedule, "NimbusPolicy.Name", np.Name, "NimbusPolicy.Namespace", np.Namespace)
+}
+
+// unschedule stops the refresh of the NimbusPolicy with the given UID.
+func (s *virtualPatchScheduler) unschedule(uid types.UID) {
+	if entry, ok := s.entries[uid]; ok {
+		s.cron.Remove(entry.id)
+		delete(s.entries, uid)
+	}
+}
+
+// updateVirtualPatchFeedStatus sets the status of the virtual patch feed the
+// given NimbusPolicy was translated with, or clears it if the NimbusPolicy no
+// longer has the virtualPatch intent.
+func updateVirtualPatchFeedStatus(ctx context.Context, np *v1alpha1.NimbusPolicy) {
+	logger := log.FromContext(ctx)
+	feedStatus := np.Status.VirtualPatchFeed
+
+	if feedStatus != nil && feedStatus.State == v1alpha1.VirtualPatchFeedFailed {
+		recorder.Eventf(np, corev1.EventTypeWarning, reasonVirtualPatchFeedFailed,
+			"Failed to load the virtual patch feed from %s: %s", feedStatus.Source, feedStatus.Message)
+	}
+
+	if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latestNp := &v1alpha1.NimbusPolicy{}
+		if err := k8sClient.Get(ctx, types.NamespacedName{Name: np.Name, Namespace: np.Namespace}, latestNp); err != nil {
+			return err
+		}
+		if latestNp.Status.VirtualPatchFeed == nil && feedStatus == nil {
+			return nil
+		}
+		latestNp.Status.VirtualPatchFeed = feedStatus
+		return k8sClient.Status().Update(ctx, latestNp)
+	}); err != nil && !errors.IsNotFound(err) {
+		logger.Error(err, "failed to update the virtual patch feed status of NimbusPolicy", "NimbusPolicy.Name", np.Name, "NimbusPolicy.Namespace", np.Namespace)
+	}
+}
+
+// deleteStaleVirtualPatchKps deletes the KyvernoPolicies of the virtualPatch
+// intent of the given NimbusPolicy that weren't rebuilt from the virtual patch
+// feed, e.g. the ones of CVEs no longer in the feed. They are kept when the
+// feed failed to load, since the policies weren't rebuilt from the feed then.
+func deleteStaleVirtualPatchKps(ctx context.Context, np v1alpha1.NimbusPolicy, kps []kyvernov1.Policy, logger logr.Logger) {
+	if feedStatus := np.Status.VirtualPatchFeed; feedStatus != nil && feedStatus.State == v1alpha1.VirtualPatchFeedFailed {
+		return
+	}
+
+	var existingKps kyvernov1.PolicyList
+	if err := k8sClient.List(ctx, &existingKps, client.InNamespace(np.Namespace)); err != nil {
+		logger.Error(err, "failed to list KyvernoPolicies for cleanup")
+		return
+	}
+
+	built := make(map[string]bool, len(kps))
+	for _, kp := range kps {
+		built[kp.Name] = true
+	}
+
+	for idx := range existingKps.Items {
+		kp := existingKps.Items[idx]
+		if built[kp.Name] || !metav1.IsControlledBy(&kp, &np) ||
+			!slices.Contains(strings.Split(kp.Annotations[adapterutil.IntentsAnnotation], ","), idpool.VirtualPatch) {
+			continue
+		}
+		if err := k8sClient.Delete(ctx, &kp); err != nil && !errors.IsNotFound(err) {
+			logger.Error(err, "failed to delete stale KyvernoPolicy", "KyvernoPolicy.Name", kp.Name, "KyvernoPolicy.Namespace", kp.Namespace)
+			continue
+		}
+		if err := adapterutil.UpdateNpStatus(ctx, k8sClient, "KyvernoPolicy/"+kp.Name, np.Name, np.Namespace, true); err != nil {
+			logger.Error(err, "failed to update KyvernoPolicy status in NimbusPolicy")
+		}
+		logger.Info("Stale virtualPatch KyvernoPolicy deleted", "KyvernoPolicy.Name", kp.Name, "KyvernoPolicy.Namespace", kp.Namespace)
+		adapterutil.RecordDanglingPolicyDeleted(recorder, &np, "KyvernoPolicy/"+kp.Name)
+	}
+}
@@ -0,0 +1,43 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package processor
+
+import (
+	v1alpha1 "github.com/5GSEC/nimbus/api/v1alpha1"
+	"github.com/5GSEC/nimbus/pkg/adapter/idpool"
+	kyvernov1 "github.com/kyverno/kyverno/api/kyverno/v1"
+)
+
+// kpActions maps the Nimbus actions to the validationFailureAction of the
+// Kyverno policies built for each intent ID. Which actions make sense depends
+// on the kind of rules the policies contain.
+//
+//	| ID           | Rules    | Nimbus | Kyverno                           |
+//	|--------------|----------|--------|-----------------------------------|
+//	| escapeToHost | validate | Audit  | Audit                             |
+//	|              |          | Block  | Enforce                           |
+//	| cocoWorkload | mutate   | Mutate | (validationFailureAction not set) |
+//	| virtualPatch | generate | Block  | (validationFailureAction not set) |
+//
+// Warn and Allow aren't supported by any of them.
+var kpActions = map[string]map[string]kyvernov1.ValidationFailureAction{
+	idpool.EscapeToHost: {
+		v1alpha1.ActionAudit: kyvernov1.ValidationFailureAction("Audit"),
+		v1alpha1.ActionBlock: kyvernov1.ValidationFailureAction("Enforce"),
+	},
+	idpool.CocoWorkload: {
+		v1alpha1.ActionMutate: "",
+	},
+	idpool.VirtualPatch: {
+		v1alpha1.ActionBlock: "",
+	},
+}
+
+// kpActionFor returns the validationFailureAction of the Kyverno policies
+// built for the given intent ID and Nimbus action, and whether Kyverno
+// supports the action for that ID.
+func kpActionFor(id, action string) (kyvernov1.ValidationFailureAction, bool) {
+	failureAction, ok := kpActions[id][action]
+	return failureAction, ok
+}
@@ -0,0 +1,48 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package processor
+
+import (
+	"testing"
+
+	kyvernov1 "github.com/kyverno/kyverno/api/kyverno/v1"
+
+	v1alpha1 "github.com/5GSEC/nimbus/api/v1alpha1"
+	"github.com/5GSEC/nimbus/pkg/adapter/idpool"
+)
+
+func TestKpActionFor(t *testing.T) {
+	actions := []string{v1alpha1.ActionAudit, v1alpha1.ActionBlock, v1alpha1.ActionWarn, v1alpha1.ActionAllow, v1alpha1.ActionMutate}
+	tests := []struct {
+		id string
+		// failureActions are the supported actions and the
+		// validationFailureAction they map to.
+		failureActions map[string]kyvernov1.ValidationFailureAction
+	}{
+		{idpool.EscapeToHost, map[string]kyvernov1.ValidationFailureAction{
+			v1alpha1.ActionAudit: "Audit",
+			v1alpha1.ActionBlock: "Enforce",
+		}},
+		{idpool.CocoWorkload, map[string]kyvernov1.ValidationFailureAction{
+			v1alpha1.ActionMutate: "",
+		}},
+		{idpool.VirtualPatch, map[string]kyvernov1.ValidationFailureAction{
+			v1alpha1.ActionBlock: "",
+		}},
+	}
+	if len(tests) != len(idpool.KyvIds) {
+		t.Fatalf("tested %d IDs, want the %d IDs supported by Kyverno", len(tests), len(idpool.KyvIds))
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			for _, action := range actions {
+				want, supported := tt.failureActions[action]
+				failureAction, ok := kpActionFor(tt.id, action)
+				if failureAction != want || ok != supported {
+					t.Errorf("kpActionFor(%q, %q) = %q, %v, want %q, %v", tt.id, action, failureAction, ok, want, supported)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package processor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	v1alpha1 "github.com/5GSEC/nimbus/api/v1alpha1"
+	"github.com/5GSEC/nimbus/pkg/adapter/idpool"
+	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
+	"github.com/go-logr/logr"
+	kyvernov1 "github.com/kyverno/kyverno/api/kyverno/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+const (
+	descriptionAnnotation = "policies.kyverno.io/description"
+
+	// maxRuleNameLength is the maximum length of a Kyverno rule name.
+	maxRuleNameLength = 63
+)
+
+// ConsolidatedKp is a KyvernoPolicy built for one or more NimbusPolicies, along
+// with these NimbusPolicies.
+type ConsolidatedKp struct {
+	Kp     kyvernov1.Policy
+	Owners []v1alpha1.NimbusPolicy
+}
+
+// ConsolidateKps builds the KyvernoPolicies of the given NimbusPolicies of a
+// namespace, merging the rules of the ones that select the same workloads with
+// the same action into a single KyvernoPolicy. Every merged rule is prefixed
+// with the ID of the intent it enforces, and the merged KyvernoPolicy lists
+// the intents it enforces and their descriptions in its annotations.
+// KyvernoPolicies acting on existing resources, like the ones of the
+// virtualPatch intent, are kept as they are.
+func ConsolidateKps(ctx context.Context, logger logr.Logger, nps []v1alpha1.NimbusPolicy) []ConsolidatedKp {
+	var consolidated []ConsolidatedKp
+	indexByName := make(map[string]int)
+
+	for idx := range nps {
+		// Built first, so that the status set while building the policies,
+		// like the one of the virtual patch feed, is set on the given slice.
+		kps := BuildKpsFrom(ctx, logger, &nps[idx])
+		np := nps[idx]
+		actions := make(map[string]string)
+		for _, nimbusRule := range np.Spec.NimbusRules {
+			actions[nimbusRule.ID] = nimbusRule.Rule.RuleAction
+		}
+
+		for _, kp := range kps {
+			id := kp.Annotations[adapterutil.IntentsAnnotation]
+			if !mergeable(id, kp) {
+				consolidated = append(consolidated, ConsolidatedKp{Kp: kp, Owners: []v1alpha1.NimbusPolicy{np}})
+				continue
+			}
+
+			for i := range kp.Spec.Rules {
+				kp.Spec.Rules[i].Name = traceRuleName(id, kp.Spec.Rules[i].Name)
+			}
+
+			name := adapterutil.ConsolidatedPolicyName(actions[id], np.Spec.Selector.MatchLabels, np.Spec.Selector.MatchExpressions)
+			if existing, ok := indexByName[name]; ok {
+				mergeKp(&consolidated[existing], kp, np, id)
+				continue
+			}
+
+			kp.Name = name
+			kp.Annotations[descriptionAnnotation] = describe(id, kp.Annotations[descriptionAnnotation])
+			indexByName[name] = len(consolidated)
+			consolidated = append(consolidated, ConsolidatedKp{Kp: kp, Owners: []v1alpha1.NimbusPolicy{np}})
+		}
+	}
+
+	return consolidated
+}
+
+func mergeable(id string, kp kyvernov1.Policy) bool {
+	return id != idpool.VirtualPatch &&
+		!kp.Spec.MutateExistingOnPolicyUpdate &&
+		!kp.Spec.GenerateExisting
+}
+
+// traceRuleName prefixes the name of a rule with the intent ID it enforces,
+// within the length allowed for rule names.
+func traceRuleName(id, name string) string {
+	return truncate(strings.ToLower(id)+"-"+name, maxRuleNameLength)
+}
+
+func truncate(name string, length int) string {
+	if len(name) > length {
+		return name[:length]
+	}
+	return name
+}
+
+func describe(id, description string) string {
+	if description == "" {
+		return ""
+	}
+	return id + ": " + description
+}
+
+// mergeKp merges the rules of the KyvernoPolicy built for the given intent of a
+// NimbusPolicy into a consolidated KyvernoPolicy. Rules that are already part
+// of it, as when several NimbusPolicies bind the same intent, are added once.
+func mergeKp(consolidated *ConsolidatedKp, kp kyvernov1.Policy, np v1alpha1.NimbusPolicy, id string) {
+	merged := &consolidated.Kp
+	for _, rule := range kp.Spec.Rules {
+		duplicate := false
+		names := make(map[string]bool)
+		for _, existing := range merged.Spec.Rules {
+			names[existing.Name] = true
+			if existing.Name == rule.Name && equality.Semantic.DeepEqual(existing, rule) {
+				duplicate = true
+			}
+		}
+		if duplicate {
+			continue
+		}
+		// Rule names must be unique within a policy.
+		base := rule.Name
+		for suffix := 2; names[rule.Name]; suffix++ {
+			rule.Name = truncate(base, maxRuleNameLength-len(fmt.Sprint(suffix))-1) + fmt.Sprintf("-%d", suffix)
+		}
+		merged.Spec.Rules = append(merged.Spec.Rules, rule)
+	}
+
+	intents := merged.Annotations[adapterutil.IntentsAnnotation]
+	adapterutil.AddIntents(merged.Annotations, id)
+	if description := describe(id, kp.Annotations[descriptionAnnotation]); description != "" &&
+		merged.Annotations[adapterutil.IntentsAnnotation] != intents {
+		merged.Annotations[descriptionAnnotation] = strings.TrimPrefix(merged.Annotations[descriptionAnnotation]+"; "+description, "; ")
+	}
+
+	for _, owner := range consolidated.Owners {
+		if owner.UID == np.UID {
+			return
+		}
+	}
+	consolidated.Owners = append(consolidated.Owners, np)
+}
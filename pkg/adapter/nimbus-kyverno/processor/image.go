@@ -0,0 +1,51 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package processor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-kyverno/feed"
+)
+
+// podContainersQuery is the JMESPath query of the containers of the pod of the
+// request, including its init and ephemeral containers.
+const podContainersQuery = "request.object.spec.[containers, initContainers, ephemeralContainers][] | [?image]"
+
+// semverTagPattern is the regex of the tags compared with the tag range of an
+// image, semantic versions with an optional "v" prefix.
+const semverTagPattern = `^v?[0-9]+\.[0-9]+\.[0-9]+(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`
+
+// matchingContainersQuery returns the JMESPath query of the containers of the
+// pod of the request running the given image of the feed, matched by
+// repository, tag and digest whatever the registry host and the Docker Hub
+// namespace it is referred to with, and by the tag range of the image.
+func matchingContainersQuery(image feed.Image) (string, error) {
+	ref, err := image.Reference()
+	if err != nil {
+		return "", err
+	}
+
+	var clauses []string
+	for _, pattern := range ref.Patterns() {
+		clauses = append(clauses, fmt.Sprintf("pattern_match(%s, image)", jmesPathString(pattern)))
+	}
+	filter := strings.Join(clauses, " || ")
+
+	if image.TagRange != "" {
+		// The tag is the last ":" separated part of the image without digest,
+		// images without tag or with the port of their registry last have no
+		// semantic version tag and are out of the range.
+		tag := "split(split(image, '@')[0], ':')[-1]"
+		filter = fmt.Sprintf("(%s) && regex_match(%s, %s) && semver_compare(trim_prefix(%s, 'v'), %s)",
+			filter, jmesPathString(semverTagPattern), tag, tag, jmesPathString(strings.TrimSpace(image.TagRange)))
+	}
+	return podContainersQuery + " | [?" + filter + "]", nil
+}
+
+// jmesPathString returns the JMESPath raw string literal of the given string.
+func jmesPathString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+}
@@ -0,0 +1,136 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package processor
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/kyverno/kyverno/pkg/config"
+	"github.com/kyverno/kyverno/pkg/engine/jmespath"
+
+	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-kyverno/feed"
+)
+
+const (
+	digest      = "sha256:0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31"
+	otherDigest = "sha256:1d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31"
+)
+
+// TestMatchingContainersQuery evaluates the queries with the JMESPath functions
+// of Kyverno against the containers of a pod running the given images.
+func TestMatchingContainersQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		image feed.Image
+		// containers, initContainers and ephemeralContainers are the images
+		// of the containers of the pod.
+		containers, initContainers, ephemeralContainers []string
+		// want are the images of the matching containers.
+		want []string
+	}{
+		{
+			name:       "registry with port",
+			image:      feed.Image{Image: "localhost:5000/app:1.0"},
+			containers: []string{"localhost:5000/app:1.0", "localhost:5000/app:1.0.1", "localhost:5001/app:1.0", "localhost:5000/app:1.0@" + digest, "app:1.0"},
+			want:       []string{"localhost:5000/app:1.0", "localhost:5000/app:1.0@" + digest},
+		},
+		{
+			name:       "docker hub normalization",
+			image:      feed.Image{Image: "docker.io/library/nginx"},
+			containers: []string{"nginx", "nginx:1.25.3", "library/nginx:latest", "docker.io/nginx:1.25", "index.docker.io/library/nginx@" + digest, "ghcr.io/nginx:1.25", "nginx-unprivileged:1.25"},
+			want:       []string{"nginx", "nginx:1.25.3", "library/nginx:latest", "docker.io/nginx:1.25", "index.docker.io/library/nginx@" + digest},
+		},
+		{
+			name:       "digest only",
+			image:      feed.Image{Image: "nginx@" + digest},
+			containers: []string{"nginx@" + digest, "docker.io/library/nginx:1.25.3@" + digest, "nginx@" + otherDigest, "nginx:1.25.3"},
+			want:       []string{"nginx@" + digest, "docker.io/library/nginx:1.25.3@" + digest},
+		},
+		{
+			name:       "tag and digest",
+			image:      feed.Image{Image: "nginx:1.25.3@" + digest},
+			containers: []string{"nginx:1.25.3@" + digest, "nginx:1.25.4@" + digest, "nginx:1.25.3", "nginx@" + digest},
+			want:       []string{"nginx:1.25.3@" + digest},
+		},
+		{
+			name:       "tag glob",
+			image:      feed.Image{Image: "nginx:1.25.*"},
+			containers: []string{"docker.io/library/nginx:1.25.3@" + digest, "nginx:1.25.0", "nginx:1.26.0", "nginx", "nginx:latest"},
+			want:       []string{"docker.io/library/nginx:1.25.3@" + digest, "nginx:1.25.0"},
+		},
+		{
+			name:       "latest",
+			image:      feed.Image{Image: "ghcr.io/org/app:latest"},
+			containers: []string{"ghcr.io/org/app", "ghcr.io/org/app@" + digest, "ghcr.io/org/app:latest", "ghcr.io/org/app:1.0"},
+			want:       []string{"ghcr.io/org/app", "ghcr.io/org/app@" + digest, "ghcr.io/org/app:latest"},
+		},
+		{
+			name:       "tag range",
+			image:      feed.Image{Image: "nginx", TagRange: ">=1.25.0 <1.26.0"},
+			containers: []string{"nginx:1.25.3", "nginx:v1.25.1", "nginx:1.25.3@" + digest, "nginx:1.26.0", "nginx:1.25.0-rc1", "nginx:1.25", "nginx:latest", "nginx"},
+			want:       []string{"nginx:1.25.3", "nginx:v1.25.1", "nginx:1.25.3@" + digest},
+		},
+		{
+			name:       "tag range with registry port",
+			image:      feed.Image{Image: "localhost:5000/app", TagRange: ">=1.0.0"},
+			containers: []string{"localhost:5000/app:1.2.0", "localhost:5000/app", "localhost:5000/app:0.9.0"},
+			want:       []string{"localhost:5000/app:1.2.0"},
+		},
+		{
+			name:                "init and ephemeral containers",
+			image:               feed.Image{Image: "busybox:1.36"},
+			containers:          []string{"nginx:1.25.3"},
+			initContainers:      []string{"busybox:1.36"},
+			ephemeralContainers: []string{"docker.io/library/busybox:1.36"},
+			want:                []string{"busybox:1.36", "docker.io/library/busybox:1.36"},
+		},
+	}
+
+	jp := jmespath.New(config.NewDefaultConfiguration(false))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := matchingContainersQuery(tt.image)
+			if err != nil {
+				t.Fatal(err)
+			}
+			request := map[string]any{
+				"request": map[string]any{
+					"object": map[string]any{
+						"spec": map[string]any{
+							"containers":          podContainers(tt.containers),
+							"initContainers":      podContainers(tt.initContainers),
+							"ephemeralContainers": podContainers(tt.ephemeralContainers),
+						},
+					},
+				},
+			}
+			result, err := jp.Search(query, request)
+			if err != nil {
+				t.Fatalf("query %s failed: %v", query, err)
+			}
+
+			var got []string
+			for _, container := range result.([]any) {
+				got = append(got, container.(map[string]any)["image"].(string))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("query %s matched %v, want %v", query, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := matchingContainersQuery(feed.Image{Image: "Nginx"}); err == nil {
+		t.Error("matchingContainersQuery() of an invalid image succeeded")
+	}
+}
+
+// podContainers returns the containers of a pod running the given images.
+func podContainers(images []string) []any {
+	containers := make([]any, 0, len(images))
+	for _, image := range images {
+		containers = append(containers, map[string]any{"name": "c", "image": image})
+	}
+	return containers
+}
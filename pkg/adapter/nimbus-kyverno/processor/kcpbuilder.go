@@ -9,10 +9,10 @@ import (
 
 	v1alpha1 "github.com/5GSEC/nimbus/api/v1alpha1"
 	"github.com/5GSEC/nimbus/pkg/adapter/idpool"
+	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
 	"github.com/go-logr/logr"
 	kyvernov1 "github.com/kyverno/kyverno/api/kyverno/v1"
 	v1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/pod-security-admission/api"
 )
 
@@ -22,16 +22,28 @@ func BuildKcpsFrom(logger logr.Logger, cnp *v1alpha1.ClusterNimbusPolicy) []kyve
 	for _, nimbusRule := range cnp.Spec.NimbusRules {
 		id := nimbusRule.ID
 		if idpool.IsIdSupportedBy(id, "kyverno") {
-			kcp := buildKcpFor(id, cnp)
+			failureAction, ok := kpActionFor(id, nimbusRule.Rule.RuleAction)
+			if !ok {
+				logger.Info("Kyverno does not support this action", "ID", id, "Action", nimbusRule.Rule.RuleAction,
+					"ClusterNimbusPolicy", cnp.Name)
+				continue
+			}
+			// Leave the intent to the conflicting NimbusPolicies taking
+			// precedence in some of the namespaces.
+			ruleCnp := *cnp
+			ruleCnp.Spec.NsSelector, ok = adapterutil.RuleNsSelector(cnp.Spec, id)
+			if !ok {
+				logger.Info("Conflicting NimbusPolicies take precedence over the intent in all the selected namespaces",
+					"ID", id, "ClusterNimbusPolicy", cnp.Name)
+				continue
+			}
+			kcp := buildKcpFor(id, &ruleCnp)
 			kcp.Name = cnp.Name + "-" + strings.ToLower(id)
 			kcp.Annotations = make(map[string]string)
 			kcp.Annotations["policies.kyverno.io/description"] = nimbusRule.Description
-			if nimbusRule.Rule.RuleAction == "Block" {
-				kcp.Spec.ValidationFailureAction = kyvernov1.ValidationFailureAction("Enforce")
-			} else {
-				kcp.Spec.ValidationFailureAction = kyvernov1.ValidationFailureAction("Audit")
-			}
+			kcp.Spec.ValidationFailureAction = failureAction
 			addManagedByAnnotationForClusterScopedPolicy(&kcp)
+			adapterutil.AddIntents(kcp.Annotations, id)
 			kcps = append(kcps, kcp)
 		} else {
 			logger.Info("Kyverno does not support this ID", "ID", id,
@@ -55,90 +67,34 @@ func buildKcpFor(id string, cnp *v1alpha1.ClusterNimbusPolicy) kyvernov1.Cluster
 
 var nsBlackList = []string{"kube-system"}
 
-func clusterCocoRuntimeAddition(cnp *v1alpha1.ClusterNimbusPolicy, rule v1alpha1.Rule) kyvernov1.ClusterPolicy {
-	var matchFilters, excludeFilters []kyvernov1.ResourceFilter
-	labels := cnp.Spec.WorkloadSelector.MatchLabels
-	excludeNamespaces := cnp.Spec.NsSelector.ExcludeNames
-	namespaces := cnp.Spec.NsSelector.MatchNames
-	// exclude kube-system
-	resourceFilter := kyvernov1.ResourceFilter{
+// namespaceFilters returns the namespaces a ClusterPolicy matches, nil for all
+// of them, along with the filters excluding the blacklisted namespaces and the
+// ones the namespace selector of a ClusterNimbusPolicy excludes.
+func namespaceFilters(nsSelector v1alpha1.NamespaceSelector) ([]string, []kyvernov1.ResourceFilter) {
+	excludeFilters := []kyvernov1.ResourceFilter{{
 		ResourceDescription: kyvernov1.ResourceDescription{
 			Namespaces: nsBlackList,
 		},
-	}
-	excludeFilters = append(excludeFilters, resourceFilter)
-
-	if namespaces[0] != "*" && len(labels) > 0 {
-		for key, value := range labels {
-			resourceFilter = kyvernov1.ResourceFilter{
-				ResourceDescription: kyvernov1.ResourceDescription{
-					Kinds: []string{
-						"apps/v1/Deployment",
-					},
-					Namespaces: namespaces,
-					Selector: &metav1.LabelSelector{
-						MatchLabels: map[string]string{
-							key: value,
-						},
-					},
-				},
-			}
-			matchFilters = append(matchFilters, resourceFilter)
-		}
-	} else if namespaces[0] != "*" && len(labels) == 0 {
-		resourceFilter = kyvernov1.ResourceFilter{
+	}}
+	if len(nsSelector.ExcludeNames) > 0 {
+		excludeFilters = append(excludeFilters, kyvernov1.ResourceFilter{
 			ResourceDescription: kyvernov1.ResourceDescription{
-				Kinds: []string{
-					"apps/v1/Deployment",
-				},
-				Namespaces: cnp.Spec.NsSelector.MatchNames,
+				Namespaces: nsSelector.ExcludeNames,
 			},
-		}
-		matchFilters = append(matchFilters, resourceFilter)
-	} else if namespaces[0] == "*" && len(labels) > 0 {
-
-		if len(excludeNamespaces) > 0 {
-			resourceFilter = kyvernov1.ResourceFilter{
-				ResourceDescription: kyvernov1.ResourceDescription{
-					Namespaces: excludeNamespaces,
-				},
-			}
-			excludeFilters = append(excludeFilters, resourceFilter)
-		}
+		})
+	}
 
-		for key, value := range labels {
-			resourceFilter = kyvernov1.ResourceFilter{
-				ResourceDescription: kyvernov1.ResourceDescription{
-					Kinds: []string{
-						"apps/v1/Deployment",
-					},
-					Selector: &metav1.LabelSelector{
-						MatchLabels: map[string]string{
-							key: value,
-						},
-					},
-				},
-			}
-			matchFilters = append(matchFilters, resourceFilter)
-		}
-	} else if namespaces[0] == "*" && len(labels) == 0 {
-		if len(excludeNamespaces) > 0 {
-			resourceFilter = kyvernov1.ResourceFilter{
-				ResourceDescription: kyvernov1.ResourceDescription{
-					Namespaces: excludeNamespaces,
-				},
-			}
-			excludeFilters = append(excludeFilters, resourceFilter)
-		}
-		resourceFilter = kyvernov1.ResourceFilter{
-			ResourceDescription: kyvernov1.ResourceDescription{
-				Kinds: []string{
-					"apps/v1/Deployment",
-				},
-			},
-		}
-		matchFilters = append(matchFilters, resourceFilter)
+	namespaces := nsSelector.MatchNames
+	if len(namespaces) > 0 && namespaces[0] == "*" {
+		namespaces = nil
 	}
+	return namespaces, excludeFilters
+}
+
+func clusterCocoRuntimeAddition(cnp *v1alpha1.ClusterNimbusPolicy, rule v1alpha1.Rule) kyvernov1.ClusterPolicy {
+	var matchFilters []kyvernov1.ResourceFilter
+	namespaces, excludeFilters := namespaceFilters(cnp.Spec.NsSelector)
+	matchFilters = append(matchFilters, workloadFilter("apps/v1/Deployment", cnp.Spec.WorkloadSelector, namespaces))
 
 	patchStrategicMerge := map[string]interface{}{
 		"spec": map[string]interface{}{
@@ -199,88 +155,10 @@ func clusterEscapeToHost(cnp *v1alpha1.ClusterNimbusPolicy, rule v1alpha1.Rule)
 
 	}
 
-	var matchFilters, excludeFilters []kyvernov1.ResourceFilter
-	labels := cnp.Spec.WorkloadSelector.MatchLabels
-	excludeNamespaces := cnp.Spec.NsSelector.ExcludeNames
-	namespaces := cnp.Spec.NsSelector.MatchNames
-	// exclude kube-system
-	resourceFilter := kyvernov1.ResourceFilter{
-		ResourceDescription: kyvernov1.ResourceDescription{
-			Namespaces: nsBlackList,
-		},
-	}
-	excludeFilters = append(excludeFilters, resourceFilter)
+	var matchFilters []kyvernov1.ResourceFilter
+	namespaces, excludeFilters := namespaceFilters(cnp.Spec.NsSelector)
+	matchFilters = append(matchFilters, workloadFilter("v1/Pod", cnp.Spec.WorkloadSelector, namespaces))
 
-	if namespaces[0] != "*" && len(labels) > 0 {
-		for key, value := range labels {
-			resourceFilter = kyvernov1.ResourceFilter{
-				ResourceDescription: kyvernov1.ResourceDescription{
-					Kinds: []string{
-						"v1/Pod",
-					},
-					Namespaces: namespaces,
-					Selector: &metav1.LabelSelector{
-						MatchLabels: map[string]string{
-							key: value,
-						},
-					},
-				},
-			}
-			matchFilters = append(matchFilters, resourceFilter)
-		}
-	} else if namespaces[0] != "*" && len(labels) == 0 {
-		resourceFilter = kyvernov1.ResourceFilter{
-			ResourceDescription: kyvernov1.ResourceDescription{
-				Kinds: []string{
-					"v1/Pod",
-				},
-				Namespaces: cnp.Spec.NsSelector.MatchNames,
-			},
-		}
-		matchFilters = append(matchFilters, resourceFilter)
-	} else if namespaces[0] == "*" && len(labels) > 0 {
-		if len(excludeNamespaces) > 0 {
-			resourceFilter = kyvernov1.ResourceFilter{
-				ResourceDescription: kyvernov1.ResourceDescription{
-					Namespaces: excludeNamespaces,
-				},
-			}
-			excludeFilters = append(excludeFilters, resourceFilter)
-		}
-		for key, value := range labels {
-			resourceFilter = kyvernov1.ResourceFilter{
-				ResourceDescription: kyvernov1.ResourceDescription{
-					Kinds: []string{
-						"v1/Pod",
-					},
-					Selector: &metav1.LabelSelector{
-						MatchLabels: map[string]string{
-							key: value,
-						},
-					},
-				},
-			}
-			matchFilters = append(matchFilters, resourceFilter)
-		}
-	} else if namespaces[0] == "*" && len(labels) == 0 {
-
-		if len(excludeNamespaces) > 0 {
-			resourceFilter = kyvernov1.ResourceFilter{
-				ResourceDescription: kyvernov1.ResourceDescription{
-					Namespaces: excludeNamespaces,
-				},
-			}
-			excludeFilters = append(excludeFilters, resourceFilter)
-		}
-		resourceFilter = kyvernov1.ResourceFilter{
-			ResourceDescription: kyvernov1.ResourceDescription{
-				Kinds: []string{
-					"v1/Pod",
-				},
-			},
-		}
-		matchFilters = append(matchFilters, resourceFilter)
-	}
 	background := true
 	return kyvernov1.ClusterPolicy{
 		Spec: kyvernov1.Spec{
@@ -7,43 +7,80 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	v1alpha1 "github.com/5GSEC/nimbus/api/v1alpha1"
 	"github.com/5GSEC/nimbus/pkg/adapter/idpool"
 	"github.com/5GSEC/nimbus/pkg/adapter/k8s"
+	"github.com/5GSEC/nimbus/pkg/adapter/metrics"
+	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-kyverno/feed"
 	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-kyverno/utils"
+	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
 	"github.com/go-logr/logr"
 	kyvernov1 "github.com/kyverno/kyverno/api/kyverno/v1"
-	"github.com/robfig/cron/v3"
 	"go.uber.org/multierr"
 	v1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/pod-security-admission/api"
 )
 
+// adapterName labels the metrics of the adapter.
+const adapterName = "nimbus-kyverno"
+
 var (
-	client dynamic.Interface
+	clientsOnce sync.Once
+	client      dynamic.Interface
+	feedLoader  *feed.Loader
 )
 
-func init() {
-	client = k8s.NewDynamicClient()
+// initClients creates the clients the first time they are used rather than
+// when the package is loaded, so that it can be loaded without a cluster.
+func initClients() {
+	clientsOnce.Do(func() {
+		client = k8s.NewDynamicClient()
+		feedLoader = feed.NewLoaderFromEnv(client)
+	})
+}
+
+// feedMaxAge is the age under which the virtual patch feed isn't loaded again,
+// so that the refreshes of the NimbusPolicies sharing a schedule load it once.
+const feedMaxAge = time.Minute
+
+// RefreshVirtualPatchFeed loads the virtual patch feed the KyvernoPolicies of
+// the virtualPatch intents are built from, unless it was just loaded, and
+// returns the status of the last load.
+func RefreshVirtualPatchFeed(ctx context.Context) v1alpha1.VirtualPatchFeedStatus {
+	initClients()
+	return feedLoader.Refresh(ctx, feedMaxAge)
 }
 
-func BuildKpsFrom(logger logr.Logger, np *v1alpha1.NimbusPolicy) []kyvernov1.Policy {
+func BuildKpsFrom(ctx context.Context, logger logr.Logger, np *v1alpha1.NimbusPolicy) []kyvernov1.Policy {
+	// The status of the virtual patch feed is only set if the NimbusPolicy
+	// still has the virtualPatch intent.
+	np.Status.VirtualPatchFeed = nil
+
 	// Build KPs based on given IDs
 	var allkps []kyvernov1.Policy
 	background := true
 	for _, nimbusRule := range np.Spec.NimbusRules {
 		id := nimbusRule.ID
 		if idpool.IsIdSupportedBy(id, "kyverno") {
-			kps, err := buildKpFor(id, np, logger)
+			failureAction, ok := kpActionFor(id, nimbusRule.Rule.RuleAction)
+			if !ok {
+				logger.Info("Kyverno does not support this action", "ID", id, "Action", nimbusRule.Rule.RuleAction,
+					"NimbusPolicy", np.Name, "NimbusPolicy.Namespace", np.Namespace)
+				continue
+			}
+			kps, err := buildKpFor(ctx, id, np, logger)
 			if err != nil {
 				logger.Error(err, "error while building kyverno policies")
+				metrics.TranslationFailed(adapterName, id)
 			}
 			for _, kp := range kps {
 				if id != "cocoWorkload" && id != "virtualPatch" {
@@ -54,12 +91,9 @@ func BuildKpsFrom(logger logr.Logger, np *v1alpha1.NimbusPolicy) []kyvernov1.Pol
 				kp.Annotations["policies.kyverno.io/description"] = nimbusRule.Description
 				kp.Spec.Background = &background
 
-				if nimbusRule.Rule.RuleAction == "Block" {
-					kp.Spec.ValidationFailureAction = kyvernov1.ValidationFailureAction("Enforce")
-				} else {
-					kp.Spec.ValidationFailureAction = kyvernov1.ValidationFailureAction("Audit")
-				}
+				kp.Spec.ValidationFailureAction = failureAction
 				addManagedByAnnotation(&kp)
+				adapterutil.AddIntents(kp.Annotations, id)
 				allkps = append(allkps, kp)
 			}
 		} else {
@@ -71,61 +105,27 @@ func BuildKpsFrom(logger logr.Logger, np *v1alpha1.NimbusPolicy) []kyvernov1.Pol
 }
 
 // buildKpFor builds a KyvernoPolicy based on intent ID supported by Kyverno Policy Engine.
-func buildKpFor(id string, np *v1alpha1.NimbusPolicy, logger logr.Logger) ([]kyvernov1.Policy, error) {
+func buildKpFor(ctx context.Context, id string, np *v1alpha1.NimbusPolicy, logger logr.Logger) ([]kyvernov1.Policy, error) {
 	var kps []kyvernov1.Policy
 	switch id {
 	case idpool.EscapeToHost:
 		kps = append(kps, escapeToHost(np))
 	case idpool.CocoWorkload:
-		kpols, err := cocoRuntimeAddition(np)
+		kpols, err := cocoRuntimeAddition(ctx, np)
 		if err != nil {
 			return kps, err
 		}
 		kps = append(kps, kpols...)
 	case idpool.VirtualPatch:
-		kpols, err := virtualPatch(np, logger)
+		kpols, err := virtualPatch(ctx, np, logger)
 		if err != nil {
 			return kps, err
 		}
 		kps = append(kps, kpols...)
-		watchCVES(np, logger)
 	}
 	return kps, nil
 }
 
-func watchCVES(np *v1alpha1.NimbusPolicy, logger logr.Logger) {
-	rule := np.Spec.NimbusRules[0].Rule
-	schedule := "0 0 * * *"
-	if rule.Params["schedule"] != nil {
-		schedule = rule.Params["schedule"][0]
-	}
-	// Schedule the deletion of the Nimbus policy
-	c := cron.New()
-	_, err := c.AddFunc(schedule, func() {
-		logger.Info("Checking for CVE updates and updation of policies")
-		err := deleteNimbusPolicy(np, logger)
-		if err != nil {
-			logger.Error(err, "error while updating policies")
-		}
-	})
-	if err != nil {
-		logger.Error(err, "error while adding the schedule to update policies")
-		os.Exit(1)
-	}
-	c.Start()
-
-}
-
-func deleteNimbusPolicy(np *v1alpha1.NimbusPolicy, logger logr.Logger) error {
-	nimbusPolicyGVR := schema.GroupVersionResource{Group: "intent.security.nimbus.com", Version: "v1alpha1", Resource: "nimbuspolicies"}
-	err := client.Resource(nimbusPolicyGVR).Namespace(np.Namespace).Delete(context.TODO(), np.Name, metav1.DeleteOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to delete Nimbus Policy: %s", err.Error())
-	}
-	logger.Info("Nimbus policy deleted successfully")
-	return nil
-}
-
 func escapeToHost(np *v1alpha1.NimbusPolicy) kyvernov1.Policy {
 	rule := np.Spec.NimbusRules[0].Rule
 	var psaLevel api.Level = api.LevelBaseline
@@ -142,34 +142,7 @@ func escapeToHost(np *v1alpha1.NimbusPolicy) kyvernov1.Policy {
 		}
 	}
 
-	labels := np.Spec.Selector.MatchLabels
-
-	if len(labels) > 0 {
-		for key, value := range labels {
-			resourceFilter := kyvernov1.ResourceFilter{
-				ResourceDescription: kyvernov1.ResourceDescription{
-					Kinds: []string{
-						"v1/Pod",
-					},
-					Selector: &metav1.LabelSelector{
-						MatchLabels: map[string]string{
-							key: value,
-						},
-					},
-				},
-			}
-			matchResourceFilters = append(matchResourceFilters, resourceFilter)
-		}
-	} else {
-		resourceFilter := kyvernov1.ResourceFilter{
-			ResourceDescription: kyvernov1.ResourceDescription{
-				Kinds: []string{
-					"v1/Pod",
-				},
-			},
-		}
-		matchResourceFilters = append(matchResourceFilters, resourceFilter)
-	}
+	matchResourceFilters = append(matchResourceFilters, workloadFilter("v1/Pod", np.Spec.Selector, nil))
 
 	background := true
 	kp := kyvernov1.Policy{
@@ -195,13 +168,13 @@ func escapeToHost(np *v1alpha1.NimbusPolicy) kyvernov1.Policy {
 	return kp
 }
 
-func cocoRuntimeAddition(np *v1alpha1.NimbusPolicy) ([]kyvernov1.Policy, error) {
+func cocoRuntimeAddition(ctx context.Context, np *v1alpha1.NimbusPolicy) ([]kyvernov1.Policy, error) {
 	var kps []kyvernov1.Policy
 	var errs []error
 	var deployNames []string
 	var mutateTargetResourceSpecs []kyvernov1.TargetResourceSpec
 	var matchResourceFilters []kyvernov1.ResourceFilter
-	labels := np.Spec.Selector.MatchLabels
+	selectsAll := toLabelSelector(np.Spec.Selector) == nil
 	runtimeClass := "kata-clh"
 	params := np.Spec.NimbusRules[0].Rule.Params["runtimeClass"]
 	if params != nil {
@@ -225,21 +198,16 @@ func cocoRuntimeAddition(np *v1alpha1.NimbusPolicy) ([]kyvernov1.Policy, error)
 	}
 
 	deploymentsGVR := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
-	deployments, err := client.Resource(deploymentsGVR).Namespace(np.Namespace).List(context.TODO(), metav1.ListOptions{})
+	initClients()
+	deployments, err := client.Resource(deploymentsGVR).Namespace(np.Namespace).List(ctx, metav1.ListOptions{})
 	if err != nil {
 		errs = append(errs, err)
 	}
-	var markLabels = make(map[string][]string)
-	for _, d := range deployments.Items {
-		for k, v := range d.GetLabels() {
-			key := k + ":" + v
-			markLabels[key] = append(markLabels[key], d.GetName())
-		}
-	}
-	for k, v := range labels {
-		key := k + ":" + v
-		if len(markLabels[key]) != 0 {
-			deployNames = append(deployNames, markLabels[key]...)
+	if deployments != nil {
+		for _, d := range deployments.Items {
+			if selects(np.Spec.Selector, d.GetLabels()) {
+				deployNames = append(deployNames, d.GetName())
+			}
 		}
 	}
 
@@ -253,23 +221,7 @@ func cocoRuntimeAddition(np *v1alpha1.NimbusPolicy) ([]kyvernov1.Policy, error)
 		}
 		mutateTargetResourceSpecs = append(mutateTargetResourceSpecs, mutateResourceSpec)
 	}
-	if len(labels) > 0 {
-		for key, value := range labels {
-			resourceFilter := kyvernov1.ResourceFilter{
-				ResourceDescription: kyvernov1.ResourceDescription{
-					Kinds: []string{
-						"apps/v1/Deployment",
-					},
-					Selector: &metav1.LabelSelector{
-						MatchLabels: map[string]string{
-							key: value,
-						},
-					},
-				},
-			}
-			matchResourceFilters = append(matchResourceFilters, resourceFilter)
-		}
-	} else if len(labels) == 0 {
+	if selectsAll {
 		mutateResourceSpec := kyvernov1.TargetResourceSpec{
 			ResourceSpec: kyvernov1.ResourceSpec{
 				APIVersion: "apps/v1",
@@ -277,17 +229,8 @@ func cocoRuntimeAddition(np *v1alpha1.NimbusPolicy) ([]kyvernov1.Policy, error)
 			},
 		}
 		mutateTargetResourceSpecs = append(mutateTargetResourceSpecs, mutateResourceSpec)
-
-		resourceFilter := kyvernov1.ResourceFilter{
-			ResourceDescription: kyvernov1.ResourceDescription{
-				Kinds: []string{
-					"apps/v1/Deployment",
-				},
-			},
-		}
-
-		matchResourceFilters = append(matchResourceFilters, resourceFilter)
 	}
+	matchResourceFilters = append(matchResourceFilters, workloadFilter("apps/v1/Deployment", np.Spec.Selector, nil))
 
 	mutateExistingKp := kyvernov1.Policy{
 		Spec: kyvernov1.Spec{
@@ -340,7 +283,7 @@ func cocoRuntimeAddition(np *v1alpha1.NimbusPolicy) ([]kyvernov1.Policy, error)
 
 	mutateNewKp.Name = np.Name + "-mutateoncreate"
 
-	if (len(deployNames) > 0) || (len(labels) == 0 && len(deployments.Items) > 0) { // if labels are present but no deploy exists with matching label or labels are not present but deployments exists
+	if (len(deployNames) > 0) || (selectsAll && len(deployments.Items) > 0) { // if the selector selects no existing deployment, or selects all of them and some exist
 		kps = append(kps, mutateExistingKp)
 	}
 	kps = append(kps, mutateNewKp)
@@ -351,59 +294,52 @@ func cocoRuntimeAddition(np *v1alpha1.NimbusPolicy) ([]kyvernov1.Policy, error)
 	return kps, multierr.Combine(errs...)
 }
 
-func virtualPatch(np *v1alpha1.NimbusPolicy, logger logr.Logger) ([]kyvernov1.Policy, error) {
+func virtualPatch(ctx context.Context, np *v1alpha1.NimbusPolicy, logger logr.Logger) ([]kyvernov1.Policy, error) {
 	rule := np.Spec.NimbusRules[0].Rule
 	requiredCVES := rule.Params["cveList"]
 	var kps []kyvernov1.Policy
-	resp, err := utils.FetchVirtualPatchData[[]map[string]any]()
-	if err != nil {
-		return kps, err
+
+	// The feed is served from the cache, refreshed on the schedules of the
+	// virtualPatch intents. It's only loaded here, or the load in progress
+	// waited for, the first time.
+	initClients()
+	vpFeed, status, loaded := feedLoader.Cached()
+	if !loaded {
+		feedLoader.Refresh(ctx, feedMaxAge)
+		vpFeed, status, _ = feedLoader.Cached()
+	}
+	np.Status.VirtualPatchFeed = &status
+	if vpFeed == nil {
+		return kps, fmt.Errorf("failed to load the virtual patch feed: %s", status.Message)
 	}
-	for _, currObj := range resp {
-		image := currObj["image"].(string)
-		cves := currObj["cves"].([]any)
-		for _, obj := range cves {
-			cveData := obj.(map[string]any)
-			cve := cveData["cve"].(string)
+	if status.State == v1alpha1.VirtualPatchFeedFailed {
+		logger.Info("Failed to load the virtual patch feed, using the last loaded feed", "Source", status.Source,
+			"Error", status.Message, "NimbusPolicy", np.Name, "NimbusPolicy.Namespace", np.Namespace)
+	}
+
+	for _, image := range vpFeed.Images {
+		for _, cveData := range image.CVEs {
+			cve := cveData.ID
 			if utils.Contains(requiredCVES, cve) {
 				// create generate kyverno policies which will generate the native virtual patch policies based on the CVE's
-				karmorPolCount := 1
-				kyvPolCount := 1
-				netPolCount := 1
-				virtualPatch := cveData["virtual_patch"].([]any)
-				for _, policy := range virtualPatch {
-					pol := policy.(map[string]any)
-					policyData, ok := pol["karmor"].(map[string]any)
-					if ok {
-						karmorPol, err := generatePol("karmor", cve, image, np, policyData, karmorPolCount, logger)
-						if err != nil {
-							logger.V(2).Error(err, "Error while  generating karmor policy")
-						} else {
-							kps = append(kps, karmorPol)
-							karmorPolCount += 1
+				polCounts := make(map[string]int)
+				for _, patch := range cveData.VirtualPatch {
+					policies := patch.Policies()
+					for _, engine := range []string{feed.EngineKubeArmor, feed.EngineKyverno, feed.EngineNetPol} {
+						policyData, ok := policies[engine]
+						if !ok {
+							continue
 						}
-
-					}
-					policyData, ok = pol["kyverno"].(map[string]any)
-					if ok {
-						kyvernoPol, err := generatePol("kyverno", cve, image, np, policyData, kyvPolCount, logger)
-						if err != nil {
-							logger.V(2).Error(err, "Error while  generating kyverno policy")
-						} else {
-							kps = append(kps, kyvernoPol)
-							kyvPolCount += 1
-						}
-					}
-
-					policyData, ok = pol["netpol"].(map[string]any)
-					if ok {
-						netPol, err := generatePol("netpol", cve, image, np, policyData, netPolCount, logger)
+						// The feed is shared by the policies, the generated
+						// policies are built from a copy of it.
+						pol, err := generatePol(engine, cve, image, np, runtime.DeepCopyJSON(policyData), polCounts[engine]+1, logger)
 						if err != nil {
-							logger.V(2).Error(err, "Error while  generating network policy")
-						} else {
-							kps = append(kps, netPol)
-							netPolCount += 1
+							logger.V(2).Error(err, "Error while generating policy", "Engine", engine)
+							metrics.TranslationFailed(adapterName, "virtualPatch")
+							continue
 						}
+						kps = append(kps, pol)
+						polCounts[engine] += 1
 					}
 				}
 			}
@@ -416,9 +352,10 @@ func addManagedByAnnotation(kp *kyvernov1.Policy) {
 	kp.Annotations["app.kubernetes.io/managed-by"] = "nimbus-kyverno"
 }
 
-func generatePol(polengine string, cve string, image string, np *v1alpha1.NimbusPolicy, policyData map[string]any, count int, logger logr.Logger) (kyvernov1.Policy, error) {
+func generatePol(polengine string, cve string, image feed.Image, np *v1alpha1.NimbusPolicy, policyData map[string]any, count int, logger logr.Logger) (kyvernov1.Policy, error) {
 	var pol kyvernov1.Policy
 	labels := np.Spec.Selector.MatchLabels
+	expressions := np.Spec.Selector.MatchExpressions
 	cve = strings.ToLower(cve)
 	uid := np.ObjectMeta.GetUID()
 	ownerShipList := []any{
@@ -432,12 +369,18 @@ func generatePol(polengine string, cve string, image string, np *v1alpha1.Nimbus
 		},
 	}
 
+	// the pods with a container running the image, by repository, tag and
+	// digest, match
+	containersQuery, err := matchingContainersQuery(image)
+	if err != nil {
+		return pol, err
+	}
 	preConditionMap := map[string]any{
 		"all": []any{
 			map[string]any{
-				"key":      image,
-				"operator": "AnyIn",
-				"value":    "{{ request.object.spec.containers[].image }}",
+				"key":      "{{ length(" + containersQuery + ") }}",
+				"operator": "GreaterThan",
+				"value":    0,
 			},
 		},
 	}
@@ -458,7 +401,7 @@ func generatePol(polengine string, cve string, image string, np *v1alpha1.Nimbus
 
 	specMap := policyData["spec"].(map[string]any)
 
-	jmesPathContainerNameQuery := "request.object.spec.containers[?(@.image=='" + image + "')].name | [0]"
+	jmesPathContainerNameQuery := containersQuery + " | [0].name"
 
 	delete(policyData, "apiVersion")
 	delete(policyData, "kind")
@@ -469,6 +412,8 @@ func generatePol(polengine string, cve string, image string, np *v1alpha1.Nimbus
 
 	if polengine == "karmor" {
 		generatedPolicyName := metadataMap["name"].(string) + "-{{ podName }}"
+		// KubeArmorPolicies only select by labels, the expressions of the
+		// selector are only met by the pods the policies are generated for.
 		selector := specMap["selector"].(map[string]any)
 		delete(selector, "matchLabels")
 		selectorLabels := make(map[string]any)
@@ -498,9 +443,7 @@ func generatePol(polengine string, cve string, image string, np *v1alpha1.Nimbus
 										Kinds: []string{
 											"v1/Pod",
 										},
-										Selector: &metav1.LabelSelector{
-											MatchLabels: labels,
-										},
+										Selector: toLabelSelector(np.Spec.Selector),
 									},
 								},
 							},
@@ -538,6 +481,9 @@ func generatePol(polengine string, cve string, image string, np *v1alpha1.Nimbus
 		selectorMap := map[string]any{
 			"matchLabels": labels,
 		}
+		if len(expressions) > 0 {
+			selectorMap["matchExpressions"] = expressions
+		}
 
 		kindMap := map[string]any{
 			"kinds": []any{
@@ -560,11 +506,15 @@ func generatePol(polengine string, cve string, image string, np *v1alpha1.Nimbus
 		delete(rule, "match")
 		rule["match"] = newMatchMap
 
-		// appending the image matching precondition to the existing preconditions
-		preCndMap := rule["preconditions"].(map[string]any)
-		conditionsList, ok := preCndMap["any"].([]any)
-		if ok {
-			preConditionMap["all"] = append(preConditionMap["all"].([]any), conditionsList...)
+		// appending the image matching precondition to the existing preconditions,
+		// escaped so that it's evaluated by the generated policy rather than
+		// substituted when generating it
+		preConditionMap["all"].([]any)[0].(map[string]any)["key"] = `\{{ length(` + containersQuery + `) }}`
+		if preCndMap, ok := rule["preconditions"].(map[string]any); ok {
+			conditionsList, ok := preCndMap["any"].([]any)
+			if ok {
+				preConditionMap["all"] = append(preConditionMap["all"].([]any), conditionsList...)
+			}
 		}
 
 		delete(rule, "preconditions")
@@ -592,9 +542,7 @@ func generatePol(polengine string, cve string, image string, np *v1alpha1.Nimbus
 										Kinds: []string{
 											"v1/Pod",
 										},
-										Selector: &metav1.LabelSelector{
-											MatchLabels: labels,
-										},
+										Selector: toLabelSelector(np.Spec.Selector),
 									},
 								},
 							},
@@ -620,7 +568,11 @@ func generatePol(polengine string, cve string, image string, np *v1alpha1.Nimbus
 		generatedPolicyName := metadataMap["name"].(string)
 		selector := specMap["podSelector"].(map[string]any)
 		delete(selector, "matchLabels")
+		delete(selector, "matchExpressions")
 		selector["matchLabels"] = labels
+		if len(expressions) > 0 {
+			selector["matchExpressions"] = expressions
+		}
 
 		policyBytes, err := json.Marshal(policyData)
 
@@ -643,9 +595,7 @@ func generatePol(polengine string, cve string, image string, np *v1alpha1.Nimbus
 										Kinds: []string{
 											"v1/Pod",
 										},
-										Selector: &metav1.LabelSelector{
-											MatchLabels: labels,
-										},
+										Selector: toLabelSelector(np.Spec.Selector),
 									},
 								},
 							},
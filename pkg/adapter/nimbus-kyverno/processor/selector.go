@@ -0,0 +1,52 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package processor
+
+import (
+	v1alpha1 "github.com/5GSEC/nimbus/api/v1alpha1"
+	kyvernov1 "github.com/kyverno/kyverno/api/kyverno/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// workloadFilter returns the filter matching the resources of the given kind,
+// e.g. "v1/Pod", in the given namespaces, any when empty, selected by the
+// whole workload selector. The selector is never split into a filter per
+// label, the resources would then match when they have any of the labels,
+// unlike with the KubeArmor and network policies.
+func workloadFilter(kind string, selector v1alpha1.LabelSelector, namespaces []string) kyvernov1.ResourceFilter {
+	return kyvernov1.ResourceFilter{
+		ResourceDescription: kyvernov1.ResourceDescription{
+			Kinds:      []string{kind},
+			Namespaces: namespaces,
+			Selector:   toLabelSelector(selector),
+		},
+	}
+}
+
+// toLabelSelector returns the label selector of the given workload selector,
+// nil if it selects all the workloads.
+func toLabelSelector(selector v1alpha1.LabelSelector) *metav1.LabelSelector {
+	if len(selector.MatchLabels) == 0 && len(selector.MatchExpressions) == 0 {
+		return nil
+	}
+	return &metav1.LabelSelector{
+		MatchLabels:      selector.MatchLabels,
+		MatchExpressions: selector.MatchExpressions,
+	}
+}
+
+// selects reports whether the given workload selector selects the resources
+// with the given labels, i.e. whether they have all the labels of the
+// selector and meet all its expressions. An invalid selector selects nothing.
+func selects(selector v1alpha1.LabelSelector, resourceLabels map[string]string) bool {
+	labelSelector, err := metav1.LabelSelectorAsSelector(&metav1.LabelSelector{
+		MatchLabels:      selector.MatchLabels,
+		MatchExpressions: selector.MatchExpressions,
+	})
+	if err != nil {
+		return false
+	}
+	return labelSelector.Matches(labels.Set(resourceLabels))
+}
@@ -0,0 +1,462 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+// Package reports aggregates the results of the Kyverno policies generated by
+// the adapter, as reported by Kyverno in its PolicyReports and
+// ClusterPolicyReports, into the status of the NimbusPolicies,
+// ClusterNimbusPolicies and bindings of the intents they enforce.
+package reports
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	kyvernov1 "github.com/kyverno/kyverno/api/kyverno/v1"
+	kyvernov1beta1 "github.com/kyverno/kyverno/api/kyverno/v1beta1"
+	policyreportv1alpha2 "github.com/kyverno/kyverno/api/policyreport/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/5GSEC/nimbus/api/v1alpha1"
+	"github.com/5GSEC/nimbus/pkg/adapter/idpool"
+	"github.com/5GSEC/nimbus/pkg/adapter/k8s"
+	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
+)
+
+const (
+	adapterName   = "nimbus-kyverno"
+	engine        = "kyverno"
+	source        = "kyverno"
+	flushInterval = 15 * time.Second
+)
+
+// The PolicyReports and ClusterPolicyReports in which Kyverno reports the
+// results of its policies.
+var (
+	policyReportGvr = schema.GroupVersionResource{
+		Group:    "wgpolicyk8s.io",
+		Version:  "v1alpha2",
+		Resource: "policyreports",
+	}
+	clusterPolicyReportGvr = schema.GroupVersionResource{
+		Group:    "wgpolicyk8s.io",
+		Version:  "v1alpha2",
+		Resource: "clusterpolicyreports",
+	}
+)
+
+// statusRef identifies a NimbusPolicy, ClusterNimbusPolicy,
+// SecurityIntentBinding or ClusterSecurityIntentBinding the results are
+// reported to.
+type statusRef struct {
+	Kind      string
+	Name      string
+	Namespace string
+}
+
+// owner is a NimbusPolicy or ClusterNimbusPolicy owning a Kyverno policy,
+// along with the binding owning it and the IDs of its intents.
+type owner struct {
+	nimbusPolicy statusRef
+	binding      *statusRef
+	ids          []string
+}
+
+// resolved are the owners of a Kyverno policy, along with the policy name
+// formatted as "<Kind>/<name>" and the IDs of the intents it enforces.
+type resolved struct {
+	policy string
+	ids    []string
+	owners []owner
+}
+
+// Watch watches the PolicyReports and ClusterPolicyReports, and sets the
+// pass, fail and warn counts of the results of the Kyverno policies generated
+// by the adapter, along with the resources that failed them, in the status of
+// the NimbusPolicies, ClusterNimbusPolicies and bindings, until the context is
+// done. The reports changed since the previous aggregation are aggregated
+// every 15 seconds.
+func Watch(ctx context.Context, k8sClient client.Client) {
+	logger := log.FromContext(ctx).WithName("reports")
+
+	factory := dynamicinformer.NewDynamicSharedInformerFactory(k8s.NewDynamicClient(), time.Minute)
+	informers := []cache.SharedIndexInformer{
+		factory.ForResource(policyReportGvr).Informer(),
+		factory.ForResource(clusterPolicyReportGvr).Informer(),
+	}
+
+	changedCh := make(chan struct{}, 1)
+	changed := func() {
+		select {
+		case changedCh <- struct{}{}:
+		default:
+		}
+	}
+	handlers := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { changed() },
+		UpdateFunc: func(oldObj, newObj interface{}) { changed() },
+		DeleteFunc: func(obj interface{}) { changed() },
+	}
+	for _, informer := range informers {
+		if _, err := informer.AddEventHandler(handlers); err != nil {
+			logger.Error(err, "failed to add event handlers")
+			return
+		}
+	}
+	factory.Start(ctx.Done())
+	// Aggregating partial reports would clear the results of the others.
+	factory.WaitForCacheSync(ctx.Done())
+	logger.Info("PolicyReport watcher started")
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	// The statuses the results were reported to, so that they're cleared
+	// once the results are gone, e.g. when the policies are deleted. The ones
+	// reported before the adapter started are seeded from the statuses.
+	reported := make(map[statusRef]bool)
+	seeded := false
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !seeded {
+				if err := seedReported(ctx, k8sClient, reported); err != nil {
+					logger.Error(err, "failed to list the reported statuses, retrying")
+					continue
+				}
+				seeded = true
+				// Aggregate even when no reports are left, to clear them.
+				changed()
+			}
+			select {
+			case <-changedCh:
+			default:
+				continue
+			}
+
+			var reports []policyreportv1alpha2.PolicyReport
+			for _, informer := range informers {
+				reports = append(reports, reportsOf(logger, informer.GetStore().List())...)
+			}
+			summaries := aggregate(ctx, k8sClient, reports)
+			for ref := range reported {
+				if _, ok := summaries[ref]; !ok {
+					summaries[ref] = nil
+				}
+			}
+			clear(reported)
+			for ref, refSummaries := range summaries {
+				if err := adapterutil.UpdatePolicyReports(ctx, k8sClient, ref.Kind, ref.Name, ref.Namespace, engine, refSummaries); err != nil {
+					logger.Error(err, "failed to report policy reports", "Kind", ref.Kind, "Name", ref.Name, "Namespace", ref.Namespace)
+					// Reported, or cleared, again on the next aggregation.
+					reported[ref] = true
+					changed()
+					continue
+				}
+				if refSummaries != nil {
+					reported[ref] = true
+				}
+			}
+		}
+	}
+}
+
+// seedReported adds the NimbusPolicies, ClusterNimbusPolicies and bindings
+// whose status has policy reports of the engine to the reported statuses.
+func seedReported(ctx context.Context, k8sClient client.Client, reported map[statusRef]bool) error {
+	hasReports := func(summaries []v1alpha1.PolicyReportSummary) bool {
+		return slices.ContainsFunc(summaries, func(summary v1alpha1.PolicyReportSummary) bool {
+			return summary.Engine == engine
+		})
+	}
+
+	npList := &v1alpha1.NimbusPolicyList{}
+	if err := k8sClient.List(ctx, npList); err != nil {
+		return err
+	}
+	for _, np := range npList.Items {
+		if hasReports(np.Status.PolicyReports) {
+			reported[statusRef{Kind: "NimbusPolicy", Name: np.Name, Namespace: np.Namespace}] = true
+		}
+	}
+
+	cwnpList := &v1alpha1.ClusterNimbusPolicyList{}
+	if err := k8sClient.List(ctx, cwnpList); err != nil {
+		return err
+	}
+	for _, cwnp := range cwnpList.Items {
+		if hasReports(cwnp.Status.PolicyReports) {
+			reported[statusRef{Kind: "ClusterNimbusPolicy", Name: cwnp.Name}] = true
+		}
+	}
+
+	sibList := &v1alpha1.SecurityIntentBindingList{}
+	if err := k8sClient.List(ctx, sibList); err != nil {
+		return err
+	}
+	for _, sib := range sibList.Items {
+		if hasReports(sib.Status.PolicyReports) {
+			reported[statusRef{Kind: "SecurityIntentBinding", Name: sib.Name, Namespace: sib.Namespace}] = true
+		}
+	}
+
+	csibList := &v1alpha1.ClusterSecurityIntentBindingList{}
+	if err := k8sClient.List(ctx, csibList); err != nil {
+		return err
+	}
+	for _, csib := range csibList.Items {
+		if hasReports(csib.Status.PolicyReports) {
+			reported[statusRef{Kind: "ClusterSecurityIntentBinding", Name: csib.Name}] = true
+		}
+	}
+	return nil
+}
+
+// reportsOf converts the given PolicyReports or ClusterPolicyReports of an
+// informer store.
+func reportsOf(logger logr.Logger, objs []interface{}) []policyreportv1alpha2.PolicyReport {
+	var reports []policyreportv1alpha2.PolicyReport
+	for _, obj := range objs {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		var report policyreportv1alpha2.PolicyReport
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, &report); err != nil {
+			logger.Error(err, "failed to convert report", "Kind", u.GetKind(), "Name", u.GetName(), "Namespace", u.GetNamespace())
+			continue
+		}
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+// aggregate sums up the results of the Kyverno policies generated by the
+// adapter in the given reports, per intent of the NimbusPolicies,
+// ClusterNimbusPolicies and bindings.
+func aggregate(ctx context.Context, k8sClient client.Client, reports []policyreportv1alpha2.PolicyReport) map[statusRef][]v1alpha1.PolicyReportSummary {
+	policies := make(map[string]resolved)
+	summaries := make(map[statusRef]map[string]*v1alpha1.PolicyReportSummary)
+
+	for _, report := range reports {
+		for _, result := range report.Results {
+			if result.Source != source || result.Policy == "" {
+				continue
+			}
+			policy, ok := policies[result.Policy]
+			if !ok {
+				policy = resolve(ctx, k8sClient, result.Policy)
+				policies[result.Policy] = policy
+			}
+			if len(policy.owners) == 0 {
+				continue
+			}
+
+			resources := result.Resources
+			if len(resources) == 0 && report.Scope != nil {
+				resources = []corev1.ObjectReference{*report.Scope}
+			}
+			ids := intentsOf(policy.ids, result.Rule)
+			for _, o := range policy.owners {
+				refs := []statusRef{o.nimbusPolicy}
+				if o.binding != nil {
+					refs = append(refs, *o.binding)
+				}
+				for _, id := range ids {
+					if !slices.Contains(o.ids, id) {
+						continue
+					}
+					for _, ref := range refs {
+						if summaries[ref] == nil {
+							summaries[ref] = make(map[string]*v1alpha1.PolicyReportSummary)
+						}
+						summary, ok := summaries[ref][id]
+						if !ok {
+							summary = &v1alpha1.PolicyReportSummary{ID: id, Engine: engine}
+							summaries[ref][id] = summary
+						}
+						count(summary, policy.policy, result, resources)
+					}
+				}
+			}
+		}
+	}
+
+	aggregated := make(map[statusRef][]v1alpha1.PolicyReportSummary, len(summaries))
+	for ref, byID := range summaries {
+		for _, summary := range byID {
+			sortFailingResources(summary.FailingResources)
+			if len(summary.FailingResources) > v1alpha1.MaxReportedResources {
+				summary.FailingResources = summary.FailingResources[:v1alpha1.MaxReportedResources]
+			}
+			aggregated[ref] = append(aggregated[ref], *summary)
+		}
+		slices.SortFunc(aggregated[ref], func(a, b v1alpha1.PolicyReportSummary) int {
+			return cmp.Compare(a.ID, b.ID)
+		})
+	}
+	return aggregated
+}
+
+// count adds the result of the given policy to the summary.
+func count(summary *v1alpha1.PolicyReportSummary, policy string, result policyreportv1alpha2.PolicyReportResult, resources []corev1.ObjectReference) {
+	switch result.Result {
+	case policyreportv1alpha2.StatusPass:
+		summary.Pass++
+	case policyreportv1alpha2.StatusFail:
+		summary.Fail++
+	case policyreportv1alpha2.StatusWarn:
+		summary.Warn++
+	case policyreportv1alpha2.StatusError:
+		summary.Error++
+	case policyreportv1alpha2.StatusSkip:
+		summary.Skip++
+	}
+
+	switch result.Result {
+	case policyreportv1alpha2.StatusFail, policyreportv1alpha2.StatusWarn, policyreportv1alpha2.StatusError:
+	default:
+		return
+	}
+	for _, resource := range resources {
+		summary.FailingResources = append(summary.FailingResources, v1alpha1.ReportedResource{
+			Kind:      resource.Kind,
+			Namespace: resource.Namespace,
+			Name:      resource.Name,
+			Policy:    policy,
+			Rule:      result.Rule,
+			Result:    string(result.Result),
+			Message:   result.Message,
+		})
+	}
+}
+
+// sortFailingResources sorts the resources that failed the policies first,
+// then the ones with errors and the ones the policies warned about, each by
+// namespace, kind and name.
+func sortFailingResources(resources []v1alpha1.ReportedResource) {
+	rank := map[string]int{
+		string(policyreportv1alpha2.StatusFail):  0,
+		string(policyreportv1alpha2.StatusError): 1,
+		string(policyreportv1alpha2.StatusWarn):  2,
+	}
+	slices.SortFunc(resources, func(a, b v1alpha1.ReportedResource) int {
+		return cmp.Or(
+			cmp.Compare(rank[a.Result], rank[b.Result]),
+			cmp.Compare(a.Namespace, b.Namespace),
+			cmp.Compare(a.Kind, b.Kind),
+			cmp.Compare(a.Name, b.Name),
+			cmp.Compare(a.Policy, b.Policy),
+			cmp.Compare(a.Rule, b.Rule),
+		)
+	})
+}
+
+// resolve maps the Kyverno policy of a result, named "<namespace>/<name>" for
+// a Policy and "<name>" for a ClusterPolicy, back to the NimbusPolicies or
+// ClusterNimbusPolicy owning it and the intents it enforces. Policies not
+// generated by the adapter don't have any owner.
+func resolve(ctx context.Context, k8sClient client.Client, name string) resolved {
+	kind, namespace, policyName := adapterutil.ReportedKyvernoPolicy(name)
+	var policy client.Object = &kyvernov1.ClusterPolicy{}
+	if namespace != "" {
+		policy = &kyvernov1.Policy{}
+	}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: policyName, Namespace: namespace}, policy); err != nil {
+		return resolved{}
+	}
+	policyFullName := kind + "/" + policyName
+
+	var ids []string
+	switch {
+	case policy.GetAnnotations()["app.kubernetes.io/managed-by"] == adapterName:
+		if intents := policy.GetAnnotations()[adapterutil.IntentsAnnotation]; intents != "" {
+			ids = strings.Split(intents, ",")
+		}
+	case policy.GetLabels()[kyvernov1beta1.URGeneratePolicyLabel] != "":
+		// The Kyverno policies of the virtual patches are generated by Kyverno
+		// from the ones of the adapter, and only owned by the NimbusPolicy.
+		ids = []string{idpool.VirtualPatch}
+	}
+	if len(ids) == 0 {
+		return resolved{}
+	}
+
+	var owners []owner
+	for _, ownerRef := range policy.GetOwnerReferences() {
+		switch ownerRef.Kind {
+		case "NimbusPolicy":
+			np := &v1alpha1.NimbusPolicy{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{Name: ownerRef.Name, Namespace: policy.GetNamespace()}, np); err != nil {
+				continue
+			}
+			owners = append(owners, ownerOf(np, np.Spec.NimbusRules))
+		case "ClusterNimbusPolicy":
+			cwnp := &v1alpha1.ClusterNimbusPolicy{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{Name: ownerRef.Name}, cwnp); err != nil {
+				continue
+			}
+			owners = append(owners, ownerOf(cwnp, cwnp.Spec.NimbusRules))
+		}
+	}
+
+	return resolved{policy: policyFullName, ids: ids, owners: owners}
+}
+
+// ownerOf returns the given NimbusPolicy or ClusterNimbusPolicy as the owner of
+// a policy, along with the binding that owns it.
+func ownerOf(nimbusPolicy client.Object, rules []v1alpha1.NimbusRules) owner {
+	o := owner{
+		nimbusPolicy: statusRef{
+			Kind:      "NimbusPolicy",
+			Name:      nimbusPolicy.GetName(),
+			Namespace: nimbusPolicy.GetNamespace(),
+		},
+	}
+	if _, ok := nimbusPolicy.(*v1alpha1.ClusterNimbusPolicy); ok {
+		o.nimbusPolicy.Kind = "ClusterNimbusPolicy"
+	}
+	for _, rule := range rules {
+		o.ids = append(o.ids, rule.ID)
+	}
+
+	if ownerRefs := nimbusPolicy.GetOwnerReferences(); len(ownerRefs) > 0 {
+		switch ownerRefs[0].Kind {
+		case "SecurityIntentBinding":
+			o.binding = &statusRef{Kind: ownerRefs[0].Kind, Name: ownerRefs[0].Name, Namespace: nimbusPolicy.GetNamespace()}
+		case "ClusterSecurityIntentBinding":
+			o.binding = &statusRef{Kind: ownerRefs[0].Kind, Name: ownerRefs[0].Name}
+		}
+	}
+	return o
+}
+
+// intentsOf returns the IDs of the intents the given rule of a policy
+// enforcing the given intents enforces. The rules of the consolidated
+// policies, enforcing several intents, are prefixed with the ID of their
+// intent.
+func intentsOf(ids []string, rule string) []string {
+	if len(ids) == 1 {
+		return ids
+	}
+	for _, id := range ids {
+		if strings.HasPrefix(rule, strings.ToLower(id)+"-") {
+			return []string{id}
+		}
+	}
+	return ids
+}
@@ -4,9 +4,7 @@
 package utils
 
 import (
-	"encoding/json"
 	"fmt"
-	"os"
 	"reflect"
 	"slices"
 	"strings"
@@ -17,8 +15,6 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-var VirtualPatchData []map[string]any
-
 func GetGVK(kind string) string {
 	// Map to store the mappings of kinds to their corresponding API versions
 	kindToAPIVersion := map[string]string{
@@ -128,41 +124,21 @@ func Title(input string) string {
     return toTitle.String(input)
 }
 
-func FetchVirtualPatchData[T any]()(T, error) {
-	var out T
-	// Open the JSON file
-	file, err := os.Open("../../../vp.json")
-	if err != nil {
-		return out, err
-	}
-	defer file.Close()
-
-	// Read the file contents
-	bytes, err := os.ReadFile("../../../vp.json")
-	if err != nil {
-		return out, err
-	}
-
-	err = json.Unmarshal(bytes, &out)
-	if err != nil {
-		return out, err
-	}
-
-	return out, nil
-}
-
 func Contains(slice []string, value string) bool {
 	return slices.Contains(slice, value)
 }
 
+// ParseImageString splits the given image into its repository, with the
+// registry, and its tag, "latest" by default. The tag follows the last ":"
+// after the last "/", so that the port of the registry isn't read as the tag,
+// and the digest is dropped.
 func ParseImageString(imageString string) (string, string) {
-    parts := strings.SplitN(imageString, ":", 2)
-    repository := parts[0]
-    tag := "latest" // Default tag
+	repository, _, _ := strings.Cut(imageString, "@")
+	tag := "latest" // Default tag
 
-    if len(parts) > 1 {
-        tag = parts[1]
-    }
+	if idx := strings.LastIndex(repository, ":"); idx > strings.LastIndex(repository, "/") {
+		repository, tag = repository[:idx], repository[idx+1:]
+	}
 
-    return repository, tag
+	return repository, tag
 }
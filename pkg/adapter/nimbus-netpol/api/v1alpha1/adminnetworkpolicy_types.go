@@ -0,0 +1,197 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AdminNetworkPolicyRuleAction is the action of a rule of an
+// AdminNetworkPolicy.
+type AdminNetworkPolicyRuleAction string
+
+const (
+	// AdminNetworkPolicyRuleActionAllow allows the traffic, regardless of the
+	// NetworkPolicies of the namespace.
+	AdminNetworkPolicyRuleActionAllow AdminNetworkPolicyRuleAction = "Allow"
+	// AdminNetworkPolicyRuleActionDeny denies the traffic, regardless of the
+	// NetworkPolicies of the namespace.
+	AdminNetworkPolicyRuleActionDeny AdminNetworkPolicyRuleAction = "Deny"
+	// AdminNetworkPolicyRuleActionPass skips the AdminNetworkPolicies of lower
+	// precedence, leaving the traffic to the NetworkPolicies of the namespace
+	// and to the BaselineAdminNetworkPolicy.
+	AdminNetworkPolicyRuleActionPass AdminNetworkPolicyRuleAction = "Pass"
+)
+
+// BaselineAdminNetworkPolicyRuleAction is the action of a rule of a
+// BaselineAdminNetworkPolicy.
+type BaselineAdminNetworkPolicyRuleAction string
+
+const (
+	BaselineAdminNetworkPolicyRuleActionAllow BaselineAdminNetworkPolicyRuleAction = "Allow"
+	BaselineAdminNetworkPolicyRuleActionDeny  BaselineAdminNetworkPolicyRuleAction = "Deny"
+)
+
+// AdminNetworkPolicySubject selects the pods a policy applies to, either all
+// the pods of the selected namespaces or the selected pods of these
+// namespaces. Exactly one of the fields must be set.
+type AdminNetworkPolicySubject struct {
+	Namespaces *metav1.LabelSelector `json:"namespaces,omitempty"`
+	Pods       *NamespacedPod        `json:"pods,omitempty"`
+}
+
+// NamespacedPod selects pods by their labels and the labels of their
+// namespace.
+type NamespacedPod struct {
+	NamespaceSelector metav1.LabelSelector `json:"namespaceSelector"`
+	PodSelector       metav1.LabelSelector `json:"podSelector"`
+}
+
+// CIDR is an IPv4 or IPv6 CIDR, e.g. "10.0.0.0/8".
+type CIDR string
+
+// AdminNetworkPolicyIngressPeer selects the sources of the ingress traffic.
+// Exactly one of the fields must be set.
+type AdminNetworkPolicyIngressPeer struct {
+	Namespaces *metav1.LabelSelector `json:"namespaces,omitempty"`
+	Pods       *NamespacedPod        `json:"pods,omitempty"`
+}
+
+// AdminNetworkPolicyEgressPeer selects the destinations of the egress traffic.
+// Exactly one of the fields must be set.
+type AdminNetworkPolicyEgressPeer struct {
+	Namespaces *metav1.LabelSelector `json:"namespaces,omitempty"`
+	Pods       *NamespacedPod        `json:"pods,omitempty"`
+	Nodes      *metav1.LabelSelector `json:"nodes,omitempty"`
+	Networks   []CIDR                `json:"networks,omitempty"`
+}
+
+// Port selects a port by its number and protocol.
+type Port struct {
+	Protocol corev1.Protocol `json:"protocol"`
+	Port     int32           `json:"port"`
+}
+
+// AdminNetworkPolicyPort selects the ports of the traffic.
+type AdminNetworkPolicyPort struct {
+	PortNumber *Port `json:"portNumber,omitempty"`
+}
+
+// AdminNetworkPolicyIngressRule matches ingress traffic.
+type AdminNetworkPolicyIngressRule struct {
+	Name   string                          `json:"name,omitempty"`
+	Action AdminNetworkPolicyRuleAction    `json:"action"`
+	From   []AdminNetworkPolicyIngressPeer `json:"from"`
+	Ports  *[]AdminNetworkPolicyPort       `json:"ports,omitempty"`
+}
+
+// AdminNetworkPolicyEgressRule matches egress traffic.
+type AdminNetworkPolicyEgressRule struct {
+	Name   string                         `json:"name,omitempty"`
+	Action AdminNetworkPolicyRuleAction   `json:"action"`
+	To     []AdminNetworkPolicyEgressPeer `json:"to"`
+	Ports  *[]AdminNetworkPolicyPort      `json:"ports,omitempty"`
+}
+
+// AdminNetworkPolicySpec defines the desired state of AdminNetworkPolicy. The
+// rules are evaluated in order, the first matching one deciding the action.
+type AdminNetworkPolicySpec struct {
+	// Priority orders the AdminNetworkPolicies, the lowest value taking
+	// precedence. It ranges from 0 to 1000.
+	Priority int32                           `json:"priority"`
+	Subject  AdminNetworkPolicySubject       `json:"subject"`
+	Ingress  []AdminNetworkPolicyIngressRule `json:"ingress,omitempty"`
+	Egress   []AdminNetworkPolicyEgressRule  `json:"egress,omitempty"`
+}
+
+// AdminNetworkPolicyStatus defines the observed state of AdminNetworkPolicy
+type AdminNetworkPolicyStatus struct {
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster,shortName="anp"
+//+kubebuilder:subresource:status
+
+// AdminNetworkPolicy is the Schema for the adminnetworkpolicies API
+type AdminNetworkPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AdminNetworkPolicySpec   `json:"spec,omitempty"`
+	Status AdminNetworkPolicyStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// AdminNetworkPolicyList contains a list of AdminNetworkPolicy
+type AdminNetworkPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AdminNetworkPolicy `json:"items"`
+}
+
+// BaselineAdminNetworkPolicyIngressRule matches ingress traffic.
+type BaselineAdminNetworkPolicyIngressRule struct {
+	Name   string                               `json:"name,omitempty"`
+	Action BaselineAdminNetworkPolicyRuleAction `json:"action"`
+	From   []AdminNetworkPolicyIngressPeer      `json:"from"`
+	Ports  *[]AdminNetworkPolicyPort            `json:"ports,omitempty"`
+}
+
+// BaselineAdminNetworkPolicyEgressRule matches egress traffic.
+type BaselineAdminNetworkPolicyEgressRule struct {
+	Name   string                               `json:"name,omitempty"`
+	Action BaselineAdminNetworkPolicyRuleAction `json:"action"`
+	To     []AdminNetworkPolicyEgressPeer       `json:"to"`
+	Ports  *[]AdminNetworkPolicyPort            `json:"ports,omitempty"`
+}
+
+// BaselineAdminNetworkPolicySpec defines the desired state of
+// BaselineAdminNetworkPolicy. Its rules apply to the traffic that no
+// AdminNetworkPolicy nor NetworkPolicy matched.
+type BaselineAdminNetworkPolicySpec struct {
+	Subject AdminNetworkPolicySubject               `json:"subject"`
+	Ingress []BaselineAdminNetworkPolicyIngressRule `json:"ingress,omitempty"`
+	Egress  []BaselineAdminNetworkPolicyEgressRule  `json:"egress,omitempty"`
+}
+
+// BaselineAdminNetworkPolicyStatus defines the observed state of
+// BaselineAdminNetworkPolicy
+type BaselineAdminNetworkPolicyStatus struct {
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// BaselineAdminNetworkPolicyName is the name of the only
+// BaselineAdminNetworkPolicy a cluster may have.
+const BaselineAdminNetworkPolicyName = "default"
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster,shortName="banp"
+//+kubebuilder:subresource:status
+
+// BaselineAdminNetworkPolicy is the Schema for the
+// baselineadminnetworkpolicies API
+type BaselineAdminNetworkPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BaselineAdminNetworkPolicySpec   `json:"spec,omitempty"`
+	Status BaselineAdminNetworkPolicyStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// BaselineAdminNetworkPolicyList contains a list of BaselineAdminNetworkPolicy
+type BaselineAdminNetworkPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BaselineAdminNetworkPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AdminNetworkPolicy{}, &AdminNetworkPolicyList{},
+		&BaselineAdminNetworkPolicy{}, &BaselineAdminNetworkPolicyList{})
+}
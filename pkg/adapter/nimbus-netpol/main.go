@@ -13,7 +13,9 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
+	"github.com/5GSEC/nimbus/pkg/adapter/metrics"
 	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-netpol/manager"
+	"github.com/5GSEC/nimbus/pkg/tracing"
 )
 
 func main() {
@@ -34,5 +36,16 @@ func main() {
 	}()
 
 	logger.Info("NetworkPolicy adapter started")
+	shutdownTracing, err := tracing.Setup(ctx, "nimbus-netpol")
+	if err != nil {
+		logger.Error(err, "failed to set up tracing")
+		os.Exit(1)
+	}
+
+	go metrics.Serve(ctx)
 	manager.Run(ctx)
+
+	if err := shutdownTracing(context.Background()); err != nil {
+		logger.Error(err, "failed to flush traces")
+	}
 }
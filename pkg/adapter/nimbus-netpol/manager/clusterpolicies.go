@@ -0,0 +1,279 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package manager
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/5GSEC/nimbus/api/v1alpha1"
+	"github.com/5GSEC/nimbus/pkg/adapter/metrics"
+	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
+	"github.com/5GSEC/nimbus/pkg/tracing"
+
+	anpv1alpha1 "github.com/5GSEC/nimbus/pkg/adapter/nimbus-netpol/api/v1alpha1"
+	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-netpol/processor"
+)
+
+// adminNetworkPoliciesSupported reports whether the AdminNetworkPolicy and
+// BaselineAdminNetworkPolicy CRDs are installed.
+func adminNetworkPoliciesSupported(ctx context.Context) bool {
+	logger := log.FromContext(ctx)
+	for _, kind := range []string{"AdminNetworkPolicy", "BaselineAdminNetworkPolicy"} {
+		_, err := k8sClient.RESTMapper().RESTMapping(anpv1alpha1.GroupVersion.WithKind(kind).GroupKind(), anpv1alpha1.GroupVersion.Version)
+		if err != nil {
+			if !meta.IsNoMatchError(err) {
+				logger.Error(err, "failed to look up the "+kind+" API")
+			}
+			return false
+		}
+	}
+	return true
+}
+
+func reconcileAnps(ctx context.Context, cwnpName string, deleted bool) {
+	logger := log.FromContext(ctx)
+	if cwnpName == "" {
+		return
+	}
+	if deleted {
+		logger.V(2).Info("Reconciling deleted AdminNetworkPolicy", "ClusterNimbusPolicy.Name", cwnpName)
+	} else {
+		logger.V(2).Info("Reconciling modified AdminNetworkPolicy", "ClusterNimbusPolicy.Name", cwnpName)
+	}
+	createOrUpdateAnps(ctx, cwnpName)
+}
+
+func createOrUpdateAnps(ctx context.Context, cwnpName string) {
+	defer metrics.ObserveReconcile(adapterName, time.Now())
+	logger := log.FromContext(ctx)
+	var cwnp v1alpha1.ClusterNimbusPolicy
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: cwnpName}, &cwnp); err != nil {
+		if !errors.IsNotFound(err) {
+			logger.Error(err, "failed to get ClusterNimbusPolicy", "ClusterNimbusPolicy.Name", cwnpName)
+		}
+		return
+	}
+
+	if adapterutil.IsOrphan(cwnp.GetOwnerReferences(), "ClusterSecurityIntentBinding") {
+		logger.V(4).Info("Ignoring orphan ClusterNimbusPolicy", "ClusterNimbusPolicy.Name", cwnpName)
+		return
+	}
+
+	// Continue the trace of the reconciliation that changed the
+	// ClusterNimbusPolicy.
+	ctx, span := tracing.Start(tracing.Extract(ctx, &cwnp), adapterName+".Reconcile", tracing.WithObject("ClusterNimbusPolicy", &cwnp))
+	defer span.End()
+
+	adapterutil.RecordUnsupportedIntents(recorder, &cwnp, cwnp.Spec.NimbusRules, "netpol")
+	adapterutil.RecordUnsupportedActions(recorder, &cwnp, cwnp.Spec.NimbusRules, "netpol")
+	if err := adapterutil.UpdateUnsupportedActions(ctx, k8sClient, "ClusterNimbusPolicy", cwnp.Name, cwnp.Namespace, "netpol", cwnp.Spec.NimbusRules); err != nil {
+		logger.Error(err, "failed to update unsupported actions status in ClusterNimbusPolicy", "ClusterNimbusPolicy.Name", cwnp.Name)
+	}
+	metrics.UnsupportedIntents(adapterName, &cwnp, cwnp.Spec.NimbusRules, "netpol")
+	_, translateSpan := tracing.Start(ctx, "AdminNetworkPolicy.Translate")
+	anps, banp := processor.BuildAnpsFrom(logger, cwnp, k8sClient)
+	translateSpan.End()
+	deleteDanglingAnps(ctx, cwnp, anps, banp, logger)
+
+	for idx := range anps {
+		anp := anps[idx]
+
+		// Set ClusterNimbusPolicy as the owner of the ANP
+		if err := ctrl.SetControllerReference(&cwnp, &anp, scheme); err != nil {
+			logger.Error(err, "failed to set OwnerReference on AdminNetworkPolicy", "Name", anp.Name)
+			return
+		}
+
+		var existingAnp anpv1alpha1.AdminNetworkPolicy
+		err := k8sClient.Get(ctx, types.NamespacedName{Name: anp.Name}, &existingAnp)
+		if err != nil && !errors.IsNotFound(err) {
+			logger.Error(err, "failed to get existing AdminNetworkPolicy", "AdminNetworkPolicy.Name", anp.Name)
+			return
+		}
+		if err != nil {
+			if err = tracing.Trace(ctx, "AdminNetworkPolicy.Create", func(ctx context.Context) error {
+				return k8sClient.Create(ctx, &anp)
+			}, tracing.WithObject("AdminNetworkPolicy", &anp)); err != nil {
+				logger.Error(err, "failed to create AdminNetworkPolicy", "AdminNetworkPolicy.Name", anp.Name)
+				adapterutil.RecordPolicyFailed(recorder, &cwnp, "AdminNetworkPolicy/"+anp.Name, err)
+				metrics.PolicyFailed(adapterName, "AdminNetworkPolicy")
+				return
+			}
+			logger.Info("AdminNetworkPolicy created", "AdminNetworkPolicy.Name", anp.Name)
+			adapterutil.RecordPolicyCreated(recorder, &cwnp, "AdminNetworkPolicy/"+anp.Name)
+			metrics.PolicyGenerated(adapterName, "AdminNetworkPolicy", &anp)
+		} else {
+			anp.ObjectMeta.ResourceVersion = existingAnp.ObjectMeta.ResourceVersion
+			if err = tracing.Trace(ctx, "AdminNetworkPolicy.Update", func(ctx context.Context) error {
+				return k8sClient.Update(ctx, &anp)
+			}, tracing.WithObject("AdminNetworkPolicy", &anp)); err != nil {
+				logger.Error(err, "failed to configure existing AdminNetworkPolicy", "AdminNetworkPolicy.Name", existingAnp.Name)
+				adapterutil.RecordPolicyFailed(recorder, &cwnp, "AdminNetworkPolicy/"+anp.Name, err)
+				metrics.PolicyFailed(adapterName, "AdminNetworkPolicy")
+				return
+			}
+			logger.Info("AdminNetworkPolicy configured", "AdminNetworkPolicy.Name", existingAnp.Name)
+			if anp.GetGeneration() != existingAnp.GetGeneration() {
+				adapterutil.RecordPolicyConfigured(recorder, &cwnp, "AdminNetworkPolicy/"+anp.Name)
+				metrics.PolicyGenerated(adapterName, "AdminNetworkPolicy", &anp)
+			}
+		}
+
+		if err = adapterutil.UpdateCwnpStatus(ctx, k8sClient, "AdminNetworkPolicy/"+anp.Name, cwnp.Name, false); err != nil {
+			logger.Error(err, "failed to update AdminNetworkPolicies status in ClusterNimbusPolicy")
+		}
+	}
+
+	if banp != nil {
+		createOrUpdateBanp(ctx, cwnp, *banp, logger)
+	}
+}
+
+// createOrUpdateBanp creates or updates the BaselineAdminNetworkPolicy of the
+// given ClusterNimbusPolicy. A cluster has a single BaselineAdminNetworkPolicy,
+// so it isn't updated when it belongs to another ClusterNimbusPolicy or wasn't
+// generated by the adapter.
+func createOrUpdateBanp(ctx context.Context, cwnp v1alpha1.ClusterNimbusPolicy, banp anpv1alpha1.BaselineAdminNetworkPolicy, logger logr.Logger) {
+	if err := ctrl.SetControllerReference(&cwnp, &banp, scheme); err != nil {
+		logger.Error(err, "failed to set OwnerReference on BaselineAdminNetworkPolicy", "Name", banp.Name)
+		return
+	}
+
+	var existingBanp anpv1alpha1.BaselineAdminNetworkPolicy
+	err := k8sClient.Get(ctx, types.NamespacedName{Name: banp.Name}, &existingBanp)
+	if err != nil && !errors.IsNotFound(err) {
+		logger.Error(err, "failed to get existing BaselineAdminNetworkPolicy", "BaselineAdminNetworkPolicy.Name", banp.Name)
+		return
+	}
+	if err != nil {
+		if err = tracing.Trace(ctx, "BaselineAdminNetworkPolicy.Create", func(ctx context.Context) error {
+			return k8sClient.Create(ctx, &banp)
+		}, tracing.WithObject("BaselineAdminNetworkPolicy", &banp)); err != nil {
+			logger.Error(err, "failed to create BaselineAdminNetworkPolicy", "BaselineAdminNetworkPolicy.Name", banp.Name)
+			adapterutil.RecordPolicyFailed(recorder, &cwnp, "BaselineAdminNetworkPolicy/"+banp.Name, err)
+			metrics.PolicyFailed(adapterName, "BaselineAdminNetworkPolicy")
+			return
+		}
+		logger.Info("BaselineAdminNetworkPolicy created", "BaselineAdminNetworkPolicy.Name", banp.Name)
+		adapterutil.RecordPolicyCreated(recorder, &cwnp, "BaselineAdminNetworkPolicy/"+banp.Name)
+		metrics.PolicyGenerated(adapterName, "BaselineAdminNetworkPolicy", &banp)
+	} else {
+		if owner := metav1.GetControllerOf(&existingBanp); owner == nil || owner.UID != cwnp.UID {
+			err := fmt.Errorf("BaselineAdminNetworkPolicy %s already exists and isn't owned by ClusterNimbusPolicy %s", banp.Name, cwnp.Name)
+			logger.Error(err, "failed to configure existing BaselineAdminNetworkPolicy", "BaselineAdminNetworkPolicy.Name", banp.Name)
+			adapterutil.RecordPolicyFailed(recorder, &cwnp, "BaselineAdminNetworkPolicy/"+banp.Name, err)
+			metrics.PolicyFailed(adapterName, "BaselineAdminNetworkPolicy")
+			return
+		}
+
+		banp.ObjectMeta.ResourceVersion = existingBanp.ObjectMeta.ResourceVersion
+		if err = tracing.Trace(ctx, "BaselineAdminNetworkPolicy.Update", func(ctx context.Context) error {
+			return k8sClient.Update(ctx, &banp)
+		}, tracing.WithObject("BaselineAdminNetworkPolicy", &banp)); err != nil {
+			logger.Error(err, "failed to configure existing BaselineAdminNetworkPolicy", "BaselineAdminNetworkPolicy.Name", banp.Name)
+			adapterutil.RecordPolicyFailed(recorder, &cwnp, "BaselineAdminNetworkPolicy/"+banp.Name, err)
+			metrics.PolicyFailed(adapterName, "BaselineAdminNetworkPolicy")
+			return
+		}
+		logger.Info("BaselineAdminNetworkPolicy configured", "BaselineAdminNetworkPolicy.Name", banp.Name)
+		if banp.GetGeneration() != existingBanp.GetGeneration() {
+			adapterutil.RecordPolicyConfigured(recorder, &cwnp, "BaselineAdminNetworkPolicy/"+banp.Name)
+			metrics.PolicyGenerated(adapterName, "BaselineAdminNetworkPolicy", &banp)
+		}
+	}
+
+	if err = adapterutil.UpdateCwnpStatus(ctx, k8sClient, "BaselineAdminNetworkPolicy/"+banp.Name, cwnp.Name, false); err != nil {
+		logger.Error(err, "failed to update BaselineAdminNetworkPolicy status in ClusterNimbusPolicy")
+	}
+}
+
+func logAnpsToDelete(ctx context.Context, deletedCwnp *unstructured.Unstructured) {
+	logger := log.FromContext(ctx)
+	var anps anpv1alpha1.AdminNetworkPolicyList
+
+	if err := k8sClient.List(ctx, &anps); err != nil {
+		logger.Error(err, "failed to list AdminNetworkPolicies")
+		return
+	}
+
+	// Kubernetes GC automatically deletes the child when the parent/owner is
+	// deleted. So, we don't need to delete the policy because ClusterNimbusPolicy
+	// is the owner and when it gets deleted all the corresponding policies will be
+	// automatically deleted.
+	for _, anp := range anps.Items {
+		for _, ownerRef := range anp.OwnerReferences {
+			if ownerRef.Name == deletedCwnp.GetName() && ownerRef.UID == deletedCwnp.GetUID() {
+				logger.Info("AdminNetworkPolicy already deleted due to ClusterNimbusPolicy deletion",
+					"AdminNetworkPolicy.Name", anp.Name, "ClusterNimbusPolicy.Name", deletedCwnp.GetName(),
+				)
+				break
+			}
+		}
+	}
+}
+
+// deleteDanglingAnps deletes the AdminNetworkPolicies and the
+// BaselineAdminNetworkPolicy owned by the given ClusterNimbusPolicy that aren't
+// built from it anymore.
+func deleteDanglingAnps(ctx context.Context, cwnp v1alpha1.ClusterNimbusPolicy, anps []anpv1alpha1.AdminNetworkPolicy,
+	banp *anpv1alpha1.BaselineAdminNetworkPolicy, logger logr.Logger) {
+	ownedByCwnp := func(ownerRef metav1.OwnerReference) bool { return ownerRef.UID == cwnp.UID }
+
+	var existingAnps anpv1alpha1.AdminNetworkPolicyList
+	if err := k8sClient.List(ctx, &existingAnps); err != nil {
+		logger.Error(err, "failed to list AdminNetworkPolicies for cleanup")
+		return
+	}
+
+	var dangling []client.Object
+	for idx := range existingAnps.Items {
+		anp := &existingAnps.Items[idx]
+		if !slices.ContainsFunc(anp.OwnerReferences, ownedByCwnp) {
+			continue
+		}
+		if slices.ContainsFunc(anps, func(built anpv1alpha1.AdminNetworkPolicy) bool { return built.Name == anp.Name }) {
+			continue
+		}
+		dangling = append(dangling, anp)
+	}
+
+	var existingBanp anpv1alpha1.BaselineAdminNetworkPolicy
+	err := k8sClient.Get(ctx, types.NamespacedName{Name: anpv1alpha1.BaselineAdminNetworkPolicyName}, &existingBanp)
+	if err != nil && !errors.IsNotFound(err) {
+		logger.Error(err, "failed to get BaselineAdminNetworkPolicy for cleanup")
+	}
+	if err == nil && banp == nil && slices.ContainsFunc(existingBanp.OwnerReferences, ownedByCwnp) {
+		dangling = append(dangling, &existingBanp)
+	}
+
+	for _, policy := range dangling {
+		kind := "AdminNetworkPolicy"
+		if _, ok := policy.(*anpv1alpha1.BaselineAdminNetworkPolicy); ok {
+			kind = "BaselineAdminNetworkPolicy"
+		}
+
+		if err := k8sClient.Delete(ctx, policy); err != nil {
+			logger.Error(err, "failed to delete dangling "+kind, kind+".Name", policy.GetName())
+			continue
+		}
+		if err := adapterutil.UpdateCwnpStatus(ctx, k8sClient, kind+"/"+policy.GetName(), cwnp.Name, true); err != nil {
+			logger.Error(err, "failed to update "+kind+" status in ClusterNimbusPolicy")
+		}
+		logger.Info("Dangling "+kind+" deleted", kind+".Name", policy.GetName())
+		adapterutil.RecordDanglingPolicyDeleted(recorder, &cwnp, kind+"/"+policy.GetName())
+	}
+}
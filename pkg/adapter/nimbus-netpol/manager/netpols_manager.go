@@ -5,46 +5,66 @@ package manager
 
 import (
 	"context"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	netv1 "k8s.io/api/networking/v1"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	"github.com/5GSEC/nimbus/api/v1alpha1"
 	"github.com/5GSEC/nimbus/pkg/adapter/common"
+	"github.com/5GSEC/nimbus/pkg/adapter/idpool"
 	"github.com/5GSEC/nimbus/pkg/adapter/k8s"
+	"github.com/5GSEC/nimbus/pkg/adapter/metrics"
 	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
 	globalwatcher "github.com/5GSEC/nimbus/pkg/adapter/watcher"
+	"github.com/5GSEC/nimbus/pkg/tracing"
 
+	anpv1alpha1 "github.com/5GSEC/nimbus/pkg/adapter/nimbus-netpol/api/v1alpha1"
 	"github.com/5GSEC/nimbus/pkg/adapter/nimbus-netpol/processor"
 	netpolwatcher "github.com/5GSEC/nimbus/pkg/adapter/nimbus-netpol/watcher"
 )
 
+const adapterName = "nimbus-netpol"
+
 var (
 	scheme    = runtime.NewScheme()
 	k8sClient client.Client
+	recorder  record.EventRecorder
+
+	// anpSupported is set when the cluster supports AdminNetworkPolicies, in
+	// which case ClusterNimbusPolicies are enforced with them rather than with
+	// the NetworkPolicies of the NimbusPolicies generated in every namespace.
+	anpSupported bool
 )
 
 func init() {
 	utilruntime.Must(v1alpha1.AddToScheme(scheme))
 	utilruntime.Must(netv1.AddToScheme(scheme))
 	utilruntime.Must(corev1.AddToScheme(scheme))
+	utilruntime.Must(appsv1.AddToScheme(scheme))
+	utilruntime.Must(discoveryv1.AddToScheme(scheme))
+	utilruntime.Must(anpv1alpha1.AddToScheme(scheme))
 	k8sClient = k8s.NewOrDie(scheme)
+	recorder = k8s.NewEventRecorderOrDie(scheme, adapterName)
 }
 
 func Run(ctx context.Context) {
+	logger := log.FromContext(ctx)
 
-	// Watch NimbusPolicies only, and not ClusterNimbusPolicies as NetworkPolicy is
-	// namespaced scoped
 	npCh := make(chan common.Request)
 	deletedNpCh := make(chan *unstructured.Unstructured)
 	go globalwatcher.WatchNimbusPolicies(ctx, npCh, deletedNpCh, "SecurityIntentBinding", "ClusterSecurityIntentBinding")
@@ -53,6 +73,27 @@ func Run(ctx context.Context) {
 	deletedNetpolCh := make(chan common.Request)
 	go netpolwatcher.WatchNetpols(ctx, updatedNetpolCh, deletedNetpolCh)
 
+	// The policies allow the networks of the cluster, which change as nodes
+	// join or leave it.
+	nodesChangedCh := make(chan struct{}, 1)
+	go globalwatcher.WatchNodes(ctx, nodesChangedCh)
+
+	// ClusterNimbusPolicies are only watched when AdminNetworkPolicies are
+	// supported. Otherwise, NetworkPolicies are built from the NimbusPolicies the
+	// controller generates in every namespace they select.
+	clusterNpCh := make(chan string)
+	deletedClusterNpCh := make(chan *unstructured.Unstructured)
+	updatedAnpCh := make(chan string)
+	deletedAnpCh := make(chan string)
+	anpSupported = adminNetworkPoliciesSupported(ctx)
+	if anpSupported {
+		logger.Info("AdminNetworkPolicies supported, enforcing ClusterNimbusPolicies with them")
+		go globalwatcher.WatchClusterNimbusPolicies(ctx, clusterNpCh, deletedClusterNpCh)
+		go netpolwatcher.WatchAnps(ctx, updatedAnpCh, deletedAnpCh)
+	} else {
+		logger.Info("AdminNetworkPolicies not supported, enforcing ClusterNimbusPolicies with NetworkPolicies")
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -60,15 +101,30 @@ func Run(ctx context.Context) {
 			close(deletedNpCh)
 			close(updatedNetpolCh)
 			close(deletedNetpolCh)
+			close(clusterNpCh)
+			close(deletedClusterNpCh)
+			close(updatedAnpCh)
+			close(deletedAnpCh)
+			close(nodesChangedCh)
 			return
 		case createdNp := <-npCh:
 			createOrUpdateNetworkPolicy(ctx, createdNp.Name, createdNp.Namespace)
+		case createdCwnp := <-clusterNpCh:
+			createOrUpdateAnps(ctx, createdCwnp)
 		case deletedNp := <-deletedNpCh:
 			logNetworkPolicyToDelete(ctx, deletedNp)
+		case deletedCwnp := <-deletedClusterNpCh:
+			logAnpsToDelete(ctx, deletedCwnp)
 		case updatedNetpol := <-updatedNetpolCh:
 			reconcileNetPol(ctx, updatedNetpol.Name, updatedNetpol.Namespace, false)
 		case deletedNetpol := <-deletedNetpolCh:
 			reconcileNetPol(ctx, deletedNetpol.Name, deletedNetpol.Namespace, true)
+		case updatedAnp := <-updatedAnpCh:
+			reconcileAnps(ctx, updatedAnp, false)
+		case deletedAnp := <-deletedAnpCh:
+			reconcileAnps(ctx, deletedAnp, true)
+		case <-nodesChangedCh:
+			reconcileAllPolicies(ctx)
 		}
 	}
 }
@@ -92,7 +148,47 @@ func reconcileNetPol(ctx context.Context, netpolName, namespace string, deleted
 	createOrUpdateNetworkPolicy(ctx, npName, namespace)
 }
 
+// reconcileAllPolicies rebuilds the policies of all the NimbusPolicies, and of
+// the ClusterNimbusPolicies when AdminNetworkPolicies are supported, with
+// intents supported by this adapter, so that they allow the current networks
+// of the cluster.
+func reconcileAllPolicies(ctx context.Context) {
+	logger := log.FromContext(ctx)
+	logger.Info("Cluster nodes changed, reconciling policies")
+
+	var nps v1alpha1.NimbusPolicyList
+	if err := k8sClient.List(ctx, &nps); err != nil {
+		logger.Error(err, "failed to list NimbusPolicies")
+	}
+	for _, np := range nps.Items {
+		if hasNetpolIntents(np.Spec.NimbusRules) {
+			createOrUpdateNetworkPolicy(ctx, np.Name, np.Namespace)
+		}
+	}
+
+	if !anpSupported {
+		return
+	}
+	var cwnps v1alpha1.ClusterNimbusPolicyList
+	if err := k8sClient.List(ctx, &cwnps); err != nil {
+		logger.Error(err, "failed to list ClusterNimbusPolicies")
+		return
+	}
+	for _, cwnp := range cwnps.Items {
+		if hasNetpolIntents(cwnp.Spec.NimbusRules) {
+			createOrUpdateAnps(ctx, cwnp.Name)
+		}
+	}
+}
+
+func hasNetpolIntents(rules []v1alpha1.NimbusRules) bool {
+	return slices.ContainsFunc(rules, func(rule v1alpha1.NimbusRules) bool {
+		return idpool.IsIdSupportedBy(rule.ID, "netpol")
+	})
+}
+
 func createOrUpdateNetworkPolicy(ctx context.Context, npName, npNamespace string) {
+	defer metrics.ObserveReconcile(adapterName, time.Now())
 	logger := log.FromContext(ctx)
 	var np v1alpha1.NimbusPolicy
 	if err := k8sClient.Get(ctx, types.NamespacedName{Name: npName, Namespace: npNamespace}, &np); err != nil {
@@ -105,8 +201,30 @@ func createOrUpdateNetworkPolicy(ctx context.Context, npName, npNamespace string
 		return
 	}
 
+	// The ClusterSecurityIntentBindings are enforced by AdminNetworkPolicies
+	// built from their ClusterNimbusPolicy when supported, so only drop the
+	// NetworkPolicies built from the NimbusPolicies generated for them.
+	if anpSupported && adapterutil.IsOrphan(np.GetOwnerReferences(), "SecurityIntentBinding") {
+		logger.V(4).Info("Ignoring NimbusPolicy of a ClusterSecurityIntentBinding", "NimbusPolicy.Name", npName, "NimbusPolicy.Namespace", npNamespace)
+		np.Spec.NimbusRules = nil
+		deleteDanglingNetpols(ctx, np, logger)
+		return
+	}
+
+	// Continue the trace of the reconciliation that changed the NimbusPolicy.
+	ctx, span := tracing.Start(tracing.Extract(ctx, &np), adapterName+".Reconcile", tracing.WithObject("NimbusPolicy", &np))
+	defer span.End()
+
 	deleteDanglingNetpols(ctx, np, logger)
+	adapterutil.RecordUnsupportedIntents(recorder, &np, np.Spec.NimbusRules, "netpol")
+	adapterutil.RecordUnsupportedActions(recorder, &np, np.Spec.NimbusRules, "netpol")
+	if err := adapterutil.UpdateUnsupportedActions(ctx, k8sClient, "NimbusPolicy", np.Name, np.Namespace, "netpol", np.Spec.NimbusRules); err != nil {
+		logger.Error(err, "failed to update unsupported actions status in NimbusPolicy", "NimbusPolicy.Name", np.Name, "NimbusPolicy.Namespace", np.Namespace)
+	}
+	metrics.UnsupportedIntents(adapterName, &np, np.Spec.NimbusRules, "netpol")
+	_, translateSpan := tracing.Start(ctx, "NetworkPolicy.Translate")
 	netPols := processor.BuildNetPolsFrom(logger, np, k8sClient)
+	translateSpan.End()
 	// Iterate using a separate index variable to avoid aliasing
 	for idx := range netPols {
 		netpol := netPols[idx]
@@ -125,19 +243,33 @@ func createOrUpdateNetworkPolicy(ctx context.Context, npName, npNamespace string
 		}
 		if err != nil {
 			if errors.IsNotFound(err) {
-				if err = k8sClient.Create(ctx, &netpol); err != nil {
+				if err = tracing.Trace(ctx, "NetworkPolicy.Create", func(ctx context.Context) error {
+					return k8sClient.Create(ctx, &netpol)
+				}, tracing.WithObject("NetworkPolicy", &netpol)); err != nil {
 					logger.Error(err, "failed to create NetworkPolicy", "NetworkPolicy.Name", netpol.Name, "NetworkPolicy.Namespace", netpol.Namespace)
+					adapterutil.RecordPolicyFailed(recorder, &np, "NetworkPolicy/"+netpol.Name, err)
+					metrics.PolicyFailed(adapterName, "NetworkPolicy")
 					return
 				}
 				logger.Info("NetworkPolicy created", "NetworkPolicy.Name", netpol.Name, "NetworkPolicy.Namespace", netpol.Namespace)
+				adapterutil.RecordPolicyCreated(recorder, &np, "NetworkPolicy/"+netpol.Name)
+				metrics.PolicyGenerated(adapterName, "NetworkPolicy", &netpol)
 			}
 		} else {
 			netpol.ObjectMeta.ResourceVersion = existingNetpol.ObjectMeta.ResourceVersion
-			if err = k8sClient.Update(ctx, &netpol); err != nil {
+			if err = tracing.Trace(ctx, "NetworkPolicy.Update", func(ctx context.Context) error {
+				return k8sClient.Update(ctx, &netpol)
+			}, tracing.WithObject("NetworkPolicy", &netpol)); err != nil {
 				logger.Error(err, "failed to configure existing NetworkPolicy", "NetworkPolicy.Name", netpol.Name, "NetworkPolicy.Namespace", netpol.Namespace)
+				adapterutil.RecordPolicyFailed(recorder, &np, "NetworkPolicy/"+netpol.Name, err)
+				metrics.PolicyFailed(adapterName, "NetworkPolicy")
 				return
 			}
 			logger.Info("NetworkPolicy configured", "NetworkPolicy.Name", netpol.Name, "NetworkPolicy.Namespace", netpol.Namespace)
+			if netpol.GetGeneration() != existingNetpol.GetGeneration() {
+				adapterutil.RecordPolicyConfigured(recorder, &np, "NetworkPolicy/"+netpol.Name)
+				metrics.PolicyGenerated(adapterName, "NetworkPolicy", &netpol)
+			}
 		}
 
 		if err = adapterutil.UpdateNpStatus(ctx, k8sClient, "NetworkPolicy/"+netpol.Name, np.Name, np.Namespace, false); err != nil {
@@ -216,5 +348,6 @@ func deleteDanglingNetpols(ctx context.Context, np v1alpha1.NimbusPolicy, logger
 			logger.Error(err, "failed to update NetworkPolicy status in NimbusPolicy")
 		}
 		logger.Info("Dangling NetworkPolicy deleted", "NetworkPolicy.Name", netpol.Name, "NetworkPolicy.Namespace", netpol.Namespace)
+		adapterutil.RecordDanglingPolicyDeleted(recorder, &np, "NetworkPolicy/"+netpol.Name)
 	}
 }
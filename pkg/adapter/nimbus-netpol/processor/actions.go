@@ -0,0 +1,22 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package processor
+
+import (
+	"github.com/5GSEC/nimbus/pkg/adapter/idpool"
+)
+
+// actionSupported reports whether NetworkPolicies can enforce the given ID with
+// the given Nimbus action, as listed in the idpool.
+//
+//	| Nimbus | NetworkPolicy                  |
+//	|--------|--------------------------------|
+//	| Block  | traffic not allowed is dropped |
+//
+// NetworkPolicies have no way to audit or warn about the traffic they would
+// drop, and the intents supported by this adapter describe traffic to deny, so
+// Audit, Warn, Allow and Mutate aren't supported.
+func actionSupported(id, action string) bool {
+	return idpool.IsActionSupportedBy(id, action, "netpol")
+}
@@ -0,0 +1,297 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package processor
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1alpha1 "github.com/5GSEC/nimbus/api/v1alpha1"
+	"github.com/5GSEC/nimbus/pkg/adapter/clustercidrs"
+	"github.com/5GSEC/nimbus/pkg/adapter/clusterdns"
+	"github.com/5GSEC/nimbus/pkg/adapter/idpool"
+	"github.com/5GSEC/nimbus/pkg/adapter/metrics"
+	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
+
+	anpv1alpha1 "github.com/5GSEC/nimbus/pkg/adapter/nimbus-netpol/api/v1alpha1"
+)
+
+const (
+	// paramTier selects the policy enforcing a cluster-wide intent: "admin",
+	// the default, builds an AdminNetworkPolicy that namespace owners can't
+	// override, and "baseline" adds the rules to the BaselineAdminNetworkPolicy,
+	// which their NetworkPolicies take precedence over.
+	paramTier    = "tier"
+	tierBaseline = "baseline"
+
+	namespaceNameLabel = "kubernetes.io/metadata.name"
+)
+
+// anpPriorities are the priorities of the AdminNetworkPolicies of the intents.
+// The DNS intent takes precedence, so that the DNS traffic it denies isn't
+// passed on by the cluster-internal traffic denyExternalNetworkAccess allows.
+var anpPriorities = map[string]int32{
+	idpool.DNSManipulation: 40,
+	idpool.DenyENAccess:    50,
+}
+
+// nsBlackList lists the namespaces never selected by the AdminNetworkPolicies,
+// as the controller never generates NimbusPolicies for them either.
+var nsBlackList = []string{"kube-system"}
+
+// anyNetwork matches all the IPv4 and IPv6 addresses.
+var anyNetwork = []anpv1alpha1.CIDR{"0.0.0.0/0", "::/0"}
+
+// BuildAnpsFrom builds the AdminNetworkPolicies enforcing the intents of the
+// given ClusterNimbusPolicy in the namespaces it selects, along with the
+// BaselineAdminNetworkPolicy enforcing its intents with the baseline tier, nil
+// when there are none.
+func BuildAnpsFrom(logger logr.Logger, cwnp v1alpha1.ClusterNimbusPolicy, k8sClient client.Client) ([]anpv1alpha1.AdminNetworkPolicy, *anpv1alpha1.BaselineAdminNetworkPolicy) {
+	subject := subjectFor(cwnp.Spec)
+	clusterCIDRs, err := clustercidrs.Discover(context.Background(), k8sClient)
+	if err != nil {
+		logger.Error(err, "Failed to discover some of the cluster CIDRs")
+	}
+	clusterDNS, err := clusterdns.Discover(context.Background(), k8sClient)
+	if err != nil {
+		logger.Error(err, "Failed to discover some of the cluster DNS servers")
+	}
+
+	var anps []anpv1alpha1.AdminNetworkPolicy
+	var banp *anpv1alpha1.BaselineAdminNetworkPolicy
+	for _, nimbusRule := range cwnp.Spec.NimbusRules {
+		id := nimbusRule.ID
+		if !idpool.IsIdSupportedBy(id, "netpol") {
+			logger.Info("Network Policy adapter does not support this ID", "ID", id, "ClusterNimbusPolicy.Name", cwnp.Name)
+			continue
+		}
+		if !actionSupported(id, nimbusRule.Rule.RuleAction) {
+			logger.Info("Network Policy adapter does not support this action", "ID", id, "Action", nimbusRule.Rule.RuleAction,
+				"ClusterNimbusPolicy.Name", cwnp.Name)
+			continue
+		}
+
+		cidrs, err := clusterCIDRs.WithParams(nimbusRule.Rule.Params)
+		if err != nil {
+			logger.Error(err, "Ignoring invalid CIDR params", "ID", id, "ClusterNimbusPolicy.Name", cwnp.Name)
+			metrics.TranslationFailed(adapterName, id)
+		}
+		dns, err := clusterDNS.WithParams(nimbusRule.Rule.Params)
+		if err != nil {
+			logger.Error(err, "Ignoring invalid DNS params", "ID", id, "ClusterNimbusPolicy.Name", cwnp.Name)
+			metrics.TranslationFailed(adapterName, id)
+		}
+		egress := egressRulesFor(id, cidrs, dns)
+		if slices.Contains(nimbusRule.Rule.Params[paramTier], tierBaseline) {
+			if banp == nil {
+				banp = &anpv1alpha1.BaselineAdminNetworkPolicy{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        anpv1alpha1.BaselineAdminNetworkPolicyName,
+						Annotations: map[string]string{"app.kubernetes.io/managed-by": "nimbus-netpol"},
+					},
+					Spec: anpv1alpha1.BaselineAdminNetworkPolicySpec{
+						Subject: subject,
+					},
+				}
+			}
+			banp.Spec.Egress = append(banp.Spec.Egress, baselineEgressRules(egress)...)
+			adapterutil.AddIntents(banp.Annotations, id)
+			continue
+		}
+
+		// Leave the intent to the conflicting NimbusPolicies taking precedence
+		// in some of the namespaces. The NetworkPolicies already take
+		// precedence over the BaselineAdminNetworkPolicy.
+		spec := cwnp.Spec
+		var ok bool
+		spec.NsSelector, ok = adapterutil.RuleNsSelector(cwnp.Spec, id)
+		if !ok {
+			logger.Info("Conflicting NimbusPolicies take precedence over the intent in all the selected namespaces",
+				"ID", id, "ClusterNimbusPolicy.Name", cwnp.Name)
+			continue
+		}
+
+		anp := anpv1alpha1.AdminNetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        cwnp.Name + "-" + strings.ToLower(id),
+				Annotations: map[string]string{"app.kubernetes.io/managed-by": "nimbus-netpol"},
+			},
+			Spec: anpv1alpha1.AdminNetworkPolicySpec{
+				Priority: anpPriorities[id],
+				Subject:  subjectFor(spec),
+				Egress:   egress,
+			},
+		}
+		adapterutil.AddIntents(anp.Annotations, id)
+		anps = append(anps, anp)
+	}
+	return anps, banp
+}
+
+// subjectFor translates the namespace and workload selectors of a
+// ClusterNimbusPolicy into the subject of an AdminNetworkPolicy:
+//
+//   - MatchNames: ["*"] selects all the namespaces but the blacklisted ones.
+//   - MatchNames selects the listed namespaces.
+//   - ExcludeNames selects all the namespaces but the listed and the
+//     blacklisted ones.
+//   - The MatchLabels of the workload selector select the pods having all of
+//     them in these namespaces.
+func subjectFor(spec v1alpha1.ClusterNimbusPolicySpec) anpv1alpha1.AdminNetworkPolicySubject {
+	requirement := metav1.LabelSelectorRequirement{
+		Key:      namespaceNameLabel,
+		Operator: metav1.LabelSelectorOpNotIn,
+		Values:   slices.Clone(nsBlackList),
+	}
+
+	matchNames, excludeNames := spec.NsSelector.MatchNames, spec.NsSelector.ExcludeNames
+	switch {
+	case len(excludeNames) > 0:
+		requirement.Values = append(requirement.Values, excludeNames...)
+	case len(matchNames) > 0 && !(len(matchNames) == 1 && matchNames[0] == "*"):
+		requirement.Operator = metav1.LabelSelectorOpIn
+		requirement.Values = slices.DeleteFunc(slices.Clone(matchNames), func(ns string) bool { return slices.Contains(nsBlackList, ns) })
+	}
+	namespaces := metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{requirement}}
+
+	if len(spec.WorkloadSelector.MatchLabels) == 0 && len(spec.WorkloadSelector.MatchExpressions) == 0 {
+		return anpv1alpha1.AdminNetworkPolicySubject{Namespaces: &namespaces}
+	}
+	return anpv1alpha1.AdminNetworkPolicySubject{
+		Pods: &anpv1alpha1.NamespacedPod{
+			NamespaceSelector: namespaces,
+			PodSelector: metav1.LabelSelector{
+				MatchLabels:      spec.WorkloadSelector.MatchLabels,
+				MatchExpressions: spec.WorkloadSelector.MatchExpressions,
+			},
+		},
+	}
+}
+
+// egressRulesFor builds the egress rules of the AdminNetworkPolicy of the
+// given intent ID. The allowed traffic is passed on to the NetworkPolicies of
+// the namespaces rather than allowed, so that namespace owners may still
+// restrict it.
+//
+// AdminNetworkPolicies can't match the external sources of ingress traffic, so
+// unlike its NetworkPolicy, the policy of denyExternalNetworkAccess only denies
+// egress traffic.
+func egressRulesFor(id string, cidrs clustercidrs.CIDRs, dns clusterdns.DNS) []anpv1alpha1.AdminNetworkPolicyEgressRule {
+	podNetworks := networksOf(cidrs.Pods)
+
+	switch id {
+	case idpool.DNSManipulation:
+		return []anpv1alpha1.AdminNetworkPolicyEgressRule{
+			{
+				Name:   "pass-cluster-dns",
+				Action: anpv1alpha1.AdminNetworkPolicyRuleActionPass,
+				To:     clusterDNSPeers(dns, podNetworks),
+				Ports:  dnsPorts(dns.Ports),
+			},
+			{
+				Name:   "deny-other-dns",
+				Action: anpv1alpha1.AdminNetworkPolicyRuleActionDeny,
+				To: []anpv1alpha1.AdminNetworkPolicyEgressPeer{
+					{Namespaces: &metav1.LabelSelector{}},
+					{Networks: anyNetwork},
+				},
+				Ports: dnsPorts([]int32{clusterdns.Port}),
+			},
+		}
+	case idpool.DenyENAccess:
+		return []anpv1alpha1.AdminNetworkPolicyEgressRule{
+			{
+				Name:   "pass-cluster-dns",
+				Action: anpv1alpha1.AdminNetworkPolicyRuleActionPass,
+				To:     clusterDNSPeers(dns, nil),
+				Ports:  dnsPorts(dns.Ports),
+			},
+			{
+				Name:   "pass-cluster-networks",
+				Action: anpv1alpha1.AdminNetworkPolicyRuleActionPass,
+				To: []anpv1alpha1.AdminNetworkPolicyEgressPeer{
+					{Namespaces: &metav1.LabelSelector{}},
+					{Networks: networksOf(cidrs.Internal())},
+				},
+			},
+			{
+				Name:   "deny-external-networks",
+				Action: anpv1alpha1.AdminNetworkPolicyRuleActionDeny,
+				To:     []anpv1alpha1.AdminNetworkPolicyEgressPeer{{Networks: anyNetwork}},
+			},
+		}
+	default:
+		return nil
+	}
+}
+
+// baselineEgressRules turns the egress rules of an AdminNetworkPolicy into the
+// ones of a BaselineAdminNetworkPolicy, which has no NetworkPolicies to pass
+// the traffic on to, so allows it instead.
+func baselineEgressRules(rules []anpv1alpha1.AdminNetworkPolicyEgressRule) []anpv1alpha1.BaselineAdminNetworkPolicyEgressRule {
+	var baselineRules []anpv1alpha1.BaselineAdminNetworkPolicyEgressRule
+	for _, rule := range rules {
+		action := anpv1alpha1.BaselineAdminNetworkPolicyRuleActionAllow
+		if rule.Action == anpv1alpha1.AdminNetworkPolicyRuleActionDeny {
+			action = anpv1alpha1.BaselineAdminNetworkPolicyRuleActionDeny
+		}
+		baselineRules = append(baselineRules, anpv1alpha1.BaselineAdminNetworkPolicyEgressRule{
+			Name:   strings.Replace(rule.Name, "pass-", "allow-", 1),
+			Action: action,
+			To:     rule.To,
+			Ports:  rule.Ports,
+		})
+	}
+	return baselineRules
+}
+
+// clusterDNSPeers returns the DNS servers of the cluster, along with the given
+// networks.
+func clusterDNSPeers(dns clusterdns.DNS, networks []anpv1alpha1.CIDR) []anpv1alpha1.AdminNetworkPolicyEgressPeer {
+	var peers []anpv1alpha1.AdminNetworkPolicyEgressPeer
+	if len(dns.Selector) > 0 {
+		peers = append(peers, anpv1alpha1.AdminNetworkPolicyEgressPeer{
+			Pods: &anpv1alpha1.NamespacedPod{
+				NamespaceSelector: metav1.LabelSelector{
+					MatchLabels: map[string]string{namespaceNameLabel: dns.Namespace},
+				},
+				PodSelector: metav1.LabelSelector{
+					MatchLabels: dns.Selector,
+				},
+			},
+		})
+	}
+	networks = append(networksOf(dns.IPs), networks...)
+	if len(networks) > 0 {
+		peers = append(peers, anpv1alpha1.AdminNetworkPolicyEgressPeer{Networks: networks})
+	}
+	return peers
+}
+
+// dnsPorts returns the given DNS ports over both UDP and TCP.
+func dnsPorts(ports []int32) *[]anpv1alpha1.AdminNetworkPolicyPort {
+	var anpPorts []anpv1alpha1.AdminNetworkPolicyPort
+	for _, port := range ports {
+		anpPorts = append(anpPorts,
+			anpv1alpha1.AdminNetworkPolicyPort{PortNumber: &anpv1alpha1.Port{Protocol: corev1.ProtocolUDP, Port: port}},
+			anpv1alpha1.AdminNetworkPolicyPort{PortNumber: &anpv1alpha1.Port{Protocol: corev1.ProtocolTCP, Port: port}},
+		)
+	}
+	return &anpPorts
+}
+
+// networksOf returns the given CIDRs as the networks of an AdminNetworkPolicy.
+func networksOf(cidrs []string) []anpv1alpha1.CIDR {
+	var networks []anpv1alpha1.CIDR
+	for _, cidr := range cidrs {
+		networks = append(networks, anpv1alpha1.CIDR(cidr))
+	}
+	return networks
+}
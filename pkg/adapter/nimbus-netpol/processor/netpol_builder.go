@@ -8,7 +8,11 @@ import (
 	"strings"
 
 	v1alpha1 "github.com/5GSEC/nimbus/api/v1alpha1"
+	"github.com/5GSEC/nimbus/pkg/adapter/clustercidrs"
+	"github.com/5GSEC/nimbus/pkg/adapter/clusterdns"
 	"github.com/5GSEC/nimbus/pkg/adapter/idpool"
+	"github.com/5GSEC/nimbus/pkg/adapter/metrics"
+	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
 	netv1 "k8s.io/api/networking/v1"
@@ -17,18 +21,47 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// adapterName labels the metrics of the adapter.
+const adapterName = "nimbus-netpol"
+
 func BuildNetPolsFrom(logger logr.Logger, np v1alpha1.NimbusPolicy, k8sClient client.Client) []netv1.NetworkPolicy {
+	clusterCIDRs, err := clustercidrs.Discover(context.Background(), k8sClient)
+	if err != nil {
+		logger.Error(err, "Failed to discover some of the cluster CIDRs")
+	}
+	clusterDNS, err := clusterdns.Discover(context.Background(), k8sClient)
+	if err != nil {
+		logger.Error(err, "Failed to discover some of the cluster DNS servers")
+	}
+
 	// Build netpols based on given IDs
 	var netpols []netv1.NetworkPolicy
 	for _, nimbusRule := range np.Spec.NimbusRules {
 		id := nimbusRule.ID
 		logger.Info(id)
 		if idpool.IsIdSupportedBy(id, "netpol") {
-			netpol := buildNetPolFor(id, k8sClient, logger)
+			if !actionSupported(id, nimbusRule.Rule.RuleAction) {
+				logger.Info("Network Policy adapter does not support this action", "ID", id, "Action", nimbusRule.Rule.RuleAction,
+					"NimbusPolicy.Name", np.Name, "NimbusPolicy.Namespace", np.Namespace)
+				continue
+			}
+			cidrs, err := clusterCIDRs.WithParams(nimbusRule.Rule.Params)
+			if err != nil {
+				logger.Error(err, "Ignoring invalid CIDR params", "ID", id, "NimbusPolicy.Name", np.Name, "NimbusPolicy.Namespace", np.Namespace)
+				metrics.TranslationFailed(adapterName, id)
+			}
+			dns, err := clusterDNS.WithParams(nimbusRule.Rule.Params)
+			if err != nil {
+				logger.Error(err, "Ignoring invalid DNS params", "ID", id, "NimbusPolicy.Name", np.Name, "NimbusPolicy.Namespace", np.Namespace)
+				metrics.TranslationFailed(adapterName, id)
+			}
+			netpol := buildNetPolFor(id, cidrs, dns)
 			netpol.Name = np.Name + "-" + strings.ToLower(id)
 			netpol.Namespace = np.Namespace
 			netpol.Spec.PodSelector.MatchLabels = np.Spec.Selector.MatchLabels
+			netpol.Spec.PodSelector.MatchExpressions = np.Spec.Selector.MatchExpressions
 			addManagedByAnnotation(&netpol)
+			adapterutil.AddIntents(netpol.Annotations, id)
 			netpols = append(netpols, netpol)
 		} else {
 			logger.Info("Network Policy adapter does not support this ID", "ID", id,
@@ -38,64 +71,21 @@ func BuildNetPolsFrom(logger logr.Logger, np v1alpha1.NimbusPolicy, k8sClient cl
 	return netpols
 }
 
-func buildNetPolFor(id string, k8sClient client.Client, logger logr.Logger) netv1.NetworkPolicy {
+func buildNetPolFor(id string, cidrs clustercidrs.CIDRs, dns clusterdns.DNS) netv1.NetworkPolicy {
 	switch id {
 	case idpool.DNSManipulation:
-		return dnsManipulationNetpol(k8sClient, logger)
+		return dnsManipulationNetpol(cidrs, dns)
 	case idpool.DenyENAccess:
-		return denyExternalNetworkAcessNetpol(k8sClient, logger)
+		return denyExternalNetworkAcessNetpol(cidrs, dns)
 	default:
 		return netv1.NetworkPolicy{}
 	}
 }
 
-func denyExternalNetworkAcessNetpol(k8sClient client.Client, logger logr.Logger) netv1.NetworkPolicy {
-	udpProtocol := corev1.ProtocolUDP
-	tcpProtocol := corev1.ProtocolTCP
-	dnsPort := &intstr.IntOrString{
-		Type:   0,
-		IntVal: 53,
-	}
-	froNetpolPeers, err := getPODCIDRs(k8sClient)
-	if err != nil {
-		logger.Error(err, "Failed to get pod CIDRs")
-	}
-	staticCIDRs := []netv1.NetworkPolicyPeer{
-		{
-			IPBlock: &netv1.IPBlock{
-				CIDR: "10.0.0.0/8",
-			},
-		},
-		{
-			IPBlock: &netv1.IPBlock{
-				CIDR: "172.16.0.0/12",
-			},
-		},
-		{
-			IPBlock: &netv1.IPBlock{
-				CIDR: "192.168.0.0/16",
-			},
-		},
-	}
+func denyExternalNetworkAcessNetpol(cidrs clustercidrs.CIDRs, dns clusterdns.DNS) netv1.NetworkPolicy {
+	froNetpolPeers := ipBlockPeers(cidrs.Internal())
 
-	froNetpolPeers = append(froNetpolPeers, staticCIDRs...)
-
-	toNetPolPeers := []netv1.NetworkPolicyPeer{}
-
-	selector := netv1.NetworkPolicyPeer{
-		PodSelector: &metav1.LabelSelector{
-			MatchLabels: map[string]string{
-				"k8s-app": "kube-dns",
-			},
-		},
-		NamespaceSelector: &metav1.LabelSelector{
-			MatchLabels: map[string]string{
-				"kubernetes.io/metadata.name": "kube-system",
-			},
-		},
-	}
-
-	toNetPolPeers = append(toNetPolPeers, selector)
+	toNetPolPeers := dnsPeers(dns)
 	toNetPolPeers = append(toNetPolPeers, froNetpolPeers...)
 
 	return netv1.NetworkPolicy{
@@ -107,17 +97,8 @@ func denyExternalNetworkAcessNetpol(k8sClient client.Client, logger logr.Logger)
 			},
 			Egress: []netv1.NetworkPolicyEgressRule{
 				{
-					To: toNetPolPeers,
-					Ports: []netv1.NetworkPolicyPort{
-						{
-							Protocol: &udpProtocol,
-							Port:     dnsPort,
-						},
-						{
-							Protocol: &tcpProtocol,
-							Port:     dnsPort,
-						},
-					},
+					To:    toNetPolPeers,
+					Ports: dnsNetpolPorts(dns),
 				},
 			},
 			PolicyTypes: []netv1.PolicyType{
@@ -128,49 +109,16 @@ func denyExternalNetworkAcessNetpol(k8sClient client.Client, logger logr.Logger)
 	}
 }
 
-func dnsManipulationNetpol(k8sClient client.Client, logger logr.Logger) netv1.NetworkPolicy {
-	udpProtocol := corev1.ProtocolUDP
-	tcpProtocol := corev1.ProtocolTCP
-	dnsPort := &intstr.IntOrString{
-		Type:   0,
-		IntVal: 53,
-	}
-
-	netpolPeers, err := getPODCIDRs(k8sClient)
-	if err != nil {
-		logger.Error(err, "Failed to get pod CIDRs")
-	}
-
-	selector := netv1.NetworkPolicyPeer{
-		PodSelector: &metav1.LabelSelector{
-			MatchLabels: map[string]string{
-				"k8s-app": "kube-dns",
-			},
-		},
-		NamespaceSelector: &metav1.LabelSelector{
-			MatchLabels: map[string]string{
-				"kubernetes.io/metadata.name": "kube-system",
-			},
-		},
-	}
-
-	netpolPeers = append(netpolPeers, selector)
+func dnsManipulationNetpol(cidrs clustercidrs.CIDRs, dns clusterdns.DNS) netv1.NetworkPolicy {
+	netpolPeers := ipBlockPeers(cidrs.Pods)
+	netpolPeers = append(netpolPeers, dnsPeers(dns)...)
 
 	return netv1.NetworkPolicy{
 		Spec: netv1.NetworkPolicySpec{
 			Egress: []netv1.NetworkPolicyEgressRule{
 				{
-					To: netpolPeers,
-					Ports: []netv1.NetworkPolicyPort{
-						{
-							Protocol: &udpProtocol,
-							Port:     dnsPort,
-						},
-						{
-							Protocol: &tcpProtocol,
-							Port:     dnsPort,
-						},
-					},
+					To:    netpolPeers,
+					Ports: dnsNetpolPorts(dns),
 				},
 			},
 			PolicyTypes: []netv1.PolicyType{
@@ -185,23 +133,48 @@ func addManagedByAnnotation(netpol *netv1.NetworkPolicy) {
 	netpol.Annotations["app.kubernetes.io/managed-by"] = "nimbus-netpol"
 }
 
-func getPODCIDRs(k8sClient client.Client) ([]netv1.NetworkPolicyPeer, error) {
-	podCIDRs := []netv1.NetworkPolicyPeer{}
-	ctx := context.Background()
-	nodes := &corev1.NodeList{}
-	if err := k8sClient.List(ctx, nodes); err != nil {
-		return nil, err
-	}
-	for _, node := range nodes.Items {
-		netPolPeer := netv1.NetworkPolicyPeer{
+// ipBlockPeers returns the peers matching the given CIDRs.
+func ipBlockPeers(cidrs []string) []netv1.NetworkPolicyPeer {
+	peers := []netv1.NetworkPolicyPeer{}
+	for _, cidr := range cidrs {
+		peers = append(peers, netv1.NetworkPolicyPeer{
 			IPBlock: &netv1.IPBlock{
-				CIDR: node.Spec.PodCIDR,
+				CIDR: cidr,
 			},
-		}
-
-		podCIDRs = append(podCIDRs, netPolPeer)
+		})
+	}
+	return peers
+}
 
+// dnsPeers returns the peers matching the DNS servers of the cluster.
+func dnsPeers(dns clusterdns.DNS) []netv1.NetworkPolicyPeer {
+	var peers []netv1.NetworkPolicyPeer
+	if len(dns.Selector) > 0 {
+		peers = append(peers, netv1.NetworkPolicyPeer{
+			PodSelector: &metav1.LabelSelector{
+				MatchLabels: dns.Selector,
+			},
+			NamespaceSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"kubernetes.io/metadata.name": dns.Namespace,
+				},
+			},
+		})
 	}
+	return append(peers, ipBlockPeers(dns.IPs)...)
+}
 
-	return podCIDRs, nil
+// dnsNetpolPorts returns the UDP and TCP ports of the DNS servers of the
+// cluster.
+func dnsNetpolPorts(dns clusterdns.DNS) []netv1.NetworkPolicyPort {
+	var ports []netv1.NetworkPolicyPort
+	for _, port := range dns.Ports {
+		for _, protocol := range []corev1.Protocol{corev1.ProtocolUDP, corev1.ProtocolTCP} {
+			ports = append(ports, netv1.NetworkPolicyPort{
+				Protocol: &protocol,
+				Port:     &intstr.IntOrString{Type: intstr.Int, IntVal: port},
+			})
+		}
+	}
+	return ports
 }
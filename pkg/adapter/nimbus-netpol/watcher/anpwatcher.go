@@ -0,0 +1,81 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package watcher
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	adapterutil "github.com/5GSEC/nimbus/pkg/adapter/util"
+)
+
+func anpInformer(resource string) cache.SharedIndexInformer {
+	anpGvr := schema.GroupVersionResource{
+		Group:    "policy.networking.k8s.io",
+		Version:  "v1alpha1",
+		Resource: resource,
+	}
+	informer := factory.ForResource(anpGvr).Informer()
+	return informer
+}
+
+// WatchAnps watches update and delete events for AdminNetworkPolicies and
+// BaselineAdminNetworkPolicies owned by ClusterNimbusPolicy and put the name of
+// their owner on respective channels.
+func WatchAnps(ctx context.Context, updatedAnpCh, deletedAnpCh chan string) {
+	go watchAnps(ctx, "adminnetworkpolicies", "AdminNetworkPolicy", updatedAnpCh, deletedAnpCh)
+	watchAnps(ctx, "baselineadminnetworkpolicies", "BaselineAdminNetworkPolicy", updatedAnpCh, deletedAnpCh)
+}
+
+func watchAnps(ctx context.Context, resource, kind string, updatedAnpCh, deletedAnpCh chan string) {
+	logger := log.FromContext(ctx)
+	informer := anpInformer(resource)
+	handlers := cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldU := oldObj.(*unstructured.Unstructured)
+			newU := newObj.(*unstructured.Unstructured)
+
+			if adapterutil.IsOrphan(newU.GetOwnerReferences(), "ClusterNimbusPolicy") {
+				logger.V(4).Info("Ignoring orphan "+kind, kind+".Name", oldU.GetName(), "Operation", "Update")
+				return
+			}
+
+			if oldU.GetGeneration() == newU.GetGeneration() {
+				return
+			}
+
+			updatedAnpCh <- ownerName(newU)
+		},
+		DeleteFunc: func(obj interface{}) {
+			u := obj.(*unstructured.Unstructured)
+			if adapterutil.IsOrphan(u.GetOwnerReferences(), "ClusterNimbusPolicy") {
+				logger.V(4).Info("Ignoring orphan "+kind, kind+".Name", u.GetName(), "Operation", "Delete")
+				return
+			}
+			deletedAnpCh <- ownerName(u)
+		},
+	}
+	_, err := informer.AddEventHandler(handlers)
+	if err != nil {
+		logger.Error(err, "failed to add event handlers")
+		return
+	}
+	logger.Info(kind + " watcher started")
+	informer.Run(ctx.Done())
+}
+
+// ownerName returns the name of the ClusterNimbusPolicy owning the given
+// AdminNetworkPolicy or BaselineAdminNetworkPolicy.
+func ownerName(u *unstructured.Unstructured) string {
+	for _, ownerRef := range u.GetOwnerReferences() {
+		if ownerRef.Kind == "ClusterNimbusPolicy" {
+			return ownerRef.Name
+		}
+	}
+	return ""
+}
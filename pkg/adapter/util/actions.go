@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package util
+
+import (
+	"context"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/5GSEC/nimbus/api/v1alpha1"
+	"github.com/5GSEC/nimbus/pkg/adapter/idpool"
+)
+
+// UnsupportedActions returns the rules whose intent ID the given security
+// engine supports but can't enforce with the rule action.
+func UnsupportedActions(rules []v1alpha1.NimbusRules, securityEngine string) []v1alpha1.UnsupportedAction {
+	var unsupported []v1alpha1.UnsupportedAction
+	for _, rule := range rules {
+		if !idpool.IsIdSupportedBy(rule.ID, securityEngine) || idpool.IsActionSupportedBy(rule.ID, rule.Rule.RuleAction, securityEngine) {
+			continue
+		}
+		unsupported = append(unsupported, v1alpha1.UnsupportedAction{
+			ID:     rule.ID,
+			Engine: securityEngine,
+			Action: rule.Rule.RuleAction,
+		})
+	}
+	return unsupported
+}
+
+// UpdateUnsupportedActions sets the rules of the given NimbusPolicy or
+// ClusterNimbusPolicy that the given engine can't enforce with their action in
+// its status. The entries of the other engines are kept.
+func UpdateUnsupportedActions(ctx context.Context, k8sClient client.Client, kind, name, namespace, engine string, rules []v1alpha1.NimbusRules) error {
+	unsupported := UnsupportedActions(rules, engine)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var obj client.Object
+		var entries *[]v1alpha1.UnsupportedAction
+		switch kind {
+		case "NimbusPolicy":
+			latestNp := &v1alpha1.NimbusPolicy{}
+			obj, entries = latestNp, &latestNp.Status.UnsupportedActions
+		case "ClusterNimbusPolicy":
+			latestCwnp := &v1alpha1.ClusterNimbusPolicy{}
+			obj, entries = latestCwnp, &latestCwnp.Status.UnsupportedActions
+		default:
+			return nil
+		}
+
+		if err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, obj); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		merged := slices.DeleteFunc(slices.Clone(*entries), func(u v1alpha1.UnsupportedAction) bool {
+			return u.Engine == engine
+		})
+		merged = append(merged, unsupported...)
+		if len(merged) == 0 {
+			merged = nil
+		}
+		if equality.Semantic.DeepEqual(merged, *entries) {
+			return nil
+		}
+		*entries = merged
+		return k8sClient.Status().Update(ctx, obj)
+	})
+}
@@ -60,3 +60,24 @@ func updateCountAndClusterPoliciesName(latestCnp *v1alpha1.ClusterNimbusPolicy,
 		}
 	}
 }
+
+// RuleNsSelector returns the namespaces the cluster-wide policies enforcing
+// the given intent of a ClusterNimbusPolicy apply to, that is its namespace
+// selector without the namespaces excluded for the intent since a conflicting
+// NimbusPolicy takes precedence there. It returns false when no namespace is
+// left.
+func RuleNsSelector(spec v1alpha1.ClusterNimbusPolicySpec, id string) (v1alpha1.NamespaceSelector, bool) {
+	selector := *spec.NsSelector.DeepCopy()
+	excluded := spec.ExcludedNamespaces[id]
+	if len(excluded) == 0 {
+		return selector, true
+	}
+
+	matchNames := selector.MatchNames
+	if len(selector.ExcludeNames) > 0 || (len(matchNames) == 1 && matchNames[0] == "*") {
+		selector.ExcludeNames = append(selector.ExcludeNames, excluded...)
+		return selector, true
+	}
+	selector.MatchNames = slices.DeleteFunc(matchNames, func(ns string) bool { return slices.Contains(excluded, ns) })
+	return selector, len(selector.MatchNames) > 0
+}
@@ -0,0 +1,184 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package util
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/5GSEC/nimbus/api/v1alpha1"
+	"github.com/5GSEC/nimbus/pkg/adapter/metrics"
+	"github.com/5GSEC/nimbus/pkg/tracing"
+)
+
+// ClusterPolicies is what an adapter provides for its security engine to
+// reconcile its cluster-wide policies, of type T, with
+// ReconcileClusterPolicies.
+type ClusterPolicies[T any] struct {
+	// Kind is the kind the policies are reported with in the status of the
+	// ClusterNimbusPolicies, e.g. "GlobalNetworkPolicy".
+	Kind string
+	// Prepare, if set, is called with the ClusterNimbusPolicy before its
+	// policies are built.
+	Prepare func(ctx context.Context, cwnp *v1alpha1.ClusterNimbusPolicy)
+	// Build builds the policies enforcing the intents of the
+	// ClusterNimbusPolicy.
+	Build func(ctx context.Context, cwnp *v1alpha1.ClusterNimbusPolicy) []T
+	// List returns the existing policies.
+	List func(ctx context.Context) ([]T, error)
+}
+
+// ReconcileClusterPolicies reconciles the cluster-wide policies of the given
+// security engine for the ClusterNimbusPolicy of the given name. The policies
+// built from it are created or configured, and the ones it owns that aren't
+// built anymore are deleted.
+func ReconcileClusterPolicies[T any, P interface {
+	*T
+	client.Object
+}](ctx context.Context, k8sClient client.Client, scheme *runtime.Scheme, recorder record.EventRecorder,
+	adapterName, engine, cwnpName string, policies ClusterPolicies[T]) {
+	defer metrics.ObserveReconcile(adapterName, time.Now())
+	logger := log.FromContext(ctx)
+	kind := policies.Kind
+
+	var cwnp v1alpha1.ClusterNimbusPolicy
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: cwnpName}, &cwnp); err != nil {
+		if !errors.IsNotFound(err) {
+			logger.Error(err, "failed to get ClusterNimbusPolicy", "ClusterNimbusPolicy.Name", cwnpName)
+		}
+		return
+	}
+
+	if IsOrphan(cwnp.GetOwnerReferences(), "ClusterSecurityIntentBinding") {
+		logger.V(4).Info("Ignoring orphan ClusterNimbusPolicy", "ClusterNimbusPolicy.Name", cwnpName)
+		return
+	}
+
+	// Continue the trace of the reconciliation that changed the
+	// ClusterNimbusPolicy.
+	ctx, span := tracing.Start(tracing.Extract(ctx, &cwnp), adapterName+".Reconcile", tracing.WithObject("ClusterNimbusPolicy", &cwnp))
+	defer span.End()
+
+	if policies.Prepare != nil {
+		policies.Prepare(ctx, &cwnp)
+	}
+	RecordUnsupportedIntents(recorder, &cwnp, cwnp.Spec.NimbusRules, engine)
+	RecordUnsupportedActions(recorder, &cwnp, cwnp.Spec.NimbusRules, engine)
+	if err := UpdateUnsupportedActions(ctx, k8sClient, "ClusterNimbusPolicy", cwnp.Name, cwnp.Namespace, engine, cwnp.Spec.NimbusRules); err != nil {
+		logger.Error(err, "failed to update unsupported actions status in ClusterNimbusPolicy", "ClusterNimbusPolicy.Name", cwnp.Name)
+	}
+	metrics.UnsupportedIntents(adapterName, &cwnp, cwnp.Spec.NimbusRules, engine)
+	translateCtx, translateSpan := tracing.Start(ctx, kind+".Translate")
+	built := policies.Build(translateCtx, &cwnp)
+	translateSpan.End()
+	deleteDanglingClusterPolicies[T, P](ctx, k8sClient, recorder, &cwnp, built, policies)
+
+	for idx := range built {
+		policy := P(&built[idx])
+		policyFullName := kind + "/" + policy.GetName()
+
+		// Set ClusterNimbusPolicy as the owner of the policy
+		if err := controllerutil.SetControllerReference(&cwnp, policy, scheme); err != nil {
+			logger.Error(err, "failed to set OwnerReference on "+kind, "Name", policy.GetName())
+			return
+		}
+
+		existing := P(new(T))
+		err := k8sClient.Get(ctx, types.NamespacedName{Name: policy.GetName()}, existing)
+		if err != nil && !errors.IsNotFound(err) {
+			logger.Error(err, "failed to get existing "+kind, kind+".Name", policy.GetName())
+			return
+		}
+		if err != nil {
+			if err = tracing.Trace(ctx, kind+".Create", func(ctx context.Context) error {
+				return k8sClient.Create(ctx, policy)
+			}, tracing.WithObject(kind, policy)); err != nil {
+				logger.Error(err, "failed to create "+kind, kind+".Name", policy.GetName())
+				RecordPolicyFailed(recorder, &cwnp, policyFullName, err)
+				metrics.PolicyFailed(adapterName, kind)
+				return
+			}
+			logger.Info(kind+" created", kind+".Name", policy.GetName())
+			RecordPolicyCreated(recorder, &cwnp, policyFullName)
+			metrics.PolicyGenerated(adapterName, kind, policy)
+		} else {
+			policy.SetResourceVersion(existing.GetResourceVersion())
+			if err = tracing.Trace(ctx, kind+".Update", func(ctx context.Context) error {
+				return k8sClient.Update(ctx, policy)
+			}, tracing.WithObject(kind, policy)); err != nil {
+				logger.Error(err, "failed to configure existing "+kind, kind+".Name", policy.GetName())
+				RecordPolicyFailed(recorder, &cwnp, policyFullName, err)
+				metrics.PolicyFailed(adapterName, kind)
+				return
+			}
+			logger.Info(kind+" configured", kind+".Name", policy.GetName())
+			if configured(existing, policy) {
+				RecordPolicyConfigured(recorder, &cwnp, policyFullName)
+				metrics.PolicyGenerated(adapterName, kind, policy)
+			}
+		}
+
+		if err = UpdateCwnpStatus(ctx, k8sClient, policyFullName, cwnp.Name, false); err != nil {
+			logger.Error(err, "failed to update "+kind+" status in ClusterNimbusPolicy")
+		}
+	}
+}
+
+// configured reports whether updating the existing policy changed it. The
+// policies of the APIs not tracking the generation, e.g. the Calico ones
+// served by its API server, are compared by resource version.
+func configured(existing, updated client.Object) bool {
+	if existing.GetGeneration() == 0 && updated.GetGeneration() == 0 {
+		return updated.GetResourceVersion() != existing.GetResourceVersion()
+	}
+	return updated.GetGeneration() != existing.GetGeneration()
+}
+
+// deleteDanglingClusterPolicies deletes the policies owned by the given
+// ClusterNimbusPolicy that aren't built from it anymore.
+func deleteDanglingClusterPolicies[T any, P interface {
+	*T
+	client.Object
+}](ctx context.Context, k8sClient client.Client, recorder record.EventRecorder, cwnp *v1alpha1.ClusterNimbusPolicy, built []T,
+	policies ClusterPolicies[T]) {
+	logger := log.FromContext(ctx)
+	kind := policies.Kind
+
+	existing, err := policies.List(ctx)
+	if err != nil {
+		logger.Error(err, "failed to list "+kind+" for cleanup")
+		return
+	}
+
+	for idx := range existing {
+		policy := P(&existing[idx])
+		if !slices.ContainsFunc(policy.GetOwnerReferences(), func(ownerRef metav1.OwnerReference) bool { return ownerRef.UID == cwnp.UID }) {
+			continue
+		}
+		if slices.ContainsFunc(built, func(b T) bool { return P(&b).GetName() == policy.GetName() }) {
+			continue
+		}
+
+		policyFullName := kind + "/" + policy.GetName()
+		if err := k8sClient.Delete(ctx, policy); err != nil {
+			logger.Error(err, "failed to delete dangling "+kind, kind+".Name", policy.GetName())
+			continue
+		}
+		if err := UpdateCwnpStatus(ctx, k8sClient, policyFullName, cwnp.Name, true); err != nil {
+			logger.Error(err, "failed to update "+kind+" status in ClusterNimbusPolicy")
+		}
+		logger.Info("Dangling "+kind+" deleted", kind+".Name", policy.GetName())
+		RecordDanglingPolicyDeleted(recorder, cwnp, policyFullName)
+	}
+}
@@ -0,0 +1,156 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package util
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/5GSEC/nimbus/api/v1alpha1"
+)
+
+// TestReconcileClusterPolicies reconciles ClusterRoles standing for the
+// cluster-wide policies of a security engine.
+func TestReconcileClusterPolicies(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	cwnp := &v1alpha1.ClusterNimbusPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "cwnp",
+			UID:  "cwnp-uid",
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: v1alpha1.GroupVersion.String(), Kind: "ClusterSecurityIntentBinding", Name: "cwnp", UID: "csib-uid",
+				Controller: ptr.To(true),
+			}},
+		},
+		Spec: v1alpha1.ClusterNimbusPolicySpec{
+			NimbusRules: []v1alpha1.NimbusRules{
+				{ID: "dnsManipulation", Rule: v1alpha1.Rule{RuleAction: v1alpha1.ActionBlock}},
+				{ID: "escapeToHost", Rule: v1alpha1.Rule{RuleAction: v1alpha1.ActionBlock}},
+			},
+		},
+		Status: v1alpha1.ClusterNimbusPolicyStatus{
+			NumberOfAdapterPolicies: 2,
+			Policies:                []string{"ClusterRole/existing", "ClusterRole/dangling"},
+		},
+	}
+	ownedBy := func(uid types.UID) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{
+			APIVersion: v1alpha1.GroupVersion.String(), Kind: "ClusterNimbusPolicy", Name: "cwnp", UID: uid,
+			Controller: ptr.To(true),
+		}}
+	}
+	existing := &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "existing", OwnerReferences: ownedBy(cwnp.UID)}}
+	dangling := &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "dangling", OwnerReferences: ownedBy(cwnp.UID)}}
+	unowned := &rbacv1.ClusterRole{ObjectMeta: metav1.ObjectMeta{Name: "unowned", OwnerReferences: ownedBy("other-uid")}}
+
+	k8sClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(cwnp, existing, dangling, unowned).
+		WithStatusSubresource(&v1alpha1.ClusterNimbusPolicy{}).
+		Build()
+	recorder := record.NewFakeRecorder(10)
+
+	var prepared []string
+	policies := ClusterPolicies[rbacv1.ClusterRole]{
+		Kind: "ClusterRole",
+		Prepare: func(ctx context.Context, cwnp *v1alpha1.ClusterNimbusPolicy) {
+			prepared = append(prepared, cwnp.Name)
+		},
+		Build: func(ctx context.Context, cwnp *v1alpha1.ClusterNimbusPolicy) []rbacv1.ClusterRole {
+			return []rbacv1.ClusterRole{
+				{ObjectMeta: metav1.ObjectMeta{Name: "existing"}, Rules: []rbacv1.PolicyRule{{Verbs: []string{"get"}}}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "created"}},
+			}
+		},
+		List: func(ctx context.Context) ([]rbacv1.ClusterRole, error) {
+			var roles rbacv1.ClusterRoleList
+			err := k8sClient.List(ctx, &roles)
+			return roles.Items, err
+		},
+	}
+	ctx := context.Background()
+	ReconcileClusterPolicies(ctx, k8sClient, scheme, recorder, "nimbus-test", "netpol", cwnp.Name, policies)
+
+	if !slices.Equal(prepared, []string{"cwnp"}) {
+		t.Errorf("prepared %v, want [cwnp]", prepared)
+	}
+
+	var roles rbacv1.ClusterRoleList
+	if err := k8sClient.List(ctx, &roles); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, role := range roles.Items {
+		names = append(names, role.Name)
+		if role.Name != "unowned" && !metav1.IsControlledBy(&role, cwnp) {
+			t.Errorf("ClusterRole %s isn't controlled by the ClusterNimbusPolicy", role.Name)
+		}
+		if role.Name == "existing" && len(role.Rules) == 0 {
+			t.Errorf("existing ClusterRole wasn't configured")
+		}
+	}
+	if want := []string{"created", "existing", "unowned"}; !slices.Equal(names, want) {
+		t.Errorf("ClusterRoles %v, want %v", names, want)
+	}
+
+	var latest v1alpha1.ClusterNimbusPolicy
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: cwnp.Name}, &latest); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"ClusterRole/existing", "ClusterRole/created"}; !slices.Equal(latest.Status.Policies, want) ||
+		latest.Status.NumberOfAdapterPolicies != 2 {
+		t.Errorf("status policies %v (%d), want %v (2)", latest.Status.Policies, latest.Status.NumberOfAdapterPolicies, want)
+	}
+	// The netpol engine blocks dnsManipulation, and doesn't support the
+	// escapeToHost intent whatever its action.
+	if len(latest.Status.UnsupportedActions) != 0 {
+		t.Errorf("status unsupported actions %v, want none", latest.Status.UnsupportedActions)
+	}
+
+	close(recorder.Events)
+	var events []string
+	for event := range recorder.Events {
+		events = append(events, event)
+	}
+	for _, want := range []string{
+		"Normal DanglingPolicyDeleted Deleted dangling ClusterRole/dangling",
+		"Normal PolicyCreated Created ClusterRole/created",
+	} {
+		if !slices.Contains(events, want) {
+			t.Errorf("events %q, missing %q", events, want)
+		}
+	}
+
+	// An orphan ClusterNimbusPolicy isn't reconciled.
+	latest.OwnerReferences = nil
+	if err := k8sClient.Update(ctx, &latest); err != nil {
+		t.Fatal(err)
+	}
+	prepared = nil
+	ReconcileClusterPolicies(ctx, k8sClient, scheme, nil, "nimbus-test", "netpol", cwnp.Name, policies)
+	if prepared != nil {
+		t.Errorf("orphan ClusterNimbusPolicy prepared")
+	}
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: "created"}, &rbacv1.ClusterRole{}); err != nil {
+		t.Errorf("ClusterRole of the orphan ClusterNimbusPolicy deleted: %v", err)
+	}
+}
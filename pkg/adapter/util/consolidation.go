@@ -0,0 +1,310 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/5GSEC/nimbus/api/v1alpha1"
+	"github.com/5GSEC/nimbus/pkg/adapter/common"
+	"github.com/5GSEC/nimbus/pkg/adapter/metrics"
+	"github.com/5GSEC/nimbus/pkg/tracing"
+)
+
+const (
+	// ConsolidatePoliciesEnv is the environment variable that enables the
+	// consolidation mode of the adapters supporting it.
+	ConsolidatePoliciesEnv = "CONSOLIDATE_POLICIES"
+
+	// IntentsAnnotation lists, comma separated, the IDs of the intents that an
+	// adapter policy enforces.
+	IntentsAnnotation = common.IntentsAnnotation
+
+	consolidatedPolicyPrefix = "nimbus-consolidated-"
+)
+
+// ConsolidationEnabled reports whether the adapter should merge the compatible
+// rules of the NimbusPolicies in a namespace into fewer policies.
+func ConsolidationEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv(ConsolidatePoliciesEnv))
+	return enabled
+}
+
+// ConsolidatedPolicyName returns the name of the policy merging the rules
+// enforced with the given action on the workloads selected by the given
+// labels and expressions, as "nimbus-consolidated-<action>-<hash of the
+// selector>".
+func ConsolidatedPolicyName(action string, matchLabels map[string]string, matchExpressions []metav1.LabelSelectorRequirement) string {
+	var keys []string
+	for key := range matchLabels {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	hash := fnv.New32a()
+	for _, key := range keys {
+		_, _ = hash.Write([]byte(key + "=" + matchLabels[key] + ","))
+	}
+	// The expressions are hashed after the labels, so that the names of the
+	// policies of the selectors without expressions don't change.
+	var expressions []string
+	for _, expression := range matchExpressions {
+		values := slices.Clone(expression.Values)
+		slices.Sort(values)
+		expressions = append(expressions, expression.Key+" "+string(expression.Operator)+" "+strings.Join(values, "|")+",")
+	}
+	slices.Sort(expressions)
+	for _, expression := range expressions {
+		_, _ = hash.Write([]byte(expression))
+	}
+	return fmt.Sprintf("%s%s-%08x", consolidatedPolicyPrefix, strings.ToLower(action), hash.Sum32())
+}
+
+// ConsolidatedPolicyAction returns the action, in lowercase, of a policy named
+// by ConsolidatedPolicyName, and whether the name is one of such a policy.
+func ConsolidatedPolicyAction(name string) (string, bool) {
+	rest, found := strings.CutPrefix(name, consolidatedPolicyPrefix)
+	if !found {
+		return "", false
+	}
+	action, _, found := strings.Cut(rest, "-")
+	return action, found
+}
+
+// AddIntents adds the given intent IDs to the IntentsAnnotation of an adapter
+// policy, keeping them sorted and unique.
+func AddIntents(annotations map[string]string, ids ...string) {
+	var intents []string
+	if existing := annotations[IntentsAnnotation]; existing != "" {
+		intents = strings.Split(existing, ",")
+	}
+	for _, id := range ids {
+		if id != "" && !slices.Contains(intents, id) {
+			intents = append(intents, id)
+		}
+	}
+	slices.Sort(intents)
+	annotations[IntentsAnnotation] = strings.Join(intents, ",")
+}
+
+// ConsolidatedPolicy is a policy merging the compatible rules of the
+// NimbusPolicies owning it.
+type ConsolidatedPolicy struct {
+	Policy client.Object
+	Owners []v1alpha1.NimbusPolicy
+}
+
+// PolicyConsolidator is what an adapter implements for its security engine to
+// reconcile its policies with ConsolidatePolicies.
+type PolicyConsolidator interface {
+	// Kind is the kind the policies are reported with in the status of the
+	// NimbusPolicies, e.g. "KyvernoPolicy".
+	Kind() string
+	// Prepare is called with every NimbusPolicy of the namespace before
+	// they are consolidated, along with whether it is, i.e. owned by a
+	// SecurityIntentBinding.
+	Prepare(ctx context.Context, np *v1alpha1.NimbusPolicy, consolidated bool)
+	// Consolidate builds the policies of the given NimbusPolicies.
+	Consolidate(ctx context.Context, nps []v1alpha1.NimbusPolicy) []ConsolidatedPolicy
+	// NewPolicy returns an empty policy to get an existing one into.
+	NewPolicy() client.Object
+	// ListPolicies returns the existing policies of the given namespace.
+	ListPolicies(ctx context.Context, namespace string) ([]client.Object, error)
+	// Changed reports whether the existing policy differs from the built
+	// one, along with why.
+	Changed(existing, built client.Object) (string, bool)
+}
+
+// ConsolidatePolicies reconciles the policies of the given security engine
+// for all the NimbusPolicies of the given namespace in consolidation mode. The
+// compatible rules of different NimbusPolicies are merged into policies owned
+// by all of them, so the whole namespace is reconciled whenever one of them
+// changes.
+func ConsolidatePolicies(ctx context.Context, k8sClient client.Client, scheme *runtime.Scheme, recorder record.EventRecorder,
+	adapterName, engine, namespace string, consolidator PolicyConsolidator) {
+	logger := log.FromContext(ctx)
+	kind := consolidator.Kind()
+
+	var npList v1alpha1.NimbusPolicyList
+	if err := k8sClient.List(ctx, &npList, client.InNamespace(namespace)); err != nil {
+		logger.Error(err, "failed to list NimbusPolicies", "NimbusPolicy.Namespace", namespace)
+		return
+	}
+
+	var nps []v1alpha1.NimbusPolicy
+	for _, np := range npList.Items {
+		if np.GetDeletionTimestamp() != nil {
+			continue
+		}
+		// The NimbusPolicies of the ClusterSecurityIntentBindings are
+		// enforced by the cluster-wide policies of the engine, if any.
+		if IsOrphan(np.GetOwnerReferences(), "SecurityIntentBinding") {
+			logger.V(4).Info("Ignoring orphan NimbusPolicy", "NimbusPolicy.Name", np.Name, "NimbusPolicy.Namespace", np.Namespace)
+			consolidator.Prepare(ctx, &np, false)
+			continue
+		}
+		consolidator.Prepare(ctx, &np, true)
+		RecordUnsupportedIntents(recorder, &np, np.Spec.NimbusRules, engine)
+		RecordUnsupportedActions(recorder, &np, np.Spec.NimbusRules, engine)
+		if err := UpdateUnsupportedActions(ctx, k8sClient, "NimbusPolicy", np.Name, np.Namespace, engine, np.Spec.NimbusRules); err != nil {
+			logger.Error(err, "failed to update unsupported actions status in NimbusPolicy", "NimbusPolicy.Name", np.Name, "NimbusPolicy.Namespace", np.Namespace)
+		}
+		metrics.UnsupportedIntents(adapterName, &np, np.Spec.NimbusRules, engine)
+		nps = append(nps, np)
+	}
+	// Keep the order of the merged rules stable across reconciliations.
+	slices.SortFunc(nps, func(a, b v1alpha1.NimbusPolicy) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	// The consolidated policies are built from all the NimbusPolicies, so
+	// link the traces of the reconciliations that changed each of them.
+	links := make([]trace.Link, 0, len(nps))
+	for idx := range nps {
+		links = append(links, tracing.LinkTo(&nps[idx]))
+	}
+	ctx, span := tracing.Start(ctx, adapterName+".Consolidate", trace.WithLinks(links...),
+		trace.WithAttributes(attribute.String("NimbusPolicy.Namespace", namespace)))
+	defer span.End()
+
+	translateCtx, translateSpan := tracing.Start(ctx, kind+".Translate")
+	consolidated := consolidator.Consolidate(translateCtx, nps)
+	translateSpan.End()
+	deleteDanglingConsolidatedPolicies(ctx, k8sClient, recorder, namespace, nps, consolidated, consolidator)
+
+	for idx := range consolidated {
+		policy := consolidated[idx].Policy
+		owners := consolidated[idx].Owners
+		policyFullName := kind + "/" + policy.GetName()
+
+		// Every contributing NimbusPolicy owns the policy, so that it's only
+		// garbage collected once all of them are deleted.
+		for ownerIdx := range owners {
+			if err := controllerutil.SetOwnerReference(&owners[ownerIdx], policy, scheme); err != nil {
+				logger.Error(err, "failed to set OwnerReference on "+kind, "Name", policy.GetName())
+				return
+			}
+		}
+
+		existing := consolidator.NewPolicy()
+		err := k8sClient.Get(ctx, types.NamespacedName{Name: policy.GetName(), Namespace: policy.GetNamespace()}, existing)
+		if err != nil && !errors.IsNotFound(err) {
+			logger.Error(err, "failed to get existing "+kind, kind+".Name", policy.GetName(), kind+".Namespace", policy.GetNamespace())
+			return
+		}
+		if err != nil {
+			if err = tracing.Trace(ctx, kind+".Create", func(ctx context.Context) error {
+				return k8sClient.Create(ctx, policy)
+			}, tracing.WithObject(kind, policy)); err != nil {
+				logger.Error(err, "failed to create "+kind, kind+".Name", policy.GetName(), kind+".Namespace", policy.GetNamespace())
+				metrics.PolicyFailed(adapterName, kind)
+				for ownerIdx := range owners {
+					RecordPolicyFailed(recorder, &owners[ownerIdx], policyFullName, err)
+				}
+				return
+			}
+			logger.Info(kind+" created", kind+".Name", policy.GetName(), kind+".Namespace", policy.GetNamespace(),
+				"Intents", policy.GetAnnotations()[IntentsAnnotation])
+			metrics.PolicyGenerated(adapterName, kind, policy)
+			for ownerIdx := range owners {
+				RecordPolicyCreated(recorder, &owners[ownerIdx], policyFullName)
+			}
+		} else if reason, changed := consolidator.Changed(existing, policy); changed {
+			policy.SetResourceVersion(existing.GetResourceVersion())
+			if err = tracing.Trace(ctx, kind+".Update", func(ctx context.Context) error {
+				return k8sClient.Update(ctx, policy)
+			}, tracing.WithObject(kind, policy)); err != nil {
+				logger.Error(err, "failed to configure existing "+kind, kind+".Name", existing.GetName(), kind+".Namespace", existing.GetNamespace())
+				metrics.PolicyFailed(adapterName, kind)
+				for ownerIdx := range owners {
+					RecordPolicyFailed(recorder, &owners[ownerIdx], policyFullName, err)
+				}
+				return
+			}
+			logger.Info(kind+" configured", kind+".Name", existing.GetName(), kind+".Namespace", existing.GetNamespace(),
+				"Intents", policy.GetAnnotations()[IntentsAnnotation], "Reason", reason)
+			if policy.GetGeneration() != existing.GetGeneration() {
+				metrics.PolicyGenerated(adapterName, kind, policy)
+				for ownerIdx := range owners {
+					RecordPolicyConfigured(recorder, &owners[ownerIdx], policyFullName)
+				}
+			}
+
+			// Remove the policy from the status of the NimbusPolicies that no
+			// longer contribute to it.
+			for _, ownerRef := range existing.GetOwnerReferences() {
+				if slices.ContainsFunc(owners, func(owner v1alpha1.NimbusPolicy) bool { return owner.UID == ownerRef.UID }) {
+					continue
+				}
+				if err = UpdateNpStatus(ctx, k8sClient, policyFullName, ownerRef.Name, namespace, true); err != nil {
+					logger.Error(err, "failed to update "+kind+" status in NimbusPolicy")
+				}
+			}
+		}
+
+		for _, owner := range owners {
+			if err = UpdateNpStatus(ctx, k8sClient, policyFullName, owner.Name, owner.Namespace, false); err != nil {
+				logger.Error(err, "failed to update "+kind+" status in NimbusPolicy")
+			}
+		}
+	}
+}
+
+// deleteDanglingConsolidatedPolicies deletes the policies owned by the
+// NimbusPolicies of the namespace that aren't built anymore, including the
+// ones built before the consolidation mode was enabled.
+func deleteDanglingConsolidatedPolicies(ctx context.Context, k8sClient client.Client, recorder record.EventRecorder, namespace string,
+	nps []v1alpha1.NimbusPolicy, consolidated []ConsolidatedPolicy, consolidator PolicyConsolidator) {
+	logger := log.FromContext(ctx)
+	kind := consolidator.Kind()
+
+	existing, err := consolidator.ListPolicies(ctx, namespace)
+	if err != nil {
+		logger.Error(err, "failed to list "+kind+" for cleanup")
+		return
+	}
+
+	for _, policy := range existing {
+		if IsOrphan(policy.GetOwnerReferences(), "NimbusPolicy") {
+			continue
+		}
+		if slices.ContainsFunc(consolidated, func(c ConsolidatedPolicy) bool { return c.Policy.GetName() == policy.GetName() }) {
+			continue
+		}
+
+		policyFullName := kind + "/" + policy.GetName()
+		if err := k8sClient.Delete(ctx, policy); err != nil {
+			logger.Error(err, "failed to delete dangling "+kind, kind+".Name", policy.GetName(), kind+".Namespace", policy.GetNamespace())
+			continue
+		}
+		for _, ownerRef := range policy.GetOwnerReferences() {
+			if err := UpdateNpStatus(ctx, k8sClient, policyFullName, ownerRef.Name, namespace, true); err != nil {
+				logger.Error(err, "failed to update "+kind+" status in NimbusPolicy")
+			}
+		}
+		logger.Info("Dangling "+kind+" deleted", kind+".Name", policy.GetName(), kind+".Namespace", policy.GetNamespace())
+		for idx := range nps {
+			if slices.ContainsFunc(policy.GetOwnerReferences(), func(ownerRef metav1.OwnerReference) bool { return ownerRef.UID == nps[idx].UID }) {
+				RecordDanglingPolicyDeleted(recorder, &nps[idx], policyFullName)
+			}
+		}
+	}
+}
@@ -0,0 +1,175 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package util
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/5GSEC/nimbus/api/v1alpha1"
+	"github.com/5GSEC/nimbus/pkg/adapter/idpool"
+)
+
+// Reasons of the Events emitted by the adapters on NimbusPolicies and
+// ClusterNimbusPolicies.
+const (
+	ReasonPolicyCreated         = "PolicyCreated"
+	ReasonPolicyConfigured      = "PolicyConfigured"
+	ReasonPolicyFailed          = "PolicyFailed"
+	ReasonDanglingPolicyDeleted = "DanglingPolicyDeleted"
+	ReasonUnsupportedIntent     = "UnsupportedIntent"
+	ReasonUnsupportedAction     = "UnsupportedAction"
+	ReasonUnsupportedSelector   = "UnsupportedSelector"
+)
+
+// ReasonIntentViolated is the reason of the Events emitted by the adapters on
+// the bindings whose intents the workloads violated.
+const ReasonIntentViolated = "IntentViolated"
+
+// RecordPolicyCreated emits an Event on the NimbusPolicy or ClusterNimbusPolicy
+// for the given adapter policy, formatted as "<Kind>/<name>", that was created.
+func RecordPolicyCreated(recorder record.EventRecorder, nimbusPolicy runtime.Object, policyFullName string) {
+	if recorder == nil {
+		return
+	}
+	recorder.Eventf(nimbusPolicy, corev1.EventTypeNormal, ReasonPolicyCreated, "Created %s", policyFullName)
+}
+
+// RecordPolicyConfigured emits an Event on the NimbusPolicy or
+// ClusterNimbusPolicy for the given adapter policy that was updated.
+func RecordPolicyConfigured(recorder record.EventRecorder, nimbusPolicy runtime.Object, policyFullName string) {
+	if recorder == nil {
+		return
+	}
+	recorder.Eventf(nimbusPolicy, corev1.EventTypeNormal, ReasonPolicyConfigured, "Configured %s", policyFullName)
+}
+
+// RecordPolicyFailed emits a warning Event on the NimbusPolicy or
+// ClusterNimbusPolicy for the given adapter policy that couldn't be created or
+// updated.
+func RecordPolicyFailed(recorder record.EventRecorder, nimbusPolicy runtime.Object, policyFullName string, err error) {
+	if recorder == nil {
+		return
+	}
+	recorder.Eventf(nimbusPolicy, corev1.EventTypeWarning, ReasonPolicyFailed, "Failed to apply %s: %v", policyFullName, err)
+}
+
+// RecordDanglingPolicyDeleted emits an Event on the NimbusPolicy or
+// ClusterNimbusPolicy for the given adapter policy that was deleted because
+// the intent it enforced isn't bound anymore.
+func RecordDanglingPolicyDeleted(recorder record.EventRecorder, nimbusPolicy runtime.Object, policyFullName string) {
+	if recorder == nil {
+		return
+	}
+	recorder.Eventf(nimbusPolicy, corev1.EventTypeNormal, ReasonDanglingPolicyDeleted, "Deleted dangling %s", policyFullName)
+}
+
+// RecordUnsupportedIntents emits an Event on the NimbusPolicy or
+// ClusterNimbusPolicy for every rule whose intent ID the given security engine
+// doesn't support, whenever they change. Other engines may support them, so
+// they aren't reported on every reconciliation.
+func RecordUnsupportedIntents(recorder record.EventRecorder, nimbusPolicy runtime.Object, rules []v1alpha1.NimbusRules, securityEngine string) {
+	if recorder == nil {
+		return
+	}
+	var messages []string
+	for _, rule := range rules {
+		if !idpool.IsIdSupportedBy(rule.ID, securityEngine) {
+			messages = append(messages, fmt.Sprintf("Intent ID %s is not supported by %s, skipping it", rule.ID, securityEngine))
+		}
+	}
+	recordOnChange(recorder, nimbusPolicy, corev1.EventTypeNormal, ReasonUnsupportedIntent, messages)
+}
+
+// RecordUnsupportedActions emits a warning Event on the NimbusPolicy or
+// ClusterNimbusPolicy for every rule whose intent ID the given security engine
+// supports but can't enforce with the rule action, whenever they change.
+func RecordUnsupportedActions(recorder record.EventRecorder, nimbusPolicy runtime.Object, rules []v1alpha1.NimbusRules, securityEngine string) {
+	if recorder == nil {
+		return
+	}
+	var messages []string
+	for _, unsupported := range UnsupportedActions(rules, securityEngine) {
+		messages = append(messages, fmt.Sprintf("Intent ID %s can't be enforced with %s by %s, skipping it",
+			unsupported.ID, unsupported.Action, securityEngine))
+	}
+	recordOnChange(recorder, nimbusPolicy, corev1.EventTypeWarning, ReasonUnsupportedAction, messages)
+}
+
+// reportKey identifies the Events of a reason emitted on an object.
+type reportKey struct {
+	uid    types.UID
+	reason string
+}
+
+// reported holds the messages of the Events last emitted by recordOnChange.
+var (
+	reportedMu sync.Mutex
+	reported   = make(map[reportKey]string)
+)
+
+// recordOnChange emits an Event of the given type and reason on the given
+// object for every given message, unless they are the ones it emitted last
+// time for the object and reason.
+func recordOnChange(recorder record.EventRecorder, obj runtime.Object, eventType, reason string, messages []string) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return
+	}
+	key := reportKey{uid: accessor.GetUID(), reason: reason}
+	joined := strings.Join(messages, "\n")
+
+	reportedMu.Lock()
+	previous := reported[key]
+	if len(messages) == 0 {
+		delete(reported, key)
+	} else {
+		reported[key] = joined
+	}
+	reportedMu.Unlock()
+
+	if joined == previous {
+		return
+	}
+	for _, message := range messages {
+		recorder.Event(obj, eventType, reason, message)
+	}
+}
+
+// RecordUnsupportedSelector emits a warning Event on the NimbusPolicy or
+// ClusterNimbusPolicy when the given security engine can't select the
+// workloads of its workload selector, so that it isn't enforced by the engine.
+// The Event is emitted again only after the selector was supported.
+func RecordUnsupportedSelector(recorder record.EventRecorder, nimbusPolicy runtime.Object, securityEngine string, supported bool) {
+	if recorder == nil {
+		return
+	}
+	var messages []string
+	if !supported {
+		messages = append(messages, fmt.Sprintf("The workload selector can't be enforced by %s, skipping the policy", securityEngine))
+	}
+	recordOnChange(recorder, nimbusPolicy, corev1.EventTypeWarning, ReasonUnsupportedSelector, messages)
+}
+
+// RecordIntentViolated emits a warning Event on the SecurityIntentBinding or
+// ClusterSecurityIntentBinding for an intent the security engine raised alerts
+// for since the previous Event.
+func RecordIntentViolated(recorder record.EventRecorder, binding runtime.Object, violation v1alpha1.IntentViolation) {
+	if recorder == nil {
+		return
+	}
+	message := fmt.Sprintf("Intent %s violated %d time(s) according to %s", violation.ID, violation.Count, violation.Engine)
+	if len(violation.Samples) > 0 {
+		sample := violation.Samples[0]
+		message += fmt.Sprintf(", last by pod %s/%s: %s %s", sample.Namespace, sample.Pod, sample.Operation, sample.Resource)
+	}
+	recorder.Event(binding, corev1.EventTypeWarning, ReasonIntentViolated, message)
+}
@@ -0,0 +1,83 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package util
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/5GSEC/nimbus/api/v1alpha1"
+)
+
+// UpdatePolicyReports sets the policy report summaries of the given engine in
+// the status of the given NimbusPolicy, ClusterNimbusPolicy,
+// SecurityIntentBinding or ClusterSecurityIntentBinding. The summaries of the
+// other engines are kept, and the ones of the engine are removed when none are
+// given.
+func UpdatePolicyReports(ctx context.Context, k8sClient client.Client, kind, name, namespace, engine string, summaries []v1alpha1.PolicyReportSummary) error {
+	// The controller and the other adapters may update the status
+	// concurrently, so retry on conflicts.
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var obj client.Object
+		var reports *[]v1alpha1.PolicyReportSummary
+		switch kind {
+		case "NimbusPolicy":
+			latestNp := &v1alpha1.NimbusPolicy{}
+			obj, reports = latestNp, &latestNp.Status.PolicyReports
+		case "ClusterNimbusPolicy":
+			latestCwnp := &v1alpha1.ClusterNimbusPolicy{}
+			obj, reports = latestCwnp, &latestCwnp.Status.PolicyReports
+		case "SecurityIntentBinding":
+			latestSib := &v1alpha1.SecurityIntentBinding{}
+			obj, reports = latestSib, &latestSib.Status.PolicyReports
+		case "ClusterSecurityIntentBinding":
+			latestCsib := &v1alpha1.ClusterSecurityIntentBinding{}
+			obj, reports = latestCsib, &latestCsib.Status.PolicyReports
+		default:
+			return nil
+		}
+
+		if err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, obj); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		merged := mergePolicyReports(*reports, engine, summaries)
+		if equality.Semantic.DeepEqual(merged, *reports) {
+			return nil
+		}
+		*reports = merged
+		return k8sClient.Status().Update(ctx, obj)
+	})
+}
+
+// mergePolicyReports replaces the reported summaries of the given engine with
+// the given ones.
+func mergePolicyReports(reported []v1alpha1.PolicyReportSummary, engine string, summaries []v1alpha1.PolicyReportSummary) []v1alpha1.PolicyReportSummary {
+	merged := slices.DeleteFunc(slices.Clone(reported), func(s v1alpha1.PolicyReportSummary) bool {
+		return s.Engine == engine
+	})
+	merged = append(merged, summaries...)
+	if len(merged) == 0 {
+		return nil
+	}
+	return merged
+}
+
+// ReportedKyvernoPolicy returns the kind, namespace and name of the Kyverno
+// policy of a PolicyReport result, which Kyverno names "<namespace>/<name>"
+// for a Policy and "<name>" for a ClusterPolicy. The kind is the one the
+// Kyverno adapter reports the policy with in the status of the NimbusPolicies,
+// KyvernoPolicy or KyvernoClusterPolicy, and the namespace is empty for the
+// latter.
+func ReportedKyvernoPolicy(policy string) (kind, namespace, name string) {
+	if namespace, name, ok := strings.Cut(policy, "/"); ok {
+		return "KyvernoPolicy", namespace, name
+	}
+	return "KyvernoClusterPolicy", "", policy
+}
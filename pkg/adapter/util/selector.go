@@ -0,0 +1,52 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package util
+
+import (
+	"context"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/5GSEC/nimbus/api/v1alpha1"
+)
+
+// UpdateUnsupportedSelector adds the given engine to the engines that can't
+// select the workloads of the workload selector of the given NimbusPolicy or
+// ClusterNimbusPolicy in its status, or removes it when supported.
+func UpdateUnsupportedSelector(ctx context.Context, k8sClient client.Client, kind, name, namespace, engine string, supported bool) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var obj client.Object
+		var engines *[]string
+		switch kind {
+		case "NimbusPolicy":
+			latestNp := &v1alpha1.NimbusPolicy{}
+			obj, engines = latestNp, &latestNp.Status.UnsupportedSelectors
+		case "ClusterNimbusPolicy":
+			latestCwnp := &v1alpha1.ClusterNimbusPolicy{}
+			obj, engines = latestCwnp, &latestCwnp.Status.UnsupportedSelectors
+		default:
+			return nil
+		}
+
+		if err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, obj); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		if slices.Contains(*engines, engine) != supported {
+			return nil
+		}
+		if supported {
+			*engines = slices.DeleteFunc(*engines, func(e string) bool { return e == engine })
+			if len(*engines) == 0 {
+				*engines = nil
+			}
+		} else {
+			*engines = append(*engines, engine)
+			slices.Sort(*engines)
+		}
+		return k8sClient.Status().Update(ctx, obj)
+	})
+}
@@ -0,0 +1,67 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package util
+
+import (
+	"context"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/5GSEC/nimbus/api/v1alpha1"
+)
+
+// UpdateBindingViolations adds the violations an adapter observed since its
+// previous call to the status of the given SecurityIntentBinding or
+// ClusterSecurityIntentBinding. The counts are added to the reported ones, and
+// the samples are prepended to the reported ones, keeping the
+// v1alpha1.MaxViolationSamples most recent.
+func UpdateBindingViolations(ctx context.Context, k8sClient client.Client, kind, name, namespace string, observed []v1alpha1.IntentViolation) error {
+	// The controller and the other adapters may update the binding status
+	// concurrently, so retry on conflicts.
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		switch kind {
+		case "SecurityIntentBinding":
+			latestSib := &v1alpha1.SecurityIntentBinding{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, latestSib); err != nil {
+				return client.IgnoreNotFound(err)
+			}
+			latestSib.Status.Violations = mergeViolations(latestSib.Status.Violations, observed)
+			return k8sClient.Status().Update(ctx, latestSib)
+		case "ClusterSecurityIntentBinding":
+			latestCsib := &v1alpha1.ClusterSecurityIntentBinding{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{Name: name}, latestCsib); err != nil {
+				return client.IgnoreNotFound(err)
+			}
+			latestCsib.Status.Violations = mergeViolations(latestCsib.Status.Violations, observed)
+			return k8sClient.Status().Update(ctx, latestCsib)
+		}
+		return nil
+	})
+}
+
+func mergeViolations(reported, observed []v1alpha1.IntentViolation) []v1alpha1.IntentViolation {
+	for _, violation := range observed {
+		idx := slices.IndexFunc(reported, func(v v1alpha1.IntentViolation) bool {
+			return v.ID == violation.ID && v.Engine == violation.Engine
+		})
+		if idx < 0 {
+			reported = append(reported, violation)
+			continue
+		}
+
+		merged := &reported[idx]
+		merged.Count += violation.Count
+		if violation.LastSeen.After(merged.LastSeen.Time) {
+			merged.LastSeen = violation.LastSeen
+		}
+		merged.Samples = append(slices.Clone(violation.Samples), merged.Samples...)
+		if len(merged.Samples) > v1alpha1.MaxViolationSamples {
+			merged.Samples = merged.Samples[:v1alpha1.MaxViolationSamples]
+		}
+	}
+	return reported
+}
@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package util
+
+import (
+	"context"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ViolationSourceLabel labels the Leases that adapters renew while they
+	// relay the violations of their engine, with the name of the engine.
+	ViolationSourceLabel = "intent.security.nimbus.com/violation-source"
+
+	// ViolationSourceLeaseDuration is how long a violation source is
+	// considered active after its Lease was last renewed.
+	ViolationSourceLeaseDuration = time.Minute
+)
+
+var (
+	leaseGVK     = schema.GroupVersionKind{Group: "coordination.k8s.io", Version: "v1", Kind: "Lease"}
+	leaseListGVK = schema.GroupVersionKind{Group: "coordination.k8s.io", Version: "v1", Kind: "LeaseList"}
+)
+
+// RenewViolationSource renews the Lease advertising that the adapter relays
+// the violations of the given engine since the given time, creating it in the
+// given namespace if needed.
+func RenewViolationSource(ctx context.Context, k8sClient client.Client, namespace, engine string, activeSince time.Time) error {
+	lease := &unstructured.Unstructured{}
+	lease.SetGroupVersionKind(leaseGVK)
+	name := "nimbus-" + engine + "-violation-source"
+	err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, lease)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	create := apierrors.IsNotFound(err)
+	if create {
+		lease.SetName(name)
+		lease.SetNamespace(namespace)
+		lease.SetLabels(map[string]string{ViolationSourceLabel: engine})
+	}
+
+	spec := map[string]any{
+		"holderIdentity":       engine,
+		"leaseDurationSeconds": int64(ViolationSourceLeaseDuration.Seconds()),
+		"acquireTime":          activeSince.UTC().Format(metav1.RFC3339Micro),
+		"renewTime":            time.Now().UTC().Format(metav1.RFC3339Micro),
+	}
+	if err := unstructured.SetNestedMap(lease.Object, spec, "spec"); err != nil {
+		return err
+	}
+
+	if create {
+		return k8sClient.Create(ctx, lease)
+	}
+	return k8sClient.Update(ctx, lease)
+}
+
+// ActiveViolationSources returns the engines whose violations an adapter
+// relays at the given time, along with since when, according to the Leases
+// they renew.
+func ActiveViolationSources(ctx context.Context, c client.Reader, now time.Time) (map[string]time.Time, error) {
+	leases := &unstructured.UnstructuredList{}
+	leases.SetGroupVersionKind(leaseListGVK)
+	if err := c.List(ctx, leases, client.HasLabels{ViolationSourceLabel}); err != nil {
+		return nil, err
+	}
+
+	active := make(map[string]time.Time)
+	for _, lease := range leases.Items {
+		engine := lease.GetLabels()[ViolationSourceLabel]
+		renewTime, _, _ := unstructured.NestedString(lease.Object, "spec", "renewTime")
+		acquireTime, _, _ := unstructured.NestedString(lease.Object, "spec", "acquireTime")
+		duration, _, _ := unstructured.NestedInt64(lease.Object, "spec", "leaseDurationSeconds")
+
+		renewed, err := time.Parse(metav1.RFC3339Micro, renewTime)
+		if err != nil || now.Sub(renewed) > time.Duration(duration)*time.Second {
+			continue
+		}
+		since, err := time.Parse(metav1.RFC3339Micro, acquireTime)
+		if err != nil {
+			since = renewed
+		}
+		if previous, ok := active[engine]; !ok || since.Before(previous) {
+			active[engine] = since
+		}
+	}
+	return active, nil
+}
@@ -0,0 +1,86 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package watcher
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func nodeInformer() cache.SharedIndexInformer {
+	nodeGvr := schema.GroupVersionResource{
+		Group:    "",
+		Version:  "v1",
+		Resource: "nodes",
+	}
+	informer := factory.ForResource(nodeGvr).Informer()
+	return informer
+}
+
+// WatchNodes watches for nodes joining or leaving the cluster, or changing
+// their pod CIDRs or addresses, and signals it on the given channel, whose
+// buffer coalesces the changes not handled yet.
+func WatchNodes(ctx context.Context, nodesChangedCh chan struct{}) {
+	logger := log.FromContext(ctx)
+	informer := nodeInformer()
+
+	signal := func(name, operation string) {
+		logger.V(2).Info("Node changed", "Node.Name", name, "Operation", operation)
+		select {
+		case nodesChangedCh <- struct{}{}:
+		default:
+		}
+	}
+
+	handlers := cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(obj interface{}, isInInitialList bool) {
+			if isInInitialList {
+				return
+			}
+			signal(obj.(*unstructured.Unstructured).GetName(), "Add")
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldU := oldObj.(*unstructured.Unstructured)
+			newU := newObj.(*unstructured.Unstructured)
+			if equality.Semantic.DeepEqual(nodeNetworks(oldU), nodeNetworks(newU)) {
+				return
+			}
+			signal(newU.GetName(), "Update")
+		},
+		DeleteFunc: func(obj interface{}) {
+			u, ok := obj.(*unstructured.Unstructured)
+			if !ok {
+				tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+				if !ok {
+					return
+				}
+				if u, ok = tombstone.Obj.(*unstructured.Unstructured); !ok {
+					return
+				}
+			}
+			signal(u.GetName(), "Delete")
+		},
+	}
+	_, err := informer.AddEventHandler(handlers)
+	if err != nil {
+		logger.Error(err, "failed to add event handlers")
+		return
+	}
+	logger.Info("Node watcher started")
+	informer.Run(ctx.Done())
+}
+
+// nodeNetworks returns the fields of a node the cluster CIDRs are discovered
+// from.
+func nodeNetworks(u *unstructured.Unstructured) []any {
+	podCIDR, _, _ := unstructured.NestedFieldNoCopy(u.Object, "spec", "podCIDR")
+	podCIDRs, _, _ := unstructured.NestedFieldNoCopy(u.Object, "spec", "podCIDRs")
+	addresses, _, _ := unstructured.NestedFieldNoCopy(u.Object, "status", "addresses")
+	return []any{podCIDR, podCIDRs, addresses}
+}
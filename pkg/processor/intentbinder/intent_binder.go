@@ -5,6 +5,7 @@ package intentbinder
 
 import (
 	"context"
+	"slices"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
@@ -14,28 +15,91 @@ import (
 	v1alpha1 "github.com/5GSEC/nimbus/api/v1alpha1"
 )
 
+// ReasonNotFound is the reason reported for a referenced SecurityIntent that
+// doesn't exist.
+const ReasonNotFound = "SecurityIntent not found"
+
 // ExtractIntents extract the SecurityIntent from the given SecurityIntentBinding
-// or ClusterSecurityIntentBinding objects.
-func ExtractIntents(ctx context.Context, c client.Client, object client.Object) []v1alpha1.SecurityIntent {
+// or ClusterSecurityIntentBinding objects, both the ones referenced by name and
+// the ones matching the intent selector. SecurityIntents referenced by name that
+// don't exist are returned as unresolved intents, while any other error fetching
+// them is returned to the caller.
+func ExtractIntents(ctx context.Context, c client.Client, object client.Object) ([]v1alpha1.SecurityIntent, []v1alpha1.UnresolvedIntent, error) {
 	logger := log.FromContext(ctx)
 	var intents []v1alpha1.SecurityIntent
+	var unresolvedIntents []v1alpha1.UnresolvedIntent
 	var givenIntents []v1alpha1.MatchIntent
+	var intentSelector *v1alpha1.IntentSelector
 
 	switch obj := object.(type) {
 	case *v1alpha1.SecurityIntentBinding:
 		givenIntents = obj.Spec.Intents
+		intentSelector = obj.Spec.IntentSelector
 	case *v1alpha1.ClusterSecurityIntentBinding:
 		givenIntents = obj.Spec.Intents
+		intentSelector = obj.Spec.IntentSelector
 	}
 
 	for _, intent := range givenIntents {
 		var si v1alpha1.SecurityIntent
-		if err := c.Get(ctx, types.NamespacedName{Name: intent.Name}, &si); err != nil && apierrors.IsNotFound(err) {
-			logger.V(2).Info("failed to fetch SecurityIntent", "SecurityIntent.Name", intent.Name)
+		if err := c.Get(ctx, types.NamespacedName{Name: intent.Name}, &si); err != nil {
+			if apierrors.IsNotFound(err) {
+				logger.V(2).Info("failed to fetch SecurityIntent", "SecurityIntent.Name", intent.Name)
+				unresolvedIntents = append(unresolvedIntents, v1alpha1.UnresolvedIntent{
+					Name:   intent.Name,
+					Reason: ReasonNotFound,
+				})
+				continue
+			}
+			return nil, nil, err
+		}
+		intents = append(intents, si)
+	}
+
+	if intentSelector == nil {
+		return intents, unresolvedIntents, nil
+	}
+
+	var sis v1alpha1.SecurityIntentList
+	if err := c.List(ctx, &sis); err != nil {
+		return nil, nil, err
+	}
+	for _, si := range sis.Items {
+		if !MatchesSelector(intentSelector, si) {
+			continue
+		}
+		// Don't bind the same SecurityIntent twice when it is both referenced by
+		// name and selected.
+		if slices.ContainsFunc(intents, func(intent v1alpha1.SecurityIntent) bool {
+			return intent.Name == si.Name
+		}) {
 			continue
 		}
 		intents = append(intents, si)
 	}
 
-	return intents
+	return intents, unresolvedIntents, nil
+}
+
+// MatchesSelector reports whether the given SecurityIntent has all the tags and
+// labels of the given intent selector. A nil selector matches nothing.
+func MatchesSelector(intentSelector *v1alpha1.IntentSelector, si v1alpha1.SecurityIntent) bool {
+	if intentSelector == nil || (len(intentSelector.MatchTags) == 0 && len(intentSelector.MatchLabels) == 0) {
+		return false
+	}
+
+	for _, tag := range intentSelector.MatchTags {
+		if !slices.Contains(si.Spec.Intent.Tags, tag) {
+			return false
+		}
+	}
+
+	siLabels := si.GetLabels()
+	for key, value := range intentSelector.MatchLabels {
+		if existingValue, ok := siLabels[key]; !ok || existingValue != value {
+			return false
+		}
+	}
+
+	return true
 }
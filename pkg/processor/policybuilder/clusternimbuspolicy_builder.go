@@ -8,6 +8,8 @@ import (
 
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -16,28 +18,30 @@ import (
 	v1alpha1 "github.com/5GSEC/nimbus/api/v1alpha1"
 	processorerrors "github.com/5GSEC/nimbus/pkg/processor/errors"
 	"github.com/5GSEC/nimbus/pkg/processor/intentbinder"
+	"github.com/5GSEC/nimbus/pkg/tracing"
 )
 
 // BuildClusterNimbusPolicy generates a ClusterNimbusPolicy based on given
 // SecurityIntents and ClusterSecurityIntentBinding.
-func BuildClusterNimbusPolicy(ctx context.Context, logger logr.Logger, k8sClient client.Client, scheme *runtime.Scheme, csib v1alpha1.ClusterSecurityIntentBinding) (*v1alpha1.ClusterNimbusPolicy, error) {
+func BuildClusterNimbusPolicy(ctx context.Context, logger logr.Logger, k8sClient client.Client, scheme *runtime.Scheme, csib v1alpha1.ClusterSecurityIntentBinding) (_ *v1alpha1.ClusterNimbusPolicy, err error) {
+	ctx, span := tracing.Start(ctx, "ClusterNimbusPolicy.Build", trace.WithAttributes(
+		attribute.String("ClusterNimbusPolicy.Name", csib.Name),
+	))
+	defer func() { tracing.End(span, err) }()
 	logger.Info("Building ClusterNimbusPolicy")
-	intents := intentbinder.ExtractIntents(ctx, k8sClient, &csib)
+	intents, _, err := intentbinder.ExtractIntents(ctx, k8sClient, &csib)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch SecurityIntents")
+	}
 	if len(intents) == 0 {
 		logger.Info("ClusterNimbusPolicy creation aborted since no SecurityIntents found")
 		return nil, processorerrors.ErrSecurityIntentsNotFound
 	}
 
-	var nimbusRules []v1alpha1.NimbusRules
-	for _, intent := range intents {
-		nimbusRules = append(nimbusRules, v1alpha1.NimbusRules{
-			ID:          intent.Spec.Intent.ID,
-			Description: intent.Spec.Intent.Description,
-			Rule: v1alpha1.Rule{
-				RuleAction: intent.Spec.Intent.Action,
-				Params:     intent.Spec.Intent.Params,
-			},
-		})
+	nimbusRules, actionDecisions, err := buildNimbusRules(ctx, k8sClient, intents, "",
+		auditsBlockIntents(csib.Spec.Rollout, csib.Status.Rollout))
+	if err != nil {
+		return nil, err
 	}
 
 	clusterNp := &v1alpha1.ClusterNimbusPolicy{
@@ -51,12 +55,19 @@ func BuildClusterNimbusPolicy(ctx context.Context, logger logr.Logger, k8sClient
 			WorkloadSelector: csib.Spec.Selector.WorkloadSelector,
 			NimbusRules:      nimbusRules,
 		},
+		Status: v1alpha1.ClusterNimbusPolicyStatus{
+			ActionDecisions: actionDecisions,
+		},
 	}
 
 	if err := ctrl.SetControllerReference(&csib, clusterNp, scheme); err != nil {
 		return nil, errors.Wrap(err, "failed to set NimbusPolicy OwnerReference")
 	}
 
+	if err := resolveClusterConflicts(ctx, k8sClient, clusterNp, &csib); err != nil {
+		return nil, err
+	}
+
 	logger.Info("ClusterNimbusPolicy built successfully", "ClusterNimbusPolicy.Name", clusterNp.Name)
 	return clusterNp, nil
 }
@@ -11,13 +11,22 @@ import (
 
 	"github.com/google/cel-go/cel"
 	"github.com/google/cel-go/checker/decls"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/5GSEC/nimbus/pkg/tracing"
 )
 
 // ProcessCEL processes CEL expressions to generate matchLabels.
-func ProcessCEL(ctx context.Context, k8sClient client.Client, namespace string, expressions []string) (map[string]string, error) {
+func ProcessCEL(ctx context.Context, k8sClient client.Client, namespace string, expressions []string) (_ map[string]string, err error) {
+	ctx, span := tracing.Start(ctx, "CEL.Evaluate", trace.WithAttributes(
+		attribute.String("Namespace", namespace),
+		attribute.StringSlice("Expressions", expressions),
+	))
+	defer func() { tracing.End(span, err) }()
 	logger := log.FromContext(ctx)
 	logger.Info("Processing CEL expressions", "Namespace", namespace)
 
@@ -0,0 +1,241 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package policybuilder
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1 "github.com/5GSEC/nimbus/api/v1alpha1"
+	"github.com/5GSEC/nimbus/pkg/adapter/idpool"
+)
+
+// actionStrictness ranks the actions from the least to the most strict.
+var actionStrictness = map[string]int{
+	v1.ActionAllow:  0,
+	v1.ActionAudit:  1,
+	v1.ActionWarn:   2,
+	v1.ActionMutate: 3,
+	v1.ActionBlock:  4,
+}
+
+// nsBlackList lists the namespaces the cluster-wide policies never apply to,
+// as the controller never generates NimbusPolicies for them either.
+var nsBlackList = []string{"kube-system"}
+
+// decidedIntent is the action decided for an intent of a NimbusPolicy, along
+// with the scope of the binding the NimbusPolicy was generated for.
+type decidedIntent struct {
+	action    string
+	clustered bool
+}
+
+// winsOver reports whether the decided intent takes precedence over the other
+// one, and why. NimbusPolicies of SecurityIntentBindings take precedence over
+// the ones generated for ClusterSecurityIntentBindings, and the stricter
+// action wins between NimbusPolicies of the same scope.
+func (d decidedIntent) winsOver(other decidedIntent) (bool, string) {
+	if d.clustered != other.clustered {
+		return !d.clustered, v1.ConflictReasonNamespacedBinding
+	}
+	return actionStrictness[d.action] > actionStrictness[other.action], v1.ConflictReasonStricterAction
+}
+
+// resolveConflicts detects the intents of the given NimbusPolicy that other
+// NimbusPolicies in its namespace also bind, or bind incompatible intents of,
+// with an overlapping selector but a different action. Each conflicting rule
+// is aligned with the action that takes precedence, so that adapters enforce
+// the intent the same way on the workloads selected by both NimbusPolicies,
+// and the conflicts are recorded in the NimbusPolicy status. The
+// NimbusPolicies are compared on the actions decided for their intents, so
+// that both sides detect the conflict whichever one was aligned.
+func resolveConflicts(ctx context.Context, k8sClient client.Client, np *v1.NimbusPolicy, clustered bool) error {
+	var nps v1.NimbusPolicyList
+	if err := k8sClient.List(ctx, &nps, client.InNamespace(np.Namespace)); err != nil {
+		return fmt.Errorf("failed to list NimbusPolicies: %w", err)
+	}
+
+	decided := decidedActions(np.Spec.NimbusRules, np.Status.ActionDecisions)
+	for idx, rule := range np.Spec.NimbusRules {
+		own := decidedIntent{action: decided[rule.ID], clustered: clustered}
+		winner := own
+		var conflicts []v1.PolicyConflict
+
+		for _, other := range nps.Items {
+			if other.Name == np.Name || !selectorsOverlap(np.Spec.Selector, other.Spec.Selector) {
+				continue
+			}
+			var otherConflicts []v1.PolicyConflict
+			otherConflicts, winner = conflictsWith(rule.ID, own, winner, other)
+			conflicts = append(conflicts, otherConflicts...)
+		}
+
+		for i := range conflicts {
+			conflicts[i].ResolvedAction = winner.action
+		}
+
+		np.Spec.NimbusRules[idx].Rule.RuleAction = winner.action
+		np.Status.Conflicts = append(np.Status.Conflicts, conflicts...)
+	}
+
+	return nil
+}
+
+// resolveClusterConflicts detects the intents of the given ClusterNimbusPolicy
+// that the NimbusPolicies of other bindings take precedence over, in the
+// namespaces both select with overlapping workload selectors. The cluster-wide
+// policies can't enforce an intent with a different action per namespace, so
+// these namespaces are excluded for the intent in the ClusterNimbusPolicy,
+// leaving it to the winning NimbusPolicy there, and the conflicts are recorded
+// in the ClusterNimbusPolicy status.
+func resolveClusterConflicts(ctx context.Context, k8sClient client.Client, cwnp *v1.ClusterNimbusPolicy, csib *v1.ClusterSecurityIntentBinding) error {
+	var nps v1.NimbusPolicyList
+	if err := k8sClient.List(ctx, &nps); err != nil {
+		return fmt.Errorf("failed to list NimbusPolicies: %w", err)
+	}
+
+	decided := decidedActions(cwnp.Spec.NimbusRules, cwnp.Status.ActionDecisions)
+	for _, rule := range cwnp.Spec.NimbusRules {
+		own := decidedIntent{action: decided[rule.ID], clustered: true}
+
+		for _, other := range nps.Items {
+			if metav1.IsControlledBy(&other, csib) || !selectsNamespace(cwnp.Spec.NsSelector, other.Namespace) ||
+				!selectorsOverlap(cwnp.Spec.WorkloadSelector, other.Spec.Selector) {
+				continue
+			}
+			conflicts, winner := conflictsWith(rule.ID, own, own, other)
+			if winner == own {
+				continue
+			}
+
+			for i := range conflicts {
+				conflicts[i].Namespace = other.Namespace
+				conflicts[i].ResolvedAction = winner.action
+			}
+			if cwnp.Spec.ExcludedNamespaces == nil {
+				cwnp.Spec.ExcludedNamespaces = make(map[string][]string)
+			}
+			if !slices.Contains(cwnp.Spec.ExcludedNamespaces[rule.ID], other.Namespace) {
+				cwnp.Spec.ExcludedNamespaces[rule.ID] = append(cwnp.Spec.ExcludedNamespaces[rule.ID], other.Namespace)
+			}
+			cwnp.Status.Conflicts = append(cwnp.Status.Conflicts, conflicts...)
+		}
+		// Keep the spec stable across reconciliations.
+		slices.Sort(cwnp.Spec.ExcludedNamespaces[rule.ID])
+	}
+
+	return nil
+}
+
+// conflictsWith returns the conflicts of an intent, decided as own, with the
+// same and the incompatible intents the other NimbusPolicy binds with a
+// different action, along with the decided intent taking precedence over them
+// and the given winner.
+func conflictsWith(id string, own, winner decidedIntent, other v1.NimbusPolicy) ([]v1.PolicyConflict, decidedIntent) {
+	owner := metav1.GetControllerOf(&other)
+	otherActions := decidedActions(other.Spec.NimbusRules, other.Status.ActionDecisions)
+
+	var conflicts []v1.PolicyConflict
+	for _, otherID := range append([]string{id}, idpool.IncompatibleIDs(id)...) {
+		otherAction, ok := otherActions[otherID]
+		if !ok || otherAction == own.action {
+			continue
+		}
+
+		theirs := decidedIntent{
+			action:    otherAction,
+			clustered: owner != nil && owner.Kind == "ClusterSecurityIntentBinding",
+		}
+		conflict := v1.PolicyConflict{
+			ID:                id,
+			NimbusPolicy:      other.Name,
+			Action:            own.action,
+			ConflictingAction: otherAction,
+		}
+		if otherID != id {
+			conflict.ConflictingID = otherID
+		}
+		if owner != nil {
+			conflict.Binding = owner.Kind + "/" + owner.Name
+		}
+		_, conflict.Reason = own.winsOver(theirs)
+		if wins, _ := theirs.winsOver(winner); wins {
+			winner = theirs
+		}
+		conflicts = append(conflicts, conflict)
+	}
+	return conflicts, winner
+}
+
+// decidedActions returns the actions decided for the intents of a Nimbus
+// policy, before any conflict was resolved. The rule actions are used for
+// policies that don't have the decisions recorded yet.
+func decidedActions(rules []v1.NimbusRules, decisions []v1.ActionDecision) map[string]string {
+	actions := make(map[string]string)
+	for _, rule := range rules {
+		actions[rule.ID] = rule.Rule.RuleAction
+	}
+	for _, decision := range decisions {
+		actions[decision.ID] = decision.EffectiveAction
+	}
+	return actions
+}
+
+// selectorsOverlap reports whether some workload could be selected by both
+// label selectors, that is whether they don't require different values for
+// the same label, and whether the labels of each one meet the expressions of
+// the other one on the same keys. Expressions on other keys are assumed to be
+// met by some workload.
+func selectorsOverlap(a, b v1.LabelSelector) bool {
+	for key, value := range a.MatchLabels {
+		if other, ok := b.MatchLabels[key]; ok && other != value {
+			return false
+		}
+	}
+	return labelsMeetExpressions(a.MatchLabels, b.MatchExpressions) &&
+		labelsMeetExpressions(b.MatchLabels, a.MatchExpressions)
+}
+
+// selectsNamespace reports whether the namespace selector of a
+// ClusterSecurityIntentBinding selects the given namespace.
+func selectsNamespace(selector v1.NamespaceSelector, namespace string) bool {
+	switch {
+	case slices.Contains(nsBlackList, namespace):
+		return false
+	case len(selector.ExcludeNames) > 0:
+		return !slices.Contains(selector.ExcludeNames, namespace)
+	case len(selector.MatchNames) == 1 && selector.MatchNames[0] == "*":
+		return true
+	default:
+		return slices.Contains(selector.MatchNames, namespace)
+	}
+}
+
+// labelsMeetExpressions reports whether the given labels meet the given
+// expressions on their keys.
+func labelsMeetExpressions(labels map[string]string, expressions []metav1.LabelSelectorRequirement) bool {
+	for _, expression := range expressions {
+		value, ok := labels[expression.Key]
+		if !ok {
+			continue
+		}
+		switch expression.Operator {
+		case metav1.LabelSelectorOpIn:
+			if !slices.Contains(expression.Values, value) {
+				return false
+			}
+		case metav1.LabelSelectorOpNotIn:
+			if slices.Contains(expression.Values, value) {
+				return false
+			}
+		case metav1.LabelSelectorOpDoesNotExist:
+			return false
+		}
+	}
+	return true
+}
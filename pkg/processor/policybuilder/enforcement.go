@@ -0,0 +1,187 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+package policybuilder
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1 "github.com/5GSEC/nimbus/api/v1alpha1"
+	"github.com/5GSEC/nimbus/pkg/adapter/idpool"
+)
+
+// actionResolver decides the effective action of intents according to the
+// ClusterEnforcementPolicies in the cluster.
+type actionResolver struct {
+	policies []v1.ClusterEnforcementPolicy
+
+	// namespace the intents are bound in, empty for cluster-wide policies.
+	namespace       string
+	namespaceLabels labels.Set
+}
+
+func newActionResolver(ctx context.Context, k8sClient client.Client, namespace string) (*actionResolver, error) {
+	var policies v1.ClusterEnforcementPolicyList
+	if err := k8sClient.List(ctx, &policies); err != nil {
+		return nil, fmt.Errorf("failed to list ClusterEnforcementPolicies: %w", err)
+	}
+	slices.SortFunc(policies.Items, func(a, b v1.ClusterEnforcementPolicy) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	resolver := &actionResolver{
+		policies:  policies.Items,
+		namespace: namespace,
+	}
+
+	if namespace != "" && resolver.needsNamespaceLabels() {
+		var ns corev1.Namespace
+		if err := k8sClient.Get(ctx, types.NamespacedName{Name: namespace}, &ns); err != nil {
+			return nil, fmt.Errorf("failed to fetch Namespace %s: %w", namespace, err)
+		}
+		resolver.namespaceLabels = ns.Labels
+	}
+
+	return resolver, nil
+}
+
+func (r *actionResolver) needsNamespaceLabels() bool {
+	for _, policy := range r.policies {
+		for _, rule := range policy.Spec.Rules {
+			if rule.NamespaceSelector != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolve returns the decision on the effective action of the given intent.
+// The first matching rule, going through the policies in name order, decides
+// the effective action. The requested action is kept when no rule matches.
+// Rules setting an action that none of the engines supporting the intent can
+// enforce it with are rejected, so that they don't drop its enforcement, and
+// the next matching rule decides.
+func (r *actionResolver) resolve(intent v1.SecurityIntent) (v1.ActionDecision, error) {
+	decision := v1.ActionDecision{
+		ID:              intent.Spec.Intent.ID,
+		Severity:        intent.Spec.Intent.Severity,
+		RequestedAction: intent.Spec.Intent.Action,
+		EffectiveAction: intent.Spec.Intent.Action,
+	}
+
+	for _, policy := range r.policies {
+		for idx, rule := range policy.Spec.Rules {
+			matched, err := r.matches(rule, intent)
+			if err != nil {
+				return decision, fmt.Errorf("invalid rule %d of ClusterEnforcementPolicy %s: %w", idx, policy.Name, err)
+			}
+			if !matched {
+				continue
+			}
+			decidedBy := fmt.Sprintf("%s/rules/%d", policy.Name, idx)
+			if !idpool.IsActionSupported(intent.Spec.Intent.ID, rule.Action) {
+				if decision.RejectedBy == "" {
+					decision.RejectedAction = rule.Action
+					decision.RejectedBy = decidedBy
+					decision.RejectionReason = fmt.Sprintf("%s can't be enforced with %s by %s", intent.Spec.Intent.ID,
+						rule.Action, strings.Join(idpool.EnginesSupporting(intent.Spec.Intent.ID), ", "))
+				}
+				continue
+			}
+			decision.EffectiveAction = rule.Action
+			decision.DecidedBy = decidedBy
+			return decision, nil
+		}
+	}
+
+	return decision, nil
+}
+
+func (r *actionResolver) matches(rule v1.EnforcementRule, intent v1.SecurityIntent) (bool, error) {
+	if len(rule.Severities) > 0 && !slices.ContainsFunc(rule.Severities, func(severity string) bool {
+		return strings.EqualFold(severity, intent.Spec.Intent.Severity)
+	}) {
+		return false, nil
+	}
+
+	if len(rule.Namespaces) == 0 && rule.NamespaceSelector == nil {
+		return true, nil
+	}
+
+	// Rules restricted to namespaces don't apply to cluster-wide policies.
+	if r.namespace == "" {
+		return false, nil
+	}
+
+	if slices.Contains(rule.Namespaces, r.namespace) {
+		return true, nil
+	}
+
+	if rule.NamespaceSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(rule.NamespaceSelector)
+		if err != nil {
+			return false, err
+		}
+		return selector.Matches(r.namespaceLabels), nil
+	}
+
+	return false, nil
+}
+
+// RolloutDecision is the DecidedBy of the decisions auditing a Block intent
+// during the rollout of its binding.
+const RolloutDecision = "rollout"
+
+// auditsBlockIntents reports whether the rollout of a binding audits its Block
+// intents, which is the case until it's promoted.
+func auditsBlockIntents(strategy *v1.RolloutStrategy, status *v1.RolloutStatus) bool {
+	return strategy != nil && (status == nil || status.Phase != v1.RolloutPhasePromoted)
+}
+
+// buildNimbusRules builds the rules of a NimbusPolicy or ClusterNimbusPolicy
+// from the given SecurityIntents, with their effective action decided by the
+// ClusterEnforcementPolicies, along with the decisions taken. Block is
+// downgraded to Audit when auditBlock is set, while the binding is rolled out,
+// for the intents that can be audited.
+func buildNimbusRules(ctx context.Context, k8sClient client.Client, intents []v1.SecurityIntent, namespace string, auditBlock bool) ([]v1.NimbusRules, []v1.ActionDecision, error) {
+	resolver, err := newActionResolver(ctx, k8sClient, namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var nimbusRules []v1.NimbusRules
+	var decisions []v1.ActionDecision
+	for _, intent := range intents {
+		decision, err := resolver.resolve(intent)
+		if err != nil {
+			return nil, nil, err
+		}
+		// Intents that can't be audited are enforced with Block right away.
+		if auditBlock && decision.EffectiveAction == v1.ActionBlock && idpool.IsActionSupported(decision.ID, v1.ActionAudit) {
+			decision.EffectiveAction = v1.ActionAudit
+			decision.DecidedBy = RolloutDecision
+		}
+		decisions = append(decisions, decision)
+
+		nimbusRules = append(nimbusRules, v1.NimbusRules{
+			ID:          intent.Spec.Intent.ID,
+			Description: intent.Spec.Intent.Description,
+			Rule: v1.Rule{
+				RuleAction: decision.EffectiveAction,
+				Params:     intent.Spec.Intent.Params,
+			},
+		})
+	}
+
+	return nimbusRules, decisions, nil
+}
@@ -9,6 +9,8 @@ import (
 
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -17,29 +19,32 @@ import (
 	v1 "github.com/5GSEC/nimbus/api/v1alpha1"
 	processorerrors "github.com/5GSEC/nimbus/pkg/processor/errors"
 	"github.com/5GSEC/nimbus/pkg/processor/intentbinder"
+	"github.com/5GSEC/nimbus/pkg/tracing"
 )
 
 // BuildNimbusPolicy generates a NimbusPolicy based on given
 // SecurityIntentBinding.
-func BuildNimbusPolicy(ctx context.Context, logger logr.Logger, k8sClient client.Client, scheme *runtime.Scheme, sib v1.SecurityIntentBinding) (*v1.NimbusPolicy, error) {
+func BuildNimbusPolicy(ctx context.Context, logger logr.Logger, k8sClient client.Client, scheme *runtime.Scheme, sib v1.SecurityIntentBinding) (_ *v1.NimbusPolicy, err error) {
+	ctx, span := tracing.Start(ctx, "NimbusPolicy.Build", trace.WithAttributes(
+		attribute.String("NimbusPolicy.Name", sib.Name),
+		attribute.String("NimbusPolicy.Namespace", sib.Namespace),
+	))
+	defer func() { tracing.End(span, err) }()
 	logger.Info("Building NimbusPolicy")
 
-	intents := intentbinder.ExtractIntents(ctx, k8sClient, &sib)
+	intents, _, err := intentbinder.ExtractIntents(ctx, k8sClient, &sib)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch SecurityIntents")
+	}
 	if len(intents) == 0 {
 		logger.Info("NimbusPolicy creation aborted since no SecurityIntents found")
 		return nil, processorerrors.ErrSecurityIntentsNotFound
 	}
 
-	var nimbusRules []v1.NimbusRules
-	for _, intent := range intents {
-		nimbusRules = append(nimbusRules, v1.NimbusRules{
-			ID:          intent.Spec.Intent.ID,
-			Description: intent.Spec.Intent.Description,
-			Rule: v1.Rule{
-				RuleAction: intent.Spec.Intent.Action,
-				Params:     intent.Spec.Intent.Params,
-			},
-		})
+	nimbusRules, actionDecisions, err := buildNimbusRules(ctx, k8sClient, intents, sib.Namespace,
+		auditsBlockIntents(sib.Spec.Rollout, sib.Status.Rollout))
+	if err != nil {
+		return nil, err
 	}
 
 	matchLabels, err := extractSelector(ctx, k8sClient, sib.Namespace, sib.Spec.Selector.WorkloadSelector, sib.Spec.CEL)
@@ -55,10 +60,18 @@ func BuildNimbusPolicy(ctx context.Context, logger logr.Logger, k8sClient client
 		},
 		Spec: v1.NimbusPolicySpec{
 			Selector: v1.LabelSelector{
-				MatchLabels: matchLabels,
+				MatchLabels:      matchLabels,
+				MatchExpressions: sib.Spec.Selector.WorkloadSelector.MatchExpressions,
 			},
 			NimbusRules: nimbusRules,
 		},
+		Status: v1.NimbusPolicyStatus{
+			ActionDecisions: actionDecisions,
+		},
+	}
+
+	if err = resolveConflicts(ctx, k8sClient, nimbusPolicy, false); err != nil {
+		return nil, err
 	}
 
 	if err = ctrl.SetControllerReference(&sib, nimbusPolicy, scheme); err != nil {
@@ -96,25 +109,27 @@ func extractSelector(ctx context.Context, k8sClient client.Client, namespace str
 }
 
 // BuildNimbusPolicyFromClusterBinding generates a NimbusPolicy based on given ClusterSecurityIntentBinding.
-func BuildNimbusPolicyFromClusterBinding(ctx context.Context, logger logr.Logger, k8sClient client.Client, scheme *runtime.Scheme, csib v1.ClusterSecurityIntentBinding, ns string) (*v1.NimbusPolicy, error) {
+func BuildNimbusPolicyFromClusterBinding(ctx context.Context, logger logr.Logger, k8sClient client.Client, scheme *runtime.Scheme, csib v1.ClusterSecurityIntentBinding, ns string) (_ *v1.NimbusPolicy, err error) {
+	ctx, span := tracing.Start(ctx, "NimbusPolicy.Build", trace.WithAttributes(
+		attribute.String("NimbusPolicy.Name", csib.Name),
+		attribute.String("NimbusPolicy.Namespace", ns),
+	))
+	defer func() { tracing.End(span, err) }()
 	logger.Info("Building NimbusPolicy")
 
-	intents := intentbinder.ExtractIntents(ctx, k8sClient, &csib)
+	intents, _, err := intentbinder.ExtractIntents(ctx, k8sClient, &csib)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch SecurityIntents")
+	}
 	if len(intents) == 0 {
 		logger.Info("NimbusPolicy creation aborted since no SecurityIntents found")
 		return nil, processorerrors.ErrSecurityIntentsNotFound
 	}
 
-	var nimbusRules []v1.NimbusRules
-	for _, intent := range intents {
-		nimbusRules = append(nimbusRules, v1.NimbusRules{
-			ID:          intent.Spec.Intent.ID,
-			Description: intent.Spec.Intent.Description,
-			Rule: v1.Rule{
-				RuleAction: intent.Spec.Intent.Action,
-				Params:     intent.Spec.Intent.Params,
-			},
-		})
+	nimbusRules, actionDecisions, err := buildNimbusRules(ctx, k8sClient, intents, ns,
+		auditsBlockIntents(csib.Spec.Rollout, csib.Status.Rollout))
+	if err != nil {
+		return nil, err
 	}
 
 	// set the namespace to the parameter passed
@@ -127,10 +142,18 @@ func BuildNimbusPolicyFromClusterBinding(ctx context.Context, logger logr.Logger
 		},
 		Spec: v1.NimbusPolicySpec{
 			Selector: v1.LabelSelector{
-				MatchLabels: csib.Spec.Selector.WorkloadSelector.MatchLabels,
+				MatchLabels:      csib.Spec.Selector.WorkloadSelector.MatchLabels,
+				MatchExpressions: csib.Spec.Selector.WorkloadSelector.MatchExpressions,
 			},
 			NimbusRules: nimbusRules,
 		},
+		Status: v1.NimbusPolicyStatus{
+			ActionDecisions: actionDecisions,
+		},
+	}
+
+	if err := resolveConflicts(ctx, k8sClient, nimbusPolicy, true); err != nil {
+		return nil, err
 	}
 
 	if err := ctrl.SetControllerReference(&csib, nimbusPolicy, scheme); err != nil {
@@ -0,0 +1,168 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2023 Authors of Nimbus
+
+// Package tracing sets up the OpenTelemetry tracing of the operator and the
+// adapters, and carries the trace context from the bindings to the policies of
+// the security engines through the annotations of the NimbusPolicies and
+// ClusterNimbusPolicies.
+package tracing
+
+import (
+	"context"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// TraceParentAnnotation holds, in the W3C Trace Context format, the span
+	// of the reconciliation that last changed a NimbusPolicy or
+	// ClusterNimbusPolicy.
+	TraceParentAnnotation = "intent.security.nimbus.com/traceparent"
+	// TraceStateAnnotation holds the vendor specific trace state that goes
+	// along with the TraceParentAnnotation.
+	TraceStateAnnotation = "intent.security.nimbus.com/tracestate"
+
+	instrumentationName = "github.com/5GSEC/nimbus"
+)
+
+var propagator = propagation.TraceContext{}
+
+// Setup exports the spans of the given service via OTLP over gRPC, to the
+// endpoint set in the standard OTEL_EXPORTER_OTLP_ENDPOINT or
+// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT environment variables. Tracing is
+// disabled when none of them is set. The returned function flushes the
+// pending spans and must be called before exiting.
+func Setup(ctx context.Context, serviceName string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagator)
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracegrpc.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start starts a span with the given name, child of the span in the context.
+func Start(ctx context.Context, spanName string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, spanName, opts...)
+}
+
+// End records the given error, if any, on the span and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Trace runs fn within a span with the given name, child of the span in the
+// context, and records the error it returns.
+func Trace(ctx context.Context, spanName string, fn func(context.Context) error, opts ...trace.SpanStartOption) error {
+	ctx, span := Start(ctx, spanName, opts...)
+	err := fn(ctx)
+	End(span, err)
+	return err
+}
+
+// WithObject sets the name and the namespace of the given object of the given
+// kind as attributes of the span.
+func WithObject(kind string, obj metav1.Object) trace.SpanStartEventOption {
+	return trace.WithAttributes(
+		attribute.String(kind+".Name", obj.GetName()),
+		attribute.String(kind+".Namespace", obj.GetNamespace()),
+	)
+}
+
+// Inject writes the trace context of the given context to the annotations of
+// the object.
+func Inject(ctx context.Context, obj metav1.Object) {
+	propagator.Inject(ctx, annotationCarrier{obj})
+}
+
+// Extract returns a copy of the given context carrying the trace context read
+// from the annotations of the object, so that the spans started from it
+// continue the trace of the reconciliation that changed the object.
+func Extract(ctx context.Context, obj metav1.Object) context.Context {
+	return propagator.Extract(ctx, annotationCarrier{obj})
+}
+
+// Preserve copies the trace context annotations of an object to its new
+// version.
+func Preserve(from, to metav1.Object) {
+	carrier := annotationCarrier{to}
+	for key, annotation := range annotations {
+		if value, ok := from.GetAnnotations()[annotation]; ok {
+			carrier.Set(key, value)
+		}
+	}
+}
+
+// LinkTo returns a link to the span carried by the annotations of the object,
+// for the spans covering several objects.
+func LinkTo(obj metav1.Object) trace.Link {
+	return trace.LinkFromContext(Extract(context.Background(), obj))
+}
+
+var annotations = map[string]string{
+	"traceparent": TraceParentAnnotation,
+	"tracestate":  TraceStateAnnotation,
+}
+
+// annotationCarrier carries the W3C Trace Context headers in the annotations
+// of an object.
+type annotationCarrier struct {
+	obj metav1.Object
+}
+
+func (c annotationCarrier) Get(key string) string {
+	return c.obj.GetAnnotations()[annotations[key]]
+}
+
+func (c annotationCarrier) Set(key, value string) {
+	annotation, ok := annotations[key]
+	if !ok {
+		return
+	}
+	objAnnotations := c.obj.GetAnnotations()
+	if objAnnotations == nil {
+		objAnnotations = make(map[string]string)
+	}
+	objAnnotations[annotation] = value
+	c.obj.SetAnnotations(objAnnotations)
+}
+
+func (c annotationCarrier) Keys() []string {
+	var keys []string
+	for key, annotation := range annotations {
+		if _, ok := c.obj.GetAnnotations()[annotation]; ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
@@ -10,7 +10,8 @@ fi
 TAG=$1
 DEPLOYMENT_ROOT_DIR="deployments"
 DIRECTORIES=("${DEPLOYMENT_ROOT_DIR}/nimbus" "${DEPLOYMENT_ROOT_DIR}/nimbus-k8tls" \
-  "${DEPLOYMENT_ROOT_DIR}/nimbus-kubearmor" "${DEPLOYMENT_ROOT_DIR}/nimbus-kyverno" "${DEPLOYMENT_ROOT_DIR}/nimbus-netpol")
+  "${DEPLOYMENT_ROOT_DIR}/nimbus-kubearmor" "${DEPLOYMENT_ROOT_DIR}/nimbus-kyverno" "${DEPLOYMENT_ROOT_DIR}/nimbus-netpol" \
+  "${DEPLOYMENT_ROOT_DIR}/nimbus-cilium" "${DEPLOYMENT_ROOT_DIR}/nimbus-calico")
 
 echo "Updating tag to $TAG"
 for directory in "${DIRECTORIES[@]}"; do